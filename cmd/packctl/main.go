@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/klausborkowski/calculator/config"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+const usage = `Usage: packctl <command> [flags]
+
+Commands:
+  simulate   replay order sizes against the current and a candidate catalog
+  recommend  search the pack sizes that suit a demand histogram best
+
+Run packctl <command> -h for the flags of a command.
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("packctl: ")
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "simulate":
+		err = runSimulate(args)
+	case "recommend":
+		err = runRecommend(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newApp builds the application like the server does. The database is only connected when
+// withRepository is set, commands working on catalogs given on the command line do not need it.
+func newApp(withRepository bool) (*app.App, func(), error) {
+	// Try to load .env file, but don't fail if it doesn't exist
+	_ = godotenv.Load()
+	cfg := config.LoadConfig()
+
+	solver, err := app.SolverByName(cfg.Solver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("configure solver: %w", err)
+	}
+	policy, err := app.PolicyByName(cfg.Policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("configure policy: %w", err)
+	}
+	opts := []app.Option{
+		app.WithSolver(solver),
+		app.WithPolicy(policy),
+		app.WithMaxPackSize(cfg.MaxPackSize),
+		app.WithBudget(app.Budget{Timeout: cfg.CalculationTimeout, MaxMemory: int64(cfg.CalculationMemoryMB) << 20}),
+	}
+	if !withRepository {
+		return app.NewApp(nil, opts...), func() {}, nil
+	}
+
+	repository, err := repo.NewRepository(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("initialize repository: %w", err)
+	}
+	closeRepository := func() {
+		if err := repository.Close(); err != nil {
+			log.Printf("Error closing repository: %v", err)
+		}
+	}
+	return app.NewApp(repository, opts...), closeRepository, nil
+}
+
+// parseSizes parses a comma separated list of pack sizes
+func parseSizes(list string) ([]app.Package, error) {
+	var packages []app.Package
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		size, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a pack size", field)
+		}
+		packages = append(packages, app.Package{Size: size})
+	}
+	return packages, nil
+}
+
+// readPackages reads packages from a JSON file, in the format of GET /catalog
+func readPackages(path string) ([]app.Package, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var packages []app.Package
+	if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return packages, nil
+}
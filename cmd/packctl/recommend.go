@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// runRecommend searches the pack sizes that suit a demand best, reporting progress on stderr.
+// An interrupt stops the search and prints the best sizes found so far.
+func runRecommend(args []string) error {
+	fs := flag.NewFlagSet("recommend", flag.ExitOnError)
+	demand := fs.String("demand", "", "order quantities with their number of orders, e.g. 250:12,500:3")
+	demandFile := fs.String("demand-file", "", "file with an order quantity and optionally its number of orders per line; - reads stdin")
+	sizes := fs.Int("sizes", 3, "number of pack sizes to recommend")
+	minSize := fs.Int("min", 0, "smallest pack size to consider, 1 when omitted")
+	maxSize := fs.Int("max", 0, "largest pack size to consider, the largest order quantity when omitted")
+	packWeight := fs.Float64("pack-weight", 0, "over-shipped items one more pack per order is worth, 0 minimises the overshoot first")
+	quiet := fs.Bool("quiet", false, "do not report progress")
+	out := fs.String("out", "", "file to write the recommendation to, stdout when omitted")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: packctl recommend -demand-file demand.csv -sizes 3 [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	req := app.RecommendationRequest{Sizes: *sizes, MinSize: *minSize, MaxSize: *maxSize, PackWeight: *packWeight}
+	var err error
+	if req.Demand, err = demandFlag(*demand, *demandFile); err != nil {
+		return err
+	}
+
+	application, closeApp, err := newApp(false)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var last app.RecommendationProgress
+	recommendation, err := application.RecommendCatalog(ctx, req, func(p app.RecommendationProgress) {
+		last = p
+		if !*quiet {
+			printProgress(p)
+		}
+	})
+	if !*quiet {
+		fmt.Fprintln(os.Stderr)
+	}
+	if errors.Is(err, context.Canceled) && last.Best != nil {
+		return fmt.Errorf("interrupted after %d catalogs, best so far %v: expected overshoot %.2f, packs %.2f",
+			last.Evaluated, last.Best, last.ExpectedOvershoot, last.ExpectedPacks)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(recommendation)
+}
+
+// printProgress overwrites the progress line on stderr
+func printProgress(p app.RecommendationProgress) {
+	line := fmt.Sprintf("%s: %d catalogs", p.Phase, p.Done)
+	if p.Total > 0 {
+		line = fmt.Sprintf("%s: %d/%d catalogs (%d%%)", p.Phase, p.Done, p.Total, 100*p.Done/p.Total)
+	}
+	if p.Best != nil {
+		line += fmt.Sprintf(", best %v: overshoot %.2f, packs %.2f", p.Best, p.ExpectedOvershoot, p.ExpectedPacks)
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+}
+
+// demandFlag returns the demand given inline and in a file
+func demandFlag(list, file string) ([]app.DemandBucket, error) {
+	var demand []app.DemandBucket
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		quantity, count, found := strings.Cut(field, ":")
+		bucket := app.DemandBucket{Count: 1}
+		var err error
+		if bucket.Quantity, err = strconv.Atoi(quantity); err != nil {
+			return nil, fmt.Errorf("%q is not an order quantity", quantity)
+		}
+		if found {
+			if bucket.Count, err = strconv.Atoi(count); err != nil {
+				return nil, fmt.Errorf("%q is not a number of orders", count)
+			}
+		}
+		demand = append(demand, bucket)
+	}
+	if file == "" {
+		return demand, nil
+	}
+
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	fromFile, err := app.ParseDemand(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return append(demand, fromFile...), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// runSimulate replays order sizes against the current and a candidate catalog and writes the
+// report as JSON or CSV
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	candidate := fs.String("candidate", "", "candidate pack sizes, comma separated")
+	candidateFile := fs.String("candidate-file", "", "JSON file with the candidate packages, including prices")
+	current := fs.String("current", "", "current pack sizes, comma separated; the stored catalog of the product when omitted")
+	currentFile := fs.String("current-file", "", "JSON file with the current packages, including prices")
+	orders := fs.String("orders", "", "order quantities, comma separated")
+	ordersFile := fs.String("orders-file", "", "file with an order quantity per line, or in the first CSV column; - reads stdin")
+	product := fs.Int("product", 0, "product whose stored catalog is the current one, the default product when omitted")
+	mode := fs.String("mode", "", "first objective of the calculations: items, packs or cost; that of the policy when omitted")
+	policy := fs.String("policy", "", "policy choosing the packs, the configured one when omitted")
+	solver := fs.String("solver", "", "packing strategy, the configured one when omitted")
+	format := fs.String("format", "json", "report format: json or csv")
+	out := fs.String("out", "", "file to write the report to, stdout when omitted")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: packctl simulate -candidate 300,1000 -orders-file orders.csv [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, expected json or csv", *format)
+	}
+
+	req := app.SimulationRequest{ProductID: *product, Mode: *mode, Policy: *policy, Solver: *solver}
+	var err error
+	if req.Candidate, err = catalogFlag("candidate", *candidate, *candidateFile); err != nil {
+		return err
+	}
+	if req.Current, err = catalogFlag("current", *current, *currentFile); err != nil {
+		return err
+	}
+	if req.Orders, err = ordersFlag(*orders, *ordersFile); err != nil {
+		return err
+	}
+
+	application, closeApp, err := newApp(req.Current == nil)
+	if err != nil {
+		return err
+	}
+	defer closeApp()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := application.Simulate(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "csv" {
+		return report.WriteCSV(w)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// catalogFlag returns the packages given by a list of sizes or a JSON file, nil when neither is set
+func catalogFlag(name, sizes, file string) ([]app.Package, error) {
+	switch {
+	case sizes != "" && file != "":
+		return nil, fmt.Errorf("-%s and -%s-file cannot be combined", name, name)
+	case file != "":
+		return readPackages(file)
+	case sizes != "":
+		return parseSizes(sizes)
+	default:
+		return nil, nil
+	}
+}
+
+// ordersFlag returns the order quantities given inline and in a file
+func ordersFlag(list, file string) ([]int, error) {
+	var orders []int
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		order, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an order quantity", field)
+		}
+		orders = append(orders, order)
+	}
+	if file == "" {
+		return orders, nil
+	}
+
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	fromFile, err := app.ParseOrderSizes(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return append(orders, fromFile...), nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -34,9 +35,47 @@ func main() {
 		}
 	}()
 
-	application := app.NewApp(repository)
+	solver, err := app.SolverByName(cfg.Solver)
+	if err != nil {
+		log.Fatalf("Failed to configure solver: %v", err)
+	}
+	policy, err := app.PolicyByName(cfg.Policy)
+	if err != nil {
+		log.Fatalf("Failed to configure policy: %v", err)
+	}
+
+	if err := app.CheckPreference(cfg.SplitPreference); err != nil {
+		log.Fatalf("Failed to configure split preference: %v", err)
+	}
+
+	var rates []app.RateTable
+	if cfg.RatesDir != "" {
+		if rates, err = app.LoadRateTables(cfg.RatesDir); err != nil {
+			log.Fatalf("Failed to load carrier rate tables: %v", err)
+		}
+		log.Printf("Loaded %d carrier rate tables from %s", len(rates), cfg.RatesDir)
+	}
+
+	budget := app.Budget{Timeout: cfg.CalculationTimeout, MaxMemory: int64(cfg.CalculationMemoryMB) << 20}
+	application := app.NewApp(repository,
+		app.WithSolver(solver),
+		app.WithPolicy(policy),
+		app.WithMaxPackSize(cfg.MaxPackSize),
+		app.WithBudget(budget),
+		app.WithBatchWorkers(cfg.BatchWorkers),
+		app.WithRateTables(rates),
+		app.WithPreference(cfg.SplitPreference),
+		app.WithHistoryRetention(cfg.HistoryRetention),
+	)
 	handler := api.NewHandler(application)
 
+	go func() {
+		if err := application.RunJobs(context.Background(), cfg.JobWorkers); err != nil {
+			log.Printf("Error running jobs: %v", err)
+		}
+	}()
+	go application.RunHistoryPruning(context.Background())
+
 	log.Printf("Starting server on :%s", cfg.Port)
 	if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.Port), handler.Router()); err != nil {
 		log.Fatalf("Server failed: %v", err)
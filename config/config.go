@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
@@ -11,11 +12,28 @@ type Config struct {
 	Port            string `env:"PORT" envDefault:"8080"`
 	LogLevel        string `env:"LOG_LEVEL" envDefault:"info"`
 	PackagesDefault []int  `env:"PACKAGES"`
+	Solver          string `env:"SOLVER" envDefault:"dp"`
+	Policy          string `env:"POLICY" envDefault:"items-first"`
+	MaxPackSize     int    `env:"MAX_PACK_SIZE" envDefault:"1000000"`
 	DBHost          string `env:"DB_HOST" envDefault:"localhost"`
 	DBPort          string `env:"DB_PORT" envDefault:"5432"`
 	DBUser          string `env:"DB_USER" envDefault:"calculator"`
 	DBPassword      string `env:"DB_PASSWORD" envDefault:"calculator"`
 	DBName          string `env:"DB_NAME" envDefault:"calculator"`
+
+	// CalculationTimeout and CalculationMemoryMB are the budget of a single calculation, 0 for none
+	CalculationTimeout  time.Duration `env:"CALCULATION_TIMEOUT" envDefault:"10s"`
+	CalculationMemoryMB int           `env:"CALCULATION_MEMORY_MB" envDefault:"512"`
+	// BatchWorkers is the number of orders of a batch calculated at the same time, 0 for one per CPU
+	BatchWorkers int `env:"BATCH_WORKERS" envDefault:"0"`
+	// JobWorkers is the number of background jobs calculated at the same time
+	JobWorkers int `env:"JOB_WORKERS" envDefault:"1"`
+	// RatesDir is the directory of the carrier rate tables, one JSON file per carrier, none when empty
+	RatesDir string `env:"RATES_DIR"`
+	// SplitPreference is how the split mode splits orders across the warehouses unless a request asks otherwise
+	SplitPreference string `env:"SPLIT_PREFERENCE" envDefault:"nearest"`
+	// HistoryRetention is how long calculations are kept in the calculation history, 0 for ever
+	HistoryRetention time.Duration `env:"HISTORY_RETENTION" envDefault:"720h"`
 }
 
 func LoadConfig() *Config {
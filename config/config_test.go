@@ -22,6 +22,8 @@ func TestLoadConfig(t *testing.T) {
 		Port:            "9090",
 		LogLevel:        "debug",
 		PackagesDefault: []int{1, 2, 3},
+		Solver:          "dp",
+		Policy:          "items-first",
 	}
 
 	if cfg.Port != expected.Port {
@@ -32,6 +34,14 @@ func TestLoadConfig(t *testing.T) {
 		t.Errorf("Expected LogLevel: %s, got: %s", expected.LogLevel, cfg.LogLevel)
 	}
 
+	if cfg.Solver != expected.Solver {
+		t.Errorf("Expected Solver: %s, got: %s", expected.Solver, cfg.Solver)
+	}
+
+	if cfg.Policy != expected.Policy {
+		t.Errorf("Expected Policy: %s, got: %s", expected.Policy, cfg.Policy)
+	}
+
 	if !reflect.DeepEqual(cfg.PackagesDefault, expected.PackagesDefault) {
 		t.Errorf("Expected PackagesDefault: %v, got: %v", expected.PackagesDefault, cfg.PackagesDefault)
 	}
@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/calculate": {
             "post": {
-                "description": "Calculates the number of packages required for an order size",
+                "description": "Calculates the packages required for an order size, shipping the least amount of items first and then the least amount of packs.\nIn cost mode the cheapest combination of packs is shipped instead, in packs mode the least amount of packs. A policy orders the objectives and breaks ties, see app.Policy.\nThe body is either the order size of a single product, or a multi-line order such as {\"lines\": [{\"productId\": 2, \"quantity\": 250}]}\nanswered with an app.OrderResult holding the result of every line and the order totals.\nEvery calculation is recorded in the calculation history, see /calculations, along with the caller named by the X-Caller header.",
                 "consumes": [
                     "application/json"
                 ],
@@ -30,25 +30,170 @@ const docTemplate = `{
                 "summary": "Calculate package sizes needed",
                 "parameters": [
                     {
-                        "description": "Order size",
+                        "description": "Order size, or a multi-line order",
                         "name": "orderSize",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "integer"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who makes the call, recorded in the calculation history; the client address when omitted",
+                        "name": "X-Caller",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product of a single order size, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dp",
+                            "greedy",
+                            "bnb"
+                        ],
+                        "type": "string",
+                        "description": "Packing strategy, the configured default is used when omitted",
+                        "name": "solver",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items",
+                            "packs",
+                            "cost",
+                            "shipping",
+                            "split"
+                        ],
+                        "type": "string",
+                        "description": "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost, split splits the order across the warehouses",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items-first",
+                            "packs-first",
+                            "large-packs",
+                            "small-packs",
+                            "cost-first"
+                        ],
+                        "type": "string",
+                        "description": "Policy choosing the packs, the configured default is used when omitted",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Also list alternative combinations: 'all' for every optimal one, or the number of best ones (up to 100)",
+                        "name": "alternatives",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint",
+                        "name": "min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint",
+                        "name": "max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of the order in percent that may be shipped less than ordered, e.g. 2",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the shortfall later, and ship what the stock allows of an order it cannot cover",
+                        "name": "backorder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Put the packs in the fewest shipping cartons, those of all lines together for a multi-line order",
+                        "name": "cartonize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode",
+                        "name": "zone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only send the cartons with this carrier",
+                        "name": "carrier",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "nearest",
+                            "fewest-shipments",
+                            "lowest-overshoot"
+                        ],
+                        "type": "string",
+                        "description": "How the split mode splits the order across the warehouses, the configured default is used when omitted",
+                        "name": "preference",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude of the destination in degrees, the split mode ships from the nearest warehouses first",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of the destination in degrees",
+                        "name": "lon",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Calculated package details",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/app.CalculationResult"
                         }
                     },
                     "400": {
-                        "description": "Invalid request format",
+                        "description": "Invalid request format, destination, or no carrier for the zone",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enough packs in stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "No pack sizes configured, pack constraints cannot be met, the packs cannot be put in cartons, or the calculation needs more memory than its budget",
                         "schema": {
                             "type": "string"
                         }
@@ -58,39 +203,138 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "The calculation ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/package": {
+        "/calculate/batch": {
             "post": {
-                "description": "Adds a new package size to the system",
+                "description": "Calculates the packages of many order sizes of the same product like /calculate, reading the catalog and the stock once and solving the orders in parallel. The stock is not taken out.\nThe response is a JSON array of an item per order in the order of the request, or with format=ndjson newline delimited JSON items streamed as the orders complete, in no particular order.\nAn order that fails comes back with its error in place of the result without failing the batch. The batch stops when the client disconnects.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "tags": [
-                    "Packages"
+                    "Orders"
                 ],
-                "summary": "Add a new package size",
+                "summary": "Calculate many orders",
                 "parameters": [
                     {
-                        "description": "Package size request",
+                        "description": "Order sizes, at most 10000",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/api.batchRequest"
                         }
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format, json when omitted",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dp",
+                            "greedy",
+                            "bnb"
+                        ],
+                        "type": "string",
+                        "description": "Packing strategy, the configured default is used when omitted",
+                        "name": "solver",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items",
+                            "packs",
+                            "cost"
+                        ],
+                        "type": "string",
+                        "description": "First calculation objective, that of the policy when omitted",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items-first",
+                            "packs-first",
+                            "large-packs",
+                            "small-packs",
+                            "cost-first"
+                        ],
+                        "type": "string",
+                        "description": "Policy choosing the packs, the configured default is used when omitted",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint",
+                        "name": "min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint",
+                        "name": "max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of the order in percent that may be shipped less than ordered, e.g. 2",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the shortfall later, and ship what the stock allows of an order it cannot cover",
+                        "name": "backorder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Put the packs in the fewest shipping cartons",
+                        "name": "cartonize",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Package added successfully",
+                        "description": "Result or error per order",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.BatchItem"
+                            }
                         }
                     },
                     "400": {
@@ -98,13 +342,171 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/package/{id}": {
-            "delete": {
-                "description": "Deletes a package by its ID",
+        "/calculations": {
+            "get": {
+                "description": "Returns the recorded /calculate calls with their request, the catalog as of the call, the result or the error, and the solver that answered them.\nCalls are sorted newest first unless asked otherwise; a page with more calls after it returns a nextCursor that continues the query.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calculations"
+                ],
+                "summary": "Get the calculation history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only calls at or after this time, RFC 3339 or a date such as 2024-05-07",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only calls before this time, RFC 3339 or a date such as 2024-05-07 which includes that day",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only calls ordering at least this quantity",
+                        "name": "minQuantity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only calls ordering at most this quantity",
+                        "name": "maxQuantity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only calls shipping packs of this size",
+                        "name": "packSize",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "quantity"
+                        ],
+                        "type": "string",
+                        "description": "Sort by the time of the call or the ordered quantity",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order, desc when omitted",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the next page, as returned by the previous one",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 when omitted and at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of the calculation history",
+                        "schema": {
+                            "$ref": "#/definitions/app.CalculationPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or cursor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get calculations",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/carriers": {
+            "get": {
+                "description": "Retrieves the carrier rate tables read from the rates directory at startup: per zone the weight bands from the lightest to the heaviest, and the fee every parcel costs on top",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Cartons"
+                ],
+                "summary": "Get the carrier rate tables",
+                "responses": {
+                    "200": {
+                        "description": "Carrier rate tables",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.RateTable"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cartons": {
+            "get": {
+                "description": "Retrieves the shipping cartons /calculate puts packs in, with their inner dimensions in millimetres and the most weight in grams they carry",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Cartons"
+                ],
+                "summary": "Get all shipping cartons",
+                "responses": {
+                    "200": {
+                        "description": "Cartons",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.Carton"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get cartons",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a shipping carton with its inner length, width and height in millimetres and the most weight in grams it carries",
                 "consumes": [
                     "application/json"
                 ],
@@ -112,13 +514,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Packages"
+                    "Cartons"
                 ],
-                "summary": "Delete a package",
+                "summary": "Add a shipping carton",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID of the package to delete",
+                        "description": "Carton request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Added carton",
+                        "schema": {
+                            "$ref": "#/definitions/repo.Carton"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format or carton",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Carton already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cartons/{id}": {
+            "delete": {
+                "description": "Deletes a shipping carton, later calculations no longer put packs in it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Cartons"
+                ],
+                "summary": "Delete a shipping carton",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the carton to delete",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -126,13 +571,19 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Package deleted successfully",
+                        "description": "Carton deleted successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid request format",
+                        "description": "Invalid carton ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Carton not found",
                         "schema": {
                             "type": "string"
                         }
@@ -140,9 +591,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/packages": {
+        "/catalog": {
             "get": {
-                "description": "Retrieves a list of all available package sizes",
+                "description": "Retrieves every package of a product with its size, price and handling cost, ordered by size",
                 "consumes": [
                     "application/json"
                 ],
@@ -152,25 +603,2972 @@ const docTemplate = `{
                 "tags": [
                     "Packages"
                 ],
-                "summary": "Get all package sizes",
+                "summary": "Get the package catalog",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "List of package sizes",
+                        "description": "Package catalog",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.Package"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
-                        "description": "Failed to encode response",
+                        "description": "Failed to get catalog",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
+        },
+        "/catalog/analysis": {
+            "get": {
+                "description": "Reports which order quantities the catalog of a product cannot ship exactly, the largest of them (the Frobenius number), sizes other packs cover for less and the worst overshoot, with warnings in plain words",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Analyse the package catalog",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Catalog analysis",
+                        "schema": {
+                            "$ref": "#/definitions/app.CatalogAnalysis"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "The catalog has a pack size that is not positive, or the analysis needs more memory than its budget",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The analysis ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Analyses the catalog of a product with packages added or removed, or the given packages instead, like GET /catalog/analysis. Use it to preview the warnings of adding or deleting a package.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Analyse a proposed package catalog",
+                "parameters": [
+                    {
+                        "description": "Proposed catalog",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/app.CatalogProposal"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Catalog analysis",
+                        "schema": {
+                            "$ref": "#/definitions/app.CatalogAnalysis"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Package size already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Pack size is not positive or above the maximum, or the analysis needs more memory than its budget",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The analysis ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/catalog/cache": {
+            "get": {
+                "description": "Reports how often calculations were served from the in-memory package catalogs and how long rebuilding them took. A catalog is rebuilt after a package of its product is added or deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Get catalog cache statistics",
+                "responses": {
+                    "200": {
+                        "description": "Cache statistics",
+                        "schema": {
+                            "$ref": "#/definitions/app.CacheStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/catalog/recommendation": {
+            "post": {
+                "description": "Searches the pack sizes that minimise the expected overshoot, then the expected pack count, of a histogram of order quantities. packWeight trades overshoot for packs: one more pack per order is worth that many over-shipped items.\nWithout progress the search stops once it runs out of the time budget of a calculation.\nWith progress=true the response is newline delimited JSON: progress lines while the search runs, then a line with the recommendation or the error. The search stops when the client disconnects.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Recommend a package catalog",
+                "parameters": [
+                    {
+                        "description": "Demand and number of sizes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/app.RecommendationRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream progress as newline delimited JSON",
+                        "name": "progress",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recommended catalog",
+                        "schema": {
+                            "$ref": "#/definitions/app.Recommendation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Pack size bounds are not positive or above the maximum",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The search ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "post": {
+                "description": "Queues a batch of order sizes to be calculated in the background like /calculate/batch, and returns the job to poll at /jobs/{id}. Jobs are kept in the database and continue after a restart.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Queue a calculation job",
+                "parameters": [
+                    {
+                        "description": "Order sizes, at most 10000",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.batchRequest"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dp",
+                            "greedy",
+                            "bnb"
+                        ],
+                        "type": "string",
+                        "description": "Packing strategy, the configured default is used when omitted",
+                        "name": "solver",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items",
+                            "packs",
+                            "cost"
+                        ],
+                        "type": "string",
+                        "description": "First calculation objective, that of the policy when omitted",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items-first",
+                            "packs-first",
+                            "large-packs",
+                            "small-packs",
+                            "cost-first"
+                        ],
+                        "type": "string",
+                        "description": "Policy choosing the packs, the configured default is used when omitted",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint",
+                        "name": "min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint",
+                        "name": "max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of the order in percent that may be shipped less than ordered, e.g. 2",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the shortfall later, and ship what the stock allows of an order it cannot cover",
+                        "name": "backorder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Put the packs in the fewest shipping cartons",
+                        "name": "cartonize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Queued job",
+                        "schema": {
+                            "$ref": "#/definitions/app.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Returns the status and progress of a job, and its result once it succeeded: an item per order in the order of the request, with its result or error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Get a calculation job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job",
+                        "schema": {
+                            "$ref": "#/definitions/app.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/cancel": {
+            "post": {
+                "description": "Cancels a queued or running job, a running job stops calculating",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Cancel a calculation job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cancelled job",
+                        "schema": {
+                            "$ref": "#/definitions/app.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Job already finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "get": {
+                "description": "Retrieves the orders with their status and plan, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get orders",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "reserved",
+                            "packed",
+                            "shipped",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Only the orders in this status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Orders",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown status",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get orders",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Calculates the packages for an order like /calculate and stores the order, pending, with the packages as its plan.\nThe order then moves through reserved, packed and shipped with /orders/{id}/status; reserving it takes the packages out of stock.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create an order",
+                "parameters": [
+                    {
+                        "description": "Order size",
+                        "name": "orderSize",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dp",
+                            "greedy",
+                            "bnb"
+                        ],
+                        "type": "string",
+                        "description": "Packing strategy, the configured default is used when omitted",
+                        "name": "solver",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items",
+                            "packs",
+                            "cost",
+                            "shipping"
+                        ],
+                        "type": "string",
+                        "description": "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items-first",
+                            "packs-first",
+                            "large-packs",
+                            "small-packs",
+                            "cost-first"
+                        ],
+                        "type": "string",
+                        "description": "Policy choosing the packs, the configured default is used when omitted",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint",
+                        "name": "min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint",
+                        "name": "max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of the order in percent that may be shipped less than ordered, e.g. 2",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the shortfall later, and ship what the stock allows of an order it cannot cover",
+                        "name": "backorder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Put the packs in the fewest shipping cartons",
+                        "name": "cartonize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode",
+                        "name": "zone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only send the cartons with this carrier",
+                        "name": "carrier",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Created order",
+                        "schema": {
+                            "$ref": "#/definitions/app.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enough packs in stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "No pack sizes configured, pack constraints cannot be met, the packs cannot be put in cartons, or the calculation needs more memory than its budget",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The calculation ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}": {
+            "get": {
+                "description": "Returns an order with its status and the packages calculated for it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order",
+                        "schema": {
+                            "$ref": "#/definitions/app.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid order ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/status": {
+            "put": {
+                "description": "Moves an order from pending to reserved, packed and shipped in turn, or cancels it until it is shipped.\nReserving an order takes its packages out of stock and cancelling a reserved or packed order puts them back, in the same transaction as the status change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Move an order to a status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Status request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated order",
+                        "schema": {
+                            "$ref": "#/definitions/app.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format or unknown status",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The order cannot move to the status, or not enough packs in stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/package": {
+            "post": {
+                "description": "Adds a new package size to the catalog of a product in a warehouse, the default product and warehouse when productId and warehouseId are omitted, with an optional price and handling cost per pack\nand the least and most packs of the size every order ships (0 for none).\nA pack holding innerCount packs of the package innerId of the same product, e.g. a case of 4 boxes, may omit packageSize, it is the items of the packs it holds.\nThe outer length, width and height in millimetres and the weight in grams of a pack are optional, /calculate needs them to put packs in cartons.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Add a new package size",
+                "parameters": [
+                    {
+                        "description": "Package size request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Package added successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format, pack constraint, inner pack or dimensions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product or warehouse not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Package size already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Pack size is not positive or above the maximum",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/package/{id}": {
+            "delete": {
+                "description": "Deletes a package by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Delete a package",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the package to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Package deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Package not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Package is held by other packs",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/package/{id}/constraints": {
+            "put": {
+                "description": "Sets the least and most packs of a package every order of its product ships, 0 for none. Requests can override them with the min and max parameters of /calculate.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Set the pack constraints of a package",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the package",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Pack constraints",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated package",
+                        "schema": {
+                            "$ref": "#/definitions/repo.Package"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format or pack constraint",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Package not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/packages": {
+            "get": {
+                "description": "Retrieves the package sizes of a product in the default warehouse by package ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Packages"
+                ],
+                "summary": "Get all package sizes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of package sizes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product parameter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to encode response",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Retrieves every product, each with its own package catalog and stock",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get all products",
+                "responses": {
+                    "200": {
+                        "description": "Products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.Product"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get products",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a product with an empty package catalog",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Add a product",
+                "parameters": [
+                    {
+                        "description": "Product request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Added product",
+                        "schema": {
+                            "$ref": "#/definitions/repo.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Product already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "delete": {
+                "description": "Deletes a product along with its package catalog and stock, the default product cannot be deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the product to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/simulate": {
+            "post": {
+                "description": "Replays order quantities against the current catalog of a product and a candidate catalog and compares the results side by side: items over-shipped, packs, cost and packs used per size. Every diff is the candidate value minus the current one.\nThe request is a JSON body, or multipart/form-data with the JSON in a \"request\" field and the order quantities in an \"orders\" file, one per line or in the first CSV column.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Simulate a candidate package catalog",
+                "parameters": [
+                    {
+                        "description": "Simulation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/app.SimulationRequest"
+                        }
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Report format, json when omitted",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Side by side report",
+                        "schema": {
+                            "$ref": "#/definitions/app.SimulationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Pack size is not positive or above the maximum, or an order needs more memory than its budget",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "An order ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock": {
+            "get": {
+                "description": "Retrieves the number of packs of a product in stock per tracked package size, sizes without a stock level never run out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Get stock levels",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock levels",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.StockLevel"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/commit": {
+            "post": {
+                "description": "Calculates the packages for a confirmed order like /calculate and takes them out of stock in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Commit an order",
+                "parameters": [
+                    {
+                        "description": "Order size",
+                        "name": "orderSize",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dp",
+                            "greedy",
+                            "bnb"
+                        ],
+                        "type": "string",
+                        "description": "Packing strategy, the configured default is used when omitted",
+                        "name": "solver",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items",
+                            "packs",
+                            "cost",
+                            "shipping"
+                        ],
+                        "type": "string",
+                        "description": "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "items-first",
+                            "packs-first",
+                            "large-packs",
+                            "small-packs",
+                            "cost-first"
+                        ],
+                        "type": "string",
+                        "description": "Policy choosing the packs, the configured default is used when omitted",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of the order in percent that may be shipped less than ordered, e.g. 2",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the shortfall later, and ship what the stock allows of an order it cannot cover",
+                        "name": "backorder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Put the packs in the fewest shipping cartons",
+                        "name": "cartonize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode",
+                        "name": "zone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only send the cartons with this carrier",
+                        "name": "carrier",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Committed package details",
+                        "schema": {
+                            "$ref": "#/definitions/app.CalculationResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enough packs in stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "No pack sizes configured, pack constraints cannot be met, or the calculation needs more memory than its budget",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The calculation ran out of time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/{size}": {
+            "put": {
+                "description": "Sets the number of packs of a package size in stock, tracking the size if it was not yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Set a stock level",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Package size",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Stock level request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock level set successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the stock level of a package size, so it never runs out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Stop tracking a stock level",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Package size",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock level deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Stock level not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Adds packs to the stock of a tracked package size, or takes them out with a negative delta",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Adjust a stock level",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Package size",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Stock adjustment request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Adjusted stock level",
+                        "schema": {
+                            "$ref": "#/definitions/repo.StockLevel"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Stock level not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enough packs in stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses": {
+            "get": {
+                "description": "Retrieves every warehouse, each with its own package catalogs and stock. The catalog and stock endpoints without a warehouse belong to the default warehouse.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Get all warehouses",
+                "responses": {
+                    "200": {
+                        "description": "Warehouses",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.Warehouse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get warehouses",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a warehouse with empty package catalogs and stock. The location in degrees is optional, the split mode of /calculate ships from the warehouses nearest to the destination first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Add a warehouse",
+                "parameters": [
+                    {
+                        "description": "Warehouse request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Added warehouse",
+                        "schema": {
+                            "$ref": "#/definitions/repo.Warehouse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format, name or location",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Warehouse already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}": {
+            "delete": {
+                "description": "Deletes a warehouse along with its package catalogs and stock, the default warehouse cannot be deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Delete a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the warehouse to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Warehouse deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Warehouse not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}/catalog": {
+            "get": {
+                "description": "Retrieves the packages of a product in a warehouse with their costs, added with the warehouseId of /package",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Get the package catalog of a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Package catalog",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.Package"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get catalog",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}/stock": {
+            "get": {
+                "description": "Retrieves the number of packs of a product in stock in a warehouse per tracked package size, sizes without a stock level never run out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Get the stock levels of a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock levels",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.StockLevel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}/stock/{size}": {
+            "put": {
+                "description": "Sets the number of packs of a package size in stock in a warehouse, tracking the size if it was not yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Set a stock level of a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Package size",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product, the default product when omitted",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Stock level request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock level set successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Warehouse or product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.batchRequest": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "description": "Orders are the order quantities to calculate",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "app.Alternative": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "description": "Lines are the packs sent out per size, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackLine"
+                    }
+                },
+                "optimal": {
+                    "description": "Optimal tells whether the combination ties with the best one on items and packs",
+                    "type": "boolean"
+                },
+                "overshoot": {
+                    "description": "Overshoot is the number of items sent on top of the ordered quantity",
+                    "type": "integer"
+                },
+                "shipped": {
+                    "description": "Shipped is the total number of items sent out",
+                    "type": "integer"
+                },
+                "totalPacks": {
+                    "description": "TotalPacks is the total number of packs sent out",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.BatchItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is why the calculation of the order failed",
+                    "type": "string"
+                },
+                "index": {
+                    "description": "Index is the position of the order in the batch, starting at 0",
+                    "type": "integer"
+                },
+                "quantity": {
+                    "description": "Quantity is the ordered quantity",
+                    "type": "integer"
+                },
+                "result": {
+                    "$ref": "#/definitions/app.CalculationResult"
+                }
+            }
+        },
+        "app.CacheStats": {
+            "type": "object",
+            "properties": {
+                "averageRebuildMs": {
+                    "description": "AverageRebuildMs is the average time a snapshot took to load and precompute, in milliseconds",
+                    "type": "number"
+                },
+                "hitRate": {
+                    "description": "HitRate is the share of calculations served from a cached catalog, 0 before the first one",
+                    "type": "number"
+                },
+                "hits": {
+                    "description": "Hits is the number of calculations served from a cached catalog",
+                    "type": "integer"
+                },
+                "lastRebuildMs": {
+                    "description": "LastRebuildMs is the time the last snapshot took to load and precompute, in milliseconds",
+                    "type": "number"
+                },
+                "misses": {
+                    "description": "Misses is the number of calculations that had to load the catalog",
+                    "type": "integer"
+                },
+                "rebuilds": {
+                    "description": "Rebuilds is the number of catalog snapshots built",
+                    "type": "integer"
+                },
+                "snapshots": {
+                    "description": "Snapshots is the number of product catalogs currently cached",
+                    "type": "integer"
+                },
+                "version": {
+                    "description": "Version is the catalog version, increased whenever a catalog changes",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CalculateOptions": {
+            "type": "object",
+            "properties": {
+                "alternatives": {
+                    "description": "Alternatives is the number of best combinations to list next to the result,\nor AllOptimalAlternatives to list every optimal one",
+                    "type": "integer"
+                },
+                "backorder": {
+                    "description": "Backorder sends the shortfall later, and lets Calculate ship what the stock allows of an\norder it cannot cover",
+                    "type": "boolean"
+                },
+                "carrier": {
+                    "description": "Carrier limits the carriers to the one of this name",
+                    "type": "string"
+                },
+                "cartonize": {
+                    "description": "Cartonize puts the chosen packs in the fewest shipping cartons, see packCartons",
+                    "type": "boolean"
+                },
+                "constraints": {
+                    "description": "Constraints bound the packs per size. Calculate applies them on top of the constraints\nstored with the catalog, replacing those of the same size.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackConstraint"
+                    }
+                },
+                "destination": {
+                    "description": "Destination is where the order is sent, used by ModeSplit to find the nearest warehouses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.Location"
+                        }
+                    ]
+                },
+                "mode": {
+                    "description": "Mode is the first objective of the calculation, that of Policy or of the configured policy\nwhen empty",
+                    "type": "string"
+                },
+                "policy": {
+                    "description": "Policy is the name of the policy choosing the packs, the configured default is used when\nempty, see WithPolicy",
+                    "type": "string"
+                },
+                "preference": {
+                    "description": "Preference is how ModeSplit splits the order across the warehouses, the configured default\nis used when empty, see WithPreference",
+                    "type": "string"
+                },
+                "productId": {
+                    "description": "ProductID is the product whose pack catalog and stock are used by Calculate,\nDefaultProductID when zero",
+                    "type": "integer"
+                },
+                "solver": {
+                    "description": "Solver is the name of the packing strategy, the configured default is used when empty",
+                    "type": "string"
+                },
+                "tolerance": {
+                    "description": "Tolerance is the share of the order in percent Calculate may ship less than ordered",
+                    "type": "number"
+                },
+                "zone": {
+                    "description": "Zone is the carrier zone the cartons are sent to, which prices them with the carrier of the\nlowest cost, see WithRateTables. ModeShipping needs one.",
+                    "type": "string"
+                }
+            }
+        },
+        "app.Calculation": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "description": "Caller identifies who made the call",
+                    "type": "string"
+                },
+                "catalog": {
+                    "description": "Catalog are the packages of the products ordered, in the default warehouse, as of the call",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.Package"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error is why the call failed",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "description": "Quantity is the ordered quantity, over all lines of a multi-line order",
+                    "type": "integer"
+                },
+                "request": {
+                    "$ref": "#/definitions/app.CalculationRequest"
+                },
+                "result": {
+                    "description": "Result is the CalculationResult of an order size or the OrderResult of a multi-line order,\nunset when the call failed",
+                    "type": "object"
+                },
+                "solver": {
+                    "description": "Solver is the packing strategy of the result, or the one asked for when the call failed",
+                    "type": "string"
+                },
+                "solverVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CalculationPage": {
+            "type": "object",
+            "properties": {
+                "calculations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.Calculation"
+                    }
+                },
+                "nextCursor": {
+                    "description": "NextCursor continues the query with the next page, empty on the last one",
+                    "type": "string"
+                }
+            }
+        },
+        "app.CalculationRequest": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.OrderLine"
+                    }
+                },
+                "options": {
+                    "$ref": "#/definitions/app.CalculateOptions"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CalculationResult": {
+            "type": "object",
+            "properties": {
+                "alternatives": {
+                    "description": "Alternatives are the ranked combinations asked for with CalculateOptions.Alternatives",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.Alternative"
+                    }
+                },
+                "backordered": {
+                    "description": "Backordered is the part of the shortfall that is sent later, see CalculateOptions.Backorder",
+                    "type": "integer"
+                },
+                "cartons": {
+                    "description": "Cartons are the shipping cartons the packs are put in, asked for with CalculateOptions.Cartonize\nor Zone",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.CartonLoad"
+                    }
+                },
+                "catalog": {
+                    "description": "Catalog are the package sizes the calculation could choose from, largest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "constraints": {
+                    "description": "Constraints are the pack constraints the packs were chosen within, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackConstraint"
+                    }
+                },
+                "cost": {
+                    "description": "Cost is the price of the chosen packs, set when the catalog is known",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.CostBreakdown"
+                        }
+                    ]
+                },
+                "lines": {
+                    "description": "Lines are the packs sent out per size, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackLine"
+                    }
+                },
+                "mode": {
+                    "description": "Mode is the objective the packs were chosen for",
+                    "type": "string"
+                },
+                "overshoot": {
+                    "description": "Overshoot is the number of items sent on top of the ordered quantity",
+                    "type": "integer"
+                },
+                "policy": {
+                    "description": "Policy is the order of objectives and the tie-break the packs were chosen by",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.Policy"
+                        }
+                    ]
+                },
+                "preference": {
+                    "description": "Preference is the split preference of ModeSplit",
+                    "type": "string"
+                },
+                "requested": {
+                    "description": "Requested is the ordered quantity",
+                    "type": "integer"
+                },
+                "shipments": {
+                    "description": "Shipments are the parts of the order sent from each warehouse in ModeSplit, the packs of\nthe result add them up",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.Shipment"
+                    }
+                },
+                "shipped": {
+                    "description": "Shipped is the total number of items sent out",
+                    "type": "integer"
+                },
+                "shipping": {
+                    "description": "Shipping is the carrier the cartons are sent with and the landed cost, set with a zone",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.ShippingBreakdown"
+                        }
+                    ]
+                },
+                "shortfall": {
+                    "description": "Shortfall is the number of items sent below the ordered quantity, see CalculateOptions.Tolerance",
+                    "type": "integer"
+                },
+                "solver": {
+                    "description": "Solver is the name of the packing strategy that produced the result",
+                    "type": "string"
+                },
+                "stock": {
+                    "description": "Stock are the stock levels the packs were limited to, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.StockLevel"
+                    }
+                },
+                "totalPacks": {
+                    "description": "TotalPacks is the total number of packs sent out",
+                    "type": "integer"
+                },
+                "tree": {
+                    "description": "Tree are the packs of Lines along with the packs they hold, set when the catalog has\npacks holding other packs",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackNode"
+                    }
+                }
+            }
+        },
+        "app.CartonItem": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "productId": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CartonLoad": {
+            "type": "object",
+            "properties": {
+                "carton": {
+                    "description": "Carton is the carton name",
+                    "type": "string"
+                },
+                "cartonId": {
+                    "type": "integer"
+                },
+                "contents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.CartonItem"
+                    }
+                },
+                "fill": {
+                    "description": "Fill is the share of the carton volume the packs take up",
+                    "type": "number"
+                },
+                "weight": {
+                    "description": "Weight is the weight of the packs in grams",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CatalogAnalysis": {
+            "type": "object",
+            "properties": {
+                "dominated": {
+                    "description": "Dominated are sizes never chosen in cost mode, other packs cover them for less.\nIn items mode every size is the only best answer to an order of its own size.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "gcd": {
+                    "description": "GCD is the greatest common divisor of the sizes, only its multiples can be shipped exactly",
+                    "type": "integer"
+                },
+                "largestUnreachable": {
+                    "description": "LargestUnreachable is the largest multiple of GCD no combination of packs adds up to,\nthe Frobenius number of the catalog when GCD is 1. Nil when every multiple is reachable.",
+                    "type": "integer"
+                },
+                "redundant": {
+                    "description": "Redundant are sizes that add up from other sizes: without them no order ships more\nitems, only more packs",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "sizes": {
+                    "description": "Sizes are the analysed pack sizes, largest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "unreachable": {
+                    "description": "Unreachable are the smallest of these quantities, at most MaxListedUnreachable of them",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "unreachableCount": {
+                    "description": "UnreachableCount is the number of multiples of GCD no combination of packs adds up to",
+                    "type": "integer"
+                },
+                "warnings": {
+                    "description": "Warnings explain the findings above in plain words",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "worstOvershoot": {
+                    "description": "WorstOvershoot is the most items any order of at least the smallest size is overshot by",
+                    "type": "integer"
+                },
+                "worstOvershootOrder": {
+                    "description": "WorstOvershootOrder is the smallest order quantity overshot by WorstOvershoot,\nzero when the overshoot is only an upper bound",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.CatalogProposal": {
+            "type": "object",
+            "properties": {
+                "add": {
+                    "description": "Add are packages to add to the catalog",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.Package"
+                    }
+                },
+                "packages": {
+                    "description": "Packages replace the stored catalog when set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.Package"
+                    }
+                },
+                "productId": {
+                    "description": "ProductID is the product whose catalog is analysed, DefaultProductID when zero",
+                    "type": "integer"
+                },
+                "remove": {
+                    "description": "Remove are pack sizes to take out of the catalog",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "app.CostBreakdown": {
+            "type": "object",
+            "properties": {
+                "handling": {
+                    "description": "Handling is the total handling cost of the packs",
+                    "type": "number"
+                },
+                "lines": {
+                    "description": "Lines are the costs per pack size, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.CostLine"
+                    }
+                },
+                "packs": {
+                    "description": "Packs is the total price of the packs",
+                    "type": "number"
+                },
+                "total": {
+                    "description": "Total is the price plus the handling cost of all packs",
+                    "type": "number"
+                }
+            }
+        },
+        "app.CostLine": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total is the price plus the handling cost of these packs",
+                    "type": "number"
+                },
+                "unitHandling": {
+                    "description": "UnitHandling is the handling cost of a single pack",
+                    "type": "number"
+                },
+                "unitPrice": {
+                    "description": "UnitPrice is the price of a single pack",
+                    "type": "number"
+                }
+            }
+        },
+        "app.DemandBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.Job": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "done": {
+                    "description": "Done is the number of orders calculated so far, out of Total",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Error is why the job failed",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request": {
+                    "$ref": "#/definitions/app.JobRequest"
+                },
+                "result": {
+                    "description": "Result holds an item per order in the order of the request once the job succeeded",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.BatchItem"
+                    }
+                },
+                "status": {
+                    "description": "Status is one of queued, running, succeeded, failed and cancelled",
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.JobRequest": {
+            "type": "object",
+            "properties": {
+                "options": {
+                    "$ref": "#/definitions/app.CalculateOptions"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "app.Location": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "app.Order": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "plan": {
+                    "description": "Plan are the packs calculated for the order when it was created",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.CalculationResult"
+                        }
+                    ]
+                },
+                "productId": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status is one of pending, reserved, packed, shipped and cancelled",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.OrderLine": {
+            "type": "object",
+            "properties": {
+                "constraints": {
+                    "description": "Constraints bound the packs per size of this line, see CalculateOptions",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackConstraint"
+                    }
+                },
+                "productId": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.PackConstraint": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "description": "Max is the most packs of the size, 0 for no limit",
+                    "type": "integer"
+                },
+                "min": {
+                    "description": "Min is the least number of packs of the size, 0 for none",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.PackLine": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of packs of this size",
+                    "type": "integer"
+                },
+                "items": {
+                    "description": "Items is the number of items in these packs",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size is the package size",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.PackNode": {
+            "type": "object",
+            "properties": {
+                "contents": {
+                    "description": "Contents are the packs a single one of these packs holds, none for loose items",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.PackNode"
+                    }
+                },
+                "count": {
+                    "description": "Count is the number of these packs in the result, or in a single pack of the parent node",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size is the number of items in a single pack",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.ParcelCost": {
+            "type": "object",
+            "properties": {
+                "carton": {
+                    "description": "Carton is the carton name",
+                    "type": "string"
+                },
+                "fee": {
+                    "description": "Fee is the parcel fee of the carrier",
+                    "type": "number"
+                },
+                "maxWeight": {
+                    "description": "MaxWeight is the weight band the parcel is charged by",
+                    "type": "integer"
+                },
+                "rate": {
+                    "description": "Rate is the price of the weight band",
+                    "type": "number"
+                },
+                "total": {
+                    "description": "Total is the rate plus the fee",
+                    "type": "number"
+                },
+                "weight": {
+                    "description": "Weight is the weight of the parcel in grams",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.Policy": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name is the policy name used in configuration and requests",
+                    "type": "string"
+                },
+                "objectives": {
+                    "description": "Objectives are compared in order, the first one decides unless it is a tie",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tieBreak": {
+                    "description": "TieBreak tells apart combinations equal on every objective",
+                    "type": "string"
+                }
+            }
+        },
+        "app.RateBand": {
+            "type": "object",
+            "properties": {
+                "maxWeight": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                }
+            }
+        },
+        "app.RateTable": {
+            "type": "object",
+            "properties": {
+                "carrier": {
+                    "description": "Carrier is the carrier name",
+                    "type": "string"
+                },
+                "parcelFee": {
+                    "description": "ParcelFee is the fee every parcel costs on top of its band",
+                    "type": "number"
+                },
+                "zones": {
+                    "description": "Zones are the weight bands by zone, from the lightest to the heaviest",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/app.RateBand"
+                        }
+                    }
+                }
+            }
+        },
+        "app.Recommendation": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "description": "Candidates is the number of pack sizes the search chose from",
+                    "type": "integer"
+                },
+                "evaluated": {
+                    "description": "Evaluated is the number of catalogs evaluated",
+                    "type": "integer"
+                },
+                "exhaustive": {
+                    "description": "Exhaustive is set when every catalog of candidate sizes was evaluated, otherwise the\nresult is the best a local search found",
+                    "type": "boolean"
+                },
+                "expectedOvershoot": {
+                    "description": "ExpectedOvershoot is the average number of items shipped on top of an order",
+                    "type": "number"
+                },
+                "expectedPacks": {
+                    "description": "ExpectedPacks is the average number of packs an order is shipped in",
+                    "type": "number"
+                },
+                "orders": {
+                    "description": "Orders is the number of orders of the demand",
+                    "type": "integer"
+                },
+                "sizes": {
+                    "description": "Sizes are the recommended pack sizes, largest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "app.RecommendationRequest": {
+            "type": "object",
+            "properties": {
+                "demand": {
+                    "description": "Demand is the histogram of order quantities",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.DemandBucket"
+                    }
+                },
+                "maxSize": {
+                    "description": "MaxSize is the largest pack size to consider, the largest order quantity when zero",
+                    "type": "integer"
+                },
+                "minSize": {
+                    "description": "MinSize is the smallest pack size to consider, 1 when zero",
+                    "type": "integer"
+                },
+                "packWeight": {
+                    "description": "PackWeight is the number of over-shipped items one more pack per order is worth.\nWhen zero the overshoot is minimised first and the pack count only breaks ties.",
+                    "type": "number"
+                },
+                "sizes": {
+                    "description": "Sizes is the number of pack sizes to recommend",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.Shipment": {
+            "type": "object",
+            "properties": {
+                "distance": {
+                    "description": "Distance is the distance from the warehouse to the destination in kilometres, set when both\nlocations are known",
+                    "type": "number"
+                },
+                "result": {
+                    "description": "Result are the packs sent from the warehouse, its Requested is the part of the order\nthey cover",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.CalculationResult"
+                        }
+                    ]
+                },
+                "warehouse": {
+                    "description": "Warehouse is the warehouse name",
+                    "type": "string"
+                },
+                "warehouseId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "app.ShippingBreakdown": {
+            "type": "object",
+            "properties": {
+                "carrier": {
+                    "type": "string"
+                },
+                "landed": {
+                    "description": "Landed is the cost of the packs plus the cost of the parcels",
+                    "type": "number"
+                },
+                "packs": {
+                    "description": "Packs is the price plus the handling cost of the packs",
+                    "type": "number"
+                },
+                "parcels": {
+                    "description": "Parcels are the costs of the parcels, one per carton in the order of the cartons",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.ParcelCost"
+                    }
+                },
+                "shipping": {
+                    "description": "Shipping is the cost of all parcels",
+                    "type": "number"
+                },
+                "zone": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.SimulationDiff": {
+            "type": "object",
+            "properties": {
+                "candidate": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                },
+                "current": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                },
+                "diff": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                }
+            }
+        },
+        "app.SimulationLine": {
+            "type": "object",
+            "properties": {
+                "candidate": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                },
+                "current": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                },
+                "diff": {
+                    "$ref": "#/definitions/app.SimulationOutcome"
+                },
+                "order": {
+                    "description": "Order is the ordered quantity",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.SimulationOutcome": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "description": "Cost is the price plus the handling cost of the packs",
+                    "type": "number"
+                },
+                "overshoot": {
+                    "description": "Overshoot is the number of items sent on top of the ordered quantities",
+                    "type": "integer"
+                },
+                "shipped": {
+                    "description": "Shipped is the number of items sent out",
+                    "type": "integer"
+                },
+                "totalPacks": {
+                    "description": "TotalPacks is the number of packs sent out",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.SimulationReport": {
+            "type": "object",
+            "properties": {
+                "candidateCatalog": {
+                    "description": "CandidateCatalog are the candidate pack sizes, largest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "currentCatalog": {
+                    "description": "CurrentCatalog are the current pack sizes, largest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "lines": {
+                    "description": "Lines are the results per order, in the order of the request",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.SimulationLine"
+                    }
+                },
+                "mode": {
+                    "description": "Mode is the objective the packs were chosen for",
+                    "type": "string"
+                },
+                "orders": {
+                    "description": "Orders is the number of replayed orders",
+                    "type": "integer"
+                },
+                "policy": {
+                    "description": "Policy is the order of objectives and the tie-break the packs were chosen by",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.Policy"
+                        }
+                    ]
+                },
+                "sizes": {
+                    "description": "Sizes are the packs used per size over all orders, largest size first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.SizeUsage"
+                    }
+                },
+                "totals": {
+                    "description": "Totals add up the results of all orders",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/app.SimulationDiff"
+                        }
+                    ]
+                }
+            }
+        },
+        "app.SimulationRequest": {
+            "type": "object",
+            "properties": {
+                "candidate": {
+                    "description": "Candidate is the proposed catalog",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.Package"
+                    }
+                },
+                "current": {
+                    "description": "Current replaces the stored catalog of the product when set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.Package"
+                    }
+                },
+                "mode": {
+                    "description": "Mode is the first objective of the calculations, see CalculateOptions",
+                    "type": "string"
+                },
+                "orders": {
+                    "description": "Orders are the order quantities to replay",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "policy": {
+                    "description": "Policy is the name of the policy choosing the packs, the configured default is used when empty",
+                    "type": "string"
+                },
+                "productId": {
+                    "description": "ProductID is the product whose stored catalog is the current one, DefaultProductID when zero",
+                    "type": "integer"
+                },
+                "solver": {
+                    "description": "Solver is the name of the packing strategy, the configured default is used when empty",
+                    "type": "string"
+                }
+            }
+        },
+        "app.SizeUsage": {
+            "type": "object",
+            "properties": {
+                "candidate": {
+                    "type": "integer"
+                },
+                "current": {
+                    "type": "integer"
+                },
+                "diff": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.Carton": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "description": "Length, Width and Height are the inner dimensions in millimetres",
+                    "type": "integer"
+                },
+                "maxWeight": {
+                    "description": "MaxWeight is the most weight in grams the carton carries",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.Package": {
+            "type": "object",
+            "properties": {
+                "handlingCost": {
+                    "description": "HandlingCost is the optional extra cost of handling a single pack",
+                    "type": "number"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "innerCount": {
+                    "type": "integer"
+                },
+                "innerId": {
+                    "description": "InnerID is the package this pack holds InnerCount packs of, 0 for a pack of loose items",
+                    "type": "integer"
+                },
+                "length": {
+                    "description": "Length, Width and Height are the outer dimensions of a pack in millimetres, 0 when not known",
+                    "type": "integer"
+                },
+                "maxCount": {
+                    "description": "MaxCount is the most packs of this size a single order ships, 0 for no limit",
+                    "type": "integer"
+                },
+                "minCount": {
+                    "description": "MinCount is the least number of packs of this size every order ships, 0 for none",
+                    "type": "integer"
+                },
+                "price": {
+                    "description": "Price is the cost of a single pack",
+                    "type": "number"
+                },
+                "productId": {
+                    "description": "ProductID is the product packed in this size",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "warehouseId": {
+                    "description": "WarehouseID is the warehouse whose catalog the package belongs to",
+                    "type": "integer"
+                },
+                "weight": {
+                    "description": "Weight is the weight of a full pack in grams, 0 when not known",
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.Product": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "repo.StockLevel": {
+            "type": "object",
+            "properties": {
+                "quantity": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.Warehouse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "latitude": {
+                    "description": "Latitude and Longitude locate the warehouse in degrees, nil when the location is not known",
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -10,9 +10,10 @@ const API_BASE_URL =
 
 function App() {
   const [packages, setPackages] = useState({})
+  const [warnings, setWarnings] = useState([])
   const [packageSizeInput, setPackageSizeInput] = useState('')
   const [orderSize, setOrderSize] = useState('')
-  const [results, setResults] = useState({})
+  const [results, setResults] = useState(null)
   const [swaggerOpen, setSwaggerOpen] = useState(false)
 
   // Load package list on component mount
@@ -30,6 +31,44 @@ function App() {
     } catch (error) {
       console.error('Error loading packages:', error)
     }
+    loadWarnings()
+  }
+
+  const loadWarnings = async () => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/catalog/analysis`)
+      if (response.ok) {
+        const analysis = await response.json()
+        setWarnings(analysis.warnings || [])
+      }
+    } catch (error) {
+      console.error('Error loading catalog analysis:', error)
+    }
+  }
+
+  // Analyses the catalog after a change and asks before going ahead when it
+  // brings new warnings. Returns false when the change should not be made.
+  // The analysis is only advice: when it fails the change itself reports why.
+  const confirmChange = async (change, action) => {
+    const response = await fetch(`${API_BASE_URL}/catalog/analysis`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(change),
+    })
+    if (!response.ok) {
+      return true
+    }
+
+    const analysis = await response.json()
+    const added = (analysis.warnings || []).filter((w) => !warnings.includes(w))
+    if (added.length === 0) {
+      return true
+    }
+    return window.confirm(
+      `After ${action} this package:\n- ${added.join('\n- ')}\n\nContinue?`
+    )
   }
 
   const addPackage = async () => {
@@ -40,6 +79,10 @@ function App() {
     }
 
     try {
+      if (!(await confirmChange({ add: [{ size: value }] }, 'adding'))) {
+        return
+      }
+
       const response = await fetch(`${API_BASE_URL}/package`, {
         method: 'POST',
         headers: {
@@ -67,6 +110,11 @@ function App() {
     }
 
     try {
+      const change = { remove: [packages[packageId]] }
+      if (!(await confirmChange(change, 'deleting'))) {
+        return
+      }
+
       const response = await fetch(`${API_BASE_URL}/package/${packageId}`, {
         method: 'DELETE',
       })
@@ -102,7 +150,8 @@ function App() {
         const result = await response.json()
         setResults(result)
       } else {
-        alert('Error calculating result')
+        const errorText = await response.text()
+        alert('Error calculating result: ' + (errorText || response.status))
       }
     } catch (error) {
       alert('Error: ' + error.message)
@@ -149,6 +198,13 @@ function App() {
           />
           <button onClick={addPackage}>✔️</button>
         </div>
+        {warnings.length > 0 && (
+          <ul id="catalog-warnings" className="catalog-warnings">
+            {warnings.map((warning) => (
+              <li key={warning}>{warning}</li>
+            ))}
+          </ul>
+        )}
       </div>
 
       {/* Block 2: Order */}
@@ -186,14 +242,20 @@ function App() {
             </tr>
           </thead>
           <tbody id="results-table">
-            {Object.entries(results).map(([packageSize, count]) => (
-              <tr key={packageSize}>
-                <td>{packageSize}</td>
-                <td>{count}</td>
+            {(results?.lines || []).map((line) => (
+              <tr key={line.size}>
+                <td>{line.size}</td>
+                <td>{line.count}</td>
               </tr>
             ))}
           </tbody>
         </table>
+        {results && (
+          <p id="results-summary">
+            Shipped: {results.shipped} (overshoot: {results.overshoot}) in{' '}
+            {results.totalPacks} packs
+          </p>
+        )}
       </div>
 
       {/* Swagger Modal */}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -21,38 +23,286 @@ type MockApp struct {
 	app.AppInterface
 }
 
-func (m *MockApp) GetPackages() ([]int, error) {
-	args := m.Called()
+func (m *MockApp) GetPackages(productID int) ([]int, error) {
+	args := m.Called(productID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]int), args.Error(1)
 }
 
-func (m *MockApp) GetPackagesMap() (map[string]int, error) {
-	args := m.Called()
+func (m *MockApp) GetPackagesMap(productID int) (map[string]int, error) {
+	args := m.Called(productID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(map[string]int), args.Error(1)
 }
 
-func (m *MockApp) AddPackage(packageSize int) error {
-	args := m.Called(packageSize)
+func (m *MockApp) GetCatalog(productID int) ([]app.Package, error) {
+	args := m.Called(productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.Package), args.Error(1)
+}
+
+func (m *MockApp) AddPackage(pkg app.Package) error {
+	args := m.Called(pkg)
 	return args.Error(0)
 }
 
+func (m *MockApp) SetPackageConstraints(id, minCount, maxCount int) (app.Package, error) {
+	args := m.Called(id, minCount, maxCount)
+	return args.Get(0).(app.Package), args.Error(1)
+}
+
 func (m *MockApp) DeletePackage(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockApp) CalculatePacksNeeded(orderQuantity int, packSizes []int) (map[int]int, error) {
-	args := m.Called(orderQuantity, packSizes)
+func (m *MockApp) Calculate(ctx context.Context, orderQuantity int, opts app.CalculateOptions) (*app.CalculationResult, error) {
+	args := m.Called(ctx, orderQuantity, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.CalculationResult), args.Error(1)
+}
+
+func (m *MockApp) CalculatePacksNeeded(ctx context.Context, orderQuantity int, packSizes []int, opts app.CalculateOptions) (*app.CalculationResult, error) {
+	args := m.Called(ctx, orderQuantity, packSizes, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.CalculationResult), args.Error(1)
+}
+
+func (m *MockApp) CommitOrder(ctx context.Context, orderQuantity int, opts app.CalculateOptions) (*app.CalculationResult, error) {
+	args := m.Called(ctx, orderQuantity, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.CalculationResult), args.Error(1)
+}
+
+func (m *MockApp) GetStock(productID int) ([]app.StockLevel, error) {
+	args := m.Called(productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.StockLevel), args.Error(1)
+}
+
+func (m *MockApp) SetStock(productID, size, quantity int) error {
+	args := m.Called(productID, size, quantity)
+	return args.Error(0)
+}
+
+func (m *MockApp) AdjustStock(productID, size, delta int) (app.StockLevel, error) {
+	args := m.Called(productID, size, delta)
+	return args.Get(0).(app.StockLevel), args.Error(1)
+}
+
+func (m *MockApp) DeleteStock(productID, size int) error {
+	args := m.Called(productID, size)
+	return args.Error(0)
+}
+
+func (m *MockApp) GetCartons() ([]app.Carton, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.Carton), args.Error(1)
+}
+
+func (m *MockApp) AddCarton(carton app.Carton) (app.Carton, error) {
+	args := m.Called(carton)
+	return args.Get(0).(app.Carton), args.Error(1)
+}
+
+func (m *MockApp) DeleteCarton(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockApp) GetCarriers() []app.RateTable {
+	args := m.Called()
+	return args.Get(0).([]app.RateTable)
+}
+
+func (m *MockApp) GetWarehouses() ([]app.Warehouse, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.Warehouse), args.Error(1)
+}
+
+func (m *MockApp) AddWarehouse(warehouse app.Warehouse) (app.Warehouse, error) {
+	args := m.Called(warehouse)
+	return args.Get(0).(app.Warehouse), args.Error(1)
+}
+
+func (m *MockApp) DeleteWarehouse(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockApp) GetWarehouseCatalog(warehouseID, productID int) ([]app.Package, error) {
+	args := m.Called(warehouseID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.Package), args.Error(1)
+}
+
+func (m *MockApp) GetWarehouseStock(warehouseID, productID int) ([]app.StockLevel, error) {
+	args := m.Called(warehouseID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.StockLevel), args.Error(1)
+}
+
+func (m *MockApp) SetWarehouseStock(warehouseID, productID, size, quantity int) error {
+	args := m.Called(warehouseID, productID, size, quantity)
+	return args.Error(0)
+}
+
+func (m *MockApp) CacheStats() app.CacheStats {
+	args := m.Called()
+	return args.Get(0).(app.CacheStats)
+}
+
+func (m *MockApp) AnalyzeCatalog(ctx context.Context, proposal app.CatalogProposal) (*app.CatalogAnalysis, error) {
+	args := m.Called(ctx, proposal)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.CatalogAnalysis), args.Error(1)
+}
+
+func (m *MockApp) Simulate(ctx context.Context, req app.SimulationRequest) (*app.SimulationReport, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.SimulationReport), args.Error(1)
+}
+
+func (m *MockApp) RecommendCatalog(ctx context.Context, req app.RecommendationRequest, progress func(app.RecommendationProgress)) (*app.Recommendation, error) {
+	args := m.Called(ctx, req, progress)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Recommendation), args.Error(1)
+}
+
+func (m *MockApp) GetProducts() ([]app.Product, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]app.Product), args.Error(1)
+}
+
+func (m *MockApp) AddProduct(name string) (app.Product, error) {
+	args := m.Called(name)
+	return args.Get(0).(app.Product), args.Error(1)
+}
+
+func (m *MockApp) DeleteProduct(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockApp) CalculateOrder(ctx context.Context, lines []app.OrderLine, opts app.CalculateOptions) (*app.OrderResult, error) {
+	args := m.Called(ctx, lines, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.OrderResult), args.Error(1)
+}
+
+func (m *MockApp) CreateOrder(ctx context.Context, orderQuantity int, opts app.CalculateOptions) (*app.Order, error) {
+	args := m.Called(ctx, orderQuantity, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Order), args.Error(1)
+}
+
+func (m *MockApp) GetOrder(id int) (*app.Order, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Order), args.Error(1)
+}
+
+func (m *MockApp) GetOrders(status string) ([]*app.Order, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*app.Order), args.Error(1)
+}
+
+func (m *MockApp) SetOrderStatus(id int, status string) (*app.Order, error) {
+	args := m.Called(id, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Order), args.Error(1)
+}
+
+func (m *MockApp) RecordCalculation(caller string, req app.CalculationRequest, result interface{}, calcErr error) error {
+	args := m.Called(caller, req, result, calcErr)
+	return args.Error(0)
+}
+
+func (m *MockApp) GetCalculations(q app.CalculationQuery) (*app.CalculationPage, error) {
+	args := m.Called(q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.CalculationPage), args.Error(1)
+}
+
+func (m *MockApp) SubmitJob(req app.JobRequest) (*app.Job, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Job), args.Error(1)
+}
+
+func (m *MockApp) GetJob(id int) (*app.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.Job), args.Error(1)
+}
+
+func (m *MockApp) CancelJob(id int) (*app.Job, error) {
+	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(map[int]int), args.Error(1)
+	return args.Get(0).(*app.Job), args.Error(1)
+}
+
+func (m *MockApp) CalculateBatch(ctx context.Context, orders []int, opts app.CalculateOptions, emit func(app.BatchItem)) error {
+	args := m.Called(ctx, orders, opts)
+	if items, ok := args.Get(0).([]app.BatchItem); ok {
+		for _, item := range items {
+			emit(item)
+		}
+	}
+	return args.Error(1)
 }
 
 func TestHealthCheck(t *testing.T) {
@@ -78,10 +328,94 @@ func TestAddPackageHandler(t *testing.T) {
 			name:        "successful add",
 			requestBody: map[string]int{"packageSize": 10},
 			setupMock: func(m *MockApp) {
-				m.On("AddPackage", 10).Return(nil)
+				m.On("AddPackage", app.Package{Size: 10}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "successful add with costs",
+			requestBody: map[string]float64{"packageSize": 10, "productId": 2, "price": 2.5, "handlingCost": 0.3},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{ProductID: 2, Size: 10, Price: 2.5, HandlingCost: 0.3}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "successful add with pack constraints",
+			requestBody: map[string]int{"packageSize": 10, "minCount": 1, "maxCount": 4},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{Size: 10, MinCount: 1, MaxCount: 4}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "invalid pack constraints",
+			requestBody: map[string]int{"packageSize": 10, "minCount": 5, "maxCount": 4},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{Size: 10, MinCount: 5, MaxCount: 4}).Return(app.ErrInvalidConstraint)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "successful add of a nested pack",
+			requestBody: map[string]int{"innerId": 3, "innerCount": 4},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{InnerID: 3, InnerCount: 4}).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:        "inner pack of another product",
+			requestBody: map[string]int{"innerId": 3, "innerCount": 4, "productId": 2},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{ProductID: 2, InnerID: 3, InnerCount: 4}).Return(app.ErrInvalidNesting)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "unknown product",
+			requestBody: map[string]int{"packageSize": 10, "productId": 9},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{ProductID: 9, Size: 10}).Return(app.ErrProductNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Failed to add package: product not found\n",
+		},
+		{
+			name:        "unknown warehouse",
+			requestBody: map[string]int{"packageSize": 10, "warehouseId": 9},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{WarehouseID: 9, Size: 10}).Return(app.ErrWarehouseNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Failed to add package: warehouse not found\n",
+		},
+		{
+			name:        "non-positive size",
+			requestBody: map[string]int{"packageSize": -10},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{Size: -10}).Return(fmt.Errorf("%w: -10", app.ErrInvalidPackSize))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "Failed to add package: invalid pack size: -10\n",
+		},
+		{
+			name:        "size above the maximum",
+			requestBody: map[string]int{"packageSize": 5000000},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{Size: 5000000}).Return(app.ErrPackSizeTooLarge)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "duplicate size",
+			requestBody: map[string]int{"packageSize": 250},
+			setupMock: func(m *MockApp) {
+				m.On("AddPackage", app.Package{Size: 250}).Return(app.ErrPackageExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Failed to add package: package size already exists\n",
+		},
 		{
 			name:           "invalid JSON",
 			requestBody:    "invalid",
@@ -93,7 +427,7 @@ func TestAddPackageHandler(t *testing.T) {
 			name:        "app error",
 			requestBody: map[string]int{"packageSize": 5},
 			setupMock: func(m *MockApp) {
-				m.On("AddPackage", 5).Return(errors.New("database error"))
+				m.On("AddPackage", app.Package{Size: 5}).Return(errors.New("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Failed to add package: database error\n",
@@ -162,6 +496,24 @@ func TestDeletePackageHandler(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Failed to delete package: package not found\n",
 		},
+		{
+			name: "not found",
+			id:   "999",
+			setupMock: func(m *MockApp) {
+				m.On("DeletePackage", "999").Return(fmt.Errorf("%w: 999", app.ErrPackageNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Failed to delete package: package not found: 999\n",
+		},
+		{
+			name: "held by other packs",
+			id:   "1",
+			setupMock: func(m *MockApp) {
+				m.On("DeletePackage", "1").Return(fmt.Errorf("%w: 1", app.ErrPackageInUse))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Failed to delete package: package is held by other packs: 1\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -192,28 +544,56 @@ func TestDeletePackageHandler(t *testing.T) {
 	}
 }
 
-func TestGetPackagesHandler(t *testing.T) {
+func TestSetPackageConstraintsHandler(t *testing.T) {
 	tests := []struct {
 		name           string
+		id             string
+		requestBody    string
 		setupMock      func(*MockApp)
 		expectedStatus int
-		expectedBody   map[string]int
+		expectedBody   *app.Package
 	}{
 		{
-			name: "successful get",
+			name:        "successful update",
+			id:          "3",
+			requestBody: `{"minCount": 1, "maxCount": 4}`,
 			setupMock: func(m *MockApp) {
-				m.On("GetPackagesMap").Return(map[string]int{"1": 10, "2": 20}, nil)
+				m.On("SetPackageConstraints", 3, 1, 4).Return(app.Package{ID: 3, ProductID: 1, Size: 250, MinCount: 1, MaxCount: 4}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   map[string]int{"1": 10, "2": 20},
+			expectedBody:   &app.Package{ID: 3, ProductID: 1, Size: 250, MinCount: 1, MaxCount: 4},
 		},
 		{
-			name: "app error",
+			name:        "unknown package",
+			id:          "9",
+			requestBody: `{"maxCount": 4}`,
 			setupMock: func(m *MockApp) {
-				m.On("GetPackagesMap").Return(nil, errors.New("database error"))
+				m.On("SetPackageConstraints", 9, 0, 4).Return(app.Package{}, app.ErrPackageNotFound)
 			},
-			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   nil,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "minimum above maximum",
+			id:          "3",
+			requestBody: `{"minCount": 5, "maxCount": 4}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetPackageConstraints", 3, 5, 4).Return(app.Package{}, app.ErrInvalidConstraint)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid id",
+			id:             "abc",
+			requestBody:    `{"maxCount": 4}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			id:             "3",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
 		},
 	}
 
@@ -224,64 +604,490 @@ func TestGetPackagesHandler(t *testing.T) {
 
 			handler := &Handler{app: mockApp}
 
-			req := httptest.NewRequest("GET", "/packages", nil)
+			req := httptest.NewRequest("PUT", "/package/"+tt.id+"/constraints", bytes.NewBufferString(tt.requestBody))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 			rec := httptest.NewRecorder()
 
-			handler.getPackages(rec, req)
+			handler.setPackageConstraints(rec, req)
 
 			require.Equal(t, tt.expectedStatus, rec.Code)
 			if tt.expectedBody != nil {
-				var response map[string]int
-				err := json.Unmarshal(rec.Body.Bytes(), &response)
-				require.NoError(t, err)
-				require.Equal(t, tt.expectedBody, response)
+				var response app.Package
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				require.Equal(t, *tt.expectedBody, response)
 			}
-
 			mockApp.AssertExpectations(t)
 		})
 	}
 }
 
-func TestCalculateHandler(t *testing.T) {
+func TestGetPackagesHandler(t *testing.T) {
 	tests := []struct {
 		name           string
-		orderSize      int
+		query          string
 		setupMock      func(*MockApp)
 		expectedStatus int
-		expectedBody   map[int]int
+		expectedBody   map[string]int
 	}{
 		{
-			name:      "successful calculate",
-			orderSize: 10,
+			name: "successful get",
 			setupMock: func(m *MockApp) {
-				m.On("GetPackages").Return([]int{5, 10}, nil)
-				m.On("CalculatePacksNeeded", 10, []int{5, 10}).Return(map[int]int{10: 1}, nil)
+				m.On("GetPackagesMap", 0).Return(map[string]int{"1": 10, "2": 20}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   map[int]int{10: 1},
+			expectedBody:   map[string]int{"1": 10, "2": 20},
 		},
 		{
-			name:           "invalid JSON",
-			orderSize:      0,
-			setupMock:      func(m *MockApp) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   nil,
-		},
+			name:  "product",
+			query: "?product=2",
+			setupMock: func(m *MockApp) {
+				m.On("GetPackagesMap", 2).Return(map[string]int{"3": 6}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]int{"3": 6},
+		},
 		{
-			name:      "app get packages error",
-			orderSize: 10,
+			name:           "invalid product",
+			query:          "?product=bolts",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name: "app error",
 			setupMock: func(m *MockApp) {
-				m.On("GetPackages").Return(nil, errors.New("database error"))
+				m.On("GetPackagesMap", 0).Return(nil, errors.New("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   nil,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+
+			req := httptest.NewRequest("GET", "/packages"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.getPackages(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != nil {
+				var response map[string]int
+				err := json.Unmarshal(rec.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedBody, response)
+			}
+
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCalculateHandler(t *testing.T) {
+	exactResult := &app.CalculationResult{
+		Requested:  10,
+		Shipped:    10,
+		TotalPacks: 1,
+		Lines:      []app.PackLine{{Size: 10, Count: 1, Items: 10}},
+		Catalog:    []int{10, 5},
+		Solver:     "dp",
+	}
+	bnbResult := &app.CalculationResult{
+		Requested:  10,
+		Shipped:    10,
+		TotalPacks: 1,
+		Lines:      []app.PackLine{{Size: 10, Count: 1, Items: 10}},
+		Catalog:    []int{10, 5},
+		Solver:     "bnb",
+	}
+	packsFirst, err := app.PolicyByName("packs-first")
+	require.NoError(t, err)
+	packsResult := &app.CalculationResult{
+		Requested:  10,
+		Shipped:    10,
+		TotalPacks: 1,
+		Lines:      []app.PackLine{{Size: 10, Count: 1, Items: 10}},
+		Catalog:    []int{10, 5},
+		Solver:     "bnb",
+		Mode:       app.ModePacks,
+		Policy:     &packsFirst,
+	}
+	shortResult := &app.CalculationResult{
+		Requested:   11,
+		Shipped:     10,
+		Shortfall:   1,
+		Backordered: 1,
+		TotalPacks:  1,
+		Lines:       []app.PackLine{{Size: 10, Count: 1, Items: 10}},
+		Catalog:     []int{10, 5},
+		Solver:      "dp",
+	}
+	cartonResult := &app.CalculationResult{
+		Requested:  500,
+		Shipped:    500,
+		TotalPacks: 1,
+		Lines:      []app.PackLine{{Size: 500, Count: 1, Items: 500}},
+		Catalog:    []int{500, 250},
+		Solver:     "dp",
+		Cartons: []app.CartonLoad{
+			{CartonID: 1, Carton: "small", Contents: []app.CartonItem{{ProductID: 1, Size: 500, Count: 1}}, Weight: 2000, Fill: 0.25},
+		},
+	}
+	overshootResult := &app.CalculationResult{
+		Requested:  251,
+		Shipped:    500,
+		Overshoot:  249,
+		TotalPacks: 1,
+		Lines:      []app.PackLine{{Size: 500, Count: 1, Items: 500}},
+		Catalog:    []int{500, 250},
+		Solver:     "dp",
+	}
+
+	costResult := &app.CalculationResult{
+		Requested:  10,
+		Shipped:    10,
+		TotalPacks: 2,
+		Lines:      []app.PackLine{{Size: 5, Count: 2, Items: 10}},
+		Catalog:    []int{10, 5},
+		Solver:     "bnb",
+		Mode:       app.ModeCost,
+		Cost: &app.CostBreakdown{
+			Lines:    []app.CostLine{{Size: 5, Count: 2, UnitPrice: 1.5, UnitHandling: 0.25, Total: 3.5}},
+			Packs:    3,
+			Handling: 0.5,
+			Total:    3.5,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		orderSize      int
+		query          string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   *app.CalculationResult
+	}{
+		{
+			name:      "successful calculate",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(exactResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   exactResult,
+		},
+		{
+			name:      "successful calculate with overshoot",
+			orderSize: 251,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 251, app.CalculateOptions{}).Return(overshootResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   overshootResult,
+		},
+		{
+			name:      "successful calculate with solver",
+			orderSize: 10,
+			query:     "?solver=bnb",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Solver: "bnb"}).Return(bnbResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   bnbResult,
+		},
+		{
+			name:      "successful calculate in cost mode",
+			orderSize: 10,
+			query:     "?mode=cost",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Mode: app.ModeCost}).Return(costResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   costResult,
+		},
+		{
+			name:      "successful calculate with policy",
+			orderSize: 10,
+			query:     "?policy=packs-first",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Policy: "packs-first"}).Return(packsResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   packsResult,
+		},
+		{
+			name:      "unknown policy",
+			orderSize: 10,
+			query:     "?policy=fewest-boxes",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Policy: "fewest-boxes"}).Return(nil, app.ErrUnknownPolicy)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "unknown solver",
+			orderSize: 10,
+			query:     "?solver=simplex",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Solver: "simplex"}).Return(nil, app.ErrUnknownSolver)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "successful calculate with alternatives",
+			orderSize: 10,
+			query:     "?alternatives=all",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Alternatives: app.AllOptimalAlternatives}).Return(exactResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   exactResult,
+		},
+		{
+			name:      "invalid alternatives",
+			orderSize: 10,
+			query:     "?alternatives=some",
+			setupMock: func(m *MockApp) {
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "too many alternatives",
+			orderSize: 10,
+			query:     "?alternatives=1000",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Alternatives: 1000}).Return(nil, app.ErrInvalidAlternatives)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:           "invalid JSON",
+			orderSize:      0,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "successful calculate for a product",
+			orderSize: 10,
+			query:     "?product=2",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{ProductID: 2}).Return(exactResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   exactResult,
+		},
+		{
+			name:           "invalid product",
+			orderSize:      10,
+			query:          "?product=bolts",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "not enough stock",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, app.ErrInsufficientStock)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   nil,
+		},
+		{
+			name:      "invalid mode",
+			orderSize: 10,
+			query:     "?mode=weight",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Mode: "weight"}).Return(nil, app.ErrInvalidMode)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   nil,
+		},
+		{
+			name:      "pack constraints",
+			orderSize: 10,
+			query:     "?solver=bnb&min=5:2&max=10:1,5:3",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Solver: "bnb", Constraints: []app.PackConstraint{
+					{Size: 5, Min: 2, Max: 3},
+					{Size: 10, Max: 1},
+				}}).Return(bnbResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   bnbResult,
+		},
+		{
+			name:      "undershoot tolerance and backorder",
+			orderSize: 11,
+			query:     "?tolerance=10&backorder=true",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 11, app.CalculateOptions{Tolerance: 10, Backorder: true}).Return(shortResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   shortResult,
+		},
+		{
+			name:           "invalid tolerance parameter",
+			orderSize:      10,
+			query:          "?tolerance=some",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid backorder parameter",
+			orderSize:      10,
+			query:          "?backorder=later",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "cartonize",
+			orderSize: 500,
+			query:     "?cartonize=true",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Cartonize: true}).Return(cartonResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   cartonResult,
+		},
+		{
+			name:      "packs fit no carton",
+			orderSize: 500,
+			query:     "?cartonize=true",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Cartonize: true}).Return(nil, app.ErrCannotCartonize)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "shipping mode",
+			orderSize: 500,
+			query:     "?mode=shipping&zone=domestic&carrier=ParcelCo",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Mode: app.ModeShipping, Zone: "domestic", Carrier: "ParcelCo"}).Return(cartonResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   cartonResult,
+		},
+		{
+			name:      "no carrier for the zone",
+			orderSize: 500,
+			query:     "?zone=mars",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Zone: "mars"}).Return(nil, app.ErrNoCarrier)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "split mode",
+			orderSize: 500,
+			query:     "?mode=split&preference=fewest-shipments&lat=53.5&lon=10",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Mode: app.ModeSplit, Preference: app.PreferFewestShipments,
+					Destination: &app.Location{Latitude: 53.5, Longitude: 10}}).Return(cartonResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   cartonResult,
+		},
+		{
+			name:      "destination out of range",
+			orderSize: 500,
+			query:     "?mode=split&lat=100&lon=10",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 500, app.CalculateOptions{Mode: app.ModeSplit, Destination: &app.Location{Latitude: 100, Longitude: 10}}).
+					Return(nil, app.ErrInvalidLocation)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "latitude without longitude",
+			orderSize:      500,
+			query:          "?mode=split&lat=53.5",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid cartonize parameter",
+			orderSize:      10,
+			query:          "?cartonize=boxes",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "tolerance out of range",
+			orderSize: 10,
+			query:     "?tolerance=100",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Tolerance: 100}).Return(nil, app.ErrInvalidTolerance)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid pack constraint parameter",
+			orderSize:      10,
+			query:          "?min=5",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "pack constraints cannot be met",
+			orderSize: 10,
+			query:     "?max=10:1",
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{Constraints: []app.PackConstraint{{Size: 10, Max: 1}}}).
+					Return(nil, app.ErrConstraintInfeasible)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "out of memory",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, app.ErrMemoryBudgetExceeded)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "no pack sizes",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, app.ErrEmptyCatalog)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "invalid quantity",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, app.ErrInvalidQuantity)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "out of time",
+			orderSize: 10,
+			setupMock: func(m *MockApp) {
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, fmt.Errorf("%w after 10s", app.ErrTimeBudgetExceeded))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
 		{
 			name:      "calculate error",
 			orderSize: 10,
 			setupMock: func(m *MockApp) {
-				m.On("GetPackages").Return([]int{5, 10}, nil)
-				m.On("CalculatePacksNeeded", 10, []int{5, 10}).Return(nil, errors.New("calculation error"))
+				m.On("Calculate", mock.Anything, 10, app.CalculateOptions{}).Return(nil, errors.New("calculation error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   nil,
@@ -292,6 +1098,7 @@ func TestCalculateHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockApp := new(MockApp)
 			tt.setupMock(mockApp)
+			mockApp.On("RecordCalculation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			handler := &Handler{app: mockApp}
 
@@ -302,7 +1109,7 @@ func TestCalculateHandler(t *testing.T) {
 				bodyBytes = []byte("invalid")
 			}
 
-			req := httptest.NewRequest("POST", "/calculate", bytes.NewBuffer(bodyBytes))
+			req := httptest.NewRequest("POST", "/calculate"+tt.query, bytes.NewBuffer(bodyBytes))
 			req.Header.Set("Content-Type", "application/json")
 			rec := httptest.NewRecorder()
 
@@ -310,10 +1117,527 @@ func TestCalculateHandler(t *testing.T) {
 
 			require.Equal(t, tt.expectedStatus, rec.Code)
 			if tt.expectedBody != nil {
-				var response map[int]int
+				var response app.CalculationResult
 				err := json.Unmarshal(rec.Body.Bytes(), &response)
 				require.NoError(t, err)
-				require.Equal(t, tt.expectedBody, response)
+				require.Equal(t, tt.expectedBody, &response)
+			}
+
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCatalogHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   []app.Package
+	}{
+		{
+			name: "successful get",
+			setupMock: func(m *MockApp) {
+				m.On("GetCatalog", 0).Return([]app.Package{{ID: 1, Size: 250, Price: 1.5, HandlingCost: 0.2}, {ID: 2, Size: 500, Price: 2.75}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []app.Package{{ID: 1, Size: 250, Price: 1.5, HandlingCost: 0.2}, {ID: 2, Size: 500, Price: 2.75}},
+		},
+		{
+			name: "app error",
+			setupMock: func(m *MockApp) {
+				m.On("GetCatalog", 0).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+
+			req := httptest.NewRequest("GET", "/catalog", nil)
+			rec := httptest.NewRecorder()
+
+			handler.getCatalog(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != nil {
+				var response []app.Package
+				err := json.Unmarshal(rec.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedBody, response)
+			}
+
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCacheStatsHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("CacheStats").Return(app.CacheStats{Version: 3, Snapshots: 1, Hits: 3, Misses: 1, HitRate: 0.75, Rebuilds: 1, LastRebuildMs: 1.5, AverageRebuildMs: 1.5})
+
+	handler := &Handler{app: mockApp}
+	req := httptest.NewRequest("GET", "/catalog/cache", nil)
+	rec := httptest.NewRecorder()
+
+	handler.getCacheStats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"version":3,"snapshots":1,"hits":3,"misses":1,"hitRate":0.75,"rebuilds":1,"lastRebuildMs":1.5,"averageRebuildMs":1.5}`, rec.Body.String())
+	mockApp.AssertExpectations(t)
+}
+
+func TestGetCatalogAnalysisHandler(t *testing.T) {
+	largest := 43
+	analysis := &app.CatalogAnalysis{
+		Sizes:               []int{20, 9, 6},
+		GCD:                 1,
+		LargestUnreachable:  &largest,
+		UnreachableCount:    22,
+		Unreachable:         []int{1, 2},
+		Redundant:           []int{},
+		Dominated:           []int{},
+		WorstOvershoot:      2,
+		WorstOvershootOrder: 7,
+		Warnings:            []string{"22 order quantities up to 43 cannot be shipped exactly"},
+	}
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "current catalog",
+			query: "?product=2",
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{ProductID: 2}).Return(analysis, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"sizes":[20,9,6],"gcd":1,"largestUnreachable":43,"unreachableCount":22,"unreachable":[1,2],"redundant":[],"dominated":[],"worstOvershoot":2,"worstOvershootOrder":7,"warnings":["22 order quantities up to 43 cannot be shipped exactly"]}`,
+		},
+		{
+			name:           "invalid product",
+			query:          "?product=abc",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid stored size",
+			query: "",
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{}).Return(nil, app.ErrInvalidPackSize)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("GET", "/catalog/analysis"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.getCatalogAnalysis(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, rec.Body.String())
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAnalyzeCatalogHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "add and remove",
+			requestBody: `{"productId": 2, "add": [{"size": 750, "price": 3}], "remove": [250]}`,
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{ProductID: 2, Add: []app.Package{{Size: 750, Price: 3}}, Remove: []int{250}}).
+					Return(&app.CatalogAnalysis{Sizes: []int{750}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "proposed catalog",
+			requestBody: `{"packages": [{"size": 6}, {"size": 9}]}`,
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{Packages: []app.Package{{Size: 6}, {Size: 9}}}).
+					Return(&app.CatalogAnalysis{Sizes: []int{9, 6}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "existing size",
+			requestBody: `{"add": [{"size": 250}]}`,
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{Add: []app.Package{{Size: 250}}}).Return(nil, app.ErrPackageExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "unknown size removed",
+			requestBody: `{"remove": [10]}`,
+			setupMock: func(m *MockApp) {
+				m.On("AnalyzeCatalog", mock.Anything, app.CatalogProposal{Remove: []int{10}}).Return(nil, app.ErrInvalidProposal)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/catalog/analysis", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.analyzeCatalog(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSimulateHandler(t *testing.T) {
+	report := &app.SimulationReport{
+		Orders:           1,
+		Mode:             app.ModeItems,
+		CurrentCatalog:   []int{500, 250},
+		CandidateCatalog: []int{300},
+		Lines: []app.SimulationLine{{Order: 251, SimulationDiff: app.SimulationDiff{
+			Current:   app.SimulationOutcome{Shipped: 500, Overshoot: 249, TotalPacks: 1},
+			Candidate: app.SimulationOutcome{Shipped: 300, Overshoot: 49, TotalPacks: 1},
+			Diff:      app.SimulationOutcome{Shipped: -200, Overshoot: -200},
+		}}},
+	}
+	request := app.SimulationRequest{Candidate: []app.Package{{Size: 300}}, Orders: []int{251}}
+
+	tests := []struct {
+		name            string
+		query           string
+		requestBody     string
+		setupMock       func(*MockApp)
+		expectedStatus  int
+		expectedType    string
+		expectedContent string
+	}{
+		{
+			name:        "json report",
+			requestBody: `{"candidate": [{"size": 300}], "orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("Simulate", mock.Anything, request).Return(report, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/json",
+			expectedContent: `"candidateCatalog":[300]`,
+		},
+		{
+			name:        "csv report",
+			query:       "?format=csv",
+			requestBody: `{"candidate": [{"size": 300}], "orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("Simulate", mock.Anything, request).Return(report, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "text/csv",
+			expectedContent: "251,500,300,-200,249,49,-200,1,1,0,0.00,0.00,0.00\n",
+		},
+		{
+			name:        "no orders",
+			requestBody: `{"candidate": [{"size": 300}]}`,
+			setupMock: func(m *MockApp) {
+				m.On("Simulate", mock.Anything, app.SimulationRequest{Candidate: []app.Package{{Size: 300}}}).Return(nil, app.ErrInvalidSimulation)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid candidate size",
+			requestBody: `{"candidate": [{"size": -3}], "orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("Simulate", mock.Anything, app.SimulationRequest{Candidate: []app.Package{{Size: -3}}, Orders: []int{251}}).Return(nil, app.ErrInvalidPackSize)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "unknown format",
+			query:          "?format=xml",
+			requestBody:    `{"candidate": [{"size": 300}], "orders": [251]}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/simulate"+tt.query, bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.simulate(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedType != "" {
+				require.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Body.String(), tt.expectedContent)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSimulateHandler_OrdersFile(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("request", `{"productId": 2, "candidate": [{"size": 300}], "mode": "cost"}`))
+	file, err := form.CreateFormFile("orders", "orders.csv")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("quantity\n251\n1200\n"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	mockApp := new(MockApp)
+	mockApp.On("Simulate", mock.Anything, app.SimulationRequest{
+		ProductID: 2,
+		Candidate: []app.Package{{Size: 300}},
+		Orders:    []int{251, 1200},
+		Mode:      app.ModeCost,
+	}).Return(&app.SimulationReport{Orders: 2}, nil)
+
+	handler := &Handler{app: mockApp}
+	req := httptest.NewRequest("POST", "/simulate", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	handler.simulate(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	mockApp.AssertExpectations(t)
+}
+
+func TestRecommendCatalogHandler(t *testing.T) {
+	demand := []app.DemandBucket{{Quantity: 250, Count: 3}, {Quantity: 500, Count: 1}}
+	recommendation := &app.Recommendation{Sizes: []int{500, 250}, Orders: 4, Evaluated: 10, Exhaustive: true}
+
+	tests := []struct {
+		name            string
+		query           string
+		requestBody     string
+		setupMock       func(*MockApp)
+		expectedStatus  int
+		expectedType    string
+		expectedContent string
+	}{
+		{
+			name:        "recommendation",
+			requestBody: `{"demand": [{"quantity": 250, "count": 3}, {"quantity": 500, "count": 1}], "sizes": 2}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Demand: demand, Sizes: 2}, mock.Anything).
+					Return(recommendation, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/json",
+			expectedContent: `"sizes":[500,250]`,
+		},
+		{
+			name:        "streamed progress",
+			query:       "?progress=true",
+			requestBody: `{"demand": [{"quantity": 250, "count": 3}, {"quantity": 500, "count": 1}], "sizes": 2}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Demand: demand, Sizes: 2}, mock.Anything).
+					Run(func(args mock.Arguments) {
+						progress := args.Get(2).(func(app.RecommendationProgress))
+						progress(app.RecommendationProgress{Phase: app.PhaseExhaustive, Done: 5, Total: 10, Evaluated: 5})
+					}).
+					Return(recommendation, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/x-ndjson",
+			expectedContent: `{"progress":{"phase":"exhaustive","done":5,"total":10,"evaluated":5,"best":null,"expectedOvershoot":0,"expectedPacks":0}}` + "\n" +
+				`{"recommendation":{"sizes":[500,250],"expectedOvershoot":0,"expectedPacks":0,"orders":4,"candidates":0,"evaluated":10,"exhaustive":true}}` + "\n",
+		},
+		{
+			name:        "streamed error",
+			query:       "?progress=true",
+			requestBody: `{"demand": [{"quantity": 250, "count": 3}, {"quantity": 500, "count": 1}], "sizes": 2}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Demand: demand, Sizes: 2}, mock.Anything).
+					Return(nil, errors.New("search failed"))
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/x-ndjson",
+			expectedContent: `{"error":"search failed"}` + "\n",
+		},
+		{
+			name:        "no demand",
+			requestBody: `{"sizes": 2}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Sizes: 2}, mock.Anything).
+					Return(nil, app.ErrInvalidRecommendation)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "max size too large",
+			requestBody: `{"demand": [{"quantity": 250, "count": 3}], "sizes": 2, "maxSize": 5000000}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Demand: demand[:1], Sizes: 2, MaxSize: 5000000}, mock.Anything).
+					Return(nil, app.ErrPackSizeTooLarge)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "out of time",
+			requestBody: `{"demand": [{"quantity": 250, "count": 3}, {"quantity": 500, "count": 1}], "sizes": 2}`,
+			setupMock: func(m *MockApp) {
+				m.On("RecommendCatalog", mock.Anything, app.RecommendationRequest{Demand: demand, Sizes: 2}, mock.Anything).
+					Return(nil, fmt.Errorf("recommendation stopped after 12 catalogs: %w", app.ErrTimeBudgetExceeded))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/catalog/recommendation"+tt.query, bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.recommendCatalog(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedType != "" {
+				require.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Body.String(), tt.expectedContent)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCalculateHandler_Order(t *testing.T) {
+	orderResult := &app.OrderResult{
+		Lines: []app.OrderLineResult{
+			{ProductID: 2, Product: "bolts", Result: &app.CalculationResult{
+				Requested:  12,
+				Shipped:    20,
+				Overshoot:  8,
+				TotalPacks: 2,
+				Lines:      []app.PackLine{{Size: 10, Count: 2, Items: 20}},
+				Catalog:    []int{10},
+				Solver:     "dp",
+			}},
+		},
+		Totals: app.OrderTotals{Requested: 12, Shipped: 20, Overshoot: 8, TotalPacks: 2},
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   *app.OrderResult
+	}{
+		{
+			name:        "successful calculate",
+			requestBody: `{"lines": [{"productId": 2, "quantity": 12}]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateOrder", mock.Anything, []app.OrderLine{{ProductID: 2, Quantity: 12}}, app.CalculateOptions{}).Return(orderResult, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   orderResult,
+		},
+		{
+			name:        "unknown product",
+			requestBody: `{"lines": [{"productId": 9, "quantity": 12}]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateOrder", mock.Anything, []app.OrderLine{{ProductID: 9, Quantity: 12}}, app.CalculateOptions{}).Return(nil, app.ErrProductNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "no lines",
+			requestBody: `{"lines": []}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateOrder", mock.Anything, []app.OrderLine{}, app.CalculateOptions{}).Return(nil, app.ErrInvalidOrder)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    `{"lines": 12}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+			mockApp.On("RecordCalculation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			handler := &Handler{app: mockApp}
+
+			req := httptest.NewRequest("POST", "/calculate", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.calculate(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != nil {
+				var response app.OrderResult
+				err := json.Unmarshal(rec.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedBody, &response)
 			}
 
 			mockApp.AssertExpectations(t)
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// batchRequest is the body of a batch calculation
+type batchRequest struct {
+	// Orders are the order quantities to calculate
+	Orders []int `json:"orders"`
+}
+
+// @Summary Calculate many orders
+// @Description Calculates the packages of many order sizes of the same product like /calculate, reading the catalog and the stock once and solving the orders in parallel. The stock is not taken out.
+// @Description The response is a JSON array of an item per order in the order of the request, or with format=ndjson newline delimited JSON items streamed as the orders complete, in no particular order.
+// @Description An order that fails comes back with its error in place of the result without failing the batch. The batch stops when the client disconnects.
+// @Tags Orders
+// @Accept json
+// @Produce json,application/x-ndjson
+// @Param request body batchRequest true "Order sizes, at most 10000"
+// @Param format query string false "Response format, json when omitted" Enums(json, ndjson)
+// @Param product query int false "Product, the default product when omitted"
+// @Param solver query string false "Packing strategy, the configured default is used when omitted" Enums(dp, greedy, bnb)
+// @Param mode query string false "First calculation objective, that of the policy when omitted" Enums(items, packs, cost)
+// @Param policy query string false "Policy choosing the packs, the configured default is used when omitted" Enums(items-first, packs-first, large-packs, small-packs, cost-first)
+// @Param min query []string false "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint" collectionFormat(multi)
+// @Param max query []string false "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint" collectionFormat(multi)
+// @Param tolerance query number false "Share of the order in percent that may be shipped less than ordered, e.g. 2"
+// @Param backorder query bool false "Send the shortfall later, and ship what the stock allows of an order it cannot cover"
+// @Param cartonize query bool false "Put the packs in the fewest shipping cartons"
+// @Success 200 {array} app.BatchItem "Result or error per order"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Product not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /calculate/batch [post]
+func (h *Handler) calculateBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := readJSON(r, &req); err != nil {
+		log.Printf("Error unmarshaling batch request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := calculateOptions(r)
+	if err != nil {
+		log.Printf("Error parsing calculation options: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		items := make([]app.BatchItem, len(req.Orders))
+		err := h.app.CalculateBatch(r.Context(), req.Orders, opts, func(item app.BatchItem) {
+			items[item.Index] = item
+		})
+		if err != nil {
+			log.Printf("Error calculating batch (%d orders): %v", len(req.Orders), err)
+			http.Error(w, "Failed to calculate batch: "+err.Error(), batchStatus(err))
+			return
+		}
+		writeJSON(w, items)
+	case "ndjson":
+		h.streamBatch(w, r, req.Orders, opts)
+	default:
+		http.Error(w, "Invalid format parameter", http.StatusBadRequest)
+	}
+}
+
+// streamBatch writes the items of a batch as newline delimited JSON as they complete.
+// Errors before the first item are answered with their status, later ones end the stream with an error line.
+func (h *Handler) streamBatch(w http.ResponseWriter, r *http.Request, orders []int, opts app.CalculateOptions) {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	started := false
+	err := h.app.CalculateBatch(r.Context(), orders, opts, func(item app.BatchItem) {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(item); err != nil {
+			log.Printf("Error writing batch item: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if err == nil {
+		return
+	}
+	log.Printf("Error calculating batch (%d orders): %v", len(orders), err)
+	if !started {
+		http.Error(w, "Failed to calculate batch: "+err.Error(), batchStatus(err))
+		return
+	}
+	if err := encoder.Encode(batchError{Error: err.Error()}); err != nil {
+		log.Printf("Error writing batch error: %v", err)
+	}
+}
+
+// batchError ends a batch stream that failed after its first item
+type batchError struct {
+	Error string `json:"error"`
+}
+
+// batchStatus returns the response status for a failed batch
+func batchStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidBatch), errors.Is(err, app.ErrUnknownSolver), errors.Is(err, app.ErrUnknownPolicy),
+		errors.Is(err, app.ErrInvalidMode), errors.Is(err, app.ErrInvalidTolerance):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrProductNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
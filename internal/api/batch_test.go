@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateBatchHandler(t *testing.T) {
+	// Items come in completion order, the JSON array is in request order
+	items := []app.BatchItem{
+		{Index: 1, Quantity: 12001, Error: "insufficient stock: 12001 items ordered"},
+		{Index: 0, Quantity: 251, Result: &app.CalculationResult{Shipped: 500, TotalPacks: 1}},
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		requestBody     string
+		setupMock       func(*MockApp)
+		expectedStatus  int
+		expectedType    string
+		expectedContent string
+	}{
+		{
+			name:        "json",
+			query:       "?product=2&tolerance=2",
+			requestBody: `{"orders": [251, 12001]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251, 12001}, app.CalculateOptions{ProductID: 2, Tolerance: 2}).
+					Return(items, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/json",
+			expectedContent: `[{"index":0,"quantity":251,"result":{`,
+		},
+		{
+			name:        "json error per order",
+			requestBody: `{"orders": [251, 12001]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251, 12001}, app.CalculateOptions{}).
+					Return(items, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/json",
+			expectedContent: `{"index":1,"quantity":12001,"error":"insufficient stock: 12001 items ordered"}]`,
+		},
+		{
+			name:        "ndjson",
+			query:       "?format=ndjson",
+			requestBody: `{"orders": [251, 12001]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251, 12001}, app.CalculateOptions{}).
+					Return(items, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/x-ndjson",
+			expectedContent: `{"index":1,"quantity":12001,"error":"insufficient stock: 12001 items ordered"}` + "\n" + `{"index":0,"quantity":251,"result":{`,
+		},
+		{
+			name:        "ndjson interrupted",
+			query:       "?format=ndjson",
+			requestBody: `{"orders": [251, 12001]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251, 12001}, app.CalculateOptions{}).
+					Return(items[:1], context.Canceled)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/x-ndjson",
+			expectedContent: `{"error":"context canceled"}` + "\n",
+		},
+		{
+			name:        "ndjson error before the first item",
+			query:       "?format=ndjson",
+			requestBody: `{"orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251}, app.CalculateOptions{}).
+					Return(nil, app.ErrProductNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "no orders",
+			requestBody: `{"orders": []}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{}, app.CalculateOptions{}).
+					Return(nil, app.ErrInvalidBatch)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "unknown policy",
+			query:       "?policy=fewest-boxes",
+			requestBody: `{"orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251}, app.CalculateOptions{Policy: "fewest-boxes"}).
+					Return(nil, app.ErrUnknownPolicy)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "catalog error",
+			requestBody: `{"orders": [251]}`,
+			setupMock: func(m *MockApp) {
+				m.On("CalculateBatch", mock.Anything, []int{251}, app.CalculateOptions{}).
+					Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "invalid format",
+			query:          "?format=csv",
+			requestBody:    `{"orders": [251]}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid tolerance",
+			query:          "?tolerance=some",
+			requestBody:    `{"orders": [251]}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/calculate/batch"+tt.query, bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.calculateBatch(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedType != "" {
+				require.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Body.String(), tt.expectedContent)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
@@ -1,24 +1,54 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/app"
 )
 
 // @Summary Calculate package sizes needed
-// @Description Calculates the number of packages required for an order size
+// @Description Calculates the packages required for an order size, shipping the least amount of items first and then the least amount of packs.
+// @Description In cost mode the cheapest combination of packs is shipped instead, in packs mode the least amount of packs. A policy orders the objectives and breaks ties, see app.Policy.
+// @Description The body is either the order size of a single product, or a multi-line order such as {"lines": [{"productId": 2, "quantity": 250}]}
+// @Description answered with an app.OrderResult holding the result of every line and the order totals.
+// @Description Every calculation is recorded in the calculation history, see /calculations, along with the caller named by the X-Caller header.
 // @Tags Orders
 // @Accept json
 // @Produce json
-// @Param orderSize body int true "Order size"
-// @Success 200 {object} map[string]interface{} "Calculated package details"
-// @Failure 400 {string} string "Invalid request format"
+// @Param orderSize body int true "Order size, or a multi-line order"
+// @Param X-Caller header string false "Who makes the call, recorded in the calculation history; the client address when omitted"
+// @Param product query int false "Product of a single order size, the default product when omitted"
+// @Param solver query string false "Packing strategy, the configured default is used when omitted" Enums(dp, greedy, bnb)
+// @Param mode query string false "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost, split splits the order across the warehouses" Enums(items, packs, cost, shipping, split)
+// @Param policy query string false "Policy choosing the packs, the configured default is used when omitted" Enums(items-first, packs-first, large-packs, small-packs, cost-first)
+// @Param alternatives query string false "Also list alternative combinations: 'all' for every optimal one, or the number of best ones (up to 100)"
+// @Param min query []string false "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint" collectionFormat(multi)
+// @Param max query []string false "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint" collectionFormat(multi)
+// @Param tolerance query number false "Share of the order in percent that may be shipped less than ordered, e.g. 2"
+// @Param backorder query bool false "Send the shortfall later, and ship what the stock allows of an order it cannot cover"
+// @Param cartonize query bool false "Put the packs in the fewest shipping cartons, those of all lines together for a multi-line order"
+// @Param zone query string false "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode"
+// @Param carrier query string false "Only send the cartons with this carrier"
+// @Param preference query string false "How the split mode splits the order across the warehouses, the configured default is used when omitted" Enums(nearest, fewest-shipments, lowest-overshoot)
+// @Param lat query number false "Latitude of the destination in degrees, the split mode ships from the nearest warehouses first"
+// @Param lon query number false "Longitude of the destination in degrees"
+// @Success 200 {object} app.CalculationResult "Calculated package details"
+// @Failure 400 {string} string "Invalid request format, destination, or no carrier for the zone"
+// @Failure 404 {string} string "Product not found"
+// @Failure 409 {string} string "Not enough packs in stock"
+// @Failure 422 {string} string "No pack sizes configured, pack constraints cannot be met, the packs cannot be put in cartons, or the calculation needs more memory than its budget"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "The calculation ran out of time"
 // @Router /calculate [post]
 func (h *Handler) calculate(w http.ResponseWriter, r *http.Request) {
-	var orderSizeRequest int
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error reading request body: %v", err)
@@ -27,34 +57,180 @@ func (h *Handler) calculate(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	opts, err := calculateOptions(r)
+	if err != nil {
+		log.Printf("Error parsing calculation options: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		h.calculateOrder(w, r, trimmed, opts)
+		return
+	}
+
+	var orderSizeRequest int
 	if err := json.Unmarshal(body, &orderSizeRequest); err != nil {
 		log.Printf("Error unmarshaling order size request: %v", err)
 		http.Error(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
-	packageSizes, err := h.app.GetPackages()
+	result, err := h.app.Calculate(r.Context(), orderSizeRequest, opts)
+	h.recordCalculation(r, app.CalculationRequest{Quantity: orderSizeRequest, Options: opts}, result, err)
 	if err != nil {
-		log.Printf("Error getting packages for calculation: %v", err)
-		http.Error(w, "Failed to get packages: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Error calculating packs needed (order size: %d): %v", orderSizeRequest, err)
+		http.Error(w, "Failed to calculate packs needed: "+err.Error(), calculationStatus(err))
 		return
 	}
 
-	result, err := h.app.CalculatePacksNeeded(orderSizeRequest, packageSizes)
-	if err != nil {
-		log.Printf("Error calculating packs needed (order size: %d): %v", orderSizeRequest, err)
-		http.Error(w, "Failed to calculate packs needed: "+err.Error(), http.StatusInternalServerError)
+	writeJSON(w, result)
+}
+
+// calculateOrder answers a multi-line order with the results of all lines
+func (h *Handler) calculateOrder(w http.ResponseWriter, r *http.Request, body []byte, opts app.CalculateOptions) {
+	var request struct {
+		Lines []app.OrderLine `json:"lines"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		log.Printf("Error unmarshaling order request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
-	responseBody, err := json.Marshal(result)
+	result, err := h.app.CalculateOrder(r.Context(), request.Lines, opts)
+	h.recordCalculation(r, app.CalculationRequest{Lines: request.Lines, Options: opts}, result, err)
 	if err != nil {
-		log.Printf("Error marshaling calculation result: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		log.Printf("Error calculating order (%d lines): %v", len(request.Lines), err)
+		http.Error(w, "Failed to calculate packs needed: "+err.Error(), calculationStatus(err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseBody)
+	writeJSON(w, result)
+}
+
+// calculateOptions reads the calculation options from the query parameters
+func calculateOptions(r *http.Request) (app.CalculateOptions, error) {
+	query := r.URL.Query()
+	opts := app.CalculateOptions{
+		Mode:       query.Get("mode"),
+		Policy:     query.Get("policy"),
+		Solver:     query.Get("solver"),
+		Zone:       query.Get("zone"),
+		Carrier:    query.Get("carrier"),
+		Preference: query.Get("preference"),
+	}
+
+	productID, err := productParam(r)
+	if err != nil {
+		return opts, err
+	}
+	opts.ProductID = productID
+
+	if alternatives := query.Get("alternatives"); alternatives == "all" {
+		opts.Alternatives = app.AllOptimalAlternatives
+	} else if alternatives != "" {
+		count, err := strconv.Atoi(alternatives)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid alternatives parameter")
+		}
+		opts.Alternatives = count
+	}
+
+	constraints, err := constraintParams(query["min"], query["max"])
+	if err != nil {
+		return opts, err
+	}
+	opts.Constraints = constraints
+
+	if tolerance := query.Get("tolerance"); tolerance != "" {
+		if opts.Tolerance, err = strconv.ParseFloat(tolerance, 64); err != nil {
+			return opts, fmt.Errorf("Invalid tolerance parameter")
+		}
+	}
+	if backorder := query.Get("backorder"); backorder != "" {
+		if opts.Backorder, err = strconv.ParseBool(backorder); err != nil {
+			return opts, fmt.Errorf("Invalid backorder parameter")
+		}
+	}
+	if cartonize := query.Get("cartonize"); cartonize != "" {
+		if opts.Cartonize, err = strconv.ParseBool(cartonize); err != nil {
+			return opts, fmt.Errorf("Invalid cartonize parameter")
+		}
+	}
+	if lat, lon := query.Get("lat"), query.Get("lon"); lat != "" || lon != "" {
+		var destination app.Location
+		if destination.Latitude, err = strconv.ParseFloat(lat, 64); err != nil {
+			return opts, fmt.Errorf("Invalid lat parameter")
+		}
+		if destination.Longitude, err = strconv.ParseFloat(lon, 64); err != nil {
+			return opts, fmt.Errorf("Invalid lon parameter")
+		}
+		opts.Destination = &destination
+	}
+
+	return opts, nil
+}
+
+// constraintParams reads the pack constraints from the min and max query parameters, each a
+// list of size:count pairs
+func constraintParams(minParams, maxParams []string) ([]app.PackConstraint, error) {
+	var constraints []app.PackConstraint
+	index := make(map[int]int)
+	parse := func(name string, params []string, set func(c *app.PackConstraint, count int)) error {
+		for _, param := range params {
+			for _, pair := range strings.Split(param, ",") {
+				size, count, ok := strings.Cut(strings.TrimSpace(pair), ":")
+				if !ok {
+					return fmt.Errorf("Invalid %s parameter: %q is not size:count", name, pair)
+				}
+				s, err := strconv.Atoi(size)
+				if err != nil {
+					return fmt.Errorf("Invalid %s parameter: %q is not size:count", name, pair)
+				}
+				n, err := strconv.Atoi(count)
+				if err != nil {
+					return fmt.Errorf("Invalid %s parameter: %q is not size:count", name, pair)
+				}
+				i, ok := index[s]
+				if !ok {
+					i = len(constraints)
+					index[s] = i
+					constraints = append(constraints, app.PackConstraint{Size: s})
+				}
+				set(&constraints[i], n)
+			}
+		}
+		return nil
+	}
+
+	if err := parse("min", minParams, func(c *app.PackConstraint, count int) { c.Min = count }); err != nil {
+		return nil, err
+	}
+	if err := parse("max", maxParams, func(c *app.PackConstraint, count int) { c.Max = count }); err != nil {
+		return nil, err
+	}
+	return constraints, nil
+}
+
+// calculationStatus returns the response status for a failed calculation
+func calculationStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrUnknownSolver), errors.Is(err, app.ErrInvalidAlternatives), errors.Is(err, app.ErrInvalidMode), errors.Is(err, app.ErrInvalidOrder),
+		errors.Is(err, app.ErrInvalidQuantity),
+		errors.Is(err, app.ErrInvalidConstraint), errors.Is(err, app.ErrUnknownPolicy), errors.Is(err, app.ErrInvalidTolerance),
+		errors.Is(err, app.ErrNoCarrier), errors.Is(err, app.ErrInvalidLocation):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrInvalidPackSize), errors.Is(err, app.ErrEmptyCatalog), errors.Is(err, app.ErrConstraintInfeasible),
+		errors.Is(err, app.ErrMemoryBudgetExceeded), errors.Is(err, app.ErrCannotCartonize):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, app.ErrTimeBudgetExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Get all shipping cartons
+// @Description Retrieves the shipping cartons /calculate puts packs in, with their inner dimensions in millimetres and the most weight in grams they carry
+// @Tags Cartons
+// @Produce json
+// @Success 200 {array} repo.Carton "Cartons"
+// @Failure 500 {string} string "Failed to get cartons"
+// @Router /cartons [get]
+func (h *Handler) getCartons(w http.ResponseWriter, r *http.Request) {
+	cartons, err := h.app.GetCartons()
+	if err != nil {
+		log.Printf("Error getting cartons: %v", err)
+		http.Error(w, "Failed to get cartons: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, cartons)
+}
+
+// @Summary Add a shipping carton
+// @Description Adds a shipping carton with its inner length, width and height in millimetres and the most weight in grams it carries
+// @Tags Cartons
+// @Accept json
+// @Produce json
+// @Param request body object true "Carton request" SchemaExample({"name": "small", "length": 300, "width": 200, "height": 200, "maxWeight": 10000})
+// @Success 200 {object} repo.Carton "Added carton"
+// @Failure 400 {string} string "Invalid request format or carton"
+// @Failure 409 {string} string "Carton already exists"
+// @Router /cartons [post]
+func (h *Handler) addCarton(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name      string `json:"name"`
+		Length    int    `json:"length"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		MaxWeight int    `json:"maxWeight"`
+	}
+	if err := readJSON(r, &request); err != nil {
+		log.Printf("Error reading carton request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	carton, err := h.app.AddCarton(app.Carton{
+		Name:      request.Name,
+		Length:    request.Length,
+		Width:     request.Width,
+		Height:    request.Height,
+		MaxWeight: request.MaxWeight,
+	})
+	if err != nil {
+		log.Printf("Error adding carton (name: %s): %v", request.Name, err)
+		http.Error(w, "Failed to add carton: "+err.Error(), cartonStatus(err))
+		return
+	}
+
+	writeJSON(w, carton)
+}
+
+// @Summary Delete a shipping carton
+// @Description Deletes a shipping carton, later calculations no longer put packs in it
+// @Tags Cartons
+// @Produce json
+// @Param id path int true "ID of the carton to delete"
+// @Success 200 {string} string "Carton deleted successfully"
+// @Failure 400 {string} string "Invalid carton ID"
+// @Failure 404 {string} string "Carton not found"
+// @Router /cartons/{id} [delete]
+func (h *Handler) deleteCarton(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Error parsing carton ID: %v", err)
+		http.Error(w, "Invalid carton ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.DeleteCarton(id); err != nil {
+		log.Printf("Error deleting carton (id: %d): %v", id, err)
+		http.Error(w, "Failed to delete carton: "+err.Error(), cartonStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// cartonStatus returns the response status for a failed carton change
+func cartonStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidCarton):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrCartonNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrCartonExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// @Summary Get the carrier rate tables
+// @Description Retrieves the carrier rate tables read from the rates directory at startup: per zone the weight bands from the lightest to the heaviest, and the fee every parcel costs on top
+// @Tags Cartons
+// @Produce json
+// @Success 200 {array} app.RateTable "Carrier rate tables"
+// @Router /carriers [get]
+func (h *Handler) getCarriers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.app.GetCarriers())
+}
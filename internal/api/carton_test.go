@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCartonsHandler(t *testing.T) {
+	cartons := []app.Carton{{ID: 1, Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}}
+	mockApp := new(MockApp)
+	mockApp.On("GetCartons").Return(cartons, nil)
+
+	handler := &Handler{app: mockApp}
+	rec := httptest.NewRecorder()
+
+	handler.getCartons(rec, httptest.NewRequest("GET", "/cartons", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []app.Carton
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, cartons, response)
+	mockApp.AssertExpectations(t)
+}
+
+func TestAddCartonHandler(t *testing.T) {
+	small := app.Carton{Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "successful add",
+			requestBody: `{"name": "small", "length": 300, "width": 200, "height": 200, "maxWeight": 10000}`,
+			setupMock: func(m *MockApp) {
+				added := small
+				added.ID = 1
+				m.On("AddCarton", small).Return(added, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "name taken",
+			requestBody: `{"name": "small", "length": 300, "width": 200, "height": 200, "maxWeight": 10000}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddCarton", small).Return(app.Carton{}, app.ErrCartonExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "no dimensions",
+			requestBody: `{"name": "small", "maxWeight": 10000}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddCarton", app.Carton{Name: "small", MaxWeight: 10000}).Return(app.Carton{}, app.ErrInvalidCarton)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.addCarton(rec, httptest.NewRequest("POST", "/cartons", bytes.NewBufferString(tt.requestBody)))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteCartonHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name: "successful delete",
+			id:   "2",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteCarton", 2).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not found",
+			id:   "9",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteCarton", 9).Return(app.ErrCartonNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "small",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("DELETE", "/cartons/"+tt.id, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			handler.deleteCarton(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCarriersHandler(t *testing.T) {
+	rates := []app.RateTable{{Carrier: "ParcelCo", ParcelFee: 1.2, Zones: map[string][]app.RateBand{"domestic": {{MaxWeight: 2000, Price: 4.5}}}}}
+	mockApp := new(MockApp)
+	mockApp.On("GetCarriers").Return(rates)
+
+	handler := &Handler{app: mockApp}
+	rec := httptest.NewRecorder()
+
+	handler.getCarriers(rec, httptest.NewRequest("GET", "/carriers", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []app.RateTable
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, rates, response)
+	mockApp.AssertExpectations(t)
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"encoding/json"
+	"io"
+	"log"
 	"net/http"
 
 	"github.com/klausborkowski/calculator/internal/app"
@@ -18,3 +21,28 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
+
+// readJSON decodes the request body into v
+func readJSON(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	return json.Unmarshal(body, v)
+}
+
+// writeJSON writes v as a successful JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	responseBody, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error marshaling response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBody)
+}
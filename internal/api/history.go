@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// callerHeader names who makes a /calculate call in the calculation history
+const callerHeader = "X-Caller"
+
+// @Summary Get the calculation history
+// @Description Returns the recorded /calculate calls with their request, the catalog as of the call, the result or the error, and the solver that answered them.
+// @Description Calls are sorted newest first unless asked otherwise; a page with more calls after it returns a nextCursor that continues the query.
+// @Tags Calculations
+// @Produce json
+// @Param from query string false "Only calls at or after this time, RFC 3339 or a date such as 2024-05-07"
+// @Param to query string false "Only calls before this time, RFC 3339 or a date such as 2024-05-07 which includes that day"
+// @Param minQuantity query int false "Only calls ordering at least this quantity"
+// @Param maxQuantity query int false "Only calls ordering at most this quantity"
+// @Param packSize query int false "Only calls shipping packs of this size"
+// @Param sort query string false "Sort by the time of the call or the ordered quantity" Enums(created, quantity)
+// @Param order query string false "Sort order, desc when omitted" Enums(asc, desc)
+// @Param cursor query string false "Cursor of the next page, as returned by the previous one"
+// @Param limit query int false "Page size, 50 when omitted and at most 500"
+// @Success 200 {object} app.CalculationPage "Page of the calculation history"
+// @Failure 400 {string} string "Invalid filter or cursor"
+// @Failure 500 {string} string "Failed to get calculations"
+// @Router /calculations [get]
+func (h *Handler) getCalculations(w http.ResponseWriter, r *http.Request) {
+	query, err := calculationQuery(r)
+	if err != nil {
+		log.Printf("Error parsing calculation history query: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.app.GetCalculations(query)
+	if err != nil {
+		log.Printf("Error getting calculations: %v", err)
+		http.Error(w, "Failed to get calculations: "+err.Error(), historyStatus(err))
+		return
+	}
+
+	writeJSON(w, page)
+}
+
+// recordCalculation records a /calculate call in the calculation history. A call that cannot be
+// recorded is still answered.
+func (h *Handler) recordCalculation(r *http.Request, req app.CalculationRequest, result interface{}, calcErr error) {
+	if err := h.app.RecordCalculation(caller(r), req, result, calcErr); err != nil {
+		log.Printf("Error recording calculation: %v", err)
+	}
+}
+
+// caller returns who makes a request, the X-Caller header or else the client address
+func caller(r *http.Request) string {
+	if name := r.Header.Get(callerHeader); name != "" {
+		return name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// calculationQuery reads the calculation history query from the query parameters
+func calculationQuery(r *http.Request) (app.CalculationQuery, error) {
+	params := r.URL.Query()
+	query := app.CalculationQuery{
+		Sort:   params.Get("sort"),
+		Cursor: params.Get("cursor"),
+	}
+
+	var err error
+	if query.From, err = timeParam(params.Get("from"), false); err != nil {
+		return query, fmt.Errorf("Invalid from parameter")
+	}
+	if query.To, err = timeParam(params.Get("to"), true); err != nil {
+		return query, fmt.Errorf("Invalid to parameter")
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"minQuantity", &query.MinQuantity},
+		{"maxQuantity", &query.MaxQuantity},
+		{"packSize", &query.PackSize},
+		{"limit", &query.Limit},
+	}
+	for _, param := range ints {
+		if value := params.Get(param.name); value != "" {
+			if *param.value, err = strconv.Atoi(value); err != nil {
+				return query, fmt.Errorf("Invalid %s parameter", param.name)
+			}
+		}
+	}
+
+	switch order := params.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, fmt.Errorf("Invalid order parameter: expected asc or desc")
+	}
+	return query, nil
+}
+
+// timeParam parses an RFC 3339 time or a date, the zero time when empty. A date is the start of
+// the day, or the end of it when it ends a range.
+func timeParam(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// historyStatus returns the response status for a failed calculation history query
+func historyStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidFilter):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
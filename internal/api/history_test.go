@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCalculationsHandler(t *testing.T) {
+	from := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+	page := &app.CalculationPage{
+		Calculations: []app.Calculation{{ID: 9, CreatedAt: from.Add(9 * time.Hour), Caller: "support", Quantity: 751,
+			Request: app.CalculationRequest{Quantity: 751}, Catalog: []app.Package{{ID: 1, Size: 500}},
+			Result: json.RawMessage(`{"requested":751}`), Solver: "dp", SolverVersion: app.SolverVersion}},
+		NextCursor: "next",
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:  "newest first",
+			query: "",
+			setupMock: func(m *MockApp) {
+				m.On("GetCalculations", app.CalculationQuery{}).Return(page, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "filtered",
+			query: "?from=2024-05-07&to=2024-05-07&minQuantity=250&maxQuantity=1000&packSize=500&sort=quantity&order=asc&cursor=next&limit=10",
+			setupMock: func(m *MockApp) {
+				m.On("GetCalculations", app.CalculationQuery{From: from, To: from.Add(24 * time.Hour), MinQuantity: 250,
+					MaxQuantity: 1000, PackSize: 500, Sort: app.SortQuantity, Ascending: true, Cursor: "next", Limit: 10}).Return(page, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "time range",
+			query: "?from=2024-05-07T09:00:00Z&to=2024-05-07T10:00:00Z",
+			setupMock: func(m *MockApp) {
+				m.On("GetCalculations", app.CalculationQuery{From: from.Add(9 * time.Hour), To: from.Add(10 * time.Hour)}).Return(page, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "invalid filter",
+			query: "?sort=caller",
+			setupMock: func(m *MockApp) {
+				m.On("GetCalculations", app.CalculationQuery{Sort: "caller"}).Return(nil, app.ErrInvalidFilter)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid date",
+			query:          "?from=last-tuesday",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid order",
+			query:          "?order=random",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=all",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "repository error",
+			query: "",
+			setupMock: func(m *MockApp) {
+				m.On("GetCalculations", app.CalculationQuery{}).Return(nil, errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("GET", "/calculations"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.getCalculations(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response app.CalculationPage
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, page, &response)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCalculateHandler_RecordsCalculation(t *testing.T) {
+	result := &app.CalculationResult{Requested: 251, Shipped: 500, Overshoot: 249, TotalPacks: 1}
+
+	mockApp := new(MockApp)
+	mockApp.On("Calculate", mock.Anything, 251, app.CalculateOptions{Solver: "bnb"}).Return(result, nil)
+	mockApp.On("Calculate", mock.Anything, 0, app.CalculateOptions{}).Return(nil, app.ErrInvalidOrder)
+	mockApp.On("RecordCalculation", "support", app.CalculationRequest{Quantity: 251, Options: app.CalculateOptions{Solver: "bnb"}}, result, nil).Return(nil)
+	mockApp.On("RecordCalculation", "192.0.2.1", app.CalculationRequest{}, (*app.CalculationResult)(nil), app.ErrInvalidOrder).
+		Return(errors.New("connection refused"))
+
+	handler := &Handler{app: mockApp}
+	req := httptest.NewRequest("POST", "/calculate?solver=bnb", bytes.NewBufferString("251"))
+	req.Header.Set("X-Caller", "support")
+	rec := httptest.NewRecorder()
+	handler.calculate(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// A failed call is recorded too, by the client address, and answered even when recording fails
+	rec = httptest.NewRecorder()
+	handler.calculate(rec, httptest.NewRequest("POST", "/calculate", bytes.NewBufferString("0")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	mockApp.AssertExpectations(t)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Queue a calculation job
+// @Description Queues a batch of order sizes to be calculated in the background like /calculate/batch, and returns the job to poll at /jobs/{id}. Jobs are kept in the database and continue after a restart.
+// @Tags Jobs
+// @Accept json
+// @Produce json
+// @Param request body batchRequest true "Order sizes, at most 10000"
+// @Param product query int false "Product, the default product when omitted"
+// @Param solver query string false "Packing strategy, the configured default is used when omitted" Enums(dp, greedy, bnb)
+// @Param mode query string false "First calculation objective, that of the policy when omitted" Enums(items, packs, cost)
+// @Param policy query string false "Policy choosing the packs, the configured default is used when omitted" Enums(items-first, packs-first, large-packs, small-packs, cost-first)
+// @Param min query []string false "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint" collectionFormat(multi)
+// @Param max query []string false "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint" collectionFormat(multi)
+// @Param tolerance query number false "Share of the order in percent that may be shipped less than ordered, e.g. 2"
+// @Param backorder query bool false "Send the shortfall later, and ship what the stock allows of an order it cannot cover"
+// @Param cartonize query bool false "Put the packs in the fewest shipping cartons"
+// @Success 200 {object} app.Job "Queued job"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 500 {string} string "Internal server error"
+// @Router /jobs [post]
+func (h *Handler) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := readJSON(r, &req); err != nil {
+		log.Printf("Error unmarshaling job request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := calculateOptions(r)
+	if err != nil {
+		log.Printf("Error parsing calculation options: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.app.SubmitJob(app.JobRequest{Orders: req.Orders, Options: opts})
+	if err != nil {
+		log.Printf("Error submitting job (%d orders): %v", len(req.Orders), err)
+		http.Error(w, "Failed to submit job: "+err.Error(), jobStatus(err))
+		return
+	}
+
+	writeJSON(w, job)
+}
+
+// @Summary Get a calculation job
+// @Description Returns the status and progress of a job, and its result once it succeeded: an item per order in the order of the request, with its result or error
+// @Tags Jobs
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} app.Job "Job"
+// @Failure 400 {string} string "Invalid job ID"
+// @Failure 404 {string} string "Job not found"
+// @Router /jobs/{id} [get]
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		log.Printf("Error parsing job ID: %v", err)
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.app.GetJob(id)
+	if err != nil {
+		log.Printf("Error getting job (id: %d): %v", id, err)
+		http.Error(w, "Failed to get job: "+err.Error(), jobStatus(err))
+		return
+	}
+
+	writeJSON(w, job)
+}
+
+// @Summary Cancel a calculation job
+// @Description Cancels a queued or running job, a running job stops calculating
+// @Tags Jobs
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} app.Job "Cancelled job"
+// @Failure 400 {string} string "Invalid job ID"
+// @Failure 404 {string} string "Job not found"
+// @Failure 409 {string} string "Job already finished"
+// @Router /jobs/{id}/cancel [post]
+func (h *Handler) cancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		log.Printf("Error parsing job ID: %v", err)
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.app.CancelJob(id)
+	if err != nil {
+		log.Printf("Error cancelling job (id: %d): %v", id, err)
+		http.Error(w, "Failed to cancel job: "+err.Error(), jobStatus(err))
+		return
+	}
+
+	writeJSON(w, job)
+}
+
+// jobID reads the job ID path parameter
+func jobID(r *http.Request) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, "id"))
+}
+
+// jobStatus returns the response status for a failed job request
+func jobStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrJobFinished):
+		return http.StatusConflict
+	default:
+		return batchStatus(err)
+	}
+}
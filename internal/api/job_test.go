@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/require"
+)
+
+// withJobID adds the id URL parameter to a request
+func withJobID(req *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestSubmitJobHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedJob    *app.Job
+	}{
+		{
+			name:        "queued",
+			query:       "?product=2&policy=packs-first",
+			requestBody: `{"orders": [251, 12001]}`,
+			setupMock: func(m *MockApp) {
+				req := app.JobRequest{Orders: []int{251, 12001}, Options: app.CalculateOptions{ProductID: 2, Policy: "packs-first"}}
+				m.On("SubmitJob", req).Return(&app.Job{ID: 3, Status: app.JobQueued, Request: req, Total: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedJob: &app.Job{ID: 3, Status: app.JobQueued, Total: 2,
+				Request: app.JobRequest{Orders: []int{251, 12001}, Options: app.CalculateOptions{ProductID: 2, Policy: "packs-first"}}},
+		},
+		{
+			name:        "no orders",
+			requestBody: `{"orders": []}`,
+			setupMock: func(m *MockApp) {
+				m.On("SubmitJob", app.JobRequest{Orders: []int{}}).Return(nil, app.ErrInvalidBatch)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid tolerance",
+			query:          "?tolerance=some",
+			requestBody:    `{"orders": [251]}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/jobs"+tt.query, bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.submitJob(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedJob != nil {
+				var job app.Job
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+				require.Equal(t, *tt.expectedJob, job)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetJobHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		id              string
+		setupMock       func(*MockApp)
+		expectedStatus  int
+		expectedContent string
+	}{
+		{
+			name: "succeeded",
+			id:   "3",
+			setupMock: func(m *MockApp) {
+				m.On("GetJob", 3).Return(&app.Job{
+					ID:     3,
+					Status: app.JobSucceeded,
+					Done:   1,
+					Total:  1,
+					Result: []app.BatchItem{{Index: 0, Quantity: 251, Result: &app.CalculationResult{Shipped: 500, TotalPacks: 1}}},
+				}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedContent: `"status":"succeeded"`,
+		},
+		{
+			name: "not found",
+			id:   "9",
+			setupMock: func(m *MockApp) {
+				m.On("GetJob", 9).Return(nil, app.ErrJobNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "latest",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.getJob(rec, withJobID(httptest.NewRequest("GET", "/jobs/"+tt.id, nil), tt.id))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.Contains(t, rec.Body.String(), tt.expectedContent)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelJobHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name: "cancelled",
+			id:   "3",
+			setupMock: func(m *MockApp) {
+				m.On("CancelJob", 3).Return(&app.Job{ID: 3, Status: app.JobCancelled}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "already finished",
+			id:   "3",
+			setupMock: func(m *MockApp) {
+				m.On("CancelJob", 3).Return(nil, app.ErrJobFinished)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "not found",
+			id:   "9",
+			setupMock: func(m *MockApp) {
+				m.On("CancelJob", 9).Return(nil, app.ErrJobNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "latest",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.cancelJob(rec, withJobID(httptest.NewRequest("POST", "/jobs/"+tt.id+"/cancel", nil), tt.id))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Create an order
+// @Description Calculates the packages for an order like /calculate and stores the order, pending, with the packages as its plan.
+// @Description The order then moves through reserved, packed and shipped with /orders/{id}/status; reserving it takes the packages out of stock.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param orderSize body int true "Order size"
+// @Param product query int false "Product, the default product when omitted"
+// @Param solver query string false "Packing strategy, the configured default is used when omitted" Enums(dp, greedy, bnb)
+// @Param mode query string false "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost" Enums(items, packs, cost, shipping)
+// @Param policy query string false "Policy choosing the packs, the configured default is used when omitted" Enums(items-first, packs-first, large-packs, small-packs, cost-first)
+// @Param min query []string false "Least packs of a size as size:count, e.g. 250:1, replacing the stored constraint" collectionFormat(multi)
+// @Param max query []string false "Most packs of a size as size:count, e.g. 5000:2, replacing the stored constraint" collectionFormat(multi)
+// @Param tolerance query number false "Share of the order in percent that may be shipped less than ordered, e.g. 2"
+// @Param backorder query bool false "Send the shortfall later, and ship what the stock allows of an order it cannot cover"
+// @Param cartonize query bool false "Put the packs in the fewest shipping cartons"
+// @Param zone query string false "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode"
+// @Param carrier query string false "Only send the cartons with this carrier"
+// @Success 200 {object} app.Order "Created order"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Product not found"
+// @Failure 409 {string} string "Not enough packs in stock"
+// @Failure 422 {string} string "No pack sizes configured, pack constraints cannot be met, the packs cannot be put in cartons, or the calculation needs more memory than its budget"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "The calculation ran out of time"
+// @Router /orders [post]
+func (h *Handler) createOrder(w http.ResponseWriter, r *http.Request) {
+	var orderSizeRequest int
+	if err := readJSON(r, &orderSizeRequest); err != nil {
+		log.Printf("Error reading order size request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := calculateOptions(r)
+	if err != nil {
+		log.Printf("Error parsing calculation options: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.app.CreateOrder(r.Context(), orderSizeRequest, opts)
+	if err != nil {
+		log.Printf("Error creating order (order size: %d): %v", orderSizeRequest, err)
+		http.Error(w, "Failed to create order: "+err.Error(), calculationStatus(err))
+		return
+	}
+
+	writeJSON(w, order)
+}
+
+// @Summary Get orders
+// @Description Retrieves the orders with their status and plan, newest first
+// @Tags Orders
+// @Produce json
+// @Param status query string false "Only the orders in this status" Enums(pending, reserved, packed, shipped, cancelled)
+// @Success 200 {array} app.Order "Orders"
+// @Failure 400 {string} string "Unknown status"
+// @Failure 500 {string} string "Failed to get orders"
+// @Router /orders [get]
+func (h *Handler) getOrders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	orders, err := h.app.GetOrders(status)
+	if err != nil {
+		log.Printf("Error getting orders (status: %s): %v", status, err)
+		http.Error(w, "Failed to get orders: "+err.Error(), orderStatus(err))
+		return
+	}
+
+	writeJSON(w, orders)
+}
+
+// @Summary Get an order
+// @Description Returns an order with its status and the packages calculated for it
+// @Tags Orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} app.Order "Order"
+// @Failure 400 {string} string "Invalid order ID"
+// @Failure 404 {string} string "Order not found"
+// @Router /orders/{id} [get]
+func (h *Handler) getOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := orderID(r)
+	if err != nil {
+		log.Printf("Error parsing order ID: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.app.GetOrder(id)
+	if err != nil {
+		log.Printf("Error getting order (id: %d): %v", id, err)
+		http.Error(w, "Failed to get order: "+err.Error(), orderStatus(err))
+		return
+	}
+
+	writeJSON(w, order)
+}
+
+// @Summary Move an order to a status
+// @Description Moves an order from pending to reserved, packed and shipped in turn, or cancels it until it is shipped.
+// @Description Reserving an order takes its packages out of stock and cancelling a reserved or packed order puts them back, in the same transaction as the status change.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body object true "Status request" SchemaExample({"status": "reserved"})
+// @Success 200 {object} app.Order "Updated order"
+// @Failure 400 {string} string "Invalid request format or unknown status"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "The order cannot move to the status, or not enough packs in stock"
+// @Router /orders/{id}/status [put]
+func (h *Handler) setOrderStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := orderID(r)
+	if err != nil {
+		log.Printf("Error parsing order ID: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+	}
+	if err := readJSON(r, &request); err != nil {
+		log.Printf("Error reading order status request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.app.SetOrderStatus(id, request.Status)
+	if err != nil {
+		log.Printf("Error setting order status (id: %d, status: %s): %v", id, request.Status, err)
+		http.Error(w, "Failed to set order status: "+err.Error(), orderStatus(err))
+		return
+	}
+
+	writeJSON(w, order)
+}
+
+// orderID reads the order ID path parameter
+func orderID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Invalid order ID")
+	}
+	return id, nil
+}
+
+// orderStatus returns the response status for a failed order request
+func orderStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrUnknownStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrInvalidTransition), errors.Is(err, app.ErrInsufficientStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
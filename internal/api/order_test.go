@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateOrderHandler(t *testing.T) {
+	plan := &app.CalculationResult{Requested: 251, Shipped: 500, Overshoot: 249, TotalPacks: 1}
+
+	tests := []struct {
+		name           string
+		query          string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "created",
+			query:       "?product=2",
+			requestBody: "251",
+			setupMock: func(m *MockApp) {
+				m.On("CreateOrder", mock.Anything, 251, app.CalculateOptions{ProductID: 2}).
+					Return(&app.Order{ID: 4, ProductID: 2, Quantity: 251, Status: app.OrderPending, Plan: plan}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "not enough stock",
+			requestBody: "251",
+			setupMock: func(m *MockApp) {
+				m.On("CreateOrder", mock.Anything, 251, app.CalculateOptions{}).Return(nil, app.ErrInsufficientStock)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/orders"+tt.query, bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.createOrder(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var order app.Order
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&order))
+				require.Equal(t, app.OrderPending, order.Status)
+				require.Equal(t, plan, order.Plan)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrdersHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("GetOrders", app.OrderReserved).Return([]*app.Order{{ID: 4, Quantity: 251, Status: app.OrderReserved}}, nil)
+	mockApp.On("GetOrders", "lost").Return(nil, app.ErrUnknownStatus)
+
+	handler := &Handler{app: mockApp}
+	rec := httptest.NewRecorder()
+	handler.getOrders(rec, httptest.NewRequest("GET", "/orders?status=reserved", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var orders []app.Order
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&orders))
+	require.Len(t, orders, 1)
+
+	rec = httptest.NewRecorder()
+	handler.getOrders(rec, httptest.NewRequest("GET", "/orders?status=lost", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	mockApp.AssertExpectations(t)
+}
+
+func TestGetOrderHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("GetOrder", 4).Return(&app.Order{ID: 4, Quantity: 251, Status: app.OrderPacked}, nil)
+	mockApp.On("GetOrder", 5).Return(nil, app.ErrOrderNotFound)
+
+	handler := &Handler{app: mockApp}
+	for id, status := range map[string]int{"4": http.StatusOK, "5": http.StatusNotFound, "x": http.StatusBadRequest} {
+		rec := httptest.NewRecorder()
+		handler.getOrder(rec, withRouteParams(httptest.NewRequest("GET", "/orders/"+id, nil), "id", id))
+		require.Equal(t, status, rec.Code, id)
+	}
+	mockApp.AssertExpectations(t)
+}
+
+func TestSetOrderStatusHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "reserved",
+			requestBody: `{"status": "reserved"}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetOrderStatus", 4, app.OrderReserved).Return(&app.Order{ID: 4, Status: app.OrderReserved}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "invalid transition",
+			requestBody: `{"status": "shipped"}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetOrderStatus", 4, app.OrderShipped).Return(nil, app.ErrInvalidTransition)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "stock taken by another order",
+			requestBody: `{"status": "reserved"}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetOrderStatus", 4, app.OrderReserved).Return(nil, app.ErrInsufficientStock)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "unknown status",
+			requestBody: `{"status": "lost"}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetOrderStatus", 4, "lost").Return(nil, app.ErrUnknownStatus)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "order not found",
+			requestBody: `{"status": "cancelled"}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetOrderStatus", 4, app.OrderCancelled).Return(nil, app.ErrOrderNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("PUT", "/orders/4/status", bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.setOrderStatus(rec, withRouteParams(req, "id", "4"))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
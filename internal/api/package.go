@@ -2,25 +2,46 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
 )
 
 // @Summary Add a new package size
-// @Description Adds a new package size to the system
+// @Description Adds a new package size to the catalog of a product in a warehouse, the default product and warehouse when productId and warehouseId are omitted, with an optional price and handling cost per pack
+// @Description and the least and most packs of the size every order ships (0 for none).
+// @Description A pack holding innerCount packs of the package innerId of the same product, e.g. a case of 4 boxes, may omit packageSize, it is the items of the packs it holds.
+// @Description The outer length, width and height in millimetres and the weight in grams of a pack are optional, /calculate needs them to put packs in cartons.
 // @Tags Packages
 // @Accept json
 // @Produce json
-// @Param request body object true "Package size request" SchemaExample({"packageSize": 10})
+// @Param request body object true "Package size request" SchemaExample({"packageSize": 10, "productId": 1, "warehouseId": 1, "price": 2.5, "handlingCost": 0.3, "minCount": 0, "maxCount": 4, "innerId": 0, "innerCount": 0, "length": 200, "width": 150, "height": 100, "weight": 2000})
 // @Success 200 {string} string "Package added successfully"
-// @Failure 400 {string} string "Invalid request format"
+// @Failure 400 {string} string "Invalid request format, pack constraint, inner pack or dimensions"
+// @Failure 404 {string} string "Product or warehouse not found"
+// @Failure 409 {string} string "Package size already exists"
+// @Failure 422 {string} string "Pack size is not positive or above the maximum"
 // @Router /package [post]
 func (h *Handler) addPackage(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		PackageSize int `json:"packageSize"`
+		PackageSize  int     `json:"packageSize"`
+		ProductID    int     `json:"productId"`
+		WarehouseID  int     `json:"warehouseId"`
+		Price        float64 `json:"price"`
+		HandlingCost float64 `json:"handlingCost"`
+		MinCount     int     `json:"minCount"`
+		MaxCount     int     `json:"maxCount"`
+		InnerID      int     `json:"innerId"`
+		InnerCount   int     `json:"innerCount"`
+		Length       int     `json:"length"`
+		Width        int     `json:"width"`
+		Height       int     `json:"height"`
+		Weight       int     `json:"weight"`
 	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -36,14 +57,92 @@ func (h *Handler) addPackage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.app.AddPackage(request.PackageSize); err != nil {
+	pkg := app.Package{
+		ProductID:    request.ProductID,
+		WarehouseID:  request.WarehouseID,
+		Size:         request.PackageSize,
+		Price:        request.Price,
+		HandlingCost: request.HandlingCost,
+		MinCount:     request.MinCount,
+		MaxCount:     request.MaxCount,
+		InnerID:      request.InnerID,
+		InnerCount:   request.InnerCount,
+		Length:       request.Length,
+		Width:        request.Width,
+		Height:       request.Height,
+		Weight:       request.Weight,
+	}
+	if err := h.app.AddPackage(pkg); err != nil {
 		log.Printf("Error adding package (size: %d): %v", request.PackageSize, err)
-		http.Error(w, "Failed to add package: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to add package: "+err.Error(), packageStatus(err))
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// packageStatus returns the response status for a package that could not be added
+func packageStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidPackSize), errors.Is(err, app.ErrPackSizeTooLarge), errors.Is(err, app.ErrMemoryBudgetExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, app.ErrTimeBudgetExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, app.ErrProductNotFound), errors.Is(err, app.ErrWarehouseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrPackageExists):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrInvalidProposal), errors.Is(err, app.ErrInvalidConstraint), errors.Is(err, app.ErrInvalidNesting),
+		errors.Is(err, app.ErrInvalidDimensions):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// @Summary Set the pack constraints of a package
+// @Description Sets the least and most packs of a package every order of its product ships, 0 for none. Requests can override them with the min and max parameters of /calculate.
+// @Tags Packages
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the package"
+// @Param request body object true "Pack constraints" SchemaExample({"minCount": 1, "maxCount": 4})
+// @Success 200 {object} repo.Package "Updated package"
+// @Failure 400 {string} string "Invalid request format or pack constraint"
+// @Failure 404 {string} string "Package not found"
+// @Router /package/{id}/constraints [put]
+func (h *Handler) setPackageConstraints(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid package ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		MinCount int `json:"minCount"`
+		MaxCount int `json:"maxCount"`
+	}
+	if err := readJSON(r, &request); err != nil {
+		log.Printf("Error unmarshaling pack constraints: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	pkg, err := h.app.SetPackageConstraints(id, request.MinCount, request.MaxCount)
+	if err != nil {
+		log.Printf("Error setting pack constraints (id: %d): %v", id, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, app.ErrInvalidConstraint):
+			status = http.StatusBadRequest
+		case errors.Is(err, app.ErrPackageNotFound):
+			status = http.StatusNotFound
+		}
+		http.Error(w, "Failed to set pack constraints: "+err.Error(), status)
+		return
+	}
+	writeJSON(w, pkg)
+}
+
 // @Summary Delete a package
 // @Description Deletes a package by its ID
 // @Tags Packages
@@ -52,6 +151,8 @@ func (h *Handler) addPackage(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "ID of the package to delete"
 // @Success 200 {string} string "Package deleted successfully"
 // @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Package not found"
+// @Failure 409 {string} string "Package is held by other packs"
 // @Router /package/{id} [delete]
 func (h *Handler) deletePackage(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -63,22 +164,37 @@ func (h *Handler) deletePackage(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.app.DeletePackage(id); err != nil {
 		log.Printf("Error deleting package (id: %s): %v", id, err)
-		http.Error(w, "Failed to delete package: "+err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, app.ErrPackageNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, app.ErrPackageInUse):
+			status = http.StatusConflict
+		}
+		http.Error(w, "Failed to delete package: "+err.Error(), status)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
 // @Summary Get all package sizes
-// @Description Retrieves a list of all available package sizes
+// @Description Retrieves the package sizes of a product in the default warehouse by package ID
 // @Tags Packages
 // @Accept json
 // @Produce json
+// @Param product query int false "Product, the default product when omitted"
 // @Success 200 {object} map[string]int "List of package sizes"
+// @Failure 400 {string} string "Invalid product parameter"
 // @Failure 500 {string} string "Failed to encode response"
 // @Router /packages [get]
 func (h *Handler) getPackages(w http.ResponseWriter, r *http.Request) {
-	packages, err := h.app.GetPackagesMap()
+	productID, err := productParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packages, err := h.app.GetPackagesMap(productID)
 	if err != nil {
 		log.Printf("Error getting packages: %v", err)
 		http.Error(w, "Failed to get packages: "+err.Error(), http.StatusInternalServerError)
@@ -96,3 +212,104 @@ func (h *Handler) getPackages(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(responseBody)
 }
+
+// @Summary Get the package catalog
+// @Description Retrieves every package of a product with its size, price and handling cost, ordered by size
+// @Tags Packages
+// @Accept json
+// @Produce json
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {array} repo.Package "Package catalog"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 500 {string} string "Failed to get catalog"
+// @Router /catalog [get]
+func (h *Handler) getCatalog(w http.ResponseWriter, r *http.Request) {
+	productID, err := productParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalog, err := h.app.GetCatalog(productID)
+	if err != nil {
+		log.Printf("Error getting catalog: %v", err)
+		http.Error(w, "Failed to get catalog: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responseBody, err := json.Marshal(catalog)
+	if err != nil {
+		log.Printf("Error marshaling catalog response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBody)
+}
+
+// @Summary Get catalog cache statistics
+// @Description Reports how often calculations were served from the in-memory package catalogs and how long rebuilding them took. A catalog is rebuilt after a package of its product is added or deleted.
+// @Tags Packages
+// @Produce json
+// @Success 200 {object} app.CacheStats "Cache statistics"
+// @Router /catalog/cache [get]
+func (h *Handler) getCacheStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.app.CacheStats())
+}
+
+// @Summary Analyse the package catalog
+// @Description Reports which order quantities the catalog of a product cannot ship exactly, the largest of them (the Frobenius number), sizes other packs cover for less and the worst overshoot, with warnings in plain words
+// @Tags Packages
+// @Produce json
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {object} app.CatalogAnalysis "Catalog analysis"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 422 {string} string "The catalog has a pack size that is not positive, or the analysis needs more memory than its budget"
+// @Failure 503 {string} string "The analysis ran out of time"
+// @Router /catalog/analysis [get]
+func (h *Handler) getCatalogAnalysis(w http.ResponseWriter, r *http.Request) {
+	productID, err := productParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := h.app.AnalyzeCatalog(r.Context(), app.CatalogProposal{ProductID: productID})
+	if err != nil {
+		log.Printf("Error analysing catalog (product: %d): %v", productID, err)
+		http.Error(w, "Failed to analyse catalog: "+err.Error(), packageStatus(err))
+		return
+	}
+	writeJSON(w, analysis)
+}
+
+// @Summary Analyse a proposed package catalog
+// @Description Analyses the catalog of a product with packages added or removed, or the given packages instead, like GET /catalog/analysis. Use it to preview the warnings of adding or deleting a package.
+// @Tags Packages
+// @Accept json
+// @Produce json
+// @Param request body app.CatalogProposal true "Proposed catalog"
+// @Success 200 {object} app.CatalogAnalysis "Catalog analysis"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 409 {string} string "Package size already exists"
+// @Failure 422 {string} string "Pack size is not positive or above the maximum, or the analysis needs more memory than its budget"
+// @Failure 503 {string} string "The analysis ran out of time"
+// @Router /catalog/analysis [post]
+func (h *Handler) analyzeCatalog(w http.ResponseWriter, r *http.Request) {
+	var proposal app.CatalogProposal
+	if err := readJSON(r, &proposal); err != nil {
+		log.Printf("Error unmarshaling catalog proposal: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := h.app.AnalyzeCatalog(r.Context(), proposal)
+	if err != nil {
+		log.Printf("Error analysing proposed catalog: %v", err)
+		http.Error(w, "Failed to analyse catalog: "+err.Error(), packageStatus(err))
+		return
+	}
+	writeJSON(w, analysis)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Get all products
+// @Description Retrieves every product, each with its own package catalog and stock
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Success 200 {array} repo.Product "Products"
+// @Failure 500 {string} string "Failed to get products"
+// @Router /products [get]
+func (h *Handler) getProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.app.GetProducts()
+	if err != nil {
+		log.Printf("Error getting products: %v", err)
+		http.Error(w, "Failed to get products: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, products)
+}
+
+// @Summary Add a product
+// @Description Adds a product with an empty package catalog
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param request body object true "Product request" SchemaExample({"name": "bolts"})
+// @Success 200 {object} repo.Product "Added product"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 409 {string} string "Product already exists"
+// @Router /products [post]
+func (h *Handler) addProduct(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := readJSON(r, &request); err != nil {
+		log.Printf("Error reading product request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.app.AddProduct(request.Name)
+	if err != nil {
+		log.Printf("Error adding product (name: %s): %v", request.Name, err)
+		http.Error(w, "Failed to add product: "+err.Error(), productStatus(err))
+		return
+	}
+
+	writeJSON(w, product)
+}
+
+// @Summary Delete a product
+// @Description Deletes a product along with its package catalog and stock, the default product cannot be deleted
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the product to delete"
+// @Success 200 {string} string "Product deleted successfully"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Product not found"
+// @Router /products/{id} [delete]
+func (h *Handler) deleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Error parsing product ID: %v", err)
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.DeleteProduct(id); err != nil {
+		log.Printf("Error deleting product (id: %d): %v", id, err)
+		http.Error(w, "Failed to delete product: "+err.Error(), productStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// productParam reads the product query parameter, zero when it is not set
+func productParam(r *http.Request) (int, error) {
+	product := r.URL.Query().Get("product")
+	if product == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(product)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Invalid product parameter")
+	}
+	return id, nil
+}
+
+// productStatus returns the response status for a failed product change
+func productStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidProduct):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrProductExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProductsHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("GetProducts").Return([]app.Product{{ID: 1, Name: "default"}, {ID: 2, Name: "bolts"}}, nil)
+
+	handler := &Handler{app: mockApp}
+	rec := httptest.NewRecorder()
+
+	handler.getProducts(rec, httptest.NewRequest("GET", "/products", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []app.Product
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, []app.Product{{ID: 1, Name: "default"}, {ID: 2, Name: "bolts"}}, response)
+	mockApp.AssertExpectations(t)
+}
+
+func TestAddProductHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "successful add",
+			requestBody: `{"name": "bolts"}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddProduct", "bolts").Return(app.Product{ID: 2, Name: "bolts"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "name taken",
+			requestBody: `{"name": "bolts"}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddProduct", "bolts").Return(app.Product{}, app.ErrProductExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "empty name",
+			requestBody: `{"name": ""}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddProduct", "").Return(app.Product{}, app.ErrInvalidProduct)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.addProduct(rec, httptest.NewRequest("POST", "/products", bytes.NewBufferString(tt.requestBody)))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteProductHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name: "successful delete",
+			id:   "2",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteProduct", 2).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not found",
+			id:   "9",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteProduct", 9).Return(app.ErrProductNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "bolts",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("DELETE", "/products/"+tt.id, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			handler.deleteProduct(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
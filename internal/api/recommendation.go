@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// recommendationEvent is a line of a streamed recommendation: progress reports followed by the
+// recommendation or an error
+type recommendationEvent struct {
+	Progress       *app.RecommendationProgress `json:"progress,omitempty"`
+	Recommendation *app.Recommendation         `json:"recommendation,omitempty"`
+	Error          string                      `json:"error,omitempty"`
+}
+
+// @Summary Recommend a package catalog
+// @Description Searches the pack sizes that minimise the expected overshoot, then the expected pack count, of a histogram of order quantities. packWeight trades overshoot for packs: one more pack per order is worth that many over-shipped items.
+// @Description Without progress the search stops once it runs out of the time budget of a calculation.
+// @Description With progress=true the response is newline delimited JSON: progress lines while the search runs, then a line with the recommendation or the error. The search stops when the client disconnects.
+// @Tags Packages
+// @Accept json
+// @Produce json,application/x-ndjson
+// @Param request body app.RecommendationRequest true "Demand and number of sizes"
+// @Param progress query bool false "Stream progress as newline delimited JSON"
+// @Success 200 {object} app.Recommendation "Recommended catalog"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 422 {string} string "Pack size bounds are not positive or above the maximum"
+// @Failure 503 {string} string "The search ran out of time"
+// @Router /catalog/recommendation [post]
+func (h *Handler) recommendCatalog(w http.ResponseWriter, r *http.Request) {
+	var req app.RecommendationRequest
+	if err := readJSON(r, &req); err != nil {
+		log.Printf("Error unmarshaling recommendation request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("progress") != "true" {
+		recommendation, err := h.app.RecommendCatalog(r.Context(), req, nil)
+		if err != nil {
+			log.Printf("Error recommending catalog: %v", err)
+			http.Error(w, "Failed to recommend catalog: "+err.Error(), recommendationStatus(err))
+			return
+		}
+		writeJSON(w, recommendation)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	send := func(event recommendationEvent) {
+		if err := encoder.Encode(event); err != nil {
+			log.Printf("Error writing recommendation progress: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	recommendation, err := h.app.RecommendCatalog(r.Context(), req, func(p app.RecommendationProgress) {
+		send(recommendationEvent{Progress: &p})
+	})
+	if err != nil {
+		log.Printf("Error recommending catalog: %v", err)
+		send(recommendationEvent{Error: err.Error()})
+		return
+	}
+	send(recommendationEvent{Recommendation: recommendation})
+}
+
+// recommendationStatus returns the response status for a failed recommendation
+func recommendationStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidRecommendation):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrInvalidPackSize), errors.Is(err, app.ErrPackSizeTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, app.ErrTimeBudgetExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
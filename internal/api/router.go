@@ -17,10 +17,50 @@ func (h *Handler) Router() http.Handler {
 	r.Get("/health", h.HealthCheck)
 
 	r.Post("/calculate", h.calculate)
+	r.Get("/calculations", h.getCalculations)
+	r.Post("/calculate/batch", h.calculateBatch)
+	r.Post("/simulate", h.simulate)
+
+	r.Post("/jobs", h.submitJob)
+	r.Get("/jobs/{id}", h.getJob)
+	r.Post("/jobs/{id}/cancel", h.cancelJob)
+
+	r.Get("/orders", h.getOrders)
+	r.Post("/orders", h.createOrder)
+	r.Get("/orders/{id}", h.getOrder)
+	r.Put("/orders/{id}/status", h.setOrderStatus)
 
 	r.Post("/package", h.addPackage)
 	r.Delete("/package/{id}", h.deletePackage)
+	r.Put("/package/{id}/constraints", h.setPackageConstraints)
 	r.Get("/packages", h.getPackages)
+	r.Get("/catalog", h.getCatalog)
+	r.Get("/catalog/cache", h.getCacheStats)
+	r.Get("/catalog/analysis", h.getCatalogAnalysis)
+	r.Post("/catalog/analysis", h.analyzeCatalog)
+	r.Post("/catalog/recommendation", h.recommendCatalog)
+
+	r.Get("/products", h.getProducts)
+	r.Post("/products", h.addProduct)
+	r.Delete("/products/{id}", h.deleteProduct)
+
+	r.Get("/cartons", h.getCartons)
+	r.Post("/cartons", h.addCarton)
+	r.Delete("/cartons/{id}", h.deleteCarton)
+	r.Get("/carriers", h.getCarriers)
+
+	r.Get("/warehouses", h.getWarehouses)
+	r.Post("/warehouses", h.addWarehouse)
+	r.Delete("/warehouses/{id}", h.deleteWarehouse)
+	r.Get("/warehouses/{id}/catalog", h.getWarehouseCatalog)
+	r.Get("/warehouses/{id}/stock", h.getWarehouseStock)
+	r.Put("/warehouses/{id}/stock/{size}", h.setWarehouseStock)
+
+	r.Get("/stock", h.getStock)
+	r.Post("/stock/commit", h.commitOrder)
+	r.Put("/stock/{size}", h.setStock)
+	r.Patch("/stock/{size}", h.adjustStock)
+	r.Delete("/stock/{size}", h.deleteStock)
 
 	// Swagger UI
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
@@ -33,8 +73,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Caller")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight requests (OPTIONS)
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// maxSimulationUpload caps the size of a multipart simulation request
+const maxSimulationUpload = 10 << 20
+
+// @Summary Simulate a candidate package catalog
+// @Description Replays order quantities against the current catalog of a product and a candidate catalog and compares the results side by side: items over-shipped, packs, cost and packs used per size. Every diff is the candidate value minus the current one.
+// @Description The request is a JSON body, or multipart/form-data with the JSON in a "request" field and the order quantities in an "orders" file, one per line or in the first CSV column.
+// @Tags Orders
+// @Accept json,mpfd
+// @Produce json,text/csv
+// @Param request body app.SimulationRequest true "Simulation request"
+// @Param format query string false "Report format, json when omitted" Enums(json, csv)
+// @Success 200 {object} app.SimulationReport "Side by side report"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 422 {string} string "Pack size is not positive or above the maximum, or an order needs more memory than its budget"
+// @Failure 503 {string} string "An order ran out of time"
+// @Router /simulate [post]
+func (h *Handler) simulate(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format parameter, expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	req, err := simulationRequest(r)
+	if err != nil {
+		log.Printf("Error reading simulation request: %v", err)
+		http.Error(w, "Invalid request format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.app.Simulate(r.Context(), req)
+	if err != nil {
+		log.Printf("Error simulating catalog: %v", err)
+		http.Error(w, "Failed to simulate: "+err.Error(), simulationStatus(err))
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="simulation.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := report.WriteCSV(w); err != nil {
+			log.Printf("Error writing simulation report: %v", err)
+		}
+		return
+	}
+	writeJSON(w, report)
+}
+
+// simulationRequest reads a simulation request from a JSON body, or from a multipart form with
+// the JSON in the request field and the order quantities in the orders file
+func simulationRequest(r *http.Request) (app.SimulationRequest, error) {
+	var req app.SimulationRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return req, readJSON(r, &req)
+	}
+
+	if err := r.ParseMultipartForm(maxSimulationUpload); err != nil {
+		return req, err
+	}
+	if field := r.FormValue("request"); strings.TrimSpace(field) != "" {
+		if err := json.Unmarshal([]byte(field), &req); err != nil {
+			return req, err
+		}
+	}
+	file, _, err := r.FormFile("orders")
+	if errors.Is(err, http.ErrMissingFile) {
+		return req, nil
+	}
+	if err != nil {
+		return req, err
+	}
+	defer file.Close()
+
+	orders, err := app.ParseOrderSizes(file)
+	if err != nil {
+		return req, fmt.Errorf("orders file: %w", err)
+	}
+	req.Orders = append(req.Orders, orders...)
+	return req, nil
+}
+
+// simulationStatus returns the response status for a failed simulation
+func simulationStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidSimulation), errors.Is(err, app.ErrInvalidMode), errors.Is(err, app.ErrUnknownSolver), errors.Is(err, app.ErrPackageExists),
+		errors.Is(err, app.ErrUnknownPolicy):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrInvalidPackSize), errors.Is(err, app.ErrPackSizeTooLarge), errors.Is(err, app.ErrEmptyCatalog),
+		errors.Is(err, app.ErrMemoryBudgetExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, app.ErrTimeBudgetExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Get stock levels
+// @Description Retrieves the number of packs of a product in stock per tracked package size, sizes without a stock level never run out
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {array} repo.StockLevel "Stock levels"
+// @Failure 500 {string} string "Failed to get stock"
+// @Router /stock [get]
+func (h *Handler) getStock(w http.ResponseWriter, r *http.Request) {
+	productID, err := productParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.app.GetStock(productID)
+	if err != nil {
+		log.Printf("Error getting stock: %v", err)
+		http.Error(w, "Failed to get stock: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, stock)
+}
+
+// @Summary Set a stock level
+// @Description Sets the number of packs of a package size in stock, tracking the size if it was not yet
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param size path int true "Package size"
+// @Param product query int false "Product, the default product when omitted"
+// @Param request body object true "Stock level request" SchemaExample({"quantity": 120})
+// @Success 200 {string} string "Stock level set successfully"
+// @Failure 400 {string} string "Invalid request format"
+// @Router /stock/{size} [put]
+func (h *Handler) setStock(w http.ResponseWriter, r *http.Request) {
+	productID, size, err := stockParams(r)
+	if err != nil {
+		log.Printf("Error parsing stock parameters: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Quantity *int `json:"quantity"`
+	}
+	if err := readJSON(r, &request); err != nil || request.Quantity == nil {
+		log.Printf("Error reading stock level request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.SetStock(productID, size, *request.Quantity); err != nil {
+		log.Printf("Error setting stock (size: %d): %v", size, err)
+		http.Error(w, "Failed to set stock: "+err.Error(), stockStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// @Summary Adjust a stock level
+// @Description Adds packs to the stock of a tracked package size, or takes them out with a negative delta
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param size path int true "Package size"
+// @Param product query int false "Product, the default product when omitted"
+// @Param request body object true "Stock adjustment request" SchemaExample({"delta": -5})
+// @Success 200 {object} repo.StockLevel "Adjusted stock level"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Stock level not found"
+// @Failure 409 {string} string "Not enough packs in stock"
+// @Router /stock/{size} [patch]
+func (h *Handler) adjustStock(w http.ResponseWriter, r *http.Request) {
+	productID, size, err := stockParams(r)
+	if err != nil {
+		log.Printf("Error parsing stock parameters: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Delta *int `json:"delta"`
+	}
+	if err := readJSON(r, &request); err != nil || request.Delta == nil {
+		log.Printf("Error reading stock adjustment request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.app.AdjustStock(productID, size, *request.Delta)
+	if err != nil {
+		log.Printf("Error adjusting stock (size: %d): %v", size, err)
+		http.Error(w, "Failed to adjust stock: "+err.Error(), stockStatus(err))
+		return
+	}
+
+	writeJSON(w, level)
+}
+
+// @Summary Stop tracking a stock level
+// @Description Deletes the stock level of a package size, so it never runs out
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param size path int true "Package size"
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {string} string "Stock level deleted successfully"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Stock level not found"
+// @Router /stock/{size} [delete]
+func (h *Handler) deleteStock(w http.ResponseWriter, r *http.Request) {
+	productID, size, err := stockParams(r)
+	if err != nil {
+		log.Printf("Error parsing stock parameters: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.DeleteStock(productID, size); err != nil {
+		log.Printf("Error deleting stock (size: %d): %v", size, err)
+		http.Error(w, "Failed to delete stock: "+err.Error(), stockStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// @Summary Commit an order
+// @Description Calculates the packages for a confirmed order like /calculate and takes them out of stock in a single transaction
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param orderSize body int true "Order size"
+// @Param product query int false "Product, the default product when omitted"
+// @Param solver query string false "Packing strategy, the configured default is used when omitted" Enums(dp, greedy, bnb)
+// @Param mode query string false "First calculation objective, that of the policy when omitted; shipping chooses the packs and parcels with the lowest landed cost" Enums(items, packs, cost, shipping)
+// @Param policy query string false "Policy choosing the packs, the configured default is used when omitted" Enums(items-first, packs-first, large-packs, small-packs, cost-first)
+// @Param tolerance query number false "Share of the order in percent that may be shipped less than ordered, e.g. 2"
+// @Param backorder query bool false "Send the shortfall later, and ship what the stock allows of an order it cannot cover"
+// @Param cartonize query bool false "Put the packs in the fewest shipping cartons"
+// @Param zone query string false "Carrier zone to send the cartons to with the carrier of the lowest cost, needed by the shipping mode"
+// @Param carrier query string false "Only send the cartons with this carrier"
+// @Success 200 {object} app.CalculationResult "Committed package details"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 409 {string} string "Not enough packs in stock"
+// @Failure 422 {string} string "No pack sizes configured, pack constraints cannot be met, or the calculation needs more memory than its budget"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "The calculation ran out of time"
+// @Router /stock/commit [post]
+func (h *Handler) commitOrder(w http.ResponseWriter, r *http.Request) {
+	var orderSizeRequest int
+	if err := readJSON(r, &orderSizeRequest); err != nil {
+		log.Printf("Error reading order size request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := calculateOptions(r)
+	if err != nil {
+		log.Printf("Error parsing calculation options: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.app.CommitOrder(r.Context(), orderSizeRequest, opts)
+	if err != nil {
+		log.Printf("Error committing order (order size: %d): %v", orderSizeRequest, err)
+		http.Error(w, "Failed to commit order: "+err.Error(), calculationStatus(err))
+		return
+	}
+
+	writeJSON(w, result)
+}
+
+// stockParams reads the product query parameter and the size path parameter of a stock level
+func stockParams(r *http.Request) (int, int, error) {
+	productID, err := productParam(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := strconv.Atoi(chi.URLParam(r, "size"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid package size")
+	}
+	return productID, size, nil
+}
+
+// stockStatus returns the response status for a failed stock change
+func stockStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidStock):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrStockNotFound), errors.Is(err, app.ErrProductNotFound), errors.Is(err, app.ErrWarehouseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrInsufficientStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
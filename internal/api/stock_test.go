@@ -0,0 +1,254 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// withSize adds the size URL parameter to a request
+func withSize(req *http.Request, size string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("size", size)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestGetStockHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("GetStock", 2).Return([]app.StockLevel{{Size: 250, Quantity: 40}, {Size: 500, Quantity: 0}}, nil)
+
+	handler := &Handler{app: mockApp}
+	req := httptest.NewRequest("GET", "/stock?product=2", nil)
+	rec := httptest.NewRecorder()
+
+	handler.getStock(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []app.StockLevel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, []app.StockLevel{{Size: 250, Quantity: 40}, {Size: 500, Quantity: 0}}, response)
+	mockApp.AssertExpectations(t)
+}
+
+func TestSetStockHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		size           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "successful set",
+			size:        "500",
+			requestBody: `{"quantity": 12}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetStock", 0, 500, 12).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid size",
+			size:           "large",
+			requestBody:    `{"quantity": 12}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing quantity",
+			size:           "500",
+			requestBody:    `{}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "negative quantity",
+			size:        "500",
+			requestBody: `{"quantity": -1}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetStock", 0, 500, -1).Return(app.ErrInvalidStock)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := withSize(httptest.NewRequest("PUT", "/stock/"+tt.size, bytes.NewBufferString(tt.requestBody)), tt.size)
+			rec := httptest.NewRecorder()
+
+			handler.setStock(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdjustStockHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   *app.StockLevel
+	}{
+		{
+			name:        "successful adjust",
+			requestBody: `{"delta": -5}`,
+			setupMock: func(m *MockApp) {
+				m.On("AdjustStock", 0, 500, -5).Return(app.StockLevel{Size: 500, Quantity: 7}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   &app.StockLevel{Size: 500, Quantity: 7},
+		},
+		{
+			name:        "not tracked",
+			requestBody: `{"delta": 5}`,
+			setupMock: func(m *MockApp) {
+				m.On("AdjustStock", 0, 500, 5).Return(app.StockLevel{}, app.ErrStockNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "below zero",
+			requestBody: `{"delta": -50}`,
+			setupMock: func(m *MockApp) {
+				m.On("AdjustStock", 0, 500, -50).Return(app.StockLevel{}, app.ErrInsufficientStock)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := withSize(httptest.NewRequest("PATCH", "/stock/500", bytes.NewBufferString(tt.requestBody)), "500")
+			rec := httptest.NewRecorder()
+
+			handler.adjustStock(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != nil {
+				var response app.StockLevel
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				require.Equal(t, *tt.expectedBody, response)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteStockHandler(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("DeleteStock", 2, 500).Return(nil)
+	mockApp.On("DeleteStock", 2, 750).Return(app.ErrStockNotFound)
+
+	handler := &Handler{app: mockApp}
+
+	rec := httptest.NewRecorder()
+	handler.deleteStock(rec, withSize(httptest.NewRequest("DELETE", "/stock/500?product=2", nil), "500"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.deleteStock(rec, withSize(httptest.NewRequest("DELETE", "/stock/750?product=2", nil), "750"))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	mockApp.AssertExpectations(t)
+}
+
+func TestCommitOrderHandler(t *testing.T) {
+	committed := &app.CalculationResult{
+		Requested:  750,
+		Shipped:    750,
+		TotalPacks: 2,
+		Lines:      []app.PackLine{{Size: 500, Count: 1, Items: 500}, {Size: 250, Count: 1, Items: 250}},
+		Catalog:    []int{500, 250},
+		Solver:     "bnb",
+		Mode:       app.ModeItems,
+		Stock:      []app.StockLevel{{Size: 500, Quantity: 1}},
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+		expectedBody   *app.CalculationResult
+	}{
+		{
+			name:        "successful commit",
+			requestBody: "750",
+			setupMock: func(m *MockApp) {
+				m.On("CommitOrder", mock.Anything, 750, app.CalculateOptions{}).Return(committed, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   committed,
+		},
+		{
+			name:        "stock taken by another order",
+			requestBody: "750",
+			setupMock: func(m *MockApp) {
+				m.On("CommitOrder", mock.Anything, 750, app.CalculateOptions{}).Return(nil, app.ErrInsufficientStock)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "app error",
+			requestBody: "750",
+			setupMock: func(m *MockApp) {
+				m.On("CommitOrder", mock.Anything, 750, app.CalculateOptions{}).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			req := httptest.NewRequest("POST", "/stock/commit", bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.commitOrder(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != nil {
+				var response app.CalculationResult
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				require.Equal(t, tt.expectedBody, &response)
+			}
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+)
+
+// @Summary Get all warehouses
+// @Description Retrieves every warehouse, each with its own package catalogs and stock. The catalog and stock endpoints without a warehouse belong to the default warehouse.
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Success 200 {array} repo.Warehouse "Warehouses"
+// @Failure 500 {string} string "Failed to get warehouses"
+// @Router /warehouses [get]
+func (h *Handler) getWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.app.GetWarehouses()
+	if err != nil {
+		log.Printf("Error getting warehouses: %v", err)
+		http.Error(w, "Failed to get warehouses: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, warehouses)
+}
+
+// @Summary Add a warehouse
+// @Description Adds a warehouse with empty package catalogs and stock. The location in degrees is optional, the split mode of /calculate ships from the warehouses nearest to the destination first.
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param request body object true "Warehouse request" SchemaExample({"name": "north", "latitude": 53.55, "longitude": 9.99})
+// @Success 200 {object} repo.Warehouse "Added warehouse"
+// @Failure 400 {string} string "Invalid request format, name or location"
+// @Failure 409 {string} string "Warehouse already exists"
+// @Router /warehouses [post]
+func (h *Handler) addWarehouse(w http.ResponseWriter, r *http.Request) {
+	var warehouse app.Warehouse
+	if err := readJSON(r, &warehouse); err != nil {
+		log.Printf("Error reading warehouse request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	warehouse.ID = 0
+
+	added, err := h.app.AddWarehouse(warehouse)
+	if err != nil {
+		log.Printf("Error adding warehouse (name: %s): %v", warehouse.Name, err)
+		http.Error(w, "Failed to add warehouse: "+err.Error(), warehouseStatus(err))
+		return
+	}
+
+	writeJSON(w, added)
+}
+
+// @Summary Delete a warehouse
+// @Description Deletes a warehouse along with its package catalogs and stock, the default warehouse cannot be deleted
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the warehouse to delete"
+// @Success 200 {string} string "Warehouse deleted successfully"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Warehouse not found"
+// @Router /warehouses/{id} [delete]
+func (h *Handler) deleteWarehouse(w http.ResponseWriter, r *http.Request) {
+	id, err := warehouseParam(r)
+	if err != nil {
+		log.Printf("Error parsing warehouse ID: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.DeleteWarehouse(id); err != nil {
+		log.Printf("Error deleting warehouse (id: %d): %v", id, err)
+		http.Error(w, "Failed to delete warehouse: "+err.Error(), warehouseStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// @Summary Get the package catalog of a warehouse
+// @Description Retrieves the packages of a product in a warehouse with their costs, added with the warehouseId of /package
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {array} repo.Package "Package catalog"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 500 {string} string "Failed to get catalog"
+// @Router /warehouses/{id}/catalog [get]
+func (h *Handler) getWarehouseCatalog(w http.ResponseWriter, r *http.Request) {
+	id, productID, err := warehouseParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalog, err := h.app.GetWarehouseCatalog(id, productID)
+	if err != nil {
+		log.Printf("Error getting catalog (warehouse: %d): %v", id, err)
+		http.Error(w, "Failed to get catalog: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, catalog)
+}
+
+// @Summary Get the stock levels of a warehouse
+// @Description Retrieves the number of packs of a product in stock in a warehouse per tracked package size, sizes without a stock level never run out
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Param product query int false "Product, the default product when omitted"
+// @Success 200 {array} repo.StockLevel "Stock levels"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 500 {string} string "Failed to get stock"
+// @Router /warehouses/{id}/stock [get]
+func (h *Handler) getWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	id, productID, err := warehouseParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.app.GetWarehouseStock(id, productID)
+	if err != nil {
+		log.Printf("Error getting stock (warehouse: %d): %v", id, err)
+		http.Error(w, "Failed to get stock: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, stock)
+}
+
+// @Summary Set a stock level of a warehouse
+// @Description Sets the number of packs of a package size in stock in a warehouse, tracking the size if it was not yet
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Param size path int true "Package size"
+// @Param product query int false "Product, the default product when omitted"
+// @Param request body object true "Stock level request" SchemaExample({"quantity": 120})
+// @Success 200 {string} string "Stock level set successfully"
+// @Failure 400 {string} string "Invalid request format"
+// @Failure 404 {string} string "Warehouse or product not found"
+// @Router /warehouses/{id}/stock/{size} [put]
+func (h *Handler) setWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	id, err := warehouseParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	productID, size, err := stockParams(r)
+	if err != nil {
+		log.Printf("Error parsing stock parameters: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Quantity *int `json:"quantity"`
+	}
+	if err := readJSON(r, &request); err != nil || request.Quantity == nil {
+		log.Printf("Error reading stock level request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.app.SetWarehouseStock(id, productID, size, *request.Quantity); err != nil {
+		log.Printf("Error setting stock (warehouse: %d, size: %d): %v", id, size, err)
+		http.Error(w, "Failed to set stock: "+err.Error(), stockStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// warehouseParam reads the warehouse ID path parameter
+func warehouseParam(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Invalid warehouse ID")
+	}
+	return id, nil
+}
+
+// warehouseParams reads the warehouse ID path parameter and the product query parameter
+func warehouseParams(r *http.Request) (int, int, error) {
+	id, err := warehouseParam(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	productID, err := productParam(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, productID, nil
+}
+
+// warehouseStatus returns the response status for a failed warehouse change
+func warehouseStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidWarehouse):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrWarehouseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrWarehouseExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
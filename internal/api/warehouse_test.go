@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/klausborkowski/calculator/internal/app"
+	"github.com/stretchr/testify/require"
+)
+
+// withRouteParams adds chi path parameters given as name, value pairs to a request
+func withRouteParams(req *http.Request, params ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(params); i += 2 {
+		rctx.URLParams.Add(params[i], params[i+1])
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestGetWarehousesHandler(t *testing.T) {
+	latitude, longitude := 53.55, 9.99
+	warehouses := []app.Warehouse{{ID: 1, Name: "main"}, {ID: 2, Name: "north", Latitude: &latitude, Longitude: &longitude}}
+	mockApp := new(MockApp)
+	mockApp.On("GetWarehouses").Return(warehouses, nil)
+
+	handler := &Handler{app: mockApp}
+	rec := httptest.NewRecorder()
+
+	handler.getWarehouses(rec, httptest.NewRequest("GET", "/warehouses", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []app.Warehouse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, warehouses, response)
+	mockApp.AssertExpectations(t)
+}
+
+func TestAddWarehouseHandler(t *testing.T) {
+	latitude, longitude := 53.55, 9.99
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "successful add",
+			requestBody: `{"name": "north", "latitude": 53.55, "longitude": 9.99}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddWarehouse", app.Warehouse{Name: "north", Latitude: &latitude, Longitude: &longitude}).
+					Return(app.Warehouse{ID: 2, Name: "north", Latitude: &latitude, Longitude: &longitude}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "name taken",
+			requestBody: `{"name": "north"}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddWarehouse", app.Warehouse{Name: "north"}).Return(app.Warehouse{}, app.ErrWarehouseExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "half a location",
+			requestBody: `{"name": "north", "latitude": 53.55}`,
+			setupMock: func(m *MockApp) {
+				m.On("AddWarehouse", app.Warehouse{Name: "north", Latitude: &latitude}).Return(app.Warehouse{}, app.ErrInvalidWarehouse)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid JSON",
+			requestBody:    "invalid",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.addWarehouse(rec, httptest.NewRequest("POST", "/warehouses", bytes.NewBufferString(tt.requestBody)))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteWarehouseHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name: "successful delete",
+			id:   "2",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteWarehouse", 2).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "default warehouse",
+			id:   "1",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteWarehouse", 1).Return(app.ErrInvalidWarehouse)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			id:   "9",
+			setupMock: func(m *MockApp) {
+				m.On("DeleteWarehouse", 9).Return(app.ErrWarehouseNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "north",
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+
+			handler.deleteWarehouse(rec, withRouteParams(httptest.NewRequest("DELETE", "/warehouses/"+tt.id, nil), "id", tt.id))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWarehouseCatalogAndStockHandlers(t *testing.T) {
+	mockApp := new(MockApp)
+	mockApp.On("GetWarehouseCatalog", 2, 3).Return([]app.Package{{ID: 4, ProductID: 3, WarehouseID: 2, Size: 500}}, nil)
+	mockApp.On("GetWarehouseStock", 2, 0).Return([]app.StockLevel{{Size: 500, Quantity: 8}}, nil)
+
+	handler := &Handler{app: mockApp}
+
+	rec := httptest.NewRecorder()
+	handler.getWarehouseCatalog(rec, withRouteParams(httptest.NewRequest("GET", "/warehouses/2/catalog?product=3", nil), "id", "2"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var catalog []app.Package
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &catalog))
+	require.Equal(t, []app.Package{{ID: 4, ProductID: 3, WarehouseID: 2, Size: 500}}, catalog)
+
+	rec = httptest.NewRecorder()
+	handler.getWarehouseStock(rec, withRouteParams(httptest.NewRequest("GET", "/warehouses/2/stock", nil), "id", "2"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[{"size": 500, "quantity": 8}]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.getWarehouseStock(rec, withRouteParams(httptest.NewRequest("GET", "/warehouses/0/stock", nil), "id", "0"))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mockApp.AssertExpectations(t)
+}
+
+func TestSetWarehouseStockHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		requestBody    string
+		setupMock      func(*MockApp)
+		expectedStatus int
+	}{
+		{
+			name:        "successful set",
+			id:          "2",
+			requestBody: `{"quantity": 8}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetWarehouseStock", 2, 0, 500, 8).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "unknown warehouse",
+			id:          "9",
+			requestBody: `{"quantity": 8}`,
+			setupMock: func(m *MockApp) {
+				m.On("SetWarehouseStock", 9, 0, 500, 8).Return(app.ErrWarehouseNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing quantity",
+			id:             "2",
+			requestBody:    `{}`,
+			setupMock:      func(m *MockApp) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApp := new(MockApp)
+			tt.setupMock(mockApp)
+
+			handler := &Handler{app: mockApp}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/warehouses/"+tt.id+"/stock/500", bytes.NewBufferString(tt.requestBody))
+
+			handler.setWarehouseStock(rec, withRouteParams(req, "id", tt.id, "size", "500"))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			mockApp.AssertExpectations(t)
+		})
+	}
+}
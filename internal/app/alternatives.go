@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidAlternatives is returned when the number of requested alternatives is out of range
+var ErrInvalidAlternatives = errors.New("invalid number of alternatives")
+
+const (
+	// AllOptimalAlternatives asks for every combination tied with the optimum
+	AllOptimalAlternatives = -1
+	// MaxAlternatives caps the number of alternatives returned by a single calculation
+	MaxAlternatives = 100
+	// alternativesSolver is the Solver of a result made of an alternative rather than solved for
+	alternativesSolver = "alternatives"
+)
+
+// Alternative is one of the ranked pack combinations covering an order
+type Alternative struct {
+	// Shipped is the total number of items sent out
+	Shipped int `json:"shipped"`
+	// Overshoot is the number of items sent on top of the ordered quantity
+	Overshoot int `json:"overshoot"`
+	// TotalPacks is the total number of packs sent out
+	TotalPacks int `json:"totalPacks"`
+	// Lines are the packs sent out per size, largest size first
+	Lines []PackLine `json:"lines"`
+	// Optimal tells whether the combination ties with the best one on items and packs
+	Optimal bool `json:"optimal"`
+}
+
+// findAlternatives lists pack combinations covering the order ranked by items, then packs.
+// With count AllOptimalAlternatives every optimal combination is listed, up to MaxAlternatives,
+// otherwise the count best ones. Only minimal combinations are considered: dropping any pack
+// from them would no longer cover the order.
+func findAlternatives(ctx context.Context, orderQuantity int, packSizes []int, count int) ([]Alternative, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	if count != AllOptimalAlternatives && (count < 0 || count > MaxAlternatives) {
+		return nil, fmt.Errorf("%w: %d, expected %d for all optimal ones or 1 to %d", ErrInvalidAlternatives, count, AllOptimalAlternatives, MaxAlternatives)
+	}
+
+	sizes := distinctDescending(packSizes)
+	optimum, err := branchAndBoundSolver{}.Solve(ctx, orderQuantity, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &alternativeSearch{
+		cancelCheck: cancelCheck{ctx: ctx},
+		sizes:       sizes,
+		counts:      make([]int, len(sizes)),
+		suffixGCD:   make([]int, len(sizes)),
+		limit:       count,
+	}
+	for i := len(sizes) - 1; i >= 0; i-- {
+		s.suffixGCD[i] = sizes[i]
+		if i+1 < len(sizes) {
+			s.suffixGCD[i] = gcd(sizes[i], s.suffixGCD[i+1])
+		}
+	}
+	step := s.suffixGCD[0]
+	s.minOvershoot = ceilDiv(orderQuantity, step)*step - orderQuantity
+	if count == AllOptimalAlternatives {
+		s.limit = MaxAlternatives
+		s.bound = &rankedCombination{overshoot: optimum.Overshoot, packs: optimum.TotalPacks}
+	}
+
+	s.search(0, orderQuantity, 0)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	alternatives := make([]Alternative, 0, len(s.found))
+	for _, combination := range s.found {
+		packs := make(map[int]int)
+		for i, n := range combination.counts {
+			if n > 0 {
+				packs[sizes[i]] = n
+			}
+		}
+		result := newCalculationResult(orderQuantity, packs)
+		alternatives = append(alternatives, Alternative{
+			Shipped:    result.Shipped,
+			Overshoot:  result.Overshoot,
+			TotalPacks: result.TotalPacks,
+			Lines:      result.Lines,
+			Optimal:    result.Overshoot == optimum.Overshoot && result.TotalPacks == optimum.TotalPacks,
+		})
+	}
+	return alternatives, nil
+}
+
+// rankedCombination is a pack combination with its ranking keys
+type rankedCombination struct {
+	overshoot int
+	packs     int
+	counts    []int
+}
+
+// ranksBefore reports whether a combination with these keys ranks strictly before this one
+func (c *rankedCombination) ranksBefore(overshoot, packs int) bool {
+	return overshoot < c.overshoot || (overshoot == c.overshoot && packs < c.packs)
+}
+
+// ranksAfter reports whether a combination with these keys ranks strictly after this one
+func (c *rankedCombination) ranksAfter(overshoot, packs int) bool {
+	return overshoot > c.overshoot || (overshoot == c.overshoot && packs > c.packs)
+}
+
+// alternativeSearch is a branch and bound search keeping the best minimal combinations.
+// It walks pack counts from the largest size down like branchAndBound, but keeps a ranked list
+// instead of a single best combination and cannot rely on optimality to limit the counts.
+type alternativeSearch struct {
+	cancelCheck
+	// sizes are the distinct pack sizes in descending order
+	sizes []int
+	// counts are the pack counts of the current branch
+	counts []int
+	// suffixGCD[i] is the gcd of sizes[i:]
+	suffixGCD []int
+	// minOvershoot is the least overshoot any combination can have
+	minOvershoot int
+	// limit is the maximum number of combinations to keep
+	limit int
+	// bound, when set, rejects every combination ranking after it
+	bound *rankedCombination
+	// found are the best combinations so far, ranked
+	found []*rankedCombination
+}
+
+// cutoff returns the combination every candidate has to rank before, or nil when anything goes
+func (s *alternativeSearch) cutoff() *rankedCombination {
+	if len(s.found) == s.limit {
+		return s.found[len(s.found)-1]
+	}
+	return s.bound
+}
+
+// rejects reports whether a combination with these keys cannot make it into the list
+func (s *alternativeSearch) rejects(overshoot, packs int) bool {
+	if s.bound != nil && s.bound.ranksAfter(overshoot, packs) {
+		return true
+	}
+	if len(s.found) == s.limit {
+		// Ties with the last kept combination lose to it
+		return !s.found[len(s.found)-1].ranksBefore(overshoot, packs)
+	}
+	return false
+}
+
+func (s *alternativeSearch) search(level, remaining, packs int) {
+	size := s.sizes[level]
+	last := level == len(s.sizes)-1
+
+	// Covering the rest with this size is only minimal with exactly enough packs,
+	// fewer packs leave the rest to the smaller sizes
+	covering := ceilDiv(remaining, size)
+	s.add(level, covering, covering*size-remaining, packs+covering)
+	if last {
+		return
+	}
+
+	for count := covering - 1; count >= 0; count-- {
+		if s.stopped() {
+			break
+		}
+		rest := remaining - count*size
+
+		step := s.suffixGCD[level+1]
+		overshootBound := ceilDiv(rest, step)*step - rest
+		packsBound := packs + count + ceilDiv(rest, s.sizes[level+1])
+		if cutoff := s.cutoff(); cutoff != nil && cutoff.overshoot == s.minOvershoot && s.rejects(s.minOvershoot, packsBound) {
+			// The pack bound only grows as this count decreases
+			break
+		}
+		if s.rejects(overshootBound, packsBound) {
+			continue
+		}
+
+		s.counts[level] = count
+		s.search(level+1, rest, packs+count)
+	}
+	s.counts[level] = 0
+}
+
+// add inserts the current branch, completed with count packs at level, into the ranked list
+func (s *alternativeSearch) add(level, count, overshoot, packs int) {
+	if s.rejects(overshoot, packs) {
+		return
+	}
+
+	counts := make([]int, len(s.sizes))
+	copy(counts, s.counts[:level])
+	counts[level] = count
+	combination := &rankedCombination{overshoot: overshoot, packs: packs, counts: counts}
+
+	// Keep the list ranked, earlier finds first among ties
+	at := sort.Search(len(s.found), func(i int) bool {
+		return s.found[i].ranksBefore(overshoot, packs)
+	})
+	s.found = append(s.found, nil)
+	copy(s.found[at+1:], s.found[at:])
+	s.found[at] = combination
+	if len(s.found) > s.limit {
+		s.found = s.found[:s.limit]
+	}
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAlternatives(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderQuantity int
+		packSizes     []int
+		count         int
+		expected      []map[int]int
+		expectError   bool
+	}{
+		{
+			name:          "no alternatives requested",
+			orderQuantity: 6,
+			packSizes:     []int{1, 2, 3, 4, 5},
+			count:         0,
+			expected:      nil,
+		},
+		{
+			name:          "all optimal",
+			orderQuantity: 6,
+			packSizes:     []int{1, 2, 3, 4, 5},
+			count:         AllOptimalAlternatives,
+			expected:      []map[int]int{{5: 1, 1: 1}, {4: 1, 2: 1}, {3: 2}},
+		},
+		{
+			name:          "top k with overshoot",
+			orderQuantity: 251,
+			packSizes:     []int{250, 500},
+			count:         5,
+			expected:      []map[int]int{{500: 1}, {250: 2}},
+		},
+		{
+			name:          "top k",
+			orderQuantity: 12001,
+			packSizes:     []int{250, 500, 1000, 2000, 5000},
+			count:         3,
+			expected:      []map[int]int{{5000: 2, 2000: 1, 250: 1}, {5000: 2, 1000: 2, 250: 1}, {5000: 2, 1000: 1, 500: 2, 250: 1}},
+		},
+		{
+			name:          "too many",
+			orderQuantity: 10,
+			packSizes:     []int{3, 5},
+			count:         MaxAlternatives + 1,
+			expectError:   true,
+		},
+		{
+			name:          "negative",
+			orderQuantity: 10,
+			packSizes:     []int{3, 5},
+			count:         -2,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alternatives, err := findAlternatives(context.Background(), tt.orderQuantity, tt.packSizes, tt.count)
+			if tt.expectError {
+				require.ErrorIs(t, err, ErrInvalidAlternatives)
+				return
+			}
+			require.NoError(t, err)
+
+			var got []map[int]int
+			for _, alternative := range alternatives {
+				got = append(got, (&CalculationResult{Lines: alternative.Lines}).Packs())
+			}
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFindAlternatives_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		sizes := make([]int, 1+rng.Intn(3))
+		for j := range sizes {
+			sizes[j] = 1 + rng.Intn(15)
+		}
+		sizes = distinctDescending(sizes)
+		n := 1 + rng.Intn(40)
+		k := 1 + rng.Intn(8)
+
+		// Every minimal combination, ranked by overshoot then packs
+		type ranked struct{ overshoot, packs int }
+		var all []ranked
+		counts := make([]int, len(sizes))
+		var walk func(level int)
+		walk = func(level int) {
+			if level == len(sizes) {
+				shipped, packs, smallest := 0, 0, 0
+				for i, c := range counts {
+					shipped += c * sizes[i]
+					packs += c
+					if c > 0 {
+						smallest = sizes[i]
+					}
+				}
+				if shipped >= n && shipped-smallest < n {
+					all = append(all, ranked{shipped - n, packs})
+				}
+				return
+			}
+			for c := 0; c <= ceilDiv(n, sizes[level]); c++ {
+				counts[level] = c
+				walk(level + 1)
+			}
+		}
+		walk(0)
+		sort.Slice(all, func(a, b int) bool {
+			return all[a].overshoot < all[b].overshoot || (all[a].overshoot == all[b].overshoot && all[a].packs < all[b].packs)
+		})
+
+		alternatives, err := findAlternatives(context.Background(), n, sizes, k)
+		require.NoError(t, err)
+		require.Len(t, alternatives, min(k, len(all)), "sizes %v, order %d", sizes, n)
+		for j, alternative := range alternatives {
+			require.Equal(t, all[j].overshoot, alternative.Overshoot, "sizes %v, order %d, rank %d", sizes, n, j)
+			require.Equal(t, all[j].packs, alternative.TotalPacks, "sizes %v, order %d, rank %d", sizes, n, j)
+			require.Equal(t, all[j] == all[0], alternative.Optimal)
+		}
+
+		optimal, err := findAlternatives(context.Background(), n, sizes, AllOptimalAlternatives)
+		require.NoError(t, err)
+		ties := 0
+		for _, combination := range all {
+			if combination == all[0] {
+				ties++
+			}
+		}
+		require.Len(t, optimal, min(ties, MaxAlternatives), "sizes %v, order %d", sizes, n)
+	}
+}
+
+func TestFindAlternatives_LargeOrder(t *testing.T) {
+	alternatives, err := findAlternatives(context.Background(), 5_000_000_000_001, []int{250, 500, 1000, 2000, 5000}, 3)
+	require.NoError(t, err)
+	require.Len(t, alternatives, 3)
+	require.Equal(t, 249, alternatives[0].Overshoot)
+	require.True(t, alternatives[0].Optimal)
+}
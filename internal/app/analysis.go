@@ -0,0 +1,315 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidProposal is returned for a proposed catalog change that does not apply to the catalog
+var ErrInvalidProposal = errors.New("invalid catalog proposal")
+
+const (
+	// MaxListedUnreachable caps the unreachable quantities listed by a catalog analysis
+	MaxListedUnreachable = 100
+	// maxOvershootSteps caps the work spent looking for the worst overshoot, above it the
+	// analysis falls back to the smallest pack size as an upper bound
+	maxOvershootSteps = 50_000_000
+)
+
+// CatalogProposal is a catalog to analyse: the stored catalog of a product, changed by Add and
+// Remove, or the Packages given instead of it
+type CatalogProposal struct {
+	// ProductID is the product whose catalog is analysed, DefaultProductID when zero
+	ProductID int `json:"productId"`
+	// Packages replace the stored catalog when set
+	Packages []Package `json:"packages,omitempty"`
+	// Add are packages to add to the catalog
+	Add []Package `json:"add,omitempty"`
+	// Remove are pack sizes to take out of the catalog
+	Remove []int `json:"remove,omitempty"`
+}
+
+// CatalogAnalysis describes which order quantities a catalog can ship exactly and which of its
+// sizes are of no use
+type CatalogAnalysis struct {
+	// Sizes are the analysed pack sizes, largest first
+	Sizes []int `json:"sizes"`
+	// GCD is the greatest common divisor of the sizes, only its multiples can be shipped exactly
+	GCD int `json:"gcd"`
+	// LargestUnreachable is the largest multiple of GCD no combination of packs adds up to,
+	// the Frobenius number of the catalog when GCD is 1. Nil when every multiple is reachable.
+	LargestUnreachable *int `json:"largestUnreachable"`
+	// UnreachableCount is the number of multiples of GCD no combination of packs adds up to
+	UnreachableCount int `json:"unreachableCount"`
+	// Unreachable are the smallest of these quantities, at most MaxListedUnreachable of them
+	Unreachable []int `json:"unreachable"`
+	// Redundant are sizes that add up from other sizes: without them no order ships more
+	// items, only more packs
+	Redundant []int `json:"redundant"`
+	// Dominated are sizes never chosen in cost mode, other packs cover them for less.
+	// In items mode every size is the only best answer to an order of its own size.
+	Dominated []int `json:"dominated"`
+	// WorstOvershoot is the most items any order of at least the smallest size is overshot by
+	WorstOvershoot int `json:"worstOvershoot"`
+	// WorstOvershootOrder is the smallest order quantity overshot by WorstOvershoot,
+	// zero when the overshoot is only an upper bound
+	WorstOvershootOrder int `json:"worstOvershootOrder"`
+	// Warnings explain the findings above in plain words
+	Warnings []string `json:"warnings"`
+}
+
+// AnalyzeCatalog analyses the catalog of a product, or a proposed change to it, within the
+// budget of a calculation
+func (a *App) AnalyzeCatalog(ctx context.Context, proposal CatalogProposal) (*CatalogAnalysis, error) {
+	ctx, cancel := a.withBudget(ctx)
+	defer cancel()
+
+	catalog, err := a.proposedCatalog(ctx, proposal)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeCatalog(ctx, catalog)
+}
+
+// proposedCatalog returns the packages of the catalog described by a proposal
+func (a *App) proposedCatalog(ctx context.Context, proposal CatalogProposal) ([]Package, error) {
+	if proposal.Packages != nil {
+		if len(proposal.Add) > 0 || len(proposal.Remove) > 0 {
+			return nil, fmt.Errorf("%w: packages cannot be combined with add or remove", ErrInvalidProposal)
+		}
+		return proposal.Packages, a.checkPackages(proposal.Packages)
+	}
+
+	current, err := a.snapshot(ctx, productOrDefault(proposal.ProductID))
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[int]bool, len(proposal.Remove))
+	for _, size := range proposal.Remove {
+		if _, ok := current.costs[size]; !ok {
+			return nil, fmt.Errorf("%w: size %d is not in the catalog", ErrInvalidProposal, size)
+		}
+		removed[size] = true
+	}
+
+	catalog := make([]Package, 0, len(current.sizes)+len(proposal.Add))
+	for _, size := range current.sizes {
+		if !removed[size] {
+			cost := current.costs[size]
+			catalog = append(catalog, Package{Size: size, Price: float64(cost.price) / 100, HandlingCost: float64(cost.handling) / 100})
+		}
+	}
+	for _, pkg := range proposal.Add {
+		if _, ok := current.costs[pkg.Size]; ok && !removed[pkg.Size] {
+			return nil, fmt.Errorf("%w: %d", ErrPackageExists, pkg.Size)
+		}
+	}
+	if err := a.checkPackages(proposal.Add); err != nil {
+		return nil, err
+	}
+	return append(catalog, proposal.Add...), nil
+}
+
+// checkPackages validates proposed packages like AddPackage does
+func (a *App) checkPackages(packages []Package) error {
+	seen := make(map[int]bool, len(packages))
+	for _, pkg := range packages {
+		if err := checkPackSize(pkg.Size); err != nil {
+			return err
+		}
+		if pkg.Size > a.maxPackSize {
+			return fmt.Errorf("%w: %d is above the maximum of %d", ErrPackSizeTooLarge, pkg.Size, a.maxPackSize)
+		}
+		if seen[pkg.Size] {
+			return fmt.Errorf("%w: %d", ErrPackageExists, pkg.Size)
+		}
+		if err := checkCounts(pkg.MinCount, pkg.MaxCount); err != nil {
+			return fmt.Errorf("size %d: %w", pkg.Size, err)
+		}
+		seen[pkg.Size] = true
+	}
+	return nil
+}
+
+// analyzeCatalog analyses a catalog of valid, distinct pack sizes
+func analyzeCatalog(ctx context.Context, catalog []Package) (*CatalogAnalysis, error) {
+	costs := catalogCosts(catalog)
+	sizes := make([]int, 0, len(costs))
+	for size := range costs {
+		sizes = append(sizes, size)
+	}
+	sizes = distinctDescending(sizes)
+
+	analysis := &CatalogAnalysis{
+		Sizes:       sizes,
+		Unreachable: []int{},
+		Redundant:   []int{},
+		Dominated:   []int{},
+		Warnings:    []string{},
+	}
+	if len(sizes) == 0 {
+		analysis.Warnings = append(analysis.Warnings, "the catalog is empty, no order can be packed")
+		return analysis, nil
+	}
+
+	analysis.GCD = sizes[0]
+	for _, size := range sizes[1:] {
+		analysis.GCD = gcd(analysis.GCD, size)
+	}
+	if analysis.GCD > 1 {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+			"every size is a multiple of %d, any other order quantity is always overshot", analysis.GCD))
+	}
+
+	// Reachability only depends on the sizes divided by their gcd
+	reduced := make([]int, len(sizes))
+	for i, size := range sizes {
+		reduced[i] = size / analysis.GCD
+	}
+	smallest := reduced[len(reduced)-1]
+	if err := reserve(ctx, tableBytes(smallest, 1)); err != nil {
+		return nil, err
+	}
+	minimum := smallestReachable(reduced)
+
+	largest := -1
+	for remainder, amount := range minimum {
+		largest = max(largest, amount-smallest)
+		analysis.UnreachableCount += (amount - remainder) / smallest
+	}
+	if largest > 0 {
+		largest *= analysis.GCD
+		analysis.LargestUnreachable = &largest
+		for n := 1; len(analysis.Unreachable) < MaxListedUnreachable && n*analysis.GCD <= largest; n++ {
+			if minimum[n%smallest] > n {
+				analysis.Unreachable = append(analysis.Unreachable, n*analysis.GCD)
+			}
+		}
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+			"%d order quantities up to %d cannot be shipped exactly", analysis.UnreachableCount, largest))
+	}
+
+	if gap, from, ok := largestGap(minimum, smallest); ok {
+		analysis.WorstOvershoot = gap*analysis.GCD - 1
+		analysis.WorstOvershootOrder = from*analysis.GCD + 1
+	} else {
+		analysis.WorstOvershoot = sizes[len(sizes)-1] - 1
+	}
+
+	for i, size := range sizes {
+		others := append(append([]int{}, sizes[:i]...), sizes[i+1:]...)
+		if addsUp(size, others) {
+			// Not a warning, the size still saves packs
+			analysis.Redundant = append(analysis.Redundant, size)
+		}
+		isDominated, err := dominated(ctx, size, others, costs)
+		if err != nil {
+			return nil, err
+		}
+		if isDominated {
+			analysis.Dominated = append(analysis.Dominated, size)
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+				"size %d is never chosen in cost mode, other packs cover it for less", size))
+		}
+	}
+	return analysis, nil
+}
+
+// smallestReachable returns, for every remainder modulo the smallest size, the smallest amount
+// with that remainder some combination of packs adds up to, or math.MaxInt when there is none.
+// Every larger amount with the same remainder is reachable by adding smallest packs.
+//
+// It is the round robin algorithm of Böcker and Lipták: sizes are added one at a time, and
+// adding size b only moves along the cycles r, r+b, r+2b, ... modulo the smallest size,
+// each of which is walked once starting from its smallest amount.
+func smallestReachable(sizes []int) []int {
+	smallest := sizes[len(sizes)-1]
+	minimum := make([]int, smallest)
+	for r := range minimum {
+		minimum[r] = math.MaxInt
+	}
+	minimum[0] = 0
+
+	for _, size := range sizes[:len(sizes)-1] {
+		cycles := gcd(smallest, size)
+		length := smallest / cycles
+		for start := 0; start < cycles; start++ {
+			// Start walking the cycle from its smallest amount
+			from := start
+			for r, i := start, 0; i < length; r, i = (r+size)%smallest, i+1 {
+				if minimum[r] < minimum[from] {
+					from = r
+				}
+			}
+			if minimum[from] == math.MaxInt {
+				continue
+			}
+			amount := minimum[from]
+			for r, i := (from+size)%smallest, 1; i < length; r, i = (r+size)%smallest, i+1 {
+				amount = min(amount+size, minimum[r])
+				minimum[r] = amount
+			}
+		}
+	}
+	return minimum
+}
+
+// largestGap returns the largest gap between consecutive reachable amounts from the smallest
+// size on, and the amount the gap starts from. Sizes must have a gcd of 1.
+//
+// Within a remainder class the gaps only shrink, since every amount reachable after one of the
+// class is reachable after the next one too, so only the first amount of each class is checked.
+// It gives up when that takes more than maxOvershootSteps steps.
+func largestGap(minimum []int, smallest int) (gap, from int, ok bool) {
+	steps := 0
+	for remainder, start := range minimum {
+		if remainder == 0 {
+			// Orders below the smallest size are left out
+			start = smallest
+		}
+		next := 1
+		for minimum[(remainder+next)%smallest] > start+next {
+			next++
+		}
+		steps += next
+		if steps > maxOvershootSteps {
+			return 0, 0, false
+		}
+		if next > gap || (next == gap && start < from) {
+			gap, from = next, start
+		}
+	}
+	return gap, from, true
+}
+
+// addsUp reports whether some combination of the other sizes adds up to size exactly
+func addsUp(size int, others []int) bool {
+	if len(others) == 0 {
+		return false
+	}
+	sorted := distinctDescending(others)
+	minimum := smallestReachable(sorted)
+	return minimum[size%sorted[len(sorted)-1]] <= size
+}
+
+// dominated reports whether the other sizes cover size for less than a pack of it costs
+func dominated(ctx context.Context, size int, others []int, costs map[int]packCost) (bool, error) {
+	if len(others) == 0 || costs[size].total() == 0 {
+		return false, nil
+	}
+	levels := make([]packCost, 0, len(others))
+	for _, other := range others {
+		levels = append(levels, costs[other])
+	}
+	packs, err := solveMinCost(ctx, size, levels, nil)
+	if err != nil {
+		return false, err
+	}
+	var cost int64
+	for other, count := range packs {
+		cost += costs[other].total() * int64(count)
+	}
+	return cost < costs[size].total(), nil
+}
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeCatalog(t *testing.T) {
+	largest := func(n int) *int { return &n }
+	tests := []struct {
+		name          string
+		catalog       []Package
+		wantGCD       int
+		wantLargest   *int
+		wantCount     int
+		wantRedundant []int
+		wantDominated []int
+		wantWorst     int
+		wantWarnings  int
+	}{
+		{
+			name:          "default catalog",
+			catalog:       []Package{{Size: 250}, {Size: 500}, {Size: 1000}, {Size: 2000}, {Size: 5000}},
+			wantGCD:       250,
+			wantRedundant: []int{5000, 2000, 1000, 500},
+			wantDominated: []int{},
+			wantWorst:     249,
+			wantWarnings:  1,
+		},
+		{
+			name:          "mcnugget numbers",
+			catalog:       []Package{{Size: 6}, {Size: 9}, {Size: 20}},
+			wantGCD:       1,
+			wantLargest:   largest(43),
+			wantCount:     22,
+			wantRedundant: []int{},
+			wantDominated: []int{},
+			wantWorst:     2,
+			wantWarnings:  1,
+		},
+		{
+			name:          "common divisor",
+			catalog:       []Package{{Size: 12}, {Size: 18}, {Size: 40}},
+			wantGCD:       2,
+			wantLargest:   largest(86),
+			wantCount:     22,
+			wantRedundant: []int{},
+			wantDominated: []int{},
+			wantWorst:     5,
+			wantWarnings:  2,
+		},
+		{
+			name:          "cheaper smaller packs",
+			catalog:       []Package{{Size: 250, Price: 1}, {Size: 500, Price: 3}, {Size: 1000, Price: 3.5}},
+			wantGCD:       250,
+			wantRedundant: []int{1000, 500},
+			wantDominated: []int{500},
+			wantWorst:     249,
+			wantWarnings:  2,
+		},
+		{
+			name:          "single size",
+			catalog:       []Package{{Size: 7}},
+			wantGCD:       7,
+			wantRedundant: []int{},
+			wantDominated: []int{},
+			wantWorst:     6,
+			wantWarnings:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := analyzeCatalog(context.Background(), tt.catalog)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantGCD, analysis.GCD)
+			require.Equal(t, tt.wantLargest, analysis.LargestUnreachable)
+			require.Equal(t, tt.wantCount, analysis.UnreachableCount)
+			require.Equal(t, tt.wantRedundant, analysis.Redundant)
+			require.Equal(t, tt.wantDominated, analysis.Dominated)
+			require.Equal(t, tt.wantWorst, analysis.WorstOvershoot)
+			require.Len(t, analysis.Warnings, tt.wantWarnings)
+		})
+	}
+}
+
+func TestAnalyzeCatalog_Empty(t *testing.T) {
+	analysis, err := analyzeCatalog(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, analysis.Sizes)
+	require.Equal(t, []string{"the catalog is empty, no order can be packed"}, analysis.Warnings)
+}
+
+func TestAnalyzeCatalog_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 200; i++ {
+		catalog := make([]Package, 0, 4)
+		seen := make(map[int]bool)
+		for len(catalog) < 1+rng.Intn(4) {
+			size := 2 + rng.Intn(40)
+			if !seen[size] {
+				seen[size] = true
+				catalog = append(catalog, Package{Size: size})
+			}
+		}
+		analysis, err := analyzeCatalog(context.Background(), catalog)
+		require.NoError(t, err)
+		sizes := analysis.Sizes
+
+		// Mark every amount some combination adds up to, far beyond the largest unreachable one
+		limit := 2 * sizes[0] * sizes[len(sizes)-1]
+		reachable := make([]bool, limit+1)
+		reachable[0] = true
+		for n := 1; n <= limit; n++ {
+			for _, size := range sizes {
+				if n >= size && reachable[n-size] {
+					reachable[n] = true
+					break
+				}
+			}
+		}
+
+		var unreachable []int
+		for n := analysis.GCD; n <= limit; n += analysis.GCD {
+			if !reachable[n] {
+				unreachable = append(unreachable, n)
+			}
+		}
+		require.Equal(t, len(unreachable), analysis.UnreachableCount, "sizes %v", sizes)
+		if len(unreachable) == 0 {
+			require.Nil(t, analysis.LargestUnreachable, "sizes %v", sizes)
+		} else {
+			require.Equal(t, unreachable[len(unreachable)-1], *analysis.LargestUnreachable, "sizes %v", sizes)
+			require.Equal(t, unreachable[:min(len(unreachable), MaxListedUnreachable)], analysis.Unreachable, "sizes %v", sizes)
+		}
+
+		// The worst overshoot of the orders from the smallest size on
+		worst, worstOrder := 0, 0
+		next := limit
+		for n := limit - sizes[0]; n >= sizes[len(sizes)-1]; n-- {
+			if reachable[n] {
+				next = n
+			}
+			if next-n >= worst {
+				worst, worstOrder = next-n, n
+			}
+		}
+		require.Equal(t, worst, analysis.WorstOvershoot, "sizes %v", sizes)
+		require.Equal(t, worstOrder, analysis.WorstOvershootOrder, "sizes %v", sizes)
+	}
+}
+
+func TestApp_AnalyzeCatalog(t *testing.T) {
+	catalog := []repo.Package{{ID: 1, Size: 6}, {ID: 2, Size: 9}, {ID: 3, Size: 20, Price: 2}}
+	tests := []struct {
+		name      string
+		proposal  CatalogProposal
+		wantSizes []int
+		wantErr   error
+	}{
+		{
+			name:      "current catalog",
+			proposal:  CatalogProposal{},
+			wantSizes: []int{20, 9, 6},
+		},
+		{
+			name:      "add and remove",
+			proposal:  CatalogProposal{Add: []Package{{Size: 4}}, Remove: []int{9}},
+			wantSizes: []int{20, 6, 4},
+		},
+		{
+			name:      "replace a size",
+			proposal:  CatalogProposal{Add: []Package{{Size: 9, Price: 1}}, Remove: []int{9}},
+			wantSizes: []int{20, 9, 6},
+		},
+		{
+			name:      "proposed catalog",
+			proposal:  CatalogProposal{Packages: []Package{{Size: 250}, {Size: 500}}},
+			wantSizes: []int{500, 250},
+		},
+		{
+			name:     "remove an unknown size",
+			proposal: CatalogProposal{Remove: []int{10}},
+			wantErr:  ErrInvalidProposal,
+		},
+		{
+			name:     "add an existing size",
+			proposal: CatalogProposal{Add: []Package{{Size: 6}}},
+			wantErr:  ErrPackageExists,
+		},
+		{
+			name:     "add a negative size",
+			proposal: CatalogProposal{Add: []Package{{Size: -6}}},
+			wantErr:  ErrInvalidPackSize,
+		},
+		{
+			name:     "proposed size too large",
+			proposal: CatalogProposal{Packages: []Package{{Size: DefaultMaxPackSize + 1}}},
+			wantErr:  ErrPackSizeTooLarge,
+		},
+		{
+			name:     "packages with changes",
+			proposal: CatalogProposal{Packages: []Package{{Size: 250}}, Remove: []int{6}},
+			wantErr:  ErrInvalidProposal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil).Maybe()
+
+			app := NewApp(mockRepo)
+			analysis, err := app.AnalyzeCatalog(context.Background(), tt.proposal)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSizes, analysis.Sizes)
+		})
+	}
+}
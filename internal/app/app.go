@@ -1,36 +1,234 @@
 package app
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/klausborkowski/calculator/internal/repo"
 )
 
+var (
+	// ErrInvalidPackSize is returned for a pack size that is zero or negative
+	ErrInvalidPackSize = errors.New("invalid pack size")
+	// ErrPackSizeTooLarge is returned for a pack size above the configured maximum
+	ErrPackSizeTooLarge = errors.New("pack size too large")
+	// ErrPackageExists is returned when a product already has a package of the same size
+	ErrPackageExists = repo.ErrPackageExists
+	// ErrPackageNotFound is returned when a package does not exist
+	ErrPackageNotFound = repo.ErrPackageNotFound
+)
+
+// DefaultMaxPackSize is the largest pack size accepted unless configured otherwise
+const DefaultMaxPackSize = 1_000_000
+
+// Package is a pack size of the catalog of a product with its costs
+type Package = repo.Package
+
 type App struct {
-	repo repo.RepositoryInterface
+	repo        repo.RepositoryInterface
+	solver      Solver
+	maxPackSize int
+	// policy chooses the packs of calculations that do not ask for a policy or mode
+	policy Policy
+	// budget bounds the work of every calculation
+	budget Budget
+	// batchWorkers is the number of orders of a batch calculated at the same time
+	batchWorkers int
+	// cache keeps the catalogs used by calculations until they change
+	cache *catalogCache
+	// jobs tracks the jobs running in this process, see RunJobs
+	jobs *jobRunner
+	// rates are the carrier rate tables shipping costs are calculated with
+	rates []RateTable
+	// preference splits the orders of ModeSplit that do not ask for a preference
+	preference string
+	// retention is how long calculations are kept in the history, see RunHistoryPruning
+	retention time.Duration
 }
 
 // Ensure App implements AppInterface
 var _ AppInterface = (*App)(nil)
 
-func NewApp(r repo.RepositoryInterface) *App {
-	return &App{repo: r}
+// Option configures optional App behaviour
+type Option func(*App)
+
+// WithSolver sets the packing strategy used when a calculation does not ask for one
+func WithSolver(s Solver) Option {
+	return func(a *App) {
+		a.solver = s
+	}
+}
+
+// WithPolicy sets the policy used when a calculation asks for neither a policy nor a mode
+func WithPolicy(p Policy) Option {
+	return func(a *App) {
+		a.policy = p
+	}
+}
+
+// WithMaxPackSize sets the largest pack size that can be added to a catalog
+func WithMaxPackSize(size int) Option {
+	return func(a *App) {
+		a.maxPackSize = size
+	}
+}
+
+// WithBudget sets the time and memory budget of a single calculation
+func WithBudget(b Budget) Option {
+	return func(a *App) {
+		a.budget = b
+	}
+}
+
+// WithBatchWorkers sets the number of orders of a batch calculated at the same time,
+// GOMAXPROCS when zero or less
+func WithBatchWorkers(workers int) Option {
+	return func(a *App) {
+		a.batchWorkers = workers
+	}
+}
+
+func NewApp(r repo.RepositoryInterface, opts ...Option) *App {
+	a := &App{
+		repo:        r,
+		solver:      solvers[DefaultSolver],
+		maxPackSize: DefaultMaxPackSize,
+		policy:      policies[DefaultPolicy],
+		budget:      Budget{Timeout: DefaultCalculationTimeout, MaxMemory: DefaultCalculationMemory},
+		cache:       newCatalogCache(),
+		jobs:        newJobRunner(),
+		preference:  DefaultPreference,
+		retention:   DefaultHistoryRetention,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
-// GetPackages returns a slice of all stored package sizes
-func (a *App) GetPackages() ([]int, error) {
-	return a.repo.GetPackages()
+// GetPackages returns the stored package sizes of a product in the default warehouse,
+// of the default product when productID is zero
+func (a *App) GetPackages(productID int) ([]int, error) {
+	return a.repo.GetPackages(productOrDefault(productID))
 }
 
-// GetPackagesMap returns package sizes as a key-value collection
-func (a *App) GetPackagesMap() (map[string]int, error) {
-	return a.repo.GetPackagesMap()
+// GetCatalog returns the stored packages of a product with their costs,
+// of the default product when productID is zero
+func (a *App) GetCatalog(productID int) ([]Package, error) {
+	return a.repo.GetCatalog(productOrDefault(productID))
 }
 
-// AddPackage adds a new package size
-func (a *App) AddPackage(packageSize int) error {
-	return a.repo.AddPackage(packageSize)
+// GetPackagesMap returns the package sizes of a product in the default warehouse as a key-value
+// collection, of the default product when productID is zero
+func (a *App) GetPackagesMap(productID int) (map[string]int, error) {
+	return a.repo.GetPackagesMap(productOrDefault(productID))
+}
+
+// AddPackage adds a new package size with its costs to the catalog of its product in its
+// warehouse, of the default product and warehouse when they are not set.
+// The size must be positive, at most the configured maximum and new to the catalog.
+// A pack holding other packs of the catalog, see nestedSize, may leave the size to be derived.
+// Its dimensions, used to put packs in cartons, are optional but given all together.
+func (a *App) AddPackage(pkg Package) error {
+	pkg.ProductID = productOrDefault(pkg.ProductID)
+	pkg.WarehouseID = warehouseOrDefault(pkg.WarehouseID)
+	nested := pkg.InnerID != 0 || pkg.InnerCount != 0
+	if !nested {
+		if err := a.checkNewPackSize(pkg.Size); err != nil {
+			return err
+		}
+	}
+	if err := checkCounts(pkg.MinCount, pkg.MaxCount); err != nil {
+		return err
+	}
+	if err := checkDimensions(pkg); err != nil {
+		return err
+	}
+
+	// The unique constraint on the catalog still catches a size added concurrently
+	catalog, err := a.catalogOf(pkg.WarehouseID, pkg.ProductID)
+	if err != nil {
+		return err
+	}
+	if nested {
+		if pkg.Size, err = a.nestedSize(pkg, catalog); err != nil {
+			return err
+		}
+		if err := a.checkNewPackSize(pkg.Size); err != nil {
+			return err
+		}
+	}
+	for _, existing := range catalog {
+		if existing.Size == pkg.Size {
+			return fmt.Errorf("%w: %d", ErrPackageExists, pkg.Size)
+		}
+	}
+	if err := a.repo.AddPackage(pkg); err != nil {
+		return err
+	}
+	// Only the catalogs of the default warehouse are cached
+	if pkg.WarehouseID == DefaultWarehouseID {
+		a.cache.invalidate(pkg.ProductID)
+	}
+	return nil
+}
+
+// checkNewPackSize checks the size of a package to add is positive and at most the maximum
+func (a *App) checkNewPackSize(size int) error {
+	if err := checkPackSize(size); err != nil {
+		return err
+	}
+	if size > a.maxPackSize {
+		return fmt.Errorf("%w: %d is above the maximum of %d", ErrPackSizeTooLarge, size, a.maxPackSize)
+	}
+	return nil
+}
+
+// checkPackSize returns ErrInvalidPackSize for a size no order can be packed in
+func checkPackSize(size int) error {
+	if size <= 0 {
+		return fmt.Errorf("%w: %d, sizes must be positive integers", ErrInvalidPackSize, size)
+	}
+	return nil
 }
 
 // DeletePackage deletes a package by its ID
 func (a *App) DeletePackage(id string) error {
-	return a.repo.DeletePackageById(id)
+	if err := a.repo.DeletePackageById(id); err != nil {
+		return err
+	}
+	// The package ID does not tell the product, so every catalog is reloaded
+	a.cache.invalidateAll()
+	return nil
+}
+
+// GetStock returns the stock levels of all tracked pack sizes of a product.
+// Like the other stock methods it uses the default product when productID is zero.
+func (a *App) GetStock(productID int) ([]StockLevel, error) {
+	return a.repo.GetStock(productOrDefault(productID))
+}
+
+// SetStock sets the number of packs of a size in stock, tracking the size if it was not yet
+func (a *App) SetStock(productID, size, quantity int) error {
+	if size <= 0 {
+		return fmt.Errorf("%w: size must be a positive integer", ErrInvalidStock)
+	}
+	if quantity < 0 {
+		return fmt.Errorf("%w: quantity must not be negative", ErrInvalidStock)
+	}
+	return a.repo.SetStock(productOrDefault(productID), size, quantity)
+}
+
+// AdjustStock adds delta packs to the stock of a tracked size, or takes them out when negative
+func (a *App) AdjustStock(productID, size, delta int) (StockLevel, error) {
+	if size <= 0 {
+		return StockLevel{}, fmt.Errorf("%w: size must be a positive integer", ErrInvalidStock)
+	}
+	return a.repo.AdjustStock(productOrDefault(productID), size, delta)
+}
+
+// DeleteStock stops tracking the stock of a size, so it never runs out
+func (a *App) DeleteStock(productID, size int) error {
+	return a.repo.DeleteStock(productOrDefault(productID), size)
 }
@@ -1,11 +1,49 @@
 package app
 
+import "context"
+
 // AppInterface defines the interface for App to enable mocking in tests
 type AppInterface interface {
-	GetPackages() ([]int, error)
-	GetPackagesMap() (map[string]int, error)
-	AddPackage(packageSize int) error
+	GetPackages(productID int) ([]int, error)
+	GetPackagesMap(productID int) (map[string]int, error)
+	GetCatalog(productID int) ([]Package, error)
+	AddPackage(pkg Package) error
+	SetPackageConstraints(id, minCount, maxCount int) (Package, error)
 	DeletePackage(id string) error
-	CalculatePacksNeeded(orderQuantity int, packSizes []int) (map[int]int, error)
+	Calculate(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error)
+	CalculatePacksNeeded(ctx context.Context, orderQuantity int, packSizes []int, opts CalculateOptions) (*CalculationResult, error)
+	RecordCalculation(caller string, req CalculationRequest, result interface{}, calcErr error) error
+	GetCalculations(q CalculationQuery) (*CalculationPage, error)
+	CommitOrder(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error)
+	CreateOrder(ctx context.Context, orderQuantity int, opts CalculateOptions) (*Order, error)
+	GetOrder(id int) (*Order, error)
+	GetOrders(status string) ([]*Order, error)
+	SetOrderStatus(id int, status string) (*Order, error)
+	GetStock(productID int) ([]StockLevel, error)
+	SetStock(productID, size, quantity int) error
+	AdjustStock(productID, size, delta int) (StockLevel, error)
+	DeleteStock(productID, size int) error
+	GetProducts() ([]Product, error)
+	AddProduct(name string) (Product, error)
+	DeleteProduct(id int) error
+	CalculateOrder(ctx context.Context, lines []OrderLine, opts CalculateOptions) (*OrderResult, error)
+	CalculateBatch(ctx context.Context, orders []int, opts CalculateOptions, emit func(BatchItem)) error
+	SubmitJob(req JobRequest) (*Job, error)
+	GetJob(id int) (*Job, error)
+	CancelJob(id int) (*Job, error)
+	GetCartons() ([]Carton, error)
+	AddCarton(carton Carton) (Carton, error)
+	DeleteCarton(id int) error
+	GetCarriers() []RateTable
+	GetWarehouses() ([]Warehouse, error)
+	AddWarehouse(warehouse Warehouse) (Warehouse, error)
+	DeleteWarehouse(id int) error
+	GetWarehouseCatalog(warehouseID, productID int) ([]Package, error)
+	GetWarehouseStock(warehouseID, productID int) ([]StockLevel, error)
+	SetWarehouseStock(warehouseID, productID, size, quantity int) error
+	CacheStats() CacheStats
+	AnalyzeCatalog(ctx context.Context, proposal CatalogProposal) (*CatalogAnalysis, error)
+	Simulate(ctx context.Context, req SimulationRequest) (*SimulationReport, error)
+	RecommendCatalog(ctx context.Context, req RecommendationRequest, progress func(RecommendationProgress)) (*Recommendation, error)
 }
 
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/klausborkowski/calculator/internal/repo"
 	"github.com/stretchr/testify/mock"
@@ -15,21 +17,34 @@ type MockRepository struct {
 	repo.RepositoryInterface
 }
 
-func (m *MockRepository) AddPackage(packageSize int) error {
-	args := m.Called(packageSize)
+func (m *MockRepository) AddPackage(pkg repo.Package) error {
+	args := m.Called(pkg)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetPackages() ([]int, error) {
-	args := m.Called()
+func (m *MockRepository) SetPackageConstraints(id, minCount, maxCount int) (repo.Package, error) {
+	args := m.Called(id, minCount, maxCount)
+	return args.Get(0).(repo.Package), args.Error(1)
+}
+
+func (m *MockRepository) GetCatalog(productID int) ([]repo.Package, error) {
+	args := m.Called(productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Package), args.Error(1)
+}
+
+func (m *MockRepository) GetPackages(productID int) ([]int, error) {
+	args := m.Called(productID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]int), args.Error(1)
 }
 
-func (m *MockRepository) GetPackagesMap() (map[string]int, error) {
-	args := m.Called()
+func (m *MockRepository) GetPackagesMap(productID int) (map[string]int, error) {
+	args := m.Called(productID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -41,6 +56,185 @@ func (m *MockRepository) DeletePackageById(id string) error {
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetStock(productID int) ([]repo.StockLevel, error) {
+	args := m.Called(productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.StockLevel), args.Error(1)
+}
+
+func (m *MockRepository) SetStock(productID, size, quantity int) error {
+	args := m.Called(productID, size, quantity)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AdjustStock(productID, size, delta int) (repo.StockLevel, error) {
+	args := m.Called(productID, size, delta)
+	return args.Get(0).(repo.StockLevel), args.Error(1)
+}
+
+func (m *MockRepository) DeleteStock(productID, size int) error {
+	args := m.Called(productID, size)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CommitStock(productID int, packs map[int]int) error {
+	args := m.Called(productID, packs)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddOrder(order repo.Order) (repo.Order, error) {
+	args := m.Called(order)
+	return args.Get(0).(repo.Order), args.Error(1)
+}
+
+func (m *MockRepository) GetOrder(id int) (repo.Order, error) {
+	args := m.Called(id)
+	return args.Get(0).(repo.Order), args.Error(1)
+}
+
+func (m *MockRepository) GetOrders(status string) ([]repo.Order, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Order), args.Error(1)
+}
+
+func (m *MockRepository) UpdateOrderStatus(id int, from, to string, stock int) (repo.Order, error) {
+	args := m.Called(id, from, to, stock)
+	return args.Get(0).(repo.Order), args.Error(1)
+}
+
+func (m *MockRepository) AddCalculation(c repo.Calculation) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetCalculations(filter repo.CalculationFilter) ([]repo.Calculation, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Calculation), args.Error(1)
+}
+
+func (m *MockRepository) DeleteCalculations(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) AddJob(request []byte, total int) (repo.Job, error) {
+	args := m.Called(request, total)
+	return args.Get(0).(repo.Job), args.Error(1)
+}
+
+func (m *MockRepository) GetJob(id int) (repo.Job, error) {
+	args := m.Called(id)
+	return args.Get(0).(repo.Job), args.Error(1)
+}
+
+func (m *MockRepository) ClaimJob(owner string) (repo.Job, error) {
+	args := m.Called(owner)
+	return args.Get(0).(repo.Job), args.Error(1)
+}
+
+func (m *MockRepository) UpdateJob(job repo.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockRepository) HeartbeatJob(id int, owner string) error {
+	args := m.Called(id, owner)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CancelJob(id int) (repo.Job, error) {
+	args := m.Called(id)
+	return args.Get(0).(repo.Job), args.Error(1)
+}
+
+func (m *MockRepository) RequeueJobs(lease time.Duration) (int, error) {
+	args := m.Called(lease)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) GetCartons() ([]repo.Carton, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Carton), args.Error(1)
+}
+
+func (m *MockRepository) AddCarton(carton repo.Carton) (repo.Carton, error) {
+	args := m.Called(carton)
+	return args.Get(0).(repo.Carton), args.Error(1)
+}
+
+func (m *MockRepository) DeleteCarton(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetProducts() ([]repo.Product, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Product), args.Error(1)
+}
+
+func (m *MockRepository) AddProduct(name string) (repo.Product, error) {
+	args := m.Called(name)
+	return args.Get(0).(repo.Product), args.Error(1)
+}
+
+func (m *MockRepository) DeleteProduct(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWarehouses() ([]repo.Warehouse, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Warehouse), args.Error(1)
+}
+
+func (m *MockRepository) AddWarehouse(warehouse repo.Warehouse) (repo.Warehouse, error) {
+	args := m.Called(warehouse)
+	return args.Get(0).(repo.Warehouse), args.Error(1)
+}
+
+func (m *MockRepository) DeleteWarehouse(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWarehouseCatalog(warehouseID, productID int) ([]repo.Package, error) {
+	args := m.Called(warehouseID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.Package), args.Error(1)
+}
+
+func (m *MockRepository) GetWarehouseStock(warehouseID, productID int) ([]repo.StockLevel, error) {
+	args := m.Called(warehouseID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repo.StockLevel), args.Error(1)
+}
+
+func (m *MockRepository) SetWarehouseStock(warehouseID, productID, size, quantity int) error {
+	args := m.Called(warehouseID, productID, size, quantity)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -64,7 +258,7 @@ func TestApp_GetPackages(t *testing.T) {
 		{
 			name: "successful get",
 			setupMock: func(m *MockRepository) {
-				m.On("GetPackages").Return([]int{1, 2, 3}, nil)
+				m.On("GetPackages", DefaultProductID).Return([]int{1, 2, 3}, nil)
 			},
 			want:    []int{1, 2, 3},
 			wantErr: false,
@@ -72,7 +266,7 @@ func TestApp_GetPackages(t *testing.T) {
 		{
 			name: "repository error",
 			setupMock: func(m *MockRepository) {
-				m.On("GetPackages").Return(nil, errors.New("database error"))
+				m.On("GetPackages", DefaultProductID).Return(nil, errors.New("database error"))
 			},
 			want:    nil,
 			wantErr: true,
@@ -85,7 +279,7 @@ func TestApp_GetPackages(t *testing.T) {
 			tt.setupMock(mockRepo)
 
 			app := NewApp(mockRepo)
-			got, err := app.GetPackages()
+			got, err := app.GetPackages(0)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -110,7 +304,7 @@ func TestApp_GetPackagesMap(t *testing.T) {
 		{
 			name: "successful get",
 			setupMock: func(m *MockRepository) {
-				m.On("GetPackagesMap").Return(map[string]int{"1": 10, "2": 20}, nil)
+				m.On("GetPackagesMap", DefaultProductID).Return(map[string]int{"1": 10, "2": 20}, nil)
 			},
 			want:    map[string]int{"1": 10, "2": 20},
 			wantErr: false,
@@ -118,7 +312,7 @@ func TestApp_GetPackagesMap(t *testing.T) {
 		{
 			name: "repository error",
 			setupMock: func(m *MockRepository) {
-				m.On("GetPackagesMap").Return(nil, errors.New("database error"))
+				m.On("GetPackagesMap", DefaultProductID).Return(nil, errors.New("database error"))
 			},
 			want:    nil,
 			wantErr: true,
@@ -131,7 +325,7 @@ func TestApp_GetPackagesMap(t *testing.T) {
 			tt.setupMock(mockRepo)
 
 			app := NewApp(mockRepo)
-			got, err := app.GetPackagesMap()
+			got, err := app.GetPackagesMap(0)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -147,27 +341,142 @@ func TestApp_GetPackagesMap(t *testing.T) {
 }
 
 func TestApp_AddPackage(t *testing.T) {
+	errDatabase := errors.New("database error")
+	catalog := []Package{{ID: 1, ProductID: DefaultProductID, Size: 250}}
 	tests := []struct {
-		name        string
-		packageSize int
-		setupMock   func(*MockRepository)
-		wantErr     bool
+		name      string
+		pkg       Package
+		options   []Option
+		setupMock func(*MockRepository)
+		wantErr   error
 	}{
 		{
-			name:        "successful add",
-			packageSize: 10,
+			name: "successful add",
+			pkg:  Package{Size: 10, Price: 2.5},
 			setupMock: func(m *MockRepository) {
-				m.On("AddPackage", 10).Return(nil)
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 10, Price: 2.5}).Return(nil)
 			},
-			wantErr: false,
 		},
 		{
-			name:        "repository error",
-			packageSize: 5,
+			name: "successful add with dimensions",
+			pkg:  Package{Size: 500, Length: 300, Width: 200, Height: 150, Weight: 5200},
 			setupMock: func(m *MockRepository) {
-				m.On("AddPackage", 5).Return(errors.New("database error"))
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 500, Length: 300, Width: 200, Height: 150, Weight: 5200}).Return(nil)
 			},
-			wantErr: true,
+		},
+		{
+			name:      "partial dimensions",
+			pkg:       Package{Size: 500, Length: 300, Width: 200},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidDimensions,
+		},
+		{
+			name:      "negative weight",
+			pkg:       Package{Size: 500, Weight: -1},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidDimensions,
+		},
+		{
+			name:      "zero size",
+			pkg:       Package{Size: 0},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidPackSize,
+		},
+		{
+			name:      "negative size",
+			pkg:       Package{Size: -250},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidPackSize,
+		},
+		{
+			name:      "above the default maximum",
+			pkg:       Package{Size: DefaultMaxPackSize + 1},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrPackSizeTooLarge,
+		},
+		{
+			name:      "above the configured maximum",
+			pkg:       Package{Size: 5000},
+			options:   []Option{WithMaxPackSize(1000)},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrPackSizeTooLarge,
+		},
+		{
+			name: "duplicate size",
+			pkg:  Package{Size: 250},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			},
+			wantErr: ErrPackageExists,
+		},
+		{
+			name: "duplicate size added concurrently",
+			pkg:  Package{Size: 500},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 500}).Return(repo.ErrPackageExists)
+			},
+			wantErr: ErrPackageExists,
+		},
+		{
+			name: "nested pack",
+			pkg:  Package{InnerID: 1, InnerCount: 4},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 1000, InnerID: 1, InnerCount: 4}).Return(nil)
+			},
+		},
+		{
+			name: "nested pack with its size",
+			pkg:  Package{Size: 1000, InnerID: 1, InnerCount: 4},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 1000, InnerID: 1, InnerCount: 4}).Return(nil)
+			},
+		},
+		{
+			name: "nested pack with another size",
+			pkg:  Package{Size: 900, InnerID: 1, InnerCount: 4},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			},
+			wantErr: ErrInvalidNesting,
+		},
+		{
+			name: "inner pack of another catalog",
+			pkg:  Package{InnerID: 7, InnerCount: 4},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			},
+			wantErr: ErrInvalidNesting,
+		},
+		{
+			name: "single inner pack",
+			pkg:  Package{InnerID: 1, InnerCount: 1},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			},
+			wantErr: ErrInvalidNesting,
+		},
+		{
+			name:    "nested pack above the maximum",
+			pkg:     Package{InnerID: 1, InnerCount: 5},
+			options: []Option{WithMaxPackSize(1000)},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			},
+			wantErr: ErrPackSizeTooLarge,
+		},
+		{
+			name: "repository error",
+			pkg:  Package{ProductID: 2, Size: 5},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", 2).Return([]Package{}, nil)
+				m.On("AddPackage", Package{ProductID: 2, WarehouseID: DefaultWarehouseID, Size: 5}).Return(errDatabase)
+			},
+			wantErr: errDatabase,
 		},
 	}
 
@@ -176,11 +485,11 @@ func TestApp_AddPackage(t *testing.T) {
 			mockRepo := new(MockRepository)
 			tt.setupMock(mockRepo)
 
-			app := NewApp(mockRepo)
-			err := app.AddPackage(tt.packageSize)
+			app := NewApp(mockRepo, tt.options...)
+			err := app.AddPackage(tt.pkg)
 
-			if tt.wantErr {
-				require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
 			} else {
 				require.NoError(t, err)
 			}
@@ -233,3 +542,277 @@ func TestApp_DeletePackage(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_Calculate(t *testing.T) {
+	catalog := []repo.Package{
+		{ID: 1, Size: 1000, Price: 10},
+		{ID: 2, Size: 5000, Price: 20, HandlingCost: 1},
+	}
+
+	tests := []struct {
+		name      string
+		opts      CalculateOptions
+		setupMock func(*MockRepository)
+		wantPacks map[int]int
+		wantCost  float64
+		wantStock []StockLevel
+		// wantSolver and wantAlternatives are checked when set
+		wantSolver       string
+		wantAlternatives []map[int]int
+		wantErr          error
+	}{
+		{
+			name: "least items",
+			opts: CalculateOptions{},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+			},
+			wantPacks: map[int]int{1000: 3},
+			wantCost:  30,
+		},
+		{
+			name: "least cost",
+			opts: CalculateOptions{Mode: ModeCost},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+			},
+			wantPacks: map[int]int{5000: 1},
+			wantCost:  21,
+		},
+		{
+			name:      "unknown mode",
+			opts:      CalculateOptions{Mode: "fastest"},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidMode,
+		},
+		{
+			name: "cost mode with another solver",
+			opts: CalculateOptions{Mode: ModeCost, Solver: "dp"},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+			},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name: "least items within stock",
+			opts: CalculateOptions{},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 2}}, nil)
+			},
+			wantPacks: map[int]int{5000: 1},
+			wantCost:  21,
+			wantStock: []StockLevel{{Size: 1000, Quantity: 2}},
+		},
+		{
+			name: "least cost within stock",
+			opts: CalculateOptions{Mode: ModeCost},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 5000, Quantity: 0}, {Size: 750, Quantity: 4}}, nil)
+			},
+			wantPacks: map[int]int{1000: 3},
+			wantCost:  30,
+			wantStock: []StockLevel{{Size: 5000, Quantity: 0}},
+		},
+		{
+			name: "out of stock",
+			opts: CalculateOptions{},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 2}, {Size: 5000, Quantity: 0}}, nil)
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name: "alternatives within stock",
+			opts: CalculateOptions{Alternatives: 3},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 2}}, nil)
+			},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name: "solver with stock to spare",
+			opts: CalculateOptions{Solver: "dp"},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 10}}, nil)
+			},
+			wantPacks:  map[int]int{1000: 3},
+			wantCost:   30,
+			wantStock:  []StockLevel{{Size: 1000, Quantity: 10}},
+			wantSolver: "dp",
+		},
+		{
+			name: "alternatives with stock to spare",
+			opts: CalculateOptions{Solver: "greedy", Alternatives: 3},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 10}, {Size: 5000, Quantity: 0}}, nil)
+			},
+			wantPacks:  map[int]int{1000: 3},
+			wantCost:   30,
+			wantStock:  []StockLevel{{Size: 5000, Quantity: 0}, {Size: 1000, Quantity: 10}},
+			wantSolver: "greedy",
+			// The single pack of 5000 is out of stock
+			wantAlternatives: []map[int]int{{1000: 3}},
+		},
+		{
+			name: "repository error",
+			opts: CalculateOptions{},
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(nil, errors.New("database error"))
+			},
+			wantErr: errors.New("database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			app := NewApp(mockRepo)
+			got, err := app.Calculate(context.Background(), 2500, tt.opts)
+
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantPacks, got.Packs())
+				require.Equal(t, tt.wantCost, got.Cost.Total)
+				require.Equal(t, tt.wantStock, got.Stock)
+				if tt.wantSolver != "" {
+					require.Equal(t, tt.wantSolver, got.Solver)
+				}
+				if tt.wantAlternatives != nil {
+					alternatives := make([]map[int]int, 0, len(got.Alternatives))
+					for _, alternative := range got.Alternatives {
+						packs := make(map[int]int)
+						for _, line := range alternative.Lines {
+							packs[line.Size] = line.Count
+						}
+						alternatives = append(alternatives, packs)
+					}
+					require.Equal(t, tt.wantAlternatives, alternatives)
+				}
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_CommitOrder(t *testing.T) {
+	catalog := []repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}}
+
+	tests := []struct {
+		name      string
+		opts      CalculateOptions
+		setupMock func(*MockRepository)
+		wantPacks map[int]int
+		wantErr   error
+	}{
+		{
+			name: "successful commit",
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 500, Quantity: 1}}, nil)
+				m.On("CommitStock", DefaultProductID, map[int]int{500: 1, 250: 1}).Return(nil)
+			},
+			wantPacks: map[int]int{500: 1, 250: 1},
+		},
+		{
+			name: "stock taken by another order",
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 500, Quantity: 1}}, nil)
+				m.On("CommitStock", DefaultProductID, map[int]int{500: 1, 250: 1}).Return(repo.ErrInsufficientStock)
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name:      "alternatives",
+			opts:      CalculateOptions{Alternatives: 2},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidAlternatives,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			app := NewApp(mockRepo)
+			got, err := app.CommitOrder(context.Background(), 750, tt.opts)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantPacks, got.Packs())
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_SetStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		quantity  int
+		setupMock func(*MockRepository)
+		wantErr   error
+	}{
+		{
+			name:     "successful set",
+			size:     500,
+			quantity: 20,
+			setupMock: func(m *MockRepository) {
+				m.On("SetStock", DefaultProductID, 500, 20).Return(nil)
+			},
+		},
+		{
+			name:      "negative quantity",
+			size:      500,
+			quantity:  -1,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidStock,
+		},
+		{
+			name:      "invalid size",
+			size:      0,
+			quantity:  20,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			app := NewApp(mockRepo)
+			err := app.SetStock(0, tt.size, tt.quantity)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ErrInvalidBatch is returned for a batch without orders or with too many of them
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// MaxBatchOrders caps the number of orders of a batch
+const MaxBatchOrders = 10_000
+
+// BatchItem is the outcome of a single order of a batch: its result, or the error it failed with
+type BatchItem struct {
+	// Index is the position of the order in the batch, starting at 0
+	Index int `json:"index"`
+	// Quantity is the ordered quantity
+	Quantity int                `json:"quantity"`
+	Result   *CalculationResult `json:"result,omitempty"`
+	// Error is why the calculation of the order failed
+	Error string `json:"error,omitempty"`
+}
+
+// CalculateBatch calculates the packs of many orders of the same product like Calculate, reading
+// the catalog, the stock and the cartons once. The stock is not taken out, so every order may use all of it.
+//
+// The orders are calculated by a bounded pool of workers, see WithBatchWorkers, and emit is called
+// with every item as it completes, so the items come in no particular order. An order that fails
+// is emitted with its error and does not stop the others; the batch itself only fails for an
+// invalid batch or options, or a catalog that cannot be read, before any item is emitted.
+// Every order has a budget of its own, and the batch stops with the reason, see interrupted,
+// when ctx is done.
+func (a *App) CalculateBatch(ctx context.Context, orders []int, opts CalculateOptions, emit func(BatchItem)) error {
+	policy, solver, err := a.prepareBatch(orders, opts)
+	if err != nil {
+		return err
+	}
+
+	productID := productOrDefault(opts.ProductID)
+	loadCtx, cancel := a.withBudget(ctx)
+	snapshot, err := a.snapshot(loadCtx, productID)
+	cancel()
+	if err != nil {
+		return err
+	}
+	stock, err := a.repo.GetStock(productID)
+	if err != nil {
+		return err
+	}
+	limits := stockLimits(stock, snapshot.sizes)
+	var cartons []Carton
+	if opts.Cartonize {
+		if cartons, err = a.repo.GetCartons(); err != nil {
+			return err
+		}
+	}
+
+	workers := a.batchWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(orders))
+
+	jobs := make(chan int)
+	items := make(chan BatchItem)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := BatchItem{Index: i, Quantity: orders[i]}
+				orderCtx, cancel := a.withBudget(ctx)
+				result, err := snapshot.fulfill(orderCtx, orders[i], solver, limits, policy, opts)
+				cancel()
+				if err == nil && opts.Cartonize {
+					result.Cartons, err = packCartons(snapshot.cartonPacks(productID, result.Lines), cartons)
+				}
+				if err != nil {
+					item.Error = err.Error()
+				} else {
+					result.Stock = stockOf(result.Catalog, limits)
+					item.Result = result
+				}
+				items <- item
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range orders {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(items)
+	}()
+
+	for item := range items {
+		emit(item)
+	}
+	return interrupted(ctx)
+}
+
+// prepareBatch validates a batch and its options, and resolves the policy and the solver its
+// orders are calculated with
+func (a *App) prepareBatch(orders []int, opts CalculateOptions) (Policy, Solver, error) {
+	if len(orders) == 0 {
+		return Policy{}, nil, fmt.Errorf("%w: no orders", ErrInvalidBatch)
+	}
+	if len(orders) > MaxBatchOrders {
+		return Policy{}, nil, fmt.Errorf("%w: %d orders, at most %d are allowed", ErrInvalidBatch, len(orders), MaxBatchOrders)
+	}
+	policy, err := a.policyFor(opts)
+	if err != nil {
+		return Policy{}, nil, err
+	}
+	solver, err := a.solverFor(opts)
+	if err != nil {
+		return Policy{}, nil, err
+	}
+	if err := checkTolerance(opts.Tolerance); err != nil {
+		return Policy{}, nil, err
+	}
+	if opts.Zone != "" || opts.Carrier != "" {
+		return Policy{}, nil, fmt.Errorf("%w: shipping costs are not calculated for batches", ErrInvalidMode)
+	}
+	return policy, solver, nil
+}
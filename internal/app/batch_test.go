@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_CalculateBatch(t *testing.T) {
+	catalog := []repo.Package{
+		{ID: 1, Size: 250},
+		{ID: 2, Size: 500},
+		{ID: 3, Size: 1000},
+		{ID: 4, Size: 2000},
+		{ID: 5, Size: 5000},
+	}
+	// 5500 items in stock
+	stock := []repo.StockLevel{{Size: 5000, Quantity: 1}, {Size: 2000, Quantity: 0}, {Size: 1000, Quantity: 0}, {Size: 500, Quantity: 0}, {Size: 250, Quantity: 2}}
+	orders := []int{251, 6000, 0, 5250, 1}
+
+	for _, workers := range []int{1, 3, 0} {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil).Once()
+		mockRepo.On("GetStock", DefaultProductID).Return(stock, nil).Once()
+
+		var items []BatchItem
+		err := NewApp(mockRepo, WithBatchWorkers(workers)).CalculateBatch(context.Background(), orders, CalculateOptions{}, func(item BatchItem) {
+			items = append(items, item)
+		})
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+
+		require.Len(t, items, len(orders), "%d workers", workers)
+		sort.Slice(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+		for i, item := range items {
+			require.Equal(t, i, item.Index)
+			require.Equal(t, orders[i], item.Quantity)
+		}
+
+		require.Equal(t, map[int]int{250: 2}, items[0].Result.Packs())
+		require.Empty(t, items[0].Error)
+		require.Nil(t, items[1].Result)
+		require.Contains(t, items[1].Error, ErrInsufficientStock.Error())
+		require.Nil(t, items[2].Result)
+		require.NotEmpty(t, items[2].Error)
+		require.Equal(t, map[int]int{5000: 1, 250: 1}, items[3].Result.Packs())
+		require.Equal(t, []StockLevel{{Size: 5000, Quantity: 1}, {Size: 2000, Quantity: 0}, {Size: 1000, Quantity: 0}, {Size: 500, Quantity: 0}, {Size: 250, Quantity: 2}}, items[3].Result.Stock)
+		require.Equal(t, map[int]int{250: 1}, items[4].Result.Packs())
+	}
+}
+
+func TestApp_CalculateBatch_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		orders     []int
+		opts       CalculateOptions
+		catalogErr error
+		wantErr    error
+	}{
+		{
+			name:    "no orders",
+			wantErr: ErrInvalidBatch,
+		},
+		{
+			name:    "too many orders",
+			orders:  make([]int, MaxBatchOrders+1),
+			wantErr: ErrInvalidBatch,
+		},
+		{
+			name:    "unknown policy",
+			orders:  []int{251},
+			opts:    CalculateOptions{Policy: "fewest-boxes"},
+			wantErr: ErrUnknownPolicy,
+		},
+		{
+			name:    "unknown solver",
+			orders:  []int{251},
+			opts:    CalculateOptions{Solver: "magic"},
+			wantErr: ErrUnknownSolver,
+		},
+		{
+			name:    "invalid tolerance",
+			orders:  []int{251},
+			opts:    CalculateOptions{Tolerance: 100},
+			wantErr: ErrInvalidTolerance,
+		},
+		{
+			name:       "unknown product",
+			orders:     []int{251},
+			opts:       CalculateOptions{ProductID: 7},
+			catalogErr: repo.ErrProductNotFound,
+			wantErr:    ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			if tt.catalogErr != nil {
+				mockRepo.On("GetCatalog", tt.opts.ProductID).Return(nil, tt.catalogErr)
+			}
+
+			emitted := 0
+			err := NewApp(mockRepo).CalculateBatch(context.Background(), tt.orders, tt.opts, func(BatchItem) { emitted++ })
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Zero(t, emitted)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_CalculateBatch_Canceled(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}}, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	orders := make([]int, 1000)
+	for i := range orders {
+		orders[i] = i + 1
+	}
+
+	emitted := 0
+	err := NewApp(mockRepo, WithBatchWorkers(1)).CalculateBatch(ctx, orders, CalculateOptions{}, func(BatchItem) {
+		emitted++
+		cancel()
+	})
+	require.True(t, errors.Is(err, context.Canceled), "got %v", err)
+	require.Less(t, emitted, len(orders))
+}
@@ -0,0 +1,159 @@
+package app
+
+import "context"
+
+// branchAndBoundSolver is an exact strategy searching pack counts from the largest size down.
+// It starts from the greedy answer and prunes every branch whose lower bound on overshoot and
+// pack count cannot beat the best combination found so far. Memory is linear in the number of
+// pack sizes, and it is the best fit for catalogs with few sizes but a very large largest pack,
+// where the residue table of the DP strategy gets big.
+type branchAndBoundSolver struct{}
+
+func (branchAndBoundSolver) Name() string { return "bnb" }
+
+func (branchAndBoundSolver) Solve(ctx context.Context, orderQuantity int, packSizes []int) (*CalculationResult, error) {
+	sizes := distinctDescending(packSizes)
+
+	initial, err := greedySolver{}.Solve(ctx, orderQuantity, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &branchAndBound{
+		cancelCheck:   cancelCheck{ctx: ctx},
+		sizes:         sizes,
+		counts:        make([]int, len(sizes)),
+		best:          make([]int, len(sizes)),
+		bestOvershoot: initial.Overshoot,
+		suffixGCD:     make([]int, len(sizes)),
+	}
+	initialPacks := initial.Packs()
+	for i, size := range sizes {
+		s.best[i] = initialPacks[size]
+	}
+	s.bestPacks = initial.TotalPacks
+	for i := len(sizes) - 1; i >= 0; i-- {
+		s.suffixGCD[i] = sizes[i]
+		if i+1 < len(sizes) {
+			s.suffixGCD[i] = gcd(sizes[i], s.suffixGCD[i+1])
+		}
+	}
+
+	s.search(0, orderQuantity, 0)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	packs := make(map[int]int)
+	for i, count := range s.best {
+		if count > 0 {
+			packs[sizes[i]] = count
+		}
+	}
+	return newCalculationResult(orderQuantity, packs), nil
+}
+
+// branchAndBound holds the state of a single branch and bound search
+type branchAndBound struct {
+	cancelCheck
+	// sizes are the distinct pack sizes in descending order
+	sizes []int
+	// counts are the pack counts of the current branch
+	counts []int
+	// best are the pack counts of the best combination found so far
+	best          []int
+	bestOvershoot int
+	bestPacks     int
+	// suffixGCD[i] is the gcd of sizes[i:], any amount made of these sizes is its multiple
+	suffixGCD []int
+}
+
+// search chooses the count of sizes[level] with remaining items still to cover
+// and packs already used on the larger sizes
+func (s *branchAndBound) search(level, remaining, packs int) {
+	size := s.sizes[level]
+	largest := s.sizes[0]
+	last := level == len(s.sizes)-1
+
+	maxCount := ceilDiv(remaining, size)
+	minCount := 0
+	if last {
+		minCount = maxCount
+	}
+	if len(s.sizes) > 1 {
+		// Among any L packs some non-empty subset sums to a multiple of the largest size L and can
+		// be swapped for fewer L-packs, so an optimal combination has fewer than L smaller packs
+		if level == 0 {
+			minCount = max(minCount, (remaining-(largest-1)*s.sizes[1])/largest)
+		} else {
+			maxCount = min(maxCount, largest-1-(packs-s.counts[0]))
+		}
+	}
+
+	for count := maxCount; count >= minCount; count-- {
+		if s.stopped() {
+			break
+		}
+		rest := remaining - count*size
+		if rest <= 0 {
+			s.consider(level, count, -rest, packs+count)
+			continue
+		}
+		if last {
+			continue
+		}
+
+		// Whatever the smaller sizes add is a multiple of their gcd and takes
+		// at least rest/next packs
+		step := s.suffixGCD[level+1]
+		overshootBound := ceilDiv(rest, step)*step - rest
+		packsBound := packs + count + ceilDiv(rest, s.sizes[level+1])
+		if s.bestOvershoot == 0 && packsBound >= s.bestPacks {
+			// The pack bound only grows as this count decreases
+			break
+		}
+		if !s.improves(overshootBound, packsBound) {
+			continue
+		}
+
+		s.counts[level] = count
+		s.search(level+1, rest, packs+count)
+	}
+	s.counts[level] = 0
+}
+
+// consider records the current branch, completed with count packs at level, if it improves on the best
+func (s *branchAndBound) consider(level, count, overshoot, packs int) {
+	if !s.improves(overshoot, packs) {
+		return
+	}
+	copy(s.best, s.counts[:level])
+	s.best[level] = count
+	for i := level + 1; i < len(s.best); i++ {
+		s.best[i] = 0
+	}
+	s.bestOvershoot = overshoot
+	s.bestPacks = packs
+}
+
+// improves reports whether a combination ships fewer items, or as many items in fewer packs,
+// than the best one found so far
+func (s *branchAndBound) improves(overshoot, packs int) bool {
+	return overshoot < s.bestOvershoot || (overshoot == s.bestOvershoot && packs < s.bestPacks)
+}
+
+// ceilDiv divides two positive integers rounding up
+func ceilDiv(a, b int) int {
+	if a <= 0 {
+		return 0
+	}
+	return (a-1)/b + 1
+}
+
+// gcd returns the greatest common divisor of two positive integers
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
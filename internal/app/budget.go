@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrBudgetExceeded is returned when a calculation runs out of its time or memory budget
+	ErrBudgetExceeded = errors.New("calculation budget exceeded")
+	// ErrTimeBudgetExceeded is returned when a calculation runs longer than its budget allows,
+	// it matches ErrBudgetExceeded
+	ErrTimeBudgetExceeded = fmt.Errorf("%w: out of time", ErrBudgetExceeded)
+	// ErrMemoryBudgetExceeded is returned when the tables of a calculation take more memory than
+	// its budget allows, it matches ErrBudgetExceeded
+	ErrMemoryBudgetExceeded = fmt.Errorf("%w: out of memory", ErrBudgetExceeded)
+)
+
+const (
+	// DefaultCalculationTimeout is the time budget of a calculation unless configured otherwise
+	DefaultCalculationTimeout = 10 * time.Second
+	// DefaultCalculationMemory is the memory budget of a calculation unless configured otherwise
+	DefaultCalculationMemory = 512 << 20
+
+	// checkInterval is the number of steps a solver loop takes between looking at its context
+	checkInterval = 1 << 12
+)
+
+// Budget bounds the work of a single calculation, zero values leave it unbounded
+type Budget struct {
+	// Timeout is the longest a calculation may run
+	Timeout time.Duration
+	// MaxMemory is the most bytes the solver tables of a calculation may take
+	MaxMemory int64
+}
+
+// budgetKey is the context key of the memory budget of a calculation
+type budgetKey struct{}
+
+// memoryBudget accounts for the memory the solver tables of a calculation take
+type memoryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// withBudget returns the context of a calculation bounded by the configured budget.
+// Running out of time cancels it with ErrTimeBudgetExceeded as the cause.
+func (a *App) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if a.budget.Timeout > 0 {
+		cause := fmt.Errorf("%w after %s", ErrTimeBudgetExceeded, a.budget.Timeout)
+		ctx, cancel = context.WithTimeoutCause(ctx, a.budget.Timeout, cause)
+	}
+	if a.budget.MaxMemory > 0 {
+		ctx = context.WithValue(ctx, budgetKey{}, &memoryBudget{limit: a.budget.MaxMemory})
+	}
+	return ctx, cancel
+}
+
+// reserve takes the memory of a table out of the budget of the calculation, failing with
+// ErrMemoryBudgetExceeded before the table is allocated when it does not fit
+func reserve(ctx context.Context, bytes int64) error {
+	budget, ok := ctx.Value(budgetKey{}).(*memoryBudget)
+	if !ok {
+		return nil
+	}
+	for {
+		used := budget.used.Load()
+		if bytes > budget.limit-used {
+			return fmt.Errorf("%w, a table of %d MiB does not fit in the %d MiB left of %d MiB",
+				ErrMemoryBudgetExceeded, bytes>>20, (budget.limit-used)>>20, budget.limit>>20)
+		}
+		if budget.used.CompareAndSwap(used, used+bytes) {
+			return nil
+		}
+	}
+}
+
+// tableBytes returns the memory of a table of n entries of ints ints each, saturating at
+// math.MaxInt64 for tables no budget allows
+func tableBytes(n, ints int) int64 {
+	size := int64(ints) * strconv.IntSize / 8
+	if n <= 0 {
+		return 0
+	}
+	if int64(n) > math.MaxInt64/size {
+		return math.MaxInt64
+	}
+	return int64(n) * size
+}
+
+// interrupted returns why a calculation has to stop, nil to carry on: the time budget ran
+// out, or the caller cancelled it
+func interrupted(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(ctx)
+}
+
+// cancelCheck looks at the context of a calculation on the first and then every checkInterval
+// steps of a solver loop
+type cancelCheck struct {
+	ctx   context.Context
+	steps int
+	// err is why the loop stopped, see interrupted
+	err error
+}
+
+// stopped reports whether the loop has to stop, keeping the reason in err
+func (c *cancelCheck) stopped() bool {
+	if c.err != nil {
+		return true
+	}
+	if c.steps%checkInterval == 0 {
+		c.err = interrupted(c.ctx)
+	}
+	c.steps++
+	return c.err != nil
+}
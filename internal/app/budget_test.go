@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// largeCatalog takes every solver long enough to look at its context
+var largeCatalog = []int{999_983, 250, 7}
+
+func TestSolvers_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, name := range SolverNames() {
+		t.Run(name, func(t *testing.T) {
+			solver, err := SolverByName(name)
+			require.NoError(t, err)
+			_, err = solver.Solve(ctx, 12_001, largeCatalog)
+			require.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestApp_CalculatePacksNeeded_Budget(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  Budget
+		opts    CalculateOptions
+		wantErr error
+	}{
+		{
+			name:    "out of memory",
+			budget:  Budget{MaxMemory: 1 << 20},
+			wantErr: ErrMemoryBudgetExceeded,
+		},
+		{
+			name:    "out of time",
+			budget:  Budget{Timeout: time.Nanosecond},
+			wantErr: ErrTimeBudgetExceeded,
+		},
+		{
+			name:    "out of time with pack constraints",
+			budget:  Budget{Timeout: time.Nanosecond},
+			opts:    CalculateOptions{Constraints: []PackConstraint{{Size: 250, Max: 1}}},
+			wantErr: ErrTimeBudgetExceeded,
+		},
+		{
+			name:   "within budget",
+			budget: Budget{Timeout: time.Minute, MaxMemory: 64 << 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp(nil, WithBudget(tt.budget))
+			result, err := app.CalculatePacksNeeded(context.Background(), 12_001, largeCatalog, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.ErrorIs(t, err, ErrBudgetExceeded)
+				return
+			}
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, result.Shipped, 12_001)
+		})
+	}
+}
+
+func TestDP_LargeCoprimeCatalog(t *testing.T) {
+	// Orders far below the residue threshold, about the square of the largest pack, must not
+	// get a table DP as large as the order
+	tests := []struct {
+		catalog  []int
+		quantity int
+	}{
+		{catalog: []int{999_999, 1_000_000}, quantity: 500_000_000},
+		{catalog: []int{9973, 10007}, quantity: 50_000_001},
+	}
+
+	for _, tt := range tests {
+		table, err := newResidueTable(context.Background(), tt.catalog)
+		require.NoError(t, err)
+		require.Less(t, tt.quantity, table.threshold)
+
+		// The best combination by brute force over the count of the larger size
+		large, small := tt.catalog[1], tt.catalog[0]
+		if large < small {
+			large, small = small, large
+		}
+		wantShipped, wantPacks := -1, 0
+		for count := 0; count <= ceilDiv(tt.quantity, large); count++ {
+			rest := ceilDiv(tt.quantity-count*large, small)
+			shipped, packs := count*large+rest*small, count+rest
+			if wantShipped < 0 || shipped < wantShipped || shipped == wantShipped && packs < wantPacks {
+				wantShipped, wantPacks = shipped, packs
+			}
+		}
+
+		app := NewApp(nil, WithBudget(Budget{Timeout: time.Minute, MaxMemory: 64 << 20}))
+		result, err := app.CalculatePacksNeeded(context.Background(), tt.quantity, tt.catalog, CalculateOptions{Solver: "dp"})
+		require.NoError(t, err, "catalog %v", tt.catalog)
+		require.Equal(t, wantShipped, result.Shipped, "catalog %v", tt.catalog)
+		require.Equal(t, wantPacks, result.TotalPacks, "catalog %v", tt.catalog)
+
+		ctx, cancel := app.withBudget(context.Background())
+		prepared, err := dpSolver{}.prepare(ctx, distinctDescending(tt.catalog))
+		require.NoError(t, err)
+		result, err = prepared.Solve(ctx, tt.quantity, nil)
+		cancel()
+		require.NoError(t, err, "catalog %v", tt.catalog)
+		require.Equal(t, wantShipped, result.Shipped, "catalog %v", tt.catalog)
+		require.Equal(t, wantPacks, result.TotalPacks, "catalog %v", tt.catalog)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	require.NoError(t, reserve(context.Background(), 1<<40), "no budget")
+
+	ctx, cancel := NewApp(nil, WithBudget(Budget{MaxMemory: 100})).withBudget(context.Background())
+	defer cancel()
+	require.NoError(t, reserve(ctx, 60))
+	require.ErrorIs(t, reserve(ctx, 60), ErrMemoryBudgetExceeded)
+	require.NoError(t, reserve(ctx, 40))
+	require.ErrorIs(t, reserve(ctx, tableBytes(1<<62, 2)), ErrMemoryBudgetExceeded)
+}
@@ -1,53 +1,403 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"sort"
 )
 
-// CalculatePacksNeeded calculates the minimum number of packs needed to fulfill an order.
-// Uses dynamic programming to find the optimal combination of package sizes.
-// Returns an error if it's not possible to fulfill the order with the given package sizes.
-func (a *App) CalculatePacksNeeded(orderQuantity int, packSizes []int) (map[int]int, error) {
-	if orderQuantity <= 0 {
-		return nil, fmt.Errorf("order quantity must be a positive integer")
-	}
-	// Sort package sizes in descending order - larger packs are considered first for optimization
-	sort.Sort(sort.Reverse(sort.IntSlice(packSizes)))
-
-	// dp stores the minimum number of packs needed to fulfill order of [i] items
-	dp := make([]int, orderQuantity+1)
-
-	// choice stores the last chosen package size to make up [i] items
-	choice := make([]int, orderQuantity+1)
-
-	for i := 1; i <= orderQuantity; i++ {
-		// Initialize with large number (infinity)
-		dp[i] = math.MaxInt32
-		for _, pack := range packSizes {
-			if i >= pack && dp[i-pack]+1 < dp[i] {
-				// Update with the minimum number of packs
-				dp[i] = dp[i-pack] + 1
-				// Store the pack size used
-				choice[i] = pack
+var (
+	// ErrInvalidMode is returned for an unknown calculation mode or options the mode does not support
+	ErrInvalidMode = errors.New("invalid calculation mode")
+	// ErrInvalidQuantity is returned for an order quantity that is zero or negative
+	ErrInvalidQuantity = errors.New("order quantity must be a positive integer")
+	// ErrEmptyCatalog is returned when there are no pack sizes to calculate an order with
+	ErrEmptyCatalog = errors.New("no package sizes configured")
+
+	errCannotFulfill = errors.New("cannot fulfill order with given pack sizes")
+	errOrderTooLarge = errors.New("order quantity is too large")
+)
+
+const (
+	// ModeItems ships the least amount of items, then the least amount of packs
+	ModeItems = "items"
+	// ModePacks ships the least amount of packs, then the least amount of items
+	ModePacks = "packs"
+	// ModeCost ships the cheapest combination of packs, then the least amount of items and packs
+	ModeCost = "cost"
+	// ModeShipping ships the packs and parcels with the lowest landed cost, see calculateShipping
+	ModeShipping = "shipping"
+	// ModeSplit splits the order across the warehouses by a preference, see calculateSplit
+	ModeSplit = "split"
+)
+
+// CalculationResult describes the packs chosen to fulfill an order
+type CalculationResult struct {
+	// Requested is the ordered quantity
+	Requested int `json:"requested"`
+	// Shipped is the total number of items sent out
+	Shipped int `json:"shipped"`
+	// Overshoot is the number of items sent on top of the ordered quantity
+	Overshoot int `json:"overshoot"`
+	// Shortfall is the number of items sent below the ordered quantity, see CalculateOptions.Tolerance
+	Shortfall int `json:"shortfall,omitempty"`
+	// Backordered is the part of the shortfall that is sent later, see CalculateOptions.Backorder
+	Backordered int `json:"backordered,omitempty"`
+	// TotalPacks is the total number of packs sent out
+	TotalPacks int `json:"totalPacks"`
+	// Lines are the packs sent out per size, largest size first
+	Lines []PackLine `json:"lines"`
+	// Tree are the packs of Lines along with the packs they hold, set when the catalog has
+	// packs holding other packs
+	Tree []PackNode `json:"tree,omitempty"`
+	// Catalog are the package sizes the calculation could choose from, largest first
+	Catalog []int `json:"catalog"`
+	// Solver is the name of the packing strategy that produced the result
+	Solver string `json:"solver"`
+	// Mode is the objective the packs were chosen for
+	Mode string `json:"mode,omitempty"`
+	// Policy is the order of objectives and the tie-break the packs were chosen by
+	Policy *Policy `json:"policy,omitempty"`
+	// Cost is the price of the chosen packs, set when the catalog is known
+	Cost *CostBreakdown `json:"cost,omitempty"`
+	// Stock are the stock levels the packs were limited to, largest size first
+	Stock []StockLevel `json:"stock,omitempty"`
+	// Constraints are the pack constraints the packs were chosen within, largest size first
+	Constraints []PackConstraint `json:"constraints,omitempty"`
+	// Alternatives are the ranked combinations asked for with CalculateOptions.Alternatives
+	Alternatives []Alternative `json:"alternatives,omitempty"`
+	// Cartons are the shipping cartons the packs are put in, asked for with CalculateOptions.Cartonize
+	// or Zone
+	Cartons []CartonLoad `json:"cartons,omitempty"`
+	// Shipping is the carrier the cartons are sent with and the landed cost, set with a zone
+	Shipping *ShippingBreakdown `json:"shipping,omitempty"`
+	// Preference is the split preference of ModeSplit
+	Preference string `json:"preference,omitempty"`
+	// Shipments are the parts of the order sent from each warehouse in ModeSplit, the packs of
+	// the result add them up
+	Shipments []Shipment `json:"shipments,omitempty"`
+}
+
+// PackLine is the number of packs of a single size in a calculation result
+type PackLine struct {
+	// Size is the package size
+	Size int `json:"size"`
+	// Count is the number of packs of this size
+	Count int `json:"count"`
+	// Items is the number of items in these packs
+	Items int `json:"items"`
+}
+
+// newCalculationResult builds the result of an order from the chosen number of packs per size
+func newCalculationResult(orderQuantity int, packs map[int]int) *CalculationResult {
+	result := &CalculationResult{
+		Requested: orderQuantity,
+		Lines:     make([]PackLine, 0, len(packs)),
+	}
+	for size, count := range packs {
+		if count == 0 {
+			continue
+		}
+		result.Lines = append(result.Lines, PackLine{Size: size, Count: count, Items: size * count})
+		result.Shipped += size * count
+		result.TotalPacks += count
+	}
+	sort.Slice(result.Lines, func(i, j int) bool {
+		return result.Lines[i].Size > result.Lines[j].Size
+	})
+	result.Overshoot = result.Shipped - orderQuantity
+	return result
+}
+
+// Packs returns the number of packs per size
+func (r *CalculationResult) Packs() map[int]int {
+	packs := make(map[int]int, len(r.Lines))
+	for _, line := range r.Lines {
+		packs[line.Size] = line.Count
+	}
+	return packs
+}
+
+// CalculateOptions tunes a single calculation
+type CalculateOptions struct {
+	// ProductID is the product whose pack catalog and stock are used by Calculate,
+	// DefaultProductID when zero
+	ProductID int `json:"productId,omitempty"`
+	// Mode is the first objective of the calculation, that of Policy or of the configured policy
+	// when empty
+	Mode string `json:"mode,omitempty"`
+	// Policy is the name of the policy choosing the packs, the configured default is used when
+	// empty, see WithPolicy
+	Policy string `json:"policy,omitempty"`
+	// Solver is the name of the packing strategy, the configured default is used when empty
+	Solver string `json:"solver,omitempty"`
+	// Alternatives is the number of best combinations to list next to the result,
+	// or AllOptimalAlternatives to list every optimal one
+	Alternatives int `json:"alternatives,omitempty"`
+	// Constraints bound the packs per size. Calculate applies them on top of the constraints
+	// stored with the catalog, replacing those of the same size.
+	Constraints []PackConstraint `json:"constraints,omitempty"`
+	// Tolerance is the share of the order in percent Calculate may ship less than ordered
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// Backorder sends the shortfall later, and lets Calculate ship what the stock allows of an
+	// order it cannot cover
+	Backorder bool `json:"backorder,omitempty"`
+	// Cartonize puts the chosen packs in the fewest shipping cartons, see packCartons
+	Cartonize bool `json:"cartonize,omitempty"`
+	// Zone is the carrier zone the cartons are sent to, which prices them with the carrier of the
+	// lowest cost, see WithRateTables. ModeShipping needs one.
+	Zone string `json:"zone,omitempty"`
+	// Carrier limits the carriers to the one of this name
+	Carrier string `json:"carrier,omitempty"`
+	// Preference is how ModeSplit splits the order across the warehouses, the configured default
+	// is used when empty, see WithPreference
+	Preference string `json:"preference,omitempty"`
+	// Destination is where the order is sent, used by ModeSplit to find the nearest warehouses
+	Destination *Location `json:"destination,omitempty"`
+}
+
+// Calculate calculates the packs needed to fulfill an order from the stored catalog of a product.
+// The packs are chosen by the policy of opts: by default the least amount of items, then the
+// least amount of packs; in ModeCost the cheapest combination covering the order using the
+// pack prices and handling costs. Either way the cost breakdown of the chosen packs is included.
+// No more packs of a size are chosen than there are in stock, sizes without a stock level
+// never run out, and the counts of every size stay within its pack constraints.
+// Within the undershoot tolerance of opts less than ordered may be shipped, and with backorders
+// an order the stock cannot cover is shipped in part, see fulfill. With opts.Cartonize the chosen
+// packs are then put in the stored shipping cartons using the dimensions of the catalog, and with
+// opts.Zone sent with the carrier of the lowest cost; ModeShipping also chooses the packs by
+// that cost, see calculateShipping. ModeSplit splits the order across the warehouses, see
+// calculateSplit.
+// The catalog is kept in memory with the state the solvers precomputed for it until a package
+// of the product is added or deleted, stock levels are read on every call.
+// The calculation stops when ctx is done or it runs out of its budget, see WithBudget.
+func (a *App) Calculate(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error) {
+	switch opts.Mode {
+	case ModeShipping:
+		return a.calculateShipping(ctx, orderQuantity, opts)
+	case ModeSplit:
+		return a.calculateSplit(ctx, orderQuantity, opts)
+	}
+	if err := a.checkShipping(opts); err != nil {
+		return nil, err
+	}
+	policy, err := a.policyFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	solver, err := a.solverFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.withBudget(ctx)
+	defer cancel()
+
+	productID := productOrDefault(opts.ProductID)
+	snapshot, limits, err := a.loadCatalog(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := snapshot.fulfill(ctx, orderQuantity, solver, limits, policy, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Stock = stockOf(result.Catalog, limits)
+	if err := a.shipResult(snapshot, productID, result, opts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadCatalog returns the catalog snapshot of a product and the stock limits of its sizes
+func (a *App) loadCatalog(ctx context.Context, productID int) (*catalogSnapshot, map[int]int, error) {
+	snapshot, err := a.snapshot(ctx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	stock, err := a.repo.GetStock(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, stockLimits(stock, snapshot.sizes), nil
+}
+
+// calculate calculates the packs for an order from the snapshot by the policy, within the stock
+// limits and the pack constraints of its packages, replaced by those of opts of the same size
+func (s *catalogSnapshot) calculate(ctx context.Context, orderQuantity int, solver Solver, limits map[int]int, policy Policy, opts CalculateOptions) (*CalculationResult, error) {
+	var result *CalculationResult
+	var err error
+	if opts.Constraints, err = mergeConstraints(s.sizes, s.constraints, opts.Constraints); err != nil {
+		return nil, err
+	}
+	switch {
+	case policy.mode() == ObjectiveCost:
+		result, err = calculateMinCost(ctx, orderQuantity, s.sizes, s.costs, limits, opts)
+	case len(opts.Constraints) > 0 || !policy.usesSolvers():
+		result, err = calculateWithStock(ctx, orderQuantity, s.sizes, limits, policy, opts)
+	default:
+		if solver, err = s.solver(ctx, solver); err == nil {
+			result, err = calculatePacks(ctx, orderQuantity, s.sizes, solver, opts)
+		}
+		// The stock only limits the order when the packs chosen without it run out
+		if err == nil && len(limits) > 0 {
+			if withinStock(result.Lines, limits) {
+				result.Alternatives = alternativesInStock(result.Alternatives, limits)
+			} else {
+				result, err = calculateWithStock(ctx, orderQuantity, s.sizes, limits, policy, opts)
 			}
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	result.Cost = newCostBreakdown(result.Lines, s.costs)
+	result.Policy = &policy
+	if len(s.nesting) > 0 {
+		result.Tree = packTree(result.Lines, s.nesting)
+	}
+	return result, nil
+}
 
-	if dp[orderQuantity] == math.MaxInt32 {
-		return nil, fmt.Errorf("cannot fulfill order with given pack sizes")
+// calculateMinCost chooses the cheapest combination of packs covering the order within the stock
+// limits and the pack constraints of opts
+func calculateMinCost(ctx context.Context, orderQuantity int, packSizes []int, costs map[int]packCost, limits map[int]int, opts CalculateOptions) (*CalculationResult, error) {
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if len(packSizes) == 0 {
+		return nil, ErrEmptyCatalog
+	}
+	if opts.Solver != "" && opts.Solver != "bnb" {
+		return nil, fmt.Errorf("%w: %s only supports the bnb solver", ErrInvalidMode, ModeCost)
+	}
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: %s does not support alternatives", ErrInvalidMode, ModeCost)
+	}
+
+	catalog := distinctDescending(packSizes)
+	levels := make([]packCost, 0, len(catalog))
+	for _, size := range catalog {
+		levels = append(levels, costs[size])
+	}
+	order, err := applyConstraints(orderQuantity, catalog, limits, opts.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	var rest map[int]int
+	if order.rest > 0 {
+		if rest, err = solveMinCost(ctx, order.rest, levels, order.limits); err != nil {
+			return nil, err
+		}
+	}
+
+	result := newCalculationResult(orderQuantity, order.packs(rest))
+	result.Catalog = catalog
+	result.Solver = "bnb"
+	result.Mode = ModeCost
+	result.Constraints = opts.Constraints
+	return result, nil
+}
+
+// CalculatePacksNeeded calculates the packs needed to fulfill an order.
+// Only whole packs are sent, so the order may be overshot: by default the least amount of items
+// is shipped first, and within that the least amount of packs, see Policy. The pack sizes have no
+// prices or dimensions, so policies putting cost first and cartons are not supported.
+// The work is delegated to the requested packing strategy, see Solver; with pack constraints
+// in opts or a policy the strategies do not follow only the bnb strategy can be used. Like
+// Calculate it stops when ctx is done or it runs out of its budget.
+func (a *App) CalculatePacksNeeded(ctx context.Context, orderQuantity int, packSizes []int, opts CalculateOptions) (*CalculationResult, error) {
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if len(packSizes) == 0 {
+		return nil, ErrEmptyCatalog
+	}
+	for _, size := range packSizes {
+		if err := checkPackSize(size); err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := a.policyFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	if policy.mode() == ObjectiveCost {
+		return nil, fmt.Errorf("%w: the %s policy needs the prices of a stored catalog", ErrInvalidMode, policy.Name)
 	}
+	if opts.Cartonize || opts.Zone != "" {
+		return nil, fmt.Errorf("%w: cartons need the pack dimensions of a stored catalog", ErrInvalidMode)
+	}
+
+	ctx, cancel := a.withBudget(ctx)
+	defer cancel()
 
-	result := make(map[int]int)
-	remaining := orderQuantity
-	for remaining > 0 {
-		// choice - which packs were used to fulfill the order
-		// remaining - track of how much of the order is still left to be packed
-		pack := choice[remaining]
-		result[pack]++
-		remaining -= pack
+	var result *CalculationResult
+	if len(opts.Constraints) > 0 || !policy.usesSolvers() {
+		if opts.Constraints, err = mergeConstraints(packSizes, nil, opts.Constraints); err != nil {
+			return nil, err
+		}
+		result, err = calculateWithStock(ctx, orderQuantity, packSizes, nil, policy, opts)
+	} else {
+		var solver Solver
+		if solver, err = a.solverFor(opts); err == nil {
+			// Larger packs are considered first for optimization, the caller's slice is left as is
+			result, err = calculatePacks(ctx, orderQuantity, distinctDescending(packSizes), solver, opts)
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
+	result.Policy = &policy
+	return result, nil
+}
 
+// solverFor returns the packing strategy asked for in opts, or the configured default
+func (a *App) solverFor(opts CalculateOptions) (Solver, error) {
+	if opts.Solver == "" {
+		return a.solver, nil
+	}
+	return SolverByName(opts.Solver)
+}
+
+// calculatePacks runs the solver over the catalog, given as distinct sizes in descending order,
+// and lists the alternatives asked for
+func calculatePacks(ctx context.Context, orderQuantity int, catalog []int, solver Solver, opts CalculateOptions) (*CalculationResult, error) {
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if len(catalog) == 0 {
+		return nil, ErrEmptyCatalog
+	}
+
+	result, err := solver.Solve(ctx, orderQuantity, catalog)
+	if err != nil {
+		return nil, err
+	}
+	// The catalog may belong to a snapshot shared by other calculations
+	result.Catalog = append([]int(nil), catalog...)
+	result.Solver = solver.Name()
+	result.Mode = ModeItems
+
+	if result.Alternatives, err = findAlternatives(ctx, orderQuantity, catalog, opts.Alternatives); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
+
+// distinctDescending returns the distinct pack sizes sorted in descending order
+func distinctDescending(packSizes []int) []int {
+	sizes := make([]int, 0, len(packSizes))
+	seen := make(map[int]bool, len(packSizes))
+	for _, size := range packSizes {
+		if !seen[size] {
+			seen[size] = true
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return sizes
+}
@@ -1,53 +1,161 @@
 package app
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
 func TestCalculatePacksNeeded(t *testing.T) {
-	app := &App{}
+	app := NewApp(nil)
 	tests := []struct {
-		orderQuantity int
-		packSizes     []int
-		expected      map[int]int
-		expectError   bool
+		orderQuantity     int
+		packSizes         []int
+		expected          map[int]int
+		expectedOvershoot int
+		expectError       error
 	}{
 		{
-			orderQuantity: 4,
-			packSizes:     []int{5, 10},
-			expected:      nil,
-			expectError:   true,
+			orderQuantity:     4,
+			packSizes:         []int{5, 10},
+			expected:          map[int]int{5: 1},
+			expectedOvershoot: 1,
+		},
+		{
+			orderQuantity:     251,
+			packSizes:         []int{250, 500},
+			expected:          map[int]int{500: 1},
+			expectedOvershoot: 249,
+		},
+		{
+			orderQuantity:     1,
+			packSizes:         []int{250, 500, 1000, 2000, 5000},
+			expected:          map[int]int{250: 1},
+			expectedOvershoot: 249,
+		},
+		{
+			orderQuantity:     501,
+			packSizes:         []int{250, 500, 1000, 2000, 5000},
+			expected:          map[int]int{500: 1, 250: 1},
+			expectedOvershoot: 249,
+		},
+		{
+			orderQuantity:     12001,
+			packSizes:         []int{250, 500, 1000, 2000, 5000},
+			expected:          map[int]int{5000: 2, 2000: 1, 250: 1},
+			expectedOvershoot: 249,
+		},
+		{
+			orderQuantity:     11,
+			packSizes:         []int{3, 5, 7},
+			expected:          map[int]int{3: 2, 5: 1},
+			expectedOvershoot: 0,
 		},
 		{
 			orderQuantity: 0,
 			packSizes:     []int{3, 5, 7},
-			expected:      nil,
-			expectError:   true,
+			expectError:   ErrInvalidQuantity,
 		},
 		{
 			orderQuantity: -5,
 			packSizes:     []int{3, 5, 7},
-			expected:      nil,
-			expectError:   true,
+			expectError:   ErrInvalidQuantity,
+		},
+		{
+			orderQuantity: 10,
+			packSizes:     []int{},
+			expectError:   ErrEmptyCatalog,
 		},
 		{
 			orderQuantity: 500000,
 			packSizes:     []int{23, 31, 53},
 			expected:      map[int]int{23: 2, 31: 7, 53: 9429},
-			expectError:   false,
 		},
 	}
 
 	for _, tt := range tests {
-		result, err := app.CalculatePacksNeeded(tt.orderQuantity, tt.packSizes)
+		result, err := app.CalculatePacksNeeded(context.Background(), tt.orderQuantity, tt.packSizes, CalculateOptions{})
 
-		if tt.expectError {
-			require.Error(t, err)
+		if tt.expectError != nil {
+			require.ErrorIs(t, err, tt.expectError)
 		} else {
 			require.NoError(t, err)
-			require.Equal(t, tt.expected[53], result[53])
+			require.Equal(t, tt.expected, result.Packs())
+			require.Equal(t, tt.orderQuantity+tt.expectedOvershoot, result.Shipped)
+			require.Equal(t, tt.expectedOvershoot, result.Overshoot)
 		}
 	}
 }
+
+func TestCalculatePacksNeeded_Solver(t *testing.T) {
+	app := NewApp(nil, WithSolver(greedySolver{}))
+
+	// The greedy default overshoots where the exact strategies do not
+	result, err := app.CalculatePacksNeeded(context.Background(), 6, []int{3, 4}, CalculateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 7, result.Shipped)
+
+	result, err = app.CalculatePacksNeeded(context.Background(), 6, []int{3, 4}, CalculateOptions{Solver: "bnb"})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{3: 2}, result.Packs())
+
+	_, err = app.CalculatePacksNeeded(context.Background(), 6, []int{3, 4}, CalculateOptions{Solver: "simplex"})
+	require.ErrorIs(t, err, ErrUnknownSolver)
+}
+
+func TestCalculatePacksNeeded_Result(t *testing.T) {
+	app := NewApp(nil)
+
+	result, err := app.CalculatePacksNeeded(context.Background(), 12001, []int{500, 250, 5000, 1000, 2000}, CalculateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, &CalculationResult{
+		Requested:  12001,
+		Shipped:    12250,
+		Overshoot:  249,
+		TotalPacks: 4,
+		Lines: []PackLine{
+			{Size: 5000, Count: 2, Items: 10000},
+			{Size: 2000, Count: 1, Items: 2000},
+			{Size: 250, Count: 1, Items: 250},
+		},
+		Catalog: []int{5000, 2000, 1000, 500, 250},
+		Solver:  "dp",
+		Mode:    ModeItems,
+		Policy:  &itemsFirst,
+	}, result)
+}
+
+func TestCalculatePacksNeeded_DoesNotModifyPackSizes(t *testing.T) {
+	app := NewApp(nil)
+
+	packSizes := []int{500, 250, 5000, 1000, 2000}
+	_, err := app.CalculatePacksNeeded(context.Background(), 12001, packSizes, CalculateOptions{Alternatives: 3})
+	require.NoError(t, err)
+	require.Equal(t, []int{500, 250, 5000, 1000, 2000}, packSizes)
+}
+
+func TestCalculatePacksNeeded_InvalidPackSize(t *testing.T) {
+	app := NewApp(nil)
+
+	for _, packSizes := range [][]int{{250, 0}, {-5, 500}} {
+		_, err := app.CalculatePacksNeeded(context.Background(), 12, packSizes, CalculateOptions{})
+		require.ErrorIs(t, err, ErrInvalidPackSize, "pack sizes %v", packSizes)
+	}
+}
+
+func TestCalculatePacksNeeded_Alternatives(t *testing.T) {
+	app := NewApp(nil)
+
+	result, err := app.CalculatePacksNeeded(context.Background(), 6, []int{1, 2, 3, 4, 5}, CalculateOptions{Alternatives: AllOptimalAlternatives})
+	require.NoError(t, err)
+	require.Len(t, result.Alternatives, 3)
+	for _, alternative := range result.Alternatives {
+		require.True(t, alternative.Optimal)
+		require.Equal(t, result.Shipped, alternative.Shipped)
+		require.Equal(t, result.TotalPacks, alternative.TotalPacks)
+	}
+
+	_, err = app.CalculatePacksNeeded(context.Background(), 6, []int{1, 2, 3, 4, 5}, CalculateOptions{Alternatives: MaxAlternatives + 1})
+	require.ErrorIs(t, err, ErrInvalidAlternatives)
+}
@@ -0,0 +1,294 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrCartonNotFound is returned when a carton does not exist
+	ErrCartonNotFound = repo.ErrCartonNotFound
+	// ErrCartonExists is returned when a carton name is already taken
+	ErrCartonExists = repo.ErrCartonExists
+	// ErrInvalidCarton is returned for a carton without a name, dimensions or weight limit
+	ErrInvalidCarton = errors.New("invalid carton")
+	// ErrInvalidDimensions is returned for a pack with negative or partly given dimensions
+	ErrInvalidDimensions = errors.New("invalid pack dimensions")
+	// ErrCannotCartonize is returned when the packs of a result cannot be put in the cartons:
+	// packs without dimensions, packs that fit no carton, or more cartons than MaxCartons
+	ErrCannotCartonize = errors.New("cannot put packs in cartons")
+)
+
+// MaxCartons caps the number of cartons the packs of a single result are put in
+const MaxCartons = 10_000
+
+// Carton is a shipping carton the packs of an order are put in
+type Carton = repo.Carton
+
+// CartonLoad is a carton along with the packs put in it
+type CartonLoad struct {
+	CartonID int `json:"cartonId"`
+	// Carton is the carton name
+	Carton   string       `json:"carton"`
+	Contents []CartonItem `json:"contents"`
+	// Weight is the weight of the packs in grams
+	Weight int `json:"weight"`
+	// Fill is the share of the carton volume the packs take up
+	Fill float64 `json:"fill"`
+}
+
+// CartonItem is the number of packs of a size of a product in a carton
+type CartonItem struct {
+	ProductID int `json:"productId"`
+	Size      int `json:"size"`
+	Count     int `json:"count"`
+}
+
+// packDimensions are the outer dimensions in millimetres and the weight in grams of a pack
+type packDimensions struct {
+	length, width, height, weight int
+}
+
+// dimensionsOf returns the dimensions of a package
+func dimensionsOf(pkg Package) packDimensions {
+	return packDimensions{length: pkg.Length, width: pkg.Width, height: pkg.Height, weight: pkg.Weight}
+}
+
+// known reports whether all three dimensions are given
+func (d packDimensions) known() bool {
+	return d.length > 0 && d.width > 0 && d.height > 0
+}
+
+func (d packDimensions) volume() int {
+	return d.length * d.width * d.height
+}
+
+// sorted returns the dimensions from the shortest to the longest, so packs can be turned to fit
+func (d packDimensions) sorted() [3]int {
+	dims := [3]int{d.length, d.width, d.height}
+	sort.Ints(dims[:])
+	return dims
+}
+
+// checkDimensions validates the dimensions of a package: none or all of them are given, and
+// none is negative
+func checkDimensions(pkg Package) error {
+	d := dimensionsOf(pkg)
+	if d.length < 0 || d.width < 0 || d.height < 0 || d.weight < 0 {
+		return fmt.Errorf("%w: dimensions and weight must not be negative", ErrInvalidDimensions)
+	}
+	if !d.known() && d.length+d.width+d.height > 0 {
+		return fmt.Errorf("%w: length, width and height are given together", ErrInvalidDimensions)
+	}
+	return nil
+}
+
+// catalogDimensions returns the dimensions of the packs of a catalog by size
+func catalogDimensions(catalog []Package) map[int]packDimensions {
+	dimensions := make(map[int]packDimensions, len(catalog))
+	for _, pkg := range catalog {
+		dimensions[pkg.Size] = dimensionsOf(pkg)
+	}
+	return dimensions
+}
+
+// GetCartons returns the shipping cartons
+func (a *App) GetCartons() ([]Carton, error) {
+	return a.repo.GetCartons()
+}
+
+// AddCarton adds a shipping carton and returns it with its ID
+func (a *App) AddCarton(carton Carton) (Carton, error) {
+	carton.Name = strings.TrimSpace(carton.Name)
+	if carton.Name == "" {
+		return Carton{}, fmt.Errorf("%w: name is required", ErrInvalidCarton)
+	}
+	if carton.Length <= 0 || carton.Width <= 0 || carton.Height <= 0 || carton.MaxWeight <= 0 {
+		return Carton{}, fmt.Errorf("%w: dimensions and maximum weight must be positive", ErrInvalidCarton)
+	}
+	return a.repo.AddCarton(carton)
+}
+
+// DeleteCarton deletes a shipping carton
+func (a *App) DeleteCarton(id int) error {
+	return a.repo.DeleteCarton(id)
+}
+
+// cartonPack is a number of packs of a size of a product to put in cartons
+type cartonPack struct {
+	productID int
+	size      int
+	count     int
+	dims      packDimensions
+}
+
+// cartonPacks returns the packs of the lines of a result of a product of the snapshot
+func (s *catalogSnapshot) cartonPacks(productID int, lines []PackLine) []cartonPack {
+	packs := make([]cartonPack, 0, len(lines))
+	for _, line := range lines {
+		packs = append(packs, cartonPack{productID: productID, size: line.Size, count: line.Count, dims: s.dimensions[line.Size]})
+	}
+	return packs
+}
+
+// cartonize puts packs in the fewest of the stored cartons, see packCartons
+func (a *App) cartonize(packs []cartonPack) ([]CartonLoad, error) {
+	cartons, err := a.repo.GetCartons()
+	if err != nil {
+		return nil, err
+	}
+	return packCartons(packs, cartons)
+}
+
+// cartonFill is a carton being filled
+type cartonFill struct {
+	carton Carton
+	// volume and weight are those of the packs put in so far
+	volume   int
+	weight   int
+	contents []cartonPack
+}
+
+// fits reports whether a single pack fits in a carton, turned any way
+func fits(dims packDimensions, carton Carton) bool {
+	pack := dims.sorted()
+	inner := packDimensions{length: carton.Length, width: carton.Width, height: carton.Height}.sorted()
+	return pack[0] <= inner[0] && pack[1] <= inner[1] && pack[2] <= inner[2] && dims.weight <= carton.MaxWeight
+}
+
+func cartonVolume(carton Carton) int {
+	return carton.Length * carton.Width * carton.Height
+}
+
+// room returns how many more packs of the dimensions the carton takes by volume and weight
+func (f *cartonFill) room(dims packDimensions) int {
+	if !fits(dims, f.carton) {
+		return 0
+	}
+	room := (cartonVolume(f.carton) - f.volume) / dims.volume()
+	if dims.weight > 0 {
+		room = min(room, (f.carton.MaxWeight-f.weight)/dims.weight)
+	}
+	return room
+}
+
+// add puts count packs in the carton
+func (f *cartonFill) add(pack cartonPack, count int) {
+	f.volume += count * pack.dims.volume()
+	f.weight += count * pack.dims.weight
+	if last := len(f.contents) - 1; last >= 0 && f.contents[last].productID == pack.productID && f.contents[last].size == pack.size {
+		f.contents[last].count += count
+		return
+	}
+	pack.count = count
+	f.contents = append(f.contents, pack)
+}
+
+// holds reports whether the packs of the fill all go in a carton
+func (f *cartonFill) holds(carton Carton) bool {
+	if f.volume > cartonVolume(carton) || f.weight > carton.MaxWeight {
+		return false
+	}
+	for _, pack := range f.contents {
+		if !fits(pack.dims, carton) {
+			return false
+		}
+	}
+	return true
+}
+
+// packCartons puts the packs in the fewest cartons it can, first fit decreasing: the largest
+// packs go first, each in the first carton with room for it, and a new carton is the one taking
+// the most packs of the kind. Only volume and weight are added up, so the packs are taken to fill
+// a carton without gaps as long as every single pack fits in it. Finally every carton is swapped
+// for the smallest carton holding its packs.
+func packCartons(packs []cartonPack, cartons []Carton) ([]CartonLoad, error) {
+	if len(cartons) == 0 {
+		return nil, fmt.Errorf("%w: no cartons configured", ErrCannotCartonize)
+	}
+	for _, pack := range packs {
+		if !pack.dims.known() {
+			return nil, fmt.Errorf("%w: packs of %d of product %d have no dimensions", ErrCannotCartonize, pack.size, pack.productID)
+		}
+	}
+	packs = append([]cartonPack(nil), packs...)
+	sort.SliceStable(packs, func(i, j int) bool {
+		if vi, vj := packs[i].dims.volume(), packs[j].dims.volume(); vi != vj {
+			return vi > vj
+		}
+		return packs[i].dims.weight > packs[j].dims.weight
+	})
+	// Smallest first, so the first carton holding a fill is the smallest
+	bySize := append([]Carton(nil), cartons...)
+	sort.SliceStable(bySize, func(i, j int) bool {
+		return cartonVolume(bySize[i]) < cartonVolume(bySize[j])
+	})
+
+	var fills []*cartonFill
+	for _, pack := range packs {
+		left := pack.count
+		for _, fill := range fills {
+			if left == 0 {
+				break
+			}
+			if n := min(left, fill.room(pack.dims)); n > 0 {
+				fill.add(pack, n)
+				left -= n
+			}
+		}
+		for left > 0 {
+			fill := newFillFor(pack.dims, bySize)
+			if fill == nil {
+				return nil, fmt.Errorf("%w: packs of %d of product %d fit no carton", ErrCannotCartonize, pack.size, pack.productID)
+			}
+			if len(fills) == MaxCartons {
+				return nil, fmt.Errorf("%w: more than %d cartons are needed", ErrCannotCartonize, MaxCartons)
+			}
+			n := min(left, fill.room(pack.dims))
+			fill.add(pack, n)
+			left -= n
+			fills = append(fills, fill)
+		}
+	}
+
+	loads := make([]CartonLoad, 0, len(fills))
+	for _, fill := range fills {
+		for _, carton := range bySize {
+			if fill.holds(carton) {
+				fill.carton = carton
+				break
+			}
+		}
+		load := CartonLoad{
+			CartonID: fill.carton.ID,
+			Carton:   fill.carton.Name,
+			Contents: make([]CartonItem, 0, len(fill.contents)),
+			Weight:   fill.weight,
+			Fill:     math.Round(float64(fill.volume)/float64(cartonVolume(fill.carton))*1000) / 1000,
+		}
+		for _, pack := range fill.contents {
+			load.Contents = append(load.Contents, CartonItem{ProductID: pack.productID, Size: pack.size, Count: pack.count})
+		}
+		loads = append(loads, load)
+	}
+	return loads, nil
+}
+
+// newFillFor returns an empty fill of the carton taking the most packs of the dimensions, the
+// smaller one of cartons taking as many, or nil when the packs fit no carton
+func newFillFor(dims packDimensions, bySize []Carton) *cartonFill {
+	var best *cartonFill
+	bestRoom := 0
+	for _, carton := range bySize {
+		fill := &cartonFill{carton: carton}
+		if room := fill.room(dims); room > bestRoom {
+			best, bestRoom = fill, room
+		}
+	}
+	return best
+}
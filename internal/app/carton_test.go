@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	smallCarton = Carton{ID: 1, Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}
+	largeCarton = Carton{ID: 2, Name: "large", Length: 600, Width: 400, Height: 400, MaxWeight: 30000}
+	// boxOf500 takes up a quarter of the small carton, boxOf250 half as much
+	boxOf500 = packDimensions{length: 200, width: 150, height: 100, weight: 2000}
+	boxOf250 = packDimensions{length: 100, width: 150, height: 100, weight: 1000}
+)
+
+func TestPackCartons(t *testing.T) {
+	tests := []struct {
+		name    string
+		packs   []cartonPack
+		cartons []Carton
+		want    []CartonLoad
+		wantErr error
+	}{
+		{
+			name:    "smallest carton holding the packs",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 4, dims: boxOf500}},
+			cartons: []Carton{largeCarton, smallCarton},
+			want: []CartonLoad{
+				{CartonID: 1, Carton: "small", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 4}}, Weight: 8000, Fill: 1},
+			},
+		},
+		{
+			name:    "weight limits the packs per carton",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 20, dims: boxOf500}},
+			cartons: []Carton{smallCarton, largeCarton},
+			want: []CartonLoad{
+				{CartonID: 2, Carton: "large", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 15}}, Weight: 30000, Fill: 0.469},
+				{CartonID: 2, Carton: "large", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 5}}, Weight: 10000, Fill: 0.156},
+			},
+		},
+		{
+			name: "smaller packs fill up the cartons",
+			packs: []cartonPack{
+				{productID: 1, size: 250, count: 3, dims: boxOf250},
+				{productID: 1, size: 500, count: 2, dims: boxOf500},
+			},
+			cartons: []Carton{smallCarton, largeCarton},
+			want: []CartonLoad{
+				{CartonID: 1, Carton: "small", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 2}, {ProductID: 1, Size: 250, Count: 3}}, Weight: 7000, Fill: 0.875},
+			},
+		},
+		{
+			name:    "packs without dimensions",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 1}},
+			cartons: []Carton{smallCarton},
+			wantErr: ErrCannotCartonize,
+		},
+		{
+			name:    "pack too long for every carton",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 1, dims: packDimensions{length: 700, width: 100, height: 100}}},
+			cartons: []Carton{smallCarton, largeCarton},
+			wantErr: ErrCannotCartonize,
+		},
+		{
+			name:    "pack too heavy for every carton",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 1, dims: packDimensions{length: 100, width: 100, height: 100, weight: 40000}}},
+			cartons: []Carton{smallCarton, largeCarton},
+			wantErr: ErrCannotCartonize,
+		},
+		{
+			name:    "no cartons",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 1, dims: boxOf500}},
+			wantErr: ErrCannotCartonize,
+		},
+		{
+			name:    "too many cartons",
+			packs:   []cartonPack{{productID: 1, size: 500, count: 4*MaxCartons + 1, dims: boxOf500}},
+			cartons: []Carton{smallCarton},
+			wantErr: ErrCannotCartonize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := packCartons(tt.packs, tt.cartons)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApp_Calculate_Cartonize(t *testing.T) {
+	catalog := []repo.Package{
+		{ID: 1, Size: 250, Length: 100, Width: 150, Height: 100, Weight: 1000},
+		{ID: 2, Size: 500, Length: 200, Width: 150, Height: 100, Weight: 2000},
+	}
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+	mockRepo.On("GetCartons").Return([]repo.Carton{smallCarton, largeCarton}, nil)
+
+	result, err := NewApp(mockRepo).Calculate(context.Background(), 1250, CalculateOptions{Cartonize: true})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{500: 2, 250: 1}, result.Packs())
+	require.Equal(t, []CartonLoad{
+		{CartonID: 1, Carton: "small", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 2}, {ProductID: 1, Size: 250, Count: 1}}, Weight: 5000, Fill: 0.625},
+	}, result.Cartons)
+	mockRepo.AssertExpectations(t)
+
+	_, err = NewApp(mockRepo).CalculatePacksNeeded(context.Background(), 1250, []int{250, 500}, CalculateOptions{Cartonize: true})
+	require.ErrorIs(t, err, ErrInvalidMode)
+}
+
+func TestApp_CalculateOrder_Cartonize(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetProducts").Return([]repo.Product{{ID: 1, Name: "default"}, {ID: 2, Name: "bolts"}}, nil)
+	mockRepo.On("GetCatalog", 1).Return([]repo.Package{{ID: 1, ProductID: 1, Size: 500, Length: 200, Width: 150, Height: 100, Weight: 2000}}, nil)
+	mockRepo.On("GetCatalog", 2).Return([]repo.Package{{ID: 2, ProductID: 2, Size: 250, Length: 100, Width: 150, Height: 100, Weight: 1000}}, nil)
+	mockRepo.On("GetStock", 1).Return([]repo.StockLevel{}, nil)
+	mockRepo.On("GetStock", 2).Return([]repo.StockLevel{}, nil)
+	mockRepo.On("GetCartons").Return([]repo.Carton{smallCarton, largeCarton}, nil).Once()
+
+	order, err := NewApp(mockRepo).CalculateOrder(context.Background(), []OrderLine{
+		{ProductID: 1, Quantity: 1000},
+		{ProductID: 2, Quantity: 500},
+	}, CalculateOptions{Cartonize: true})
+	require.NoError(t, err)
+	require.Nil(t, order.Lines[0].Result.Cartons)
+	require.Equal(t, []CartonLoad{
+		{CartonID: 1, Carton: "small", Contents: []CartonItem{{ProductID: 1, Size: 500, Count: 2}, {ProductID: 2, Size: 250, Count: 2}}, Weight: 6000, Fill: 0.75},
+	}, order.Cartons)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_AddCarton(t *testing.T) {
+	tests := []struct {
+		name      string
+		carton    Carton
+		setupMock func(*MockRepository)
+		wantErr   error
+	}{
+		{
+			name:   "successful add",
+			carton: Carton{Name: " small ", Length: 300, Width: 200, Height: 200, MaxWeight: 10000},
+			setupMock: func(m *MockRepository) {
+				m.On("AddCarton", Carton{Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}).Return(smallCarton, nil)
+			},
+		},
+		{
+			name:      "no name",
+			carton:    Carton{Length: 300, Width: 200, Height: 200, MaxWeight: 10000},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidCarton,
+		},
+		{
+			name:      "no weight limit",
+			carton:    Carton{Name: "small", Length: 300, Width: 200, Height: 200},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidCarton,
+		},
+		{
+			name:   "name taken",
+			carton: Carton{Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000},
+			setupMock: func(m *MockRepository) {
+				m.On("AddCarton", Carton{Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}).Return(Carton{}, ErrCartonExists)
+			},
+			wantErr: ErrCartonExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			got, err := NewApp(mockRepo).AddCarton(tt.carton)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, smallCarton, got)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
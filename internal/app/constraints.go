@@ -0,0 +1,195 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidConstraint is returned for a pack constraint on a size outside the catalog, with a
+	// negative count or with a minimum above its maximum
+	ErrInvalidConstraint = errors.New("invalid pack constraint")
+	// ErrConstraintInfeasible is returned when no combination of packs meets the pack constraints
+	ErrConstraintInfeasible = errors.New("pack constraints cannot be met")
+)
+
+// MaxConstraintCount caps the counts of a pack constraint
+const MaxConstraintCount = 1_000_000
+
+// PackConstraint bounds the number of packs of a size in a single order
+type PackConstraint struct {
+	Size int `json:"size"`
+	// Min is the least number of packs of the size, 0 for none
+	Min int `json:"min,omitempty"`
+	// Max is the most packs of the size, 0 for no limit
+	Max int `json:"max,omitempty"`
+}
+
+func (c PackConstraint) String() string {
+	switch {
+	case c.Min > 0 && c.Max > 0:
+		return fmt.Sprintf("%d to %d packs of %d", c.Min, c.Max, c.Size)
+	case c.Min > 0:
+		return fmt.Sprintf("at least %d packs of %d", c.Min, c.Size)
+	default:
+		return fmt.Sprintf("at most %d packs of %d", c.Max, c.Size)
+	}
+}
+
+// checkCounts validates the counts of a constraint
+func checkCounts(minCount, maxCount int) error {
+	if minCount < 0 || maxCount < 0 {
+		return fmt.Errorf("%w: counts must not be negative", ErrInvalidConstraint)
+	}
+	if minCount > MaxConstraintCount || maxCount > MaxConstraintCount {
+		return fmt.Errorf("%w: counts must be at most %d", ErrInvalidConstraint, MaxConstraintCount)
+	}
+	if maxCount > 0 && minCount > maxCount {
+		return fmt.Errorf("%w: minimum %d is above maximum %d", ErrInvalidConstraint, minCount, maxCount)
+	}
+	return nil
+}
+
+// SetPackageConstraints sets the least and most packs of a package every order ships,
+// 0 for no constraint
+func (a *App) SetPackageConstraints(id, minCount, maxCount int) (Package, error) {
+	if err := checkCounts(minCount, maxCount); err != nil {
+		return Package{}, err
+	}
+	pkg, err := a.repo.SetPackageConstraints(id, minCount, maxCount)
+	if err != nil {
+		return Package{}, err
+	}
+	a.cache.invalidate(pkg.ProductID)
+	return pkg, nil
+}
+
+// catalogConstraints returns the constraints stored with the packages of a catalog
+func catalogConstraints(catalog []Package) map[int]PackConstraint {
+	constraints := make(map[int]PackConstraint)
+	for _, pkg := range catalog {
+		if pkg.MinCount > 0 || pkg.MaxCount > 0 {
+			constraints[pkg.Size] = PackConstraint{Size: pkg.Size, Min: pkg.MinCount, Max: pkg.MaxCount}
+		}
+	}
+	return constraints
+}
+
+// mergeConstraints returns the stored constraints with those of a request in their place, largest
+// size first. Requested constraints must be on sizes of the catalog; a constraint without counts
+// lifts the stored one.
+func mergeConstraints(sizes []int, stored map[int]PackConstraint, requested []PackConstraint) ([]PackConstraint, error) {
+	inCatalog := make(map[int]bool, len(sizes))
+	for _, size := range sizes {
+		inCatalog[size] = true
+	}
+	merged := make(map[int]PackConstraint, len(stored)+len(requested))
+	for size, c := range stored {
+		merged[size] = c
+	}
+	seen := make(map[int]bool, len(requested))
+	for _, c := range requested {
+		if !inCatalog[c.Size] {
+			return nil, fmt.Errorf("%w: size %d is not in the catalog", ErrInvalidConstraint, c.Size)
+		}
+		if seen[c.Size] {
+			return nil, fmt.Errorf("%w: size %d is constrained more than once", ErrInvalidConstraint, c.Size)
+		}
+		seen[c.Size] = true
+		if err := checkCounts(c.Min, c.Max); err != nil {
+			return nil, fmt.Errorf("size %d: %w", c.Size, err)
+		}
+		if c.Min == 0 && c.Max == 0 {
+			delete(merged, c.Size)
+		} else {
+			merged[c.Size] = c
+		}
+	}
+
+	constraints := make([]PackConstraint, 0, len(merged))
+	for _, c := range merged {
+		constraints = append(constraints, c)
+	}
+	sort.Slice(constraints, func(i, j int) bool { return constraints[i].Size > constraints[j].Size })
+	return constraints, nil
+}
+
+// boundedOrder is an order within the pack constraints and the stock: the packs every shipment
+// must hold are set aside, and the limits are lowered to the packs allowed on top of them
+type boundedOrder struct {
+	// rest is the number of items still to cover after the required packs, zero or less when
+	// they cover the order
+	rest int
+	// required are the least packs per size
+	required map[int]int
+	// limits are the most packs per size on top of the required ones, see stockLimits
+	limits map[int]int
+}
+
+// applyConstraints bounds an order by the constraints and the stock limits of the catalog,
+// sizes in descending order. It fails naming the constraint that cannot be met.
+func applyConstraints(orderQuantity int, sizes []int, stock map[int]int, constraints []PackConstraint) (*boundedOrder, error) {
+	order := &boundedOrder{rest: orderQuantity, required: make(map[int]int), limits: make(map[int]int, len(stock))}
+	for size, limit := range stock {
+		order.limits[size] = limit
+	}
+
+	byMax := make(map[int]bool)
+	for _, c := range constraints {
+		inStock, tracked := stock[c.Size]
+		if tracked && inStock < c.Min {
+			return nil, fmt.Errorf("%w: %s required, %d in stock", ErrConstraintInfeasible, c, inStock)
+		}
+		order.required[c.Size] = c.Min
+		order.rest -= c.Min * c.Size
+
+		limit := unlimitedStock
+		if c.Max > 0 && (!tracked || c.Max <= inStock) {
+			limit = c.Max
+			byMax[c.Size] = true
+		} else if tracked {
+			limit = inStock
+		}
+		if limit != unlimitedStock {
+			order.limits[c.Size] = limit - c.Min
+		}
+	}
+
+	capacity := stockCapacity(sizes, order.limits)[0]
+	if capacity >= order.rest {
+		return order, nil
+	}
+
+	// Every size is limited, name the limits
+	shippable := capacity + orderQuantity - order.rest
+	var limits []string
+	constrained := false
+	for _, size := range sizes {
+		total := order.limits[size] + order.required[size]
+		if byMax[size] {
+			constrained = true
+			limits = append(limits, fmt.Sprintf("at most %d packs of %d", total, size))
+		} else {
+			limits = append(limits, fmt.Sprintf("%d packs of %d in stock", total, size))
+		}
+	}
+	if !constrained {
+		return nil, fmt.Errorf("%w: %d items ordered, at most %d can be shipped", ErrInsufficientStock, orderQuantity, shippable)
+	}
+	return nil, fmt.Errorf("%w: %d items ordered, at most %d can be shipped with %s",
+		ErrConstraintInfeasible, orderQuantity, shippable, strings.Join(limits, ", "))
+}
+
+// packs adds the required packs to the packs chosen for the rest of the order
+func (o *boundedOrder) packs(rest map[int]int) map[int]int {
+	packs := make(map[int]int, len(rest)+len(o.required))
+	for size, count := range rest {
+		packs[size] += count
+	}
+	for size, count := range o.required {
+		packs[size] += count
+	}
+	return packs
+}
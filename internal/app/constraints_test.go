@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Calculate_Constraints(t *testing.T) {
+	catalog := []repo.Package{
+		{ID: 1, Size: 250},
+		{ID: 2, Size: 500},
+		{ID: 3, Size: 1000},
+		{ID: 4, Size: 2000},
+		{ID: 5, Size: 5000, MaxCount: 1},
+	}
+
+	tests := []struct {
+		name      string
+		order     int
+		stock     []repo.StockLevel
+		opts      CalculateOptions
+		wantPacks map[int]int
+		// wantConstraints are the constraints echoed in the result
+		wantConstraints []PackConstraint
+		wantErr         error
+		wantMsg         string
+	}{
+		{
+			name:            "stored maximum",
+			order:           12001,
+			wantPacks:       map[int]int{5000: 1, 2000: 3, 1000: 1, 250: 1},
+			wantConstraints: []PackConstraint{{Size: 5000, Max: 1}},
+		},
+		{
+			name:      "requested constraint replaces the stored one",
+			order:     12001,
+			opts:      CalculateOptions{Constraints: []PackConstraint{{Size: 5000}}},
+			wantPacks: map[int]int{5000: 2, 2000: 1, 250: 1},
+		},
+		{
+			name:            "minimum forces packs",
+			order:           251,
+			opts:            CalculateOptions{Constraints: []PackConstraint{{Size: 250, Min: 2}}},
+			wantPacks:       map[int]int{250: 2},
+			wantConstraints: []PackConstraint{{Size: 5000, Max: 1}, {Size: 250, Min: 2}},
+		},
+		{
+			name:      "minimum above the order",
+			order:     100,
+			opts:      CalculateOptions{Constraints: []PackConstraint{{Size: 500, Min: 1}, {Size: 250, Min: 1}}},
+			wantPacks: map[int]int{500: 1, 250: 1},
+		},
+		{
+			name:      "cost mode",
+			order:     12001,
+			opts:      CalculateOptions{Mode: ModeCost, Constraints: []PackConstraint{{Size: 2000, Max: 2}}},
+			wantPacks: map[int]int{5000: 1, 2000: 2, 1000: 3, 250: 1},
+		},
+		{
+			name:  "every size bounded",
+			order: 4000,
+			opts: CalculateOptions{Constraints: []PackConstraint{
+				{Size: 2000, Max: 1}, {Size: 1000, Max: 1}, {Size: 500, Max: 1}, {Size: 250, Max: 1},
+			}},
+			stock:   []repo.StockLevel{{Size: 5000, Quantity: 0}},
+			wantErr: ErrConstraintInfeasible,
+			wantMsg: "at most 1 packs of 2000",
+		},
+		{
+			name:    "minimum above stock",
+			order:   251,
+			opts:    CalculateOptions{Constraints: []PackConstraint{{Size: 250, Min: 3}}},
+			stock:   []repo.StockLevel{{Size: 250, Quantity: 2}},
+			wantErr: ErrConstraintInfeasible,
+			wantMsg: "at least 3 packs of 250 required, 2 in stock",
+		},
+		{
+			name:    "stored maximum and stock bind",
+			order:   6000,
+			stock:   []repo.StockLevel{{Size: 250, Quantity: 0}, {Size: 500, Quantity: 0}, {Size: 1000, Quantity: 0}, {Size: 2000, Quantity: 0}},
+			wantErr: ErrConstraintInfeasible,
+			wantMsg: "at most 1 packs of 5000, 0 packs of 2000 in stock",
+		},
+		{
+			name:    "only stock binds",
+			order:   6000,
+			opts:    CalculateOptions{Constraints: []PackConstraint{{Size: 5000}}},
+			stock:   []repo.StockLevel{{Size: 5000, Quantity: 1}, {Size: 2000, Quantity: 0}, {Size: 1000, Quantity: 0}, {Size: 500, Quantity: 0}, {Size: 250, Quantity: 0}},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name:    "size outside the catalog",
+			order:   251,
+			opts:    CalculateOptions{Constraints: []PackConstraint{{Size: 750, Max: 1}}},
+			wantErr: ErrInvalidConstraint,
+		},
+		{
+			name:    "minimum above maximum",
+			order:   251,
+			opts:    CalculateOptions{Constraints: []PackConstraint{{Size: 250, Min: 2, Max: 1}}},
+			wantErr: ErrInvalidConstraint,
+		},
+		{
+			name:    "solver without constraint support",
+			order:   251,
+			opts:    CalculateOptions{Solver: "dp", Constraints: []PackConstraint{{Size: 250, Min: 1}}},
+			wantErr: ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			pricedCatalog := make([]repo.Package, len(catalog))
+			for i, pkg := range catalog {
+				pkg.Price = float64(pkg.Size) / 100
+				pricedCatalog[i] = pkg
+			}
+			mockRepo.On("GetCatalog", DefaultProductID).Return(pricedCatalog, nil)
+			mockRepo.On("GetStock", DefaultProductID).Return(tt.stock, nil)
+
+			result, err := NewApp(mockRepo).Calculate(context.Background(), tt.order, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.ErrorContains(t, err, tt.wantMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			if tt.wantConstraints != nil {
+				require.Equal(t, tt.wantConstraints, result.Constraints)
+			}
+		})
+	}
+}
+
+func TestApp_SetPackageConstraints(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("SetPackageConstraints", 3, 1, 4).Return(repo.Package{ID: 3, ProductID: 2, Size: 250, MinCount: 1, MaxCount: 4}, nil)
+	mockRepo.On("SetPackageConstraints", 9, 0, 0).Return(repo.Package{}, repo.ErrPackageNotFound)
+
+	app := NewApp(mockRepo)
+	pkg, err := app.SetPackageConstraints(3, 1, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, pkg.MaxCount)
+
+	_, err = app.SetPackageConstraints(9, 0, 0)
+	require.ErrorIs(t, err, ErrPackageNotFound)
+
+	_, err = app.SetPackageConstraints(3, 5, 4)
+	require.ErrorIs(t, err, ErrInvalidConstraint)
+	_, err = app.SetPackageConstraints(3, -1, 0)
+	require.ErrorIs(t, err, ErrInvalidConstraint)
+
+	mockRepo.AssertExpectations(t)
+}
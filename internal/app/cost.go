@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// CostBreakdown is the cost of the packs chosen for an order
+type CostBreakdown struct {
+	// Lines are the costs per pack size, largest size first
+	Lines []CostLine `json:"lines"`
+	// Packs is the total price of the packs
+	Packs float64 `json:"packs"`
+	// Handling is the total handling cost of the packs
+	Handling float64 `json:"handling"`
+	// Total is the price plus the handling cost of all packs
+	Total float64 `json:"total"`
+}
+
+// CostLine is the cost of the packs of a single size
+type CostLine struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+	// UnitPrice is the price of a single pack
+	UnitPrice float64 `json:"unitPrice"`
+	// UnitHandling is the handling cost of a single pack
+	UnitHandling float64 `json:"unitHandling"`
+	// Total is the price plus the handling cost of these packs
+	Total float64 `json:"total"`
+}
+
+// packCost is the price and handling cost of a pack size in cents
+type packCost struct {
+	size     int
+	price    int64
+	handling int64
+}
+
+// total returns the full cost of a single pack in cents
+func (c packCost) total() int64 {
+	return c.price + c.handling
+}
+
+// cents converts an amount of money to cents
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// catalogCosts returns the costs per pack size, keeping the cheapest one for duplicated sizes
+func catalogCosts(catalog []Package) map[int]packCost {
+	costs := make(map[int]packCost, len(catalog))
+	for _, pkg := range catalog {
+		cost := packCost{size: pkg.Size, price: cents(pkg.Price), handling: cents(pkg.HandlingCost)}
+		if current, ok := costs[pkg.Size]; !ok || cost.total() < current.total() {
+			costs[pkg.Size] = cost
+		}
+	}
+	return costs
+}
+
+// newCostBreakdown prices the lines of a calculation result
+func newCostBreakdown(lines []PackLine, costs map[int]packCost) *CostBreakdown {
+	breakdown := &CostBreakdown{Lines: make([]CostLine, 0, len(lines))}
+	var packs, handling int64
+	for _, line := range lines {
+		cost := costs[line.Size]
+		breakdown.Lines = append(breakdown.Lines, CostLine{
+			Size:         line.Size,
+			Count:        line.Count,
+			UnitPrice:    float64(cost.price) / 100,
+			UnitHandling: float64(cost.handling) / 100,
+			Total:        float64(cost.total()*int64(line.Count)) / 100,
+		})
+		packs += cost.price * int64(line.Count)
+		handling += cost.handling * int64(line.Count)
+	}
+	breakdown.Packs = float64(packs) / 100
+	breakdown.Handling = float64(handling) / 100
+	breakdown.Total = float64(packs+handling) / 100
+	return breakdown
+}
+
+// solveMinCost finds the cheapest combination of packs covering the order, then the one
+// with the least amount of items and packs.
+//
+// It is a branch and bound search like branchAndBound, with sizes ordered by cost per item.
+// The pack B with the best cost per item that never runs out plays the role of the largest pack:
+// among any B packs with a worse cost per item some subset sums to a multiple of B and can be
+// swapped for B-packs without raising the cost, so an optimal combination has fewer than B of them.
+// Packs with a better cost per item than B are only limited by their stock, see stockLimits.
+// The caller makes sure the stock covers the order.
+func solveMinCost(ctx context.Context, orderQuantity int, costs []packCost, limits map[int]int) (map[int]int, error) {
+	levels := make([]packCost, 0, len(costs))
+	for _, cost := range costs {
+		if limitOf(limits, cost.size) != 0 {
+			levels = append(levels, cost)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		// Compare cost per item without division, larger packs first on ties
+		left := levels[i].total() * int64(levels[j].size)
+		right := levels[j].total() * int64(levels[i].size)
+		if left != right {
+			return left < right
+		}
+		return levels[i].size > levels[j].size
+	})
+
+	s := &costSearch{
+		cancelCheck: cancelCheck{ctx: ctx},
+		levels:      levels,
+		limits:      make([]int, len(levels)),
+		counts:      make([]int, len(levels)),
+		best:        make([]int, len(levels)),
+		bestCost:    -1,
+		minRatio:    make([]float64, len(levels)),
+		maxSize:     make([]int, len(levels)),
+		suffixGCD:   make([]int, len(levels)),
+		anchor:      -1,
+	}
+	sizes := make([]int, len(levels))
+	for i, level := range levels {
+		sizes[i] = level.size
+		s.limits[i] = limitOf(limits, level.size)
+		if s.anchor < 0 && s.limits[i] == unlimitedStock {
+			s.anchor = i
+		}
+	}
+	s.capacity = stockCapacity(sizes, limits)
+	for i := len(levels) - 1; i >= 0; i-- {
+		s.minRatio[i] = float64(levels[i].total()) / float64(levels[i].size)
+		s.maxSize[i] = levels[i].size
+		s.suffixGCD[i] = levels[i].size
+		if i+1 < len(levels) {
+			s.minRatio[i] = math.Min(s.minRatio[i], s.minRatio[i+1])
+			s.maxSize[i] = max(s.maxSize[i], s.maxSize[i+1])
+			s.suffixGCD[i] = gcd(levels[i].size, s.suffixGCD[i+1])
+		}
+	}
+
+	s.search(0, orderQuantity, 0, 0)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	packs := make(map[int]int)
+	for i, count := range s.best {
+		if count > 0 {
+			packs[levels[i].size] = count
+		}
+	}
+	return packs, nil
+}
+
+// costSearch holds the state of a single minimum cost search
+type costSearch struct {
+	cancelCheck
+	// levels are the pack costs ordered by cost per item, best first
+	levels []packCost
+	// limits[i] is the number of packs of levels[i] in stock, or unlimitedStock
+	limits []int
+	// capacity[i] is the most items levels[i:] can ship
+	capacity []int
+	// anchor is the level of the best cost per item that never runs out, or -1
+	anchor int
+	// counts are the pack counts of the current branch
+	counts []int
+	// best are the pack counts of the cheapest combination found so far, bestCost is -1 before the first one
+	best          []int
+	bestCost      int64
+	bestOvershoot int
+	bestPacks     int
+	// minRatio[i] is the lowest cost per item of levels[i:]
+	minRatio []float64
+	// maxSize[i] is the largest size of levels[i:]
+	maxSize []int
+	// suffixGCD[i] is the gcd of the sizes of levels[i:]
+	suffixGCD []int
+}
+
+func (s *costSearch) search(level, remaining int, cost int64, packs int) {
+	pack := s.levels[level]
+	last := level == len(s.levels)-1
+
+	maxCount := ceilDiv(remaining, pack.size)
+	if s.limits[level] != unlimitedStock {
+		maxCount = min(maxCount, s.limits[level])
+	}
+	minCount := 0
+	if last {
+		minCount = maxCount
+	}
+	if s.anchor >= 0 {
+		anchor := s.levels[s.anchor].size
+		if level == s.anchor && !last {
+			minCount = max(minCount, (remaining-(anchor-1)*s.maxSize[level+1])/anchor)
+		}
+		if level > s.anchor {
+			worse := packs
+			for i := 0; i <= s.anchor; i++ {
+				worse -= s.counts[i]
+			}
+			maxCount = min(maxCount, anchor-1-worse)
+		}
+	}
+
+	for count := maxCount; count >= minCount; count-- {
+		if s.stopped() {
+			break
+		}
+		rest := remaining - count*pack.size
+		spent := cost + int64(count)*pack.total()
+		if rest <= 0 {
+			s.consider(level, count, spent, -rest, packs+count)
+			continue
+		}
+		if last || s.capacity[level+1] < rest {
+			// The rest only grows as this count decreases
+			break
+		}
+
+		// The rest costs at least its items at the best remaining cost per item. The bound is
+		// computed in floating point, so it is only trusted with a small relative margin.
+		costBound := float64(spent) + float64(rest)*s.minRatio[level+1]
+		costBound -= 1e-9*costBound + 1
+		if s.bestCost >= 0 && costBound > float64(s.bestCost) {
+			// Sizes are ordered by cost per item, so the bound only grows as this count decreases
+			break
+		}
+		step := s.suffixGCD[level+1]
+		overshootBound := ceilDiv(rest, step)*step - rest
+		packsBound := packs + count + ceilDiv(rest, s.maxSize[level+1])
+		if s.bestCost >= 0 && costBound >= float64(s.bestCost) && !s.improves(s.bestCost, overshootBound, packsBound) {
+			continue
+		}
+
+		s.counts[level] = count
+		s.search(level+1, rest, spent, packs+count)
+	}
+	s.counts[level] = 0
+}
+
+// consider records the current branch, completed with count packs at level, if it improves on the best
+func (s *costSearch) consider(level, count int, cost int64, overshoot, packs int) {
+	if s.bestCost >= 0 && !s.improves(cost, overshoot, packs) {
+		return
+	}
+	copy(s.best, s.counts[:level])
+	s.best[level] = count
+	for i := level + 1; i < len(s.best); i++ {
+		s.best[i] = 0
+	}
+	s.bestCost = cost
+	s.bestOvershoot = overshoot
+	s.bestPacks = packs
+}
+
+// improves reports whether a combination is cheaper than the best one, or as cheap with fewer
+// items, or as cheap with as many items in fewer packs
+func (s *costSearch) improves(cost int64, overshoot, packs int) bool {
+	if cost != s.bestCost {
+		return cost < s.bestCost
+	}
+	if overshoot != s.bestOvershoot {
+		return overshoot < s.bestOvershoot
+	}
+	return packs < s.bestPacks
+}
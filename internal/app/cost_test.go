@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolveMinCost(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderQuantity int
+		costs         []packCost
+		expected      map[int]int
+	}{
+		{
+			name:          "bulk pack is cheaper per item",
+			orderQuantity: 5000,
+			costs:         []packCost{{size: 1000, price: 1000}, {size: 5000, price: 3000}},
+			expected:      map[int]int{5000: 1},
+		},
+		{
+			name:          "overshoot is cheaper than an exact fit",
+			orderQuantity: 4000,
+			costs:         []packCost{{size: 1000, price: 1000}, {size: 5000, price: 3000}},
+			expected:      map[int]int{5000: 1},
+		},
+		{
+			name:          "handling cost tips the balance",
+			orderQuantity: 2000,
+			costs:         []packCost{{size: 1000, price: 1000, handling: 100}, {size: 5000, price: 2000, handling: 1000}},
+			expected:      map[int]int{1000: 2},
+		},
+		{
+			name:          "ties fall back to least items",
+			orderQuantity: 251,
+			costs:         []packCost{{size: 250, price: 100}, {size: 500, price: 200}},
+			expected:      map[int]int{500: 1},
+		},
+		{
+			name:          "large order",
+			orderQuantity: 5_000_000_000_001,
+			costs:         []packCost{{size: 250, price: 100}, {size: 5000, price: 1500}},
+			expected:      map[int]int{5000: 1_000_000_000, 250: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packs, err := solveMinCost(context.Background(), tt.orderQuantity, tt.costs, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, packs)
+		})
+	}
+}
+
+func TestSolveMinCost_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 300; i++ {
+		costs := make([]packCost, 0, 3)
+		for _, size := range distinctDescending([]int{1 + rng.Intn(20), 1 + rng.Intn(20), 1 + rng.Intn(20)}) {
+			costs = append(costs, packCost{size: size, price: int64(rng.Intn(50)), handling: int64(rng.Intn(5))})
+		}
+		n := 1 + rng.Intn(60)
+
+		// Cheapest, then least items, then least packs over every covering combination
+		var bestCost int64 = -1
+		bestShipped, bestPacks := 0, 0
+		counts := make([]int, len(costs))
+		var walk func(level int)
+		walk = func(level int) {
+			if level == len(costs) {
+				var cost int64
+				shipped, packs := 0, 0
+				for j, c := range counts {
+					cost += int64(c) * costs[j].total()
+					shipped += c * costs[j].size
+					packs += c
+				}
+				if shipped < n {
+					return
+				}
+				if bestCost < 0 || cost < bestCost || (cost == bestCost && (shipped < bestShipped || (shipped == bestShipped && packs < bestPacks))) {
+					bestCost, bestShipped, bestPacks = cost, shipped, packs
+				}
+				return
+			}
+			for c := 0; c <= ceilDiv(n, costs[level].size); c++ {
+				counts[level] = c
+				walk(level + 1)
+			}
+		}
+		walk(0)
+
+		packs, err := solveMinCost(context.Background(), n, costs, nil)
+		require.NoError(t, err)
+		var cost int64
+		shipped, total := 0, 0
+		for _, c := range costs {
+			cost += int64(packs[c.size]) * c.total()
+			shipped += packs[c.size] * c.size
+			total += packs[c.size]
+		}
+		require.Equal(t, bestCost, cost, "costs %v, order %d", costs, n)
+		require.Equal(t, bestShipped, shipped, "costs %v, order %d", costs, n)
+		require.Equal(t, bestPacks, total, "costs %v, order %d", costs, n)
+	}
+}
+
+func TestNewCostBreakdown(t *testing.T) {
+	costs := catalogCosts([]Package{
+		{Size: 250, Price: 1.1, HandlingCost: 0.05},
+		{Size: 5000, Price: 12.5},
+		{Size: 5000, Price: 14},
+	})
+	lines := []PackLine{{Size: 5000, Count: 2, Items: 10000}, {Size: 250, Count: 3, Items: 750}}
+
+	require.Equal(t, &CostBreakdown{
+		Lines: []CostLine{
+			{Size: 5000, Count: 2, UnitPrice: 12.5, Total: 25},
+			{Size: 250, Count: 3, UnitPrice: 1.1, UnitHandling: 0.05, Total: 3.45},
+		},
+		Packs:    28.3,
+		Handling: 0.15,
+		Total:    28.45,
+	}, newCostBreakdown(lines, costs))
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidTolerance is returned for an undershoot tolerance outside [0, 100)
+var ErrInvalidTolerance = errors.New("invalid undershoot tolerance")
+
+// checkTolerance validates an undershoot tolerance in percent
+func checkTolerance(tolerance float64) error {
+	if math.IsNaN(tolerance) || tolerance < 0 || tolerance >= 100 {
+		return fmt.Errorf("%w: %v, expected a percentage of at least 0 and below 100", ErrInvalidTolerance, tolerance)
+	}
+	return nil
+}
+
+// leastAcceptable returns the fewest items an order may ship within the undershoot tolerance
+func leastAcceptable(orderQuantity int, tolerance float64) int {
+	// The margin keeps tolerances like 2% of 150 from rounding down to 2 items
+	shortfall := int(math.Floor(float64(orderQuantity)*tolerance/100 + 1e-9))
+	return max(orderQuantity-shortfall, 1)
+}
+
+// fulfill calculates the packs for an order like calculate, shipping less than ordered where
+// opts allow it.
+//
+// Within the undershoot tolerance the packs only have to cover the least acceptable quantity,
+// and an undershoot is chosen when the policy rates it better than the full shipment, with the
+// items counted off the ordered quantity either way: items first, the shortfall has to be
+// smaller than the overshoot. On a tie the full shipment wins. With backorders an order the
+// stock cannot cover ships as many items as it can, and the shortfall is backordered.
+func (s *catalogSnapshot) fulfill(ctx context.Context, orderQuantity int, solver Solver, limits map[int]int, policy Policy, opts CalculateOptions) (*CalculationResult, error) {
+	if opts.Tolerance == 0 && !opts.Backorder {
+		return s.calculate(ctx, orderQuantity, solver, limits, policy, opts)
+	}
+	if err := checkTolerance(opts.Tolerance); err != nil {
+		return nil, err
+	}
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: alternatives are not listed with an undershoot tolerance or backorders", ErrInvalidAlternatives)
+	}
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	calculate := func(quantity int) (*CalculationResult, error) {
+		return s.calculate(ctx, quantity, solver, limits, policy, opts)
+	}
+
+	best, fullErr := calculate(orderQuantity)
+	if fullErr != nil && !uncovered(fullErr) {
+		return nil, fullErr
+	}
+	if least := leastAcceptable(orderQuantity, opts.Tolerance); least < orderQuantity {
+		var under *CalculationResult
+		var err error
+		if policy.mode() == ObjectiveItems {
+			// Only quantities closer to the order than the overshoot can do better
+			from := least
+			if best != nil {
+				from = max(from, orderQuantity-best.Overshoot+1)
+			}
+			under, err = closestBelow(calculate, from, orderQuantity)
+		} else if under, err = calculate(least); uncovered(err) {
+			under, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if under != nil && under.Shipped < orderQuantity && (best == nil || policy.prefers(orderQuantity, under, best)) {
+			best = under
+		}
+	}
+	if best == nil && opts.Backorder {
+		var err error
+		if best, err = closestBelow(calculate, 1, orderQuantity); err != nil {
+			return nil, err
+		}
+	}
+	if best == nil {
+		return nil, fullErr
+	}
+
+	best.Requested = orderQuantity
+	best.Overshoot = max(best.Shipped-orderQuantity, 0)
+	best.Shortfall = max(orderQuantity-best.Shipped, 0)
+	if opts.Backorder {
+		best.Backordered = best.Shortfall
+	}
+	return best, nil
+}
+
+// uncovered reports whether a calculation failed because the stock or the pack constraints
+// cannot cover the quantity, so they cannot cover any larger one either
+func uncovered(err error) bool {
+	return errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrConstraintInfeasible)
+}
+
+// closestBelow returns the result of the largest quantity from from up to below the order that
+// the packs ship exactly, or nil when there is none.
+//
+// Packs covering a quantity cover every smaller one, and items first the items shipped only grow
+// with the quantity, so it is a binary search jumping to the shipped quantity on every hit.
+func closestBelow(calculate func(int) (*CalculationResult, error), from, orderQuantity int) (*CalculationResult, error) {
+	var best *CalculationResult
+	low, high := from, orderQuantity-1
+	for low <= high {
+		mid := low + (high-low)/2
+		result, err := calculate(mid)
+		switch {
+		case result != nil && result.Shipped < orderQuantity:
+			best = result
+			low = result.Shipped + 1
+		case err != nil && !uncovered(err):
+			return nil, err
+		default:
+			high = mid - 1
+		}
+	}
+	return best, nil
+}
+
+// prefers reports whether the policy rates a better than b for the order, counting the
+// items either result ships off the ordered quantity
+func (p Policy) prefers(orderQuantity int, a, b *CalculationResult) bool {
+	for _, objective := range p.Objectives {
+		var left, right int64
+		switch objective {
+		case ObjectiveItems:
+			left, right = int64(deviation(orderQuantity, a.Shipped)), int64(deviation(orderQuantity, b.Shipped))
+		case ObjectivePacks:
+			left, right = int64(a.TotalPacks), int64(b.TotalPacks)
+		case ObjectiveCost:
+			left, right = cents(a.Cost.Total), cents(b.Cost.Total)
+		}
+		if left != right {
+			return left < right
+		}
+	}
+	return false
+}
+
+// deviation returns how many items a shipment is off the ordered quantity
+func deviation(orderQuantity, shipped int) int {
+	if shipped < orderQuantity {
+		return orderQuantity - shipped
+	}
+	return shipped - orderQuantity
+}
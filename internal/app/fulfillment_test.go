@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Calculate_Undershoot(t *testing.T) {
+	catalog := []repo.Package{
+		{ID: 1, Size: 250},
+		{ID: 2, Size: 500},
+		{ID: 3, Size: 1000},
+		{ID: 4, Size: 2000},
+		{ID: 5, Size: 5000},
+	}
+	// 5500 items in stock
+	lowStock := []repo.StockLevel{{Size: 5000, Quantity: 1}, {Size: 2000, Quantity: 0}, {Size: 1000, Quantity: 0}, {Size: 500, Quantity: 0}, {Size: 250, Quantity: 2}}
+
+	tests := []struct {
+		name            string
+		order           int
+		stock           []repo.StockLevel
+		opts            CalculateOptions
+		wantPacks       map[int]int
+		wantShipped     int
+		wantShortfall   int
+		wantBackordered int
+		wantErr         error
+	}{
+		{
+			name:          "undershoot closer than the overshoot",
+			order:         12001,
+			opts:          CalculateOptions{Tolerance: 2},
+			wantPacks:     map[int]int{5000: 2, 2000: 1},
+			wantShipped:   12000,
+			wantShortfall: 1,
+		},
+		{
+			name:        "tolerance below a single item",
+			order:       12001,
+			opts:        CalculateOptions{Tolerance: 0.001},
+			wantPacks:   map[int]int{5000: 2, 2000: 1, 250: 1},
+			wantShipped: 12250,
+		},
+		{
+			name:          "small order",
+			order:         251,
+			opts:          CalculateOptions{Tolerance: 1},
+			wantPacks:     map[int]int{250: 1},
+			wantShipped:   250,
+			wantShortfall: 1,
+		},
+		{
+			name:        "exact order",
+			order:       1500,
+			opts:        CalculateOptions{Tolerance: 10},
+			wantPacks:   map[int]int{1000: 1, 500: 1},
+			wantShipped: 1500,
+		},
+		{
+			name:            "undershoot backordered",
+			order:           12001,
+			opts:            CalculateOptions{Tolerance: 2, Backorder: true},
+			wantPacks:       map[int]int{5000: 2, 2000: 1},
+			wantShipped:     12000,
+			wantShortfall:   1,
+			wantBackordered: 1,
+		},
+		{
+			name:          "packs first",
+			order:         12001,
+			opts:          CalculateOptions{Tolerance: 2, Policy: "packs-first"},
+			wantPacks:     map[int]int{5000: 2, 2000: 1},
+			wantShipped:   12000,
+			wantShortfall: 1,
+		},
+		{
+			name:          "stock covers the order within the tolerance",
+			order:         5600,
+			stock:         lowStock,
+			opts:          CalculateOptions{Tolerance: 2},
+			wantPacks:     map[int]int{5000: 1, 250: 2},
+			wantShipped:   5500,
+			wantShortfall: 100,
+		},
+		{
+			name:            "partial shipment backordered",
+			order:           6000,
+			stock:           lowStock,
+			opts:            CalculateOptions{Backorder: true},
+			wantPacks:       map[int]int{5000: 1, 250: 2},
+			wantShipped:     5500,
+			wantShortfall:   500,
+			wantBackordered: 500,
+		},
+		{
+			name:    "stock short of the tolerance",
+			order:   6000,
+			stock:   lowStock,
+			opts:    CalculateOptions{Tolerance: 2},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name:    "tolerance of the whole order",
+			order:   251,
+			opts:    CalculateOptions{Tolerance: 100},
+			wantErr: ErrInvalidTolerance,
+		},
+		{
+			name:    "negative tolerance",
+			order:   251,
+			opts:    CalculateOptions{Tolerance: -1},
+			wantErr: ErrInvalidTolerance,
+		},
+		{
+			name:    "alternatives",
+			order:   251,
+			opts:    CalculateOptions{Tolerance: 1, Alternatives: 3},
+			wantErr: ErrInvalidAlternatives,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			mockRepo.On("GetStock", DefaultProductID).Return(tt.stock, nil)
+
+			result, err := NewApp(mockRepo).Calculate(context.Background(), tt.order, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			require.Equal(t, tt.order, result.Requested)
+			require.Equal(t, tt.wantShipped, result.Shipped)
+			require.Equal(t, max(tt.wantShipped-tt.order, 0), result.Overshoot)
+			require.Equal(t, tt.wantShortfall, result.Shortfall)
+			require.Equal(t, tt.wantBackordered, result.Backordered)
+		})
+	}
+}
+
+func TestFulfill_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+	for i := 0; i < 200; i++ {
+		var catalog []Package
+		for j := 0; j < 3; j++ {
+			catalog = append(catalog, Package{Size: 2 + rng.Intn(30)})
+		}
+		s, err := newCatalogSnapshot(0, catalog)
+		require.NoError(t, err)
+		n := 1 + rng.Intn(200)
+		tolerance := float64(rng.Intn(20))
+
+		// Quantities some combination of packs adds up to
+		reachable := make([]bool, n+s.sizes[0])
+		reachable[0] = true
+		for q := range reachable {
+			for _, size := range s.sizes {
+				if q >= size && reachable[q-size] {
+					reachable[q] = true
+				}
+			}
+		}
+		full := n
+		for !reachable[full] {
+			full++
+		}
+		want := full
+		for q := full - 1; q >= leastAcceptable(n, tolerance); q-- {
+			if reachable[q] && n-q < full-n {
+				want = q
+				break
+			}
+		}
+
+		result, err := s.fulfill(context.Background(), n, dpSolver{}, nil, itemsFirst, CalculateOptions{Tolerance: tolerance})
+		require.NoError(t, err)
+		require.Equal(t, want, result.Shipped, "sizes %v, order %d, tolerance %v%%", s.sizes, n, tolerance)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import "context"
+
+// greedySolver is a fast heuristic strategy. It takes as many packs of each size as fit,
+// largest first, tops up the rest with one smallest pack and then merges pairs of packs
+// whose combined size is itself a pack size. Results always cover the order but may ship
+// more items or packs than the exact strategies. It is the best fit when speed matters
+// more than optimality, e.g. for catalogs where every size is a multiple of the next one.
+type greedySolver struct{}
+
+func (greedySolver) Name() string { return "greedy" }
+
+func (greedySolver) Solve(ctx context.Context, orderQuantity int, packSizes []int) (*CalculationResult, error) {
+	// The work is linear in the number of sizes, so the context is only checked once
+	if err := interrupted(ctx); err != nil {
+		return nil, err
+	}
+	sizes := distinctDescending(packSizes)
+	available := make(map[int]bool, len(sizes))
+	for _, size := range sizes {
+		available[size] = true
+	}
+
+	packs := make(map[int]int)
+	remaining := orderQuantity
+	for _, size := range sizes {
+		if count := remaining / size; count > 0 {
+			packs[size] += count
+			remaining -= count * size
+		}
+	}
+	if remaining > 0 {
+		// Whatever is left is smaller than the smallest pack
+		packs[sizes[len(sizes)-1]]++
+	}
+
+	// Replace pairs of packs with a single pack of their combined size while possible
+	for merged := true; merged; {
+		merged = false
+		for i, a := range sizes {
+			for _, b := range sizes[i:] {
+				if !available[a+b] || packs[a] == 0 || packs[b] == 0 || (a == b && packs[a] < 2) {
+					continue
+				}
+				packs[a]--
+				packs[b]--
+				packs[a+b]++
+				merged = true
+			}
+		}
+	}
+
+	return newCalculationResult(orderQuantity, packs), nil
+}
@@ -0,0 +1,356 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+// ErrInvalidFilter is returned for a calculation history query with an unknown sort order,
+// an empty range, a limit out of range or a cursor of another query
+var ErrInvalidFilter = errors.New("invalid calculation filter")
+
+// SolverVersion is recorded with every calculation in the history. Increase it whenever a change
+// to the packing strategies or policies can give an order other packs than before.
+const SolverVersion = 1
+
+const (
+	// DefaultHistoryRetention is how long calculations are kept unless configured otherwise
+	DefaultHistoryRetention = 30 * 24 * time.Hour
+	// DefaultHistoryLimit and MaxHistoryLimit are the default and the largest page size of
+	// the calculation history
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+	// historyPruneInterval is how often calculations older than the retention are deleted
+	historyPruneInterval = time.Hour
+)
+
+// Sort orders of the calculation history
+const (
+	SortCreated  = repo.SortCreated
+	SortQuantity = repo.SortQuantity
+)
+
+// CalculationRequest is what a /calculate call asked for: an order size, or the lines of a
+// multi-line order
+type CalculationRequest struct {
+	Quantity int              `json:"quantity,omitempty"`
+	Lines    []OrderLine      `json:"lines,omitempty"`
+	Options  CalculateOptions `json:"options"`
+}
+
+// Calculation is a recorded /calculate call
+type Calculation struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Caller identifies who made the call
+	Caller string `json:"caller"`
+	// Quantity is the ordered quantity, over all lines of a multi-line order
+	Quantity int                `json:"quantity"`
+	Request  CalculationRequest `json:"request"`
+	// Catalog are the packages of the products ordered, in the default warehouse, as of the call
+	Catalog []Package `json:"catalog"`
+	// Result is the CalculationResult of an order size or the OrderResult of a multi-line order,
+	// unset when the call failed
+	Result json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	// Error is why the call failed
+	Error string `json:"error,omitempty"`
+	// Solver is the packing strategy of the result, or the one asked for when the call failed
+	Solver        string `json:"solver"`
+	SolverVersion int    `json:"solverVersion"`
+}
+
+// CalculationQuery selects a page of the calculation history, zero fields select all
+type CalculationQuery struct {
+	// From and To bound the time of the call, From inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// MinQuantity and MaxQuantity bound the ordered quantity, both inclusive
+	MinQuantity int
+	MaxQuantity int
+	// PackSize selects the calculations shipping packs of this size
+	PackSize int
+	// Sort is SortCreated, the default, or SortQuantity
+	Sort string
+	// Ascending sorts the oldest or smallest calculations first instead of the newest or largest
+	Ascending bool
+	// Cursor continues a query after the page that returned it
+	Cursor string
+	// Limit is the page size, DefaultHistoryLimit when zero
+	Limit int
+}
+
+// CalculationPage is a page of the calculation history
+type CalculationPage struct {
+	Calculations []Calculation `json:"calculations"`
+	// NextCursor continues the query with the next page, empty on the last one
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// historyCursor is the position of the last calculation of a page, along with the order it is in
+type historyCursor struct {
+	Sort      string    `json:"s"`
+	Ascending bool      `json:"a,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	Quantity  int       `json:"q"`
+	ID        int64     `json:"i"`
+}
+
+// WithHistoryRetention sets how long calculations are kept in the history, forever when zero
+func WithHistoryRetention(d time.Duration) Option {
+	return func(a *App) {
+		a.retention = d
+	}
+}
+
+// RecordCalculation stores a /calculate call in the calculation history, with the result of the
+// call, a *CalculationResult or an *OrderResult, or the error it failed with. The catalogs of the
+// products ordered are taken from the cached snapshots calculations use, so recording a call
+// rarely reads the database more than storing it; a split order adds the catalogs of the other
+// warehouses it ships from.
+func (a *App) RecordCalculation(caller string, req CalculationRequest, result interface{}, calcErr error) error {
+	record := repo.Calculation{
+		Caller:        caller,
+		Quantity:      req.Quantity,
+		PackSizes:     make([]int64, 0),
+		Solver:        req.Options.Solver,
+		SolverVersion: SolverVersion,
+	}
+	if record.Solver == "" {
+		record.Solver = a.solver.Name()
+	}
+
+	productIDs := []int{productOrDefault(req.Options.ProductID)}
+	if len(req.Lines) > 0 {
+		productIDs = productIDs[:0]
+		seen := make(map[int]bool)
+		for _, line := range req.Lines {
+			record.Quantity += line.Quantity
+			if productID := productOrDefault(line.ProductID); !seen[productID] {
+				seen[productID] = true
+				productIDs = append(productIDs, productID)
+			}
+		}
+	}
+	// warehouseIDs are the warehouses other than the default one the result ships from
+	var warehouseIDs []int
+	if calcErr != nil {
+		record.Error = calcErr.Error()
+	} else {
+		var results []*CalculationResult
+		switch r := result.(type) {
+		case *CalculationResult:
+			results = append(results, r)
+		case *OrderResult:
+			for _, line := range r.Lines {
+				results = append(results, line.Result)
+			}
+		}
+		sizes, shipped := make(map[int]bool), make(map[int]bool)
+		for _, r := range results {
+			if r == nil {
+				continue
+			}
+			for _, line := range r.Lines {
+				if line.Count > 0 && !sizes[line.Size] {
+					sizes[line.Size] = true
+					record.PackSizes = append(record.PackSizes, int64(line.Size))
+				}
+			}
+			record.Solver = r.Solver
+			for _, shipment := range r.Shipments {
+				if shipment.WarehouseID != DefaultWarehouseID && !shipped[shipment.WarehouseID] {
+					shipped[shipment.WarehouseID] = true
+					warehouseIDs = append(warehouseIDs, shipment.WarehouseID)
+				}
+			}
+		}
+		sort.Slice(record.PackSizes, func(i, j int) bool { return record.PackSizes[i] < record.PackSizes[j] })
+
+		var err error
+		if record.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+
+	catalog := make([]Package, 0)
+	for _, productID := range productIDs {
+		if snapshot := a.cache.cached(productID); snapshot != nil {
+			catalog = append(catalog, snapshot.packages...)
+			continue
+		}
+		// The catalog changed since the call, or the call failed before loading it
+		packages, err := a.repo.GetCatalog(productID)
+		if err != nil {
+			return err
+		}
+		catalog = append(catalog, packages...)
+	}
+	// A split order also shipped from the catalogs of the other warehouses
+	for _, warehouseID := range warehouseIDs {
+		for _, productID := range productIDs {
+			packages, err := a.repo.GetWarehouseCatalog(warehouseID, productID)
+			if err != nil {
+				return err
+			}
+			catalog = append(catalog, packages...)
+		}
+	}
+
+	var err error
+	if record.Request, err = json.Marshal(req); err != nil {
+		return err
+	}
+	if record.Catalog, err = json.Marshal(catalog); err != nil {
+		return err
+	}
+	return a.repo.AddCalculation(record)
+}
+
+// GetCalculations returns a page of the calculation history selected and sorted by the query,
+// the newest calculations first unless asked otherwise. The next page continues after the last
+// calculation of this one, so calls recorded meanwhile do not shift the pages.
+func (a *App) GetCalculations(q CalculationQuery) (*CalculationPage, error) {
+	filter := repo.CalculationFilter{
+		From:        q.From,
+		To:          q.To,
+		MinQuantity: q.MinQuantity,
+		MaxQuantity: q.MaxQuantity,
+		PackSize:    q.PackSize,
+		Sort:        q.Sort,
+		Descending:  !q.Ascending,
+		Limit:       q.Limit,
+	}
+	if filter.Sort == "" {
+		filter.Sort = SortCreated
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultHistoryLimit
+	}
+	if err := checkFilter(filter); err != nil {
+		return nil, err
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != filter.Sort || cursor.Ascending != q.Ascending {
+			return nil, fmt.Errorf("%w: the cursor belongs to a query of another sort order", ErrInvalidFilter)
+		}
+		filter.After = &repo.CalculationCursor{CreatedAt: cursor.CreatedAt, Quantity: cursor.Quantity, ID: cursor.ID}
+	}
+
+	// One more than the page tells whether there is a next one
+	limit := filter.Limit
+	filter.Limit++
+	stored, err := a.repo.GetCalculations(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CalculationPage{Calculations: make([]Calculation, 0, min(len(stored), limit))}
+	for i, s := range stored {
+		if i == limit {
+			last := stored[i-1]
+			page.NextCursor = encodeCursor(historyCursor{
+				Sort: filter.Sort, Ascending: q.Ascending, CreatedAt: last.CreatedAt, Quantity: last.Quantity, ID: last.ID,
+			})
+			break
+		}
+		calculation, err := calculationOf(s)
+		if err != nil {
+			return nil, err
+		}
+		page.Calculations = append(page.Calculations, calculation)
+	}
+	return page, nil
+}
+
+// RunHistoryPruning deletes the calculations older than the retention every hour until ctx is
+// done, right away first. Nothing is deleted when the retention is zero.
+func (a *App) RunHistoryPruning(ctx context.Context) {
+	if a.retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(historyPruneInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := a.repo.DeleteCalculations(time.Now().Add(-a.retention))
+		if err != nil {
+			log.Printf("Error pruning calculation history: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Pruned %d calculations older than %s", deleted, a.retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkFilter validates the sort order, the ranges and the page size of a history filter
+func checkFilter(f repo.CalculationFilter) error {
+	if f.Sort != SortCreated && f.Sort != SortQuantity {
+		return fmt.Errorf("%w: unknown sort %q, expected %s or %s", ErrInvalidFilter, f.Sort, SortCreated, SortQuantity)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+	if f.MinQuantity < 0 || f.MaxQuantity < 0 || f.PackSize < 0 {
+		return fmt.Errorf("%w: quantities and pack sizes must not be negative", ErrInvalidFilter)
+	}
+	if f.MaxQuantity > 0 && f.MinQuantity > f.MaxQuantity {
+		return fmt.Errorf("%w: the least quantity %d is above the most %d", ErrInvalidFilter, f.MinQuantity, f.MaxQuantity)
+	}
+	if f.Limit < 1 || f.Limit > MaxHistoryLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxHistoryLimit)
+	}
+	return nil
+}
+
+func encodeCursor(c historyCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (historyCursor, error) {
+	var c historyCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// calculationOf decodes the request, the catalog and the result of a stored calculation
+func calculationOf(stored repo.Calculation) (Calculation, error) {
+	c := Calculation{
+		ID:            stored.ID,
+		CreatedAt:     stored.CreatedAt,
+		Caller:        stored.Caller,
+		Quantity:      stored.Quantity,
+		Result:        stored.Result,
+		Error:         stored.Error,
+		Solver:        stored.Solver,
+		SolverVersion: stored.SolverVersion,
+	}
+	if err := json.Unmarshal(stored.Request, &c.Request); err != nil {
+		return c, fmt.Errorf("failed to decode request of calculation %d: %w", stored.ID, err)
+	}
+	if err := json.Unmarshal(stored.Catalog, &c.Catalog); err != nil {
+		return c, fmt.Errorf("failed to decode catalog of calculation %d: %w", stored.ID, err)
+	}
+	return c, nil
+}
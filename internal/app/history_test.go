@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_RecordCalculation(t *testing.T) {
+	catalog := []repo.Package{{ID: 1, ProductID: DefaultProductID, Size: 250}, {ID: 2, ProductID: DefaultProductID, Size: 500}}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil).Once()
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+	app := NewApp(mockRepo)
+	req := CalculationRequest{Quantity: 751, Options: CalculateOptions{Policy: "packs-first"}}
+	result, err := app.Calculate(context.Background(), req.Quantity, req.Options)
+	require.NoError(t, err)
+
+	var recorded repo.Calculation
+	mockRepo.On("AddCalculation", mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(0).(repo.Calculation)
+	}).Return(nil)
+
+	// The catalog comes from the snapshot the calculation cached
+	require.NoError(t, app.RecordCalculation("support", req, result, nil))
+	require.Equal(t, "support", recorded.Caller)
+	require.Equal(t, 751, recorded.Quantity)
+	require.Equal(t, []int64{500}, recorded.PackSizes)
+	require.Equal(t, result.Solver, recorded.Solver)
+	require.Equal(t, SolverVersion, recorded.SolverVersion)
+	require.Empty(t, recorded.Error)
+	require.JSONEq(t, `{"quantity":751,"options":{"policy":"packs-first"}}`, string(recorded.Request))
+
+	var packages []Package
+	require.NoError(t, json.Unmarshal(recorded.Catalog, &packages))
+	require.Equal(t, catalog, packages)
+	var stored CalculationResult
+	require.NoError(t, json.Unmarshal(recorded.Result, &stored))
+	require.Equal(t, result.Packs(), stored.Packs())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_RecordCalculation_Split(t *testing.T) {
+	mockRepo := splitRepository(false)
+	app := NewApp(mockRepo)
+	req := CalculationRequest{Quantity: 1100, Options: CalculateOptions{Mode: ModeSplit, Destination: &Location{Latitude: 53.5, Longitude: 10}}}
+	result, err := app.Calculate(context.Background(), req.Quantity, req.Options)
+	require.NoError(t, err)
+	require.Len(t, result.Shipments, 2)
+
+	var recorded repo.Calculation
+	mockRepo.On("AddCalculation", mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(0).(repo.Calculation)
+	}).Return(nil)
+
+	// The catalog of every warehouse the order ships from is recorded
+	require.NoError(t, app.RecordCalculation("support", req, result, nil))
+	require.Equal(t, []int64{100, 1000}, recorded.PackSizes)
+	var packages []Package
+	require.NoError(t, json.Unmarshal(recorded.Catalog, &packages))
+	require.Equal(t, []Package{
+		{ID: 1, Size: 100, Price: 3},
+		{ID: 2, Size: 500, Price: 12},
+		{ID: 3, WarehouseID: 2, Size: 1000, Price: 20},
+	}, packages)
+}
+
+func TestApp_RecordCalculation_Failed(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", 2).Return([]repo.Package{{ID: 3, ProductID: 2, Size: 6}}, nil)
+	mockRepo.On("GetCatalog", 3).Return([]repo.Package{}, nil)
+	mockRepo.On("AddCalculation", mock.MatchedBy(func(c repo.Calculation) bool {
+		return c.Quantity == 14 && c.Result == nil && c.Error == "insufficient stock" &&
+			c.Solver == "bnb" && len(c.PackSizes) == 0 && string(c.Catalog) != "[]"
+	})).Return(nil)
+
+	req := CalculationRequest{
+		Lines:   []OrderLine{{ProductID: 2, Quantity: 12}, {ProductID: 3, Quantity: 1}, {ProductID: 2, Quantity: 1}},
+		Options: CalculateOptions{Solver: "bnb"},
+	}
+	require.NoError(t, NewApp(mockRepo).RecordCalculation("10.0.0.7", req, (*OrderResult)(nil), ErrInsufficientStock))
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "GetCatalog", 2)
+}
+
+func TestApp_GetCalculations(t *testing.T) {
+	created := time.Date(2024, 5, 7, 9, 0, 0, 0, time.UTC)
+	stored := func(id int64, quantity int) repo.Calculation {
+		return repo.Calculation{ID: id, CreatedAt: created, Quantity: quantity, PackSizes: []int64{500},
+			Request: []byte(`{"quantity":1}`), Catalog: []byte(`[]`), Result: []byte(`{}`), Solver: "dp", SolverVersion: 1}
+	}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCalculations", repo.CalculationFilter{MinQuantity: 250, Sort: SortQuantity, Descending: true, Limit: 3}).
+		Return([]repo.Calculation{stored(9, 1000), stored(4, 751), stored(7, 500)}, nil)
+	mockRepo.On("GetCalculations", repo.CalculationFilter{MinQuantity: 250, Sort: SortQuantity, Descending: true, Limit: 3,
+		After: &repo.CalculationCursor{CreatedAt: created, Quantity: 751, ID: 4}}).
+		Return([]repo.Calculation{stored(7, 500)}, nil)
+
+	app := NewApp(mockRepo)
+	query := CalculationQuery{MinQuantity: 250, Sort: SortQuantity, Limit: 2}
+	page, err := app.GetCalculations(query)
+	require.NoError(t, err)
+	require.Len(t, page.Calculations, 2)
+	require.Equal(t, int64(9), page.Calculations[0].ID)
+	require.Equal(t, 1, page.Calculations[0].Request.Quantity)
+	require.NotEmpty(t, page.NextCursor)
+
+	query.Cursor = page.NextCursor
+	page, err = app.GetCalculations(query)
+	require.NoError(t, err)
+	require.Len(t, page.Calculations, 1)
+	require.Empty(t, page.NextCursor)
+
+	// A cursor only continues a query of the same order
+	query.Ascending = true
+	_, err = app.GetCalculations(query)
+	require.ErrorIs(t, err, ErrInvalidFilter)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_GetCalculations_InvalidFilter(t *testing.T) {
+	from := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query CalculationQuery
+	}{
+		{name: "unknown sort", query: CalculationQuery{Sort: "caller"}},
+		{name: "empty date range", query: CalculationQuery{From: from, To: from}},
+		{name: "empty quantity range", query: CalculationQuery{MinQuantity: 1000, MaxQuantity: 250}},
+		{name: "negative pack size", query: CalculationQuery{PackSize: -250}},
+		{name: "limit too large", query: CalculationQuery{Limit: MaxHistoryLimit + 1}},
+		{name: "malformed cursor", query: CalculationQuery{Cursor: "not a cursor"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+
+			_, err := NewApp(mockRepo).GetCalculations(tt.query)
+			require.ErrorIs(t, err, ErrInvalidFilter)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_RunHistoryPruning(t *testing.T) {
+	mockRepo := new(MockRepository)
+	pruned := make(chan time.Time, 1)
+	mockRepo.On("DeleteCalculations", mock.Anything).Run(func(args mock.Arguments) {
+		pruned <- args.Get(0).(time.Time)
+	}).Return(3, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		NewApp(mockRepo, WithHistoryRetention(24*time.Hour)).RunHistoryPruning(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case before := <-pruned:
+		require.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Minute)
+	case <-time.After(5 * time.Second):
+		t.Fatal("history not pruned")
+	}
+	cancel()
+	<-stopped
+
+	// Without a retention the history is kept
+	NewApp(mockRepo, WithHistoryRetention(0)).RunHistoryPruning(context.Background())
+	mockRepo.AssertNumberOfCalls(t, "DeleteCalculations", 1)
+}
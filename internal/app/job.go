@@ -0,0 +1,358 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrJobNotFound is returned when a job does not exist
+	ErrJobNotFound = repo.ErrJobNotFound
+	// ErrJobFinished is returned when a job that already finished is cancelled
+	ErrJobFinished = repo.ErrJobFinished
+	// ErrJobLost is returned when a worker saves a job that was cancelled or taken over meanwhile
+	ErrJobLost = repo.ErrJobLost
+	// errJobCancelled is the cause a running job is stopped with when it is cancelled
+	errJobCancelled = errors.New("job cancelled")
+)
+
+// Job statuses; queued and running jobs are unfinished, the others are final
+const (
+	JobQueued    = repo.JobQueued
+	JobRunning   = repo.JobRunning
+	JobSucceeded = repo.JobSucceeded
+	JobFailed    = repo.JobFailed
+	JobCancelled = repo.JobCancelled
+)
+
+const (
+	// jobPollInterval is how often an idle worker looks for jobs it was not told about,
+	// e.g. queued through another server
+	jobPollInterval = 5 * time.Second
+	// jobProgressInterval is how often a running job saves its progress
+	jobProgressInterval = time.Second
+	// jobHeartbeatInterval is how often a worker renews the lease on the job it runs
+	jobHeartbeatInterval = 10 * time.Second
+	// jobLease is how long a running job is left to its worker without a heartbeat before it is
+	// queued again for another worker
+	jobLease = time.Minute
+)
+
+// JobRequest is the batch a job calculates, see CalculateBatch
+type JobRequest struct {
+	Orders  []int            `json:"orders"`
+	Options CalculateOptions `json:"options"`
+}
+
+// Job is a batch calculation run in the background
+type Job struct {
+	ID int `json:"id"`
+	// Status is one of queued, running, succeeded, failed and cancelled
+	Status  string     `json:"status"`
+	Request JobRequest `json:"request"`
+	// Done is the number of orders calculated so far, out of Total
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	// Result holds an item per order in the order of the request once the job succeeded
+	Result []BatchItem `json:"result,omitempty"`
+	// Error is why the job failed
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// jobRunner tracks the jobs running in this process
+type jobRunner struct {
+	// owner identifies the workers of this process in the leases of the jobs they run
+	owner string
+	// heartbeat is how often the lease on a running job is renewed, jobHeartbeatInterval
+	heartbeat time.Duration
+	// wake tells an idle worker that a job was queued
+	wake    chan struct{}
+	mu      sync.Mutex
+	running map[int]*runningJob
+}
+
+// runningJob is a job a worker of this process is calculating
+type runningJob struct {
+	cancel context.CancelCauseFunc
+	// done is the live progress, saved only every jobProgressInterval
+	done atomic.Int64
+}
+
+func newJobRunner() *jobRunner {
+	return &jobRunner{
+		owner:     jobOwner(),
+		heartbeat: jobHeartbeatInterval,
+		wake:      make(chan struct{}, 1),
+		running:   make(map[int]*runningJob),
+	}
+}
+
+// jobOwner returns a name for the workers of this process, unique across servers and restarts
+func jobOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// notify wakes an idle worker, if there is none the next one looking finds the job anyway
+func (r *jobRunner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *jobRunner) get(id int) *runningJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running[id]
+}
+
+func (r *jobRunner) set(id int, job *runningJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job == nil {
+		delete(r.running, id)
+	} else {
+		r.running[id] = job
+	}
+}
+
+// SubmitJob queues a batch to be calculated in the background by the workers of RunJobs and
+// returns the queued job. The batch is validated like CalculateBatch before it is queued.
+func (a *App) SubmitJob(req JobRequest) (*Job, error) {
+	if _, _, err := a.prepareBatch(req.Orders, req.Options); err != nil {
+		return nil, err
+	}
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := a.repo.AddJob(request, len(req.Orders))
+	if err != nil {
+		return nil, err
+	}
+	a.jobs.notify()
+	return jobOf(stored)
+}
+
+// GetJob returns a job with its status, its progress and, once it succeeded, its result
+func (a *App) GetJob(id int) (*Job, error) {
+	stored, err := a.repo.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	job, err := jobOf(stored)
+	if err != nil {
+		return nil, err
+	}
+	if running := a.jobs.get(id); running != nil && job.Status == JobRunning {
+		job.Done = max(job.Done, int(running.done.Load()))
+	}
+	return job, nil
+}
+
+// CancelJob cancels a queued or running job, returning ErrJobFinished for a job that already
+// finished. A job running in this process stops right away, one running elsewhere once it
+// saves its progress or renews its lease next.
+func (a *App) CancelJob(id int) (*Job, error) {
+	stored, err := a.repo.CancelJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if running := a.jobs.get(id); running != nil {
+		running.cancel(errJobCancelled)
+	}
+	return jobOf(stored)
+}
+
+// RunJobs calculates the queued jobs, workers of them at a time, until ctx is done. Running jobs
+// are leased by their worker; jobs whose lease expired, e.g. because the server running them
+// stopped, are queued again right away and then every jobLease, so jobs survive a restart while
+// the jobs other servers are still running are left to them.
+func (a *App) RunJobs(ctx context.Context, workers int) error {
+	if err := a.requeueJobs(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(jobLease)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.requeueJobs(); err != nil {
+					log.Printf("Error requeueing jobs: %v", err)
+				}
+			}
+		}
+	}()
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.jobWorker(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// requeueJobs queues the jobs whose lease expired again
+func (a *App) requeueJobs() error {
+	requeued, err := a.repo.RequeueJobs(jobLease)
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		log.Printf("Requeued %d interrupted jobs", requeued)
+	}
+	return nil
+}
+
+// jobWorker claims and runs queued jobs one after the other until ctx is done
+func (a *App) jobWorker(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		stored, err := a.repo.ClaimJob(a.jobs.owner)
+		if err == nil {
+			// More jobs may be queued, let an idle worker look
+			a.jobs.notify()
+			a.runJob(ctx, stored)
+			continue
+		}
+		if !errors.Is(err, ErrJobNotFound) {
+			log.Printf("Error claiming job: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-a.jobs.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob calculates the batch of a claimed job, saving its progress every jobProgressInterval
+// and its result or error at the end while renewing its lease, see keepJobLeased. A job that was
+// cancelled or taken over is left as it is, and so is a job interrupted because ctx is done, to
+// be queued again once its lease expires.
+func (a *App) runJob(ctx context.Context, stored repo.Job) {
+	var req JobRequest
+	if err := json.Unmarshal(stored.Request, &req); err != nil {
+		stored.Status, stored.Error = JobFailed, fmt.Sprintf("invalid job request: %v", err)
+		a.saveJob(stored)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	running := &runningJob{cancel: cancel}
+	a.jobs.set(stored.ID, running)
+	defer a.jobs.set(stored.ID, nil)
+	go a.keepJobLeased(jobCtx, stored, cancel)
+
+	items := make([]BatchItem, len(req.Orders))
+	stored.Done, stored.Total = 0, len(req.Orders)
+	saved := time.Now()
+	err := a.CalculateBatch(jobCtx, req.Orders, req.Options, func(item BatchItem) {
+		items[item.Index] = item
+		stored.Done++
+		running.done.Store(int64(stored.Done))
+		if time.Since(saved) < jobProgressInterval {
+			return
+		}
+		saved = time.Now()
+		if err := a.repo.UpdateJob(stored); errors.Is(err, ErrJobLost) {
+			// Cancelled through another server, or taken over after the lease expired
+			cancel(err)
+		} else if err != nil {
+			log.Printf("Error saving job progress (id: %d): %v", stored.ID, err)
+		}
+	})
+
+	switch {
+	case ctx.Err() != nil, errors.Is(context.Cause(jobCtx), errJobCancelled), errors.Is(context.Cause(jobCtx), ErrJobLost):
+		return
+	case err != nil:
+		stored.Status, stored.Error = JobFailed, err.Error()
+	default:
+		if stored.Result, err = json.Marshal(items); err != nil {
+			stored.Status, stored.Error = JobFailed, fmt.Sprintf("failed to encode result: %v", err)
+		} else {
+			stored.Status = JobSucceeded
+		}
+	}
+	a.saveJob(stored)
+}
+
+// keepJobLeased renews the lease on a running job until jobCtx is done, so other workers leave
+// it alone however long its orders take. A job cancelled or taken over meanwhile is stopped.
+func (a *App) keepJobLeased(jobCtx context.Context, stored repo.Job, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(a.jobs.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-jobCtx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := a.repo.HeartbeatJob(stored.ID, stored.Owner); errors.Is(err, ErrJobLost) {
+			cancel(err)
+			return
+		} else if err != nil {
+			log.Printf("Error renewing job lease (id: %d): %v", stored.ID, err)
+		}
+	}
+}
+
+// saveJob saves the outcome of a job, unless it was cancelled or taken over meanwhile
+func (a *App) saveJob(stored repo.Job) {
+	if err := a.repo.UpdateJob(stored); err != nil && !errors.Is(err, ErrJobLost) {
+		log.Printf("Error saving job (id: %d, status: %s): %v", stored.ID, stored.Status, err)
+	}
+}
+
+// jobOf decodes the request and the result of a stored job
+func jobOf(stored repo.Job) (*Job, error) {
+	job := &Job{
+		ID:        stored.ID,
+		Status:    stored.Status,
+		Done:      stored.Done,
+		Total:     stored.Total,
+		Error:     stored.Error,
+		CreatedAt: stored.CreatedAt,
+		UpdatedAt: stored.UpdatedAt,
+	}
+	if err := json.Unmarshal(stored.Request, &job.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode request of job %d: %w", stored.ID, err)
+	}
+	if stored.Result != nil {
+		if err := json.Unmarshal(stored.Result, &job.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode result of job %d: %w", stored.ID, err)
+		}
+	}
+	return job, nil
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_SubmitJob(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       JobRequest
+		setupMock func(*MockRepository)
+		wantErr   error
+	}{
+		{
+			name: "queued",
+			req:  JobRequest{Orders: []int{251, 12001}, Options: CalculateOptions{ProductID: 2, Tolerance: 2}},
+			setupMock: func(m *MockRepository) {
+				m.On("AddJob", []byte(`{"orders":[251,12001],"options":{"productId":2,"tolerance":2}}`), 2).
+					Return(repo.Job{ID: 3, Status: JobQueued, Request: []byte(`{"orders":[251,12001],"options":{"productId":2,"tolerance":2}}`), Total: 2}, nil)
+			},
+		},
+		{
+			name:      "no orders",
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidBatch,
+		},
+		{
+			name:      "unknown policy",
+			req:       JobRequest{Orders: []int{251}, Options: CalculateOptions{Policy: "fewest-boxes"}},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrUnknownPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			job, err := NewApp(mockRepo).SubmitJob(tt.req)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, &Job{ID: 3, Status: JobQueued, Request: tt.req, Total: 2}, job)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_RunJobs(t *testing.T) {
+	catalog := []repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}}
+	stored := repo.Job{ID: 3, Status: JobRunning, Request: []byte(`{"orders":[251,0],"options":{}}`), Total: 2}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("RequeueJobs", jobLease).Return(1, nil)
+	mockRepo.On("ClaimJob", mock.Anything).Return(stored, nil).Once()
+	mockRepo.On("ClaimJob", mock.Anything).Return(repo.Job{}, repo.ErrJobNotFound)
+	mockRepo.On("HeartbeatJob", 3, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+	saved := make(chan repo.Job, 1)
+	mockRepo.On("UpdateJob", mock.Anything).Run(func(args mock.Arguments) {
+		saved <- args.Get(0).(repo.Job)
+	}).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error)
+	go func() { stopped <- NewApp(mockRepo, WithBatchWorkers(1)).RunJobs(ctx, 2) }()
+
+	var finished repo.Job
+	select {
+	case finished = <-saved:
+	case <-time.After(10 * time.Second):
+		t.Fatal("job did not finish")
+	}
+	cancel()
+	require.NoError(t, <-stopped)
+
+	require.Equal(t, JobSucceeded, finished.Status)
+	require.Equal(t, 2, finished.Done)
+	job, err := jobOf(finished)
+	require.NoError(t, err)
+	require.Len(t, job.Result, 2)
+	require.Equal(t, map[int]int{500: 1}, job.Result[0].Result.Packs())
+	require.NotEmpty(t, job.Result[1].Error)
+}
+
+func TestApp_RunJobs_LeaseLost(t *testing.T) {
+	stored := repo.Job{ID: 3, Status: JobRunning, Owner: "server-a"}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("HeartbeatJob", 3, "server-a").Return(nil).Once()
+	mockRepo.On("HeartbeatJob", 3, "server-a").Return(repo.ErrJobLost).Once()
+
+	app := NewApp(mockRepo)
+	app.jobs.heartbeat = time.Millisecond
+	jobCtx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	// The job stops once another worker took it over
+	app.keepJobLeased(jobCtx, stored, cancel)
+	require.ErrorIs(t, context.Cause(jobCtx), ErrJobLost)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_RunJobs_LeaseOwner(t *testing.T) {
+	other := NewApp(new(MockRepository))
+	app := NewApp(new(MockRepository))
+
+	// Every process leases jobs under a name of its own
+	require.NotEmpty(t, app.jobs.owner)
+	require.NotEqual(t, other.jobs.owner, app.jobs.owner)
+}
+
+func TestApp_CancelJob(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("CancelJob", 3).Return(repo.Job{ID: 3, Status: JobCancelled, Request: []byte(`{"orders":[251],"options":{}}`), Total: 1}, nil)
+	mockRepo.On("CancelJob", 4).Return(repo.Job{}, repo.ErrJobFinished)
+
+	app := NewApp(mockRepo)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	app.jobs.set(3, &runningJob{cancel: cancel})
+
+	job, err := app.CancelJob(3)
+	require.NoError(t, err)
+	require.Equal(t, JobCancelled, job.Status)
+	require.ErrorIs(t, context.Cause(ctx), errJobCancelled)
+
+	_, err = app.CancelJob(4)
+	require.ErrorIs(t, err, ErrJobFinished)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_GetJob_LiveProgress(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetJob", 3).Return(repo.Job{ID: 3, Status: JobRunning, Request: []byte(`{"orders":[251,500,750],"options":{}}`), Done: 1, Total: 3}, nil)
+
+	app := NewApp(mockRepo)
+	running := &runningJob{}
+	running.done.Store(2)
+	app.jobs.set(3, running)
+
+	job, err := app.GetJob(3)
+	require.NoError(t, err)
+	require.Equal(t, 2, job.Done)
+	require.Equal(t, JobRequest{Orders: []int{251, 500, 750}}, job.Request)
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrInvalidNesting is returned for a pack holding packs that are not in its catalog, fewer
+	// than two of them, or a size other than theirs added up
+	ErrInvalidNesting = errors.New("invalid nested pack")
+	// ErrPackageInUse is returned when a package that other packs hold is deleted
+	ErrPackageInUse = repo.ErrPackageInUse
+)
+
+// PackNode is a pack of a result along with the packs it holds
+type PackNode struct {
+	// Size is the number of items in a single pack
+	Size int `json:"size"`
+	// Count is the number of these packs in the result, or in a single pack of the parent node
+	Count int `json:"count"`
+	// Contents are the packs a single one of these packs holds, none for loose items
+	Contents []PackNode `json:"contents,omitempty"`
+}
+
+// innerPack is the content of a nested pack: count packs of size items
+type innerPack struct {
+	size  int
+	count int
+}
+
+// nestedSize returns the size of a pack holding packages of the catalog: the size of the inner
+// package times their count. A size given with the pack has to match it.
+func (a *App) nestedSize(pkg Package, catalog []Package) (int, error) {
+	if pkg.InnerCount < 2 {
+		return 0, fmt.Errorf("%w: a pack holds at least 2 inner packs, not %d", ErrInvalidNesting, pkg.InnerCount)
+	}
+	var inner *Package
+	for i := range catalog {
+		if catalog[i].ID == pkg.InnerID {
+			inner = &catalog[i]
+		}
+	}
+	if inner == nil {
+		return 0, fmt.Errorf("%w: package %d is not in the catalog of product %d", ErrInvalidNesting, pkg.InnerID, pkg.ProductID)
+	}
+	if pkg.InnerCount > a.maxPackSize/inner.Size {
+		return 0, fmt.Errorf("%w: %d packs of %d are above the maximum of %d", ErrPackSizeTooLarge, pkg.InnerCount, inner.Size, a.maxPackSize)
+	}
+	size := pkg.InnerCount * inner.Size
+	if pkg.Size != 0 && pkg.Size != size {
+		return 0, fmt.Errorf("%w: size %d is not the %d items of %d packs of %d", ErrInvalidNesting, pkg.Size, size, pkg.InnerCount, inner.Size)
+	}
+	return size, nil
+}
+
+// catalogNesting returns the content of the nested packs of a catalog by size
+func catalogNesting(catalog []Package) map[int]innerPack {
+	sizes := make(map[int]int, len(catalog))
+	for _, pkg := range catalog {
+		sizes[pkg.ID] = pkg.Size
+	}
+	nesting := make(map[int]innerPack)
+	for _, pkg := range catalog {
+		if size, ok := sizes[pkg.InnerID]; ok && pkg.InnerID != 0 && pkg.InnerCount > 0 {
+			nesting[pkg.Size] = innerPack{size: size, count: pkg.InnerCount}
+		}
+	}
+	return nesting
+}
+
+// packTree returns the packs of the lines of a result as a tree of the packs they hold,
+// largest size first
+func packTree(lines []PackLine, nesting map[int]innerPack) []PackNode {
+	tree := make([]PackNode, 0, len(lines))
+	for _, line := range lines {
+		tree = append(tree, packNode(line.Size, line.Count, nesting))
+	}
+	return tree
+}
+
+// packNode returns the node of count packs of a size. Inner packs are smaller than the packs
+// holding them, so the tree ends.
+func packNode(size, count int, nesting map[int]innerPack) PackNode {
+	node := PackNode{Size: size, Count: count}
+	if inner, ok := nesting[size]; ok && inner.size < size {
+		node.Contents = []PackNode{packNode(inner.size, inner.count, nesting)}
+	}
+	return node
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Calculate_NestedPacks(t *testing.T) {
+	// A case holds 4 boxes of 250, a pallet 20 cases
+	catalog := []repo.Package{
+		{ID: 1, Size: 250},
+		{ID: 2, Size: 1000, InnerID: 1, InnerCount: 4},
+		{ID: 3, Size: 20000, InnerID: 2, InnerCount: 20},
+	}
+	box := PackNode{Size: 250, Count: 4}
+	caseOfBoxes := PackNode{Size: 1000, Count: 20, Contents: []PackNode{box}}
+
+	tests := []struct {
+		name      string
+		order     int
+		stock     []repo.StockLevel
+		wantPacks map[int]int
+		wantTree  []PackNode
+	}{
+		{
+			name:      "pallet, cases and boxes",
+			order:     23500,
+			wantPacks: map[int]int{20000: 1, 1000: 3, 250: 2},
+			wantTree: []PackNode{
+				{Size: 20000, Count: 1, Contents: []PackNode{caseOfBoxes}},
+				{Size: 1000, Count: 3, Contents: []PackNode{box}},
+				{Size: 250, Count: 2},
+			},
+		},
+		{
+			name:      "loose boxes",
+			order:     501,
+			wantPacks: map[int]int{250: 3},
+			wantTree:  []PackNode{{Size: 250, Count: 3}},
+		},
+		{
+			name:      "boxes for cases out of stock",
+			order:     2000,
+			stock:     []repo.StockLevel{{Size: 1000, Quantity: 1}},
+			wantPacks: map[int]int{1000: 1, 250: 4},
+			wantTree: []PackNode{
+				{Size: 1000, Count: 1, Contents: []PackNode{box}},
+				{Size: 250, Count: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			mockRepo.On("GetStock", DefaultProductID).Return(tt.stock, nil)
+
+			result, err := NewApp(mockRepo).Calculate(context.Background(), tt.order, CalculateOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			require.Equal(t, tt.wantTree, result.Tree)
+		})
+	}
+}
+
+func TestApp_Calculate_FlatCatalogHasNoTree(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 1000}}, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+	result, err := NewApp(mockRepo).Calculate(context.Background(), 1250, CalculateOptions{})
+	require.NoError(t, err)
+	require.Nil(t, result.Tree)
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrOrderNotFound is returned when an order does not exist
+	ErrOrderNotFound = repo.ErrOrderNotFound
+	// ErrInvalidTransition is returned when an order cannot move from its status to the one asked
+	ErrInvalidTransition = repo.ErrInvalidTransition
+	// ErrUnknownStatus is returned for an order status that does not exist
+	ErrUnknownStatus = errors.New("unknown order status")
+)
+
+// Order statuses; shipped and cancelled orders are final
+const (
+	OrderPending   = repo.OrderPending
+	OrderReserved  = repo.OrderReserved
+	OrderPacked    = repo.OrderPacked
+	OrderShipped   = repo.OrderShipped
+	OrderCancelled = repo.OrderCancelled
+)
+
+// orderTransitions are the statuses an order moves to from each status, with how its packs move
+// in the stock. Reserving an order takes its packs out of the stock, cancelling a reserved or
+// packed order puts them back; a shipped order has left with its packs.
+var orderTransitions = map[string]map[string]int{
+	OrderPending:   {OrderReserved: repo.StockReserved, OrderCancelled: repo.StockKept},
+	OrderReserved:  {OrderPacked: repo.StockKept, OrderCancelled: repo.StockReleased},
+	OrderPacked:    {OrderShipped: repo.StockKept, OrderCancelled: repo.StockReleased},
+	OrderShipped:   {},
+	OrderCancelled: {},
+}
+
+// Order is an order of a product with the packs calculated for it
+type Order struct {
+	ID        int `json:"id"`
+	ProductID int `json:"productId"`
+	Quantity  int `json:"quantity"`
+	// Status is one of pending, reserved, packed, shipped and cancelled
+	Status string `json:"status"`
+	// Plan are the packs calculated for the order when it was created
+	Plan      *CalculationResult `json:"plan"`
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// CreateOrder calculates the packs for an order like Calculate and stores the order, pending,
+// with the packs as its plan. The stock is left alone until the order is reserved.
+func (a *App) CreateOrder(ctx context.Context, orderQuantity int, opts CalculateOptions) (*Order, error) {
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: orders have a single plan", ErrInvalidAlternatives)
+	}
+	if opts.Mode == ModeSplit {
+		return nil, fmt.Errorf("%w: orders are packed from the default warehouse, %s orders are not supported", ErrInvalidMode, ModeSplit)
+	}
+	result, err := a.Calculate(ctx, orderQuantity, opts)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := a.repo.AddOrder(repo.Order{
+		ProductID: productOrDefault(opts.ProductID),
+		Quantity:  orderQuantity,
+		Plan:      plan,
+		Packs:     result.Packs(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderOf(stored)
+}
+
+// GetOrder returns an order with its status and plan
+func (a *App) GetOrder(id int) (*Order, error) {
+	stored, err := a.repo.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	return orderOf(stored)
+}
+
+// GetOrders returns the orders in a status, or all orders when status is empty, newest first
+func (a *App) GetOrders(status string) ([]*Order, error) {
+	if status != "" {
+		if err := checkStatus(status); err != nil {
+			return nil, err
+		}
+	}
+	stored, err := a.repo.GetOrders(status)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*Order, 0, len(stored))
+	for _, s := range stored {
+		order, err := orderOf(s)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// SetOrderStatus moves an order to a status, returning ErrInvalidTransition when it cannot move
+// there from the status it is in. An order moves from pending to reserved, packed and shipped in
+// turn and can be cancelled until it is shipped. The packs of the plan are taken out of the stock
+// of the product in the default warehouse when the order is reserved and put back when a reserved
+// or packed order is cancelled, in the same transaction as the status change; an order whose
+// packs are no longer in stock stays pending and ErrInsufficientStock is returned.
+func (a *App) SetOrderStatus(id int, status string) (*Order, error) {
+	if err := checkStatus(status); err != nil {
+		return nil, err
+	}
+	stored, err := a.repo.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	stock, ok := orderTransitions[stored.Status][status]
+	if !ok {
+		return nil, fmt.Errorf("%w: order %d cannot move from %s to %s", ErrInvalidTransition, id, stored.Status, status)
+	}
+	stored, err = a.repo.UpdateOrderStatus(id, stored.Status, status, stock)
+	if err != nil {
+		return nil, err
+	}
+	return orderOf(stored)
+}
+
+// checkStatus returns ErrUnknownStatus for a status an order cannot be in
+func checkStatus(status string) error {
+	if _, ok := orderTransitions[status]; !ok {
+		return fmt.Errorf("%w: %q, expected %s, %s, %s, %s or %s", ErrUnknownStatus, status,
+			OrderPending, OrderReserved, OrderPacked, OrderShipped, OrderCancelled)
+	}
+	return nil
+}
+
+// orderOf decodes the plan of a stored order
+func orderOf(stored repo.Order) (*Order, error) {
+	order := &Order{
+		ID:        stored.ID,
+		ProductID: stored.ProductID,
+		Quantity:  stored.Quantity,
+		Status:    stored.Status,
+		CreatedAt: stored.CreatedAt,
+		UpdatedAt: stored.UpdatedAt,
+	}
+	if err := json.Unmarshal(stored.Plan, &order.Plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan of order %d: %w", stored.ID, err)
+	}
+	return order, nil
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_CreateOrder(t *testing.T) {
+	catalog := []repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}}
+
+	tests := []struct {
+		name      string
+		opts      CalculateOptions
+		setupMock func(*MockRepository)
+		wantErr   error
+	}{
+		{
+			name: "stored pending with its plan",
+			setupMock: func(m *MockRepository) {
+				m.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+				m.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+				m.On("AddOrder", mock.MatchedBy(func(o repo.Order) bool {
+					return o.ProductID == DefaultProductID && o.Quantity == 751 && len(o.Plan) > 0 && o.Packs[500] == 2
+				})).Return(repo.Order{ID: 4, ProductID: DefaultProductID, Quantity: 751, Status: OrderPending,
+					Plan: []byte(`{"requested":751,"lines":[{"size":500,"count":2}]}`), Packs: map[int]int{500: 2}}, nil)
+			},
+		},
+		{
+			name:      "alternatives",
+			opts:      CalculateOptions{Alternatives: 2},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidAlternatives,
+		},
+		{
+			name:      "split mode",
+			opts:      CalculateOptions{Mode: ModeSplit},
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			got, err := NewApp(mockRepo).CreateOrder(context.Background(), 751, tt.opts)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, 4, got.ID)
+				require.Equal(t, OrderPending, got.Status)
+				require.Equal(t, map[int]int{500: 2}, got.Plan.Packs())
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_SetOrderStatus(t *testing.T) {
+	order := func(status string) repo.Order {
+		return repo.Order{ID: 4, Quantity: 251, Status: status, Plan: []byte(`{"requested":251}`), Packs: map[int]int{500: 1}}
+	}
+
+	tests := []struct {
+		name      string
+		from, to  string
+		setupMock func(*MockRepository)
+		wantErr   error
+	}{
+		{
+			name: "reserve",
+			from: OrderPending, to: OrderReserved,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderPending, OrderReserved, repo.StockReserved).Return(order(OrderReserved), nil)
+			},
+		},
+		{
+			name: "pack",
+			from: OrderReserved, to: OrderPacked,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderReserved, OrderPacked, repo.StockKept).Return(order(OrderPacked), nil)
+			},
+		},
+		{
+			name: "ship",
+			from: OrderPacked, to: OrderShipped,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderPacked, OrderShipped, repo.StockKept).Return(order(OrderShipped), nil)
+			},
+		},
+		{
+			name: "cancel pending",
+			from: OrderPending, to: OrderCancelled,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderPending, OrderCancelled, repo.StockKept).Return(order(OrderCancelled), nil)
+			},
+		},
+		{
+			name: "cancel packed releases the stock",
+			from: OrderPacked, to: OrderCancelled,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderPacked, OrderCancelled, repo.StockReleased).Return(order(OrderCancelled), nil)
+			},
+		},
+		{
+			name: "stock taken by another order",
+			from: OrderPending, to: OrderReserved,
+			setupMock: func(m *MockRepository) {
+				m.On("UpdateOrderStatus", 4, OrderPending, OrderReserved, repo.StockReserved).Return(repo.Order{}, repo.ErrInsufficientStock)
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name: "skip reserving",
+			from: OrderPending, to: OrderPacked,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidTransition,
+		},
+		{
+			name: "cancel shipped",
+			from: OrderShipped, to: OrderCancelled,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidTransition,
+		},
+		{
+			name: "back to pending",
+			from: OrderReserved, to: OrderPending,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetOrder", 4).Return(order(tt.from), nil)
+			tt.setupMock(mockRepo)
+
+			got, err := NewApp(mockRepo).SetOrderStatus(4, tt.to)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.to, got.Status)
+				require.Equal(t, 251, got.Plan.Requested)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_SetOrderStatus_UnknownStatus(t *testing.T) {
+	mockRepo := new(MockRepository)
+
+	_, err := NewApp(mockRepo).SetOrderStatus(4, "lost")
+	require.ErrorIs(t, err, ErrUnknownStatus)
+
+	_, err = NewApp(mockRepo).GetOrders("lost")
+	require.ErrorIs(t, err, ErrUnknownStatus)
+	mockRepo.AssertExpectations(t)
+}
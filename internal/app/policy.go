@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownPolicy is returned when a policy name is not registered
+var ErrUnknownPolicy = errors.New("unknown policy")
+
+// Objectives a policy orders packs by
+const (
+	// ObjectiveItems ships the least amount of items
+	ObjectiveItems = "items"
+	// ObjectivePacks ships the least amount of packs
+	ObjectivePacks = "packs"
+	// ObjectiveCost ships the cheapest packs
+	ObjectiveCost = "cost"
+)
+
+// Tie-break rules between combinations a policy rates the same
+const (
+	// TieBreakNone keeps the combination the packing strategy finds first
+	TieBreakNone = "none"
+	// TieBreakLargest prefers more packs of the largest size, then of the next one and so on
+	TieBreakLargest = "largest"
+	// TieBreakSmallest prefers more packs of the smallest size, then of the next one and so on
+	TieBreakSmallest = "smallest"
+)
+
+// DefaultPolicy is the policy used when none is configured, items first like ModeItems
+const DefaultPolicy = "items-first"
+
+// Policy chooses between the combinations of packs covering an order: the objectives are
+// compared one after another, and combinations equal on all of them are told apart by TieBreak
+type Policy struct {
+	// Name is the policy name used in configuration and requests
+	Name string `json:"name"`
+	// Objectives are compared in order, the first one decides unless it is a tie
+	Objectives []string `json:"objectives"`
+	// TieBreak tells apart combinations equal on every objective
+	TieBreak string `json:"tieBreak"`
+}
+
+// policies holds every available policy by name
+var policies = map[string]Policy{
+	DefaultPolicy: {Name: DefaultPolicy, Objectives: []string{ObjectiveItems, ObjectivePacks}, TieBreak: TieBreakNone},
+	"packs-first": {Name: "packs-first", Objectives: []string{ObjectivePacks, ObjectiveItems}, TieBreak: TieBreakNone},
+	"large-packs": {Name: "large-packs", Objectives: []string{ObjectiveItems, ObjectivePacks}, TieBreak: TieBreakLargest},
+	"small-packs": {Name: "small-packs", Objectives: []string{ObjectiveItems, ObjectivePacks}, TieBreak: TieBreakSmallest},
+	"cost-first":  {Name: "cost-first", Objectives: []string{ObjectiveCost, ObjectiveItems, ObjectivePacks}, TieBreak: TieBreakNone},
+}
+
+// modePolicies are the policies of the calculation modes, see CalculateOptions.Mode
+var modePolicies = map[string]string{
+	ModeItems: DefaultPolicy,
+	ModePacks: "packs-first",
+	ModeCost:  "cost-first",
+}
+
+// PolicyByName returns the policy registered under the given name
+func PolicyByName(name string) (Policy, error) {
+	policy, ok := policies[name]
+	if !ok {
+		return Policy{}, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownPolicy, name, strings.Join(PolicyNames(), ", "))
+	}
+	return policy, nil
+}
+
+// PolicyNames returns the names of all policies in alphabetical order
+func PolicyNames() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mode returns the calculation mode of the policy, its first objective
+func (p Policy) mode() string {
+	return p.Objectives[0]
+}
+
+// usesSolvers reports whether the packing strategies choose the packs of the policy: items
+// first, then packs, with ties left to the strategy
+func (p Policy) usesSolvers() bool {
+	return p.mode() == ObjectiveItems && p.Objectives[1] == ObjectivePacks && p.TieBreak == TieBreakNone
+}
+
+// policyFor returns the policy asked for in opts. A mode without a policy picks the configured
+// default when it has the same first objective, or else the policy of the mode.
+func (a *App) policyFor(opts CalculateOptions) (Policy, error) {
+	if opts.Policy != "" {
+		policy, err := PolicyByName(opts.Policy)
+		if err != nil {
+			return Policy{}, err
+		}
+		if opts.Mode != "" && opts.Mode != policy.mode() {
+			return Policy{}, fmt.Errorf("%w %q, the %s policy is %s first", ErrInvalidMode, opts.Mode, policy.Name, policy.mode())
+		}
+		return policy, nil
+	}
+
+	if opts.Mode == "" || opts.Mode == a.policy.mode() {
+		return a.policy, nil
+	}
+	name, ok := modePolicies[opts.Mode]
+	if !ok {
+		return Policy{}, fmt.Errorf("%w %q, expected %s, %s or %s", ErrInvalidMode, opts.Mode, ModeItems, ModePacks, ModeCost)
+	}
+	return policies[name], nil
+}
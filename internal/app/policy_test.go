@@ -0,0 +1,244 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+var itemsFirst = policies[DefaultPolicy]
+
+func TestApp_Calculate_Policies(t *testing.T) {
+	// An order of 32 ships exactly in 4 packs three ways: 11+11+5+5, 11+9+9+3 and 9+9+9+5
+	catalog := []repo.Package{
+		{ID: 1, Size: 3, Price: 1},
+		{ID: 2, Size: 5, Price: 1},
+		{ID: 3, Size: 9, Price: 1},
+		{ID: 4, Size: 11, Price: 1},
+	}
+
+	tests := []struct {
+		name       string
+		options    []Option
+		opts       CalculateOptions
+		wantPacks  map[int]int
+		wantMode   string
+		wantPolicy string
+		wantErr    error
+	}{
+		{
+			name:       "default policy",
+			opts:       CalculateOptions{Solver: "bnb"},
+			wantPacks:  map[int]int{11: 2, 5: 2},
+			wantMode:   ModeItems,
+			wantPolicy: DefaultPolicy,
+		},
+		{
+			name:       "packs first",
+			opts:       CalculateOptions{Policy: "packs-first"},
+			wantPacks:  map[int]int{11: 3},
+			wantMode:   ModePacks,
+			wantPolicy: "packs-first",
+		},
+		{
+			name:       "packs mode",
+			opts:       CalculateOptions{Mode: ModePacks},
+			wantPacks:  map[int]int{11: 3},
+			wantMode:   ModePacks,
+			wantPolicy: "packs-first",
+		},
+		{
+			name:       "large packs",
+			opts:       CalculateOptions{Policy: "large-packs"},
+			wantPacks:  map[int]int{11: 2, 5: 2},
+			wantMode:   ModeItems,
+			wantPolicy: "large-packs",
+		},
+		{
+			name:       "small packs",
+			opts:       CalculateOptions{Policy: "small-packs", Mode: ModeItems},
+			wantPacks:  map[int]int{11: 1, 9: 2, 3: 1},
+			wantMode:   ModeItems,
+			wantPolicy: "small-packs",
+		},
+		{
+			name:       "cost mode",
+			opts:       CalculateOptions{Mode: ModeCost},
+			wantPacks:  map[int]int{11: 3},
+			wantMode:   ModeCost,
+			wantPolicy: "cost-first",
+		},
+		{
+			name:       "configured policy",
+			options:    []Option{WithPolicy(policies["small-packs"])},
+			wantPacks:  map[int]int{11: 1, 9: 2, 3: 1},
+			wantMode:   ModeItems,
+			wantPolicy: "small-packs",
+		},
+		{
+			name:       "mode of the configured policy",
+			options:    []Option{WithPolicy(policies["small-packs"])},
+			opts:       CalculateOptions{Mode: ModeItems},
+			wantPacks:  map[int]int{11: 1, 9: 2, 3: 1},
+			wantMode:   ModeItems,
+			wantPolicy: "small-packs",
+		},
+		{
+			name:       "another mode than the configured policy",
+			options:    []Option{WithPolicy(policies["small-packs"])},
+			opts:       CalculateOptions{Mode: ModePacks},
+			wantPacks:  map[int]int{11: 3},
+			wantMode:   ModePacks,
+			wantPolicy: "packs-first",
+		},
+		{
+			name:    "unknown policy",
+			opts:    CalculateOptions{Policy: "fewest-boxes"},
+			wantErr: ErrUnknownPolicy,
+		},
+		{
+			name:    "mode of another policy",
+			opts:    CalculateOptions{Policy: "packs-first", Mode: ModeItems},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name:    "policy with another solver",
+			opts:    CalculateOptions{Policy: "large-packs", Solver: "dp"},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name:    "policy with alternatives",
+			opts:    CalculateOptions{Policy: "packs-first", Alternatives: 3},
+			wantErr: ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+			result, err := NewApp(mockRepo, tt.options...).Calculate(context.Background(), 32, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			require.Equal(t, tt.wantMode, result.Mode)
+			require.Equal(t, tt.wantPolicy, result.Policy.Name)
+		})
+	}
+}
+
+func TestCalculatePacksNeeded_Policies(t *testing.T) {
+	app := NewApp(nil)
+
+	result, err := app.CalculatePacksNeeded(context.Background(), 12001, []int{250, 500, 1000, 2000, 5000}, CalculateOptions{Policy: "packs-first"})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{5000: 3}, result.Packs())
+	require.Equal(t, "bnb", result.Solver)
+	require.Equal(t, "packs-first", result.Policy.Name)
+
+	_, err = app.CalculatePacksNeeded(context.Background(), 12001, []int{250, 500}, CalculateOptions{Policy: "cost-first"})
+	require.ErrorIs(t, err, ErrInvalidMode)
+}
+
+func TestSolveWithStock_PoliciesMatchBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(16))
+	for i := 0; i < 300; i++ {
+		sizes := distinctDescending([]int{1 + rng.Intn(20), 1 + rng.Intn(20), 1 + rng.Intn(20), 1 + rng.Intn(20)})
+		limits := make(map[int]int)
+		for _, size := range sizes {
+			if rng.Intn(3) == 0 {
+				limits[size] = rng.Intn(4)
+			}
+		}
+		n := 1 + rng.Intn(60)
+
+		for _, name := range []string{"packs-first", "large-packs", "small-packs"} {
+			policy := policies[name]
+
+			// The best of every covering combination within the stock by the policy
+			var best []int
+			counts := make([]int, len(sizes))
+			var walk func(level int)
+			walk = func(level int) {
+				if level == len(sizes) {
+					if covers(counts, sizes, n) && (best == nil || prefers(policy, sizes, counts, best)) {
+						best = append([]int(nil), counts...)
+					}
+					return
+				}
+				most := ceilDiv(n, sizes[level])
+				if limit := limitOf(limits, sizes[level]); limit != unlimitedStock {
+					most = min(most, limit)
+				}
+				for c := 0; c <= most; c++ {
+					counts[level] = c
+					walk(level + 1)
+				}
+			}
+			walk(0)
+
+			packs, err := solveWithStock(context.Background(), n, sizes, limits, policy)
+			if best == nil {
+				require.ErrorIs(t, err, ErrInsufficientStock, "sizes %v, limits %v, order %d", sizes, limits, n)
+				continue
+			}
+			require.NoError(t, err)
+			got := make([]int, len(sizes))
+			for j, size := range sizes {
+				got[j] = packs[size]
+			}
+			// Ties left to the search may be broken either way, the tie-breaks leave none
+			require.True(t, covers(got, sizes, n), "%s: sizes %v, limits %v, order %d", name, sizes, limits, n)
+			require.False(t, prefers(policy, sizes, best, got), "%s: sizes %v, limits %v, order %d: %v is better than %v", name, sizes, limits, n, best, got)
+			require.False(t, prefers(policy, sizes, got, best), "%s: sizes %v, limits %v, order %d: %v is better than %v", name, sizes, limits, n, got, best)
+		}
+	}
+}
+
+// covers reports whether the pack counts per size add up to at least n items
+func covers(counts, sizes []int, n int) bool {
+	shipped := 0
+	for i, count := range counts {
+		shipped += count * sizes[i]
+	}
+	return shipped >= n
+}
+
+// prefers reports whether the policy rates the pack counts a better than b, sizes are in
+// descending order and costs are not compared
+func prefers(policy Policy, sizes, a, b []int) bool {
+	score := func(counts []int, objective string) int {
+		total := 0
+		for i, count := range counts {
+			if objective == ObjectiveItems {
+				total += count * sizes[i]
+			} else {
+				total += count
+			}
+		}
+		return total
+	}
+	for _, objective := range policy.Objectives {
+		if left, right := score(a, objective), score(b, objective); left != right {
+			return left < right
+		}
+	}
+	for j := range a {
+		i := j
+		if policy.TieBreak == TieBreakSmallest {
+			i = len(a) - 1 - j
+		}
+		if a[i] != b[i] {
+			return policy.TieBreak != TieBreakNone && a[i] > b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrProductNotFound is returned when a product does not exist
+	ErrProductNotFound = repo.ErrProductNotFound
+	// ErrProductExists is returned when a product name is already taken
+	ErrProductExists = repo.ErrProductExists
+	// ErrInvalidProduct is returned for an empty product name or a change to the default product
+	ErrInvalidProduct = errors.New("invalid product")
+	// ErrInvalidOrder is returned for a multi-line order without lines, with too many of them
+	// or with a product on more than one line
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+const (
+	// DefaultProductID is the product used when none is given
+	DefaultProductID = repo.DefaultProductID
+	// MaxOrderLines caps the number of lines of a multi-line order
+	MaxOrderLines = 100
+)
+
+// Product is an item sold in its own pack sizes
+type Product = repo.Product
+
+// OrderLine is the ordered quantity of a product
+type OrderLine struct {
+	ProductID int `json:"productId"`
+	Quantity  int `json:"quantity"`
+	// Constraints bound the packs per size of this line, see CalculateOptions
+	Constraints []PackConstraint `json:"constraints,omitempty"`
+}
+
+// OrderResult describes the packs chosen for every line of a multi-line order
+type OrderResult struct {
+	// Lines are the results per order line, in the order of the request
+	Lines []OrderLineResult `json:"lines"`
+	// Totals add up the results of all lines
+	Totals OrderTotals `json:"totals"`
+	// Cartons are the shipping cartons the packs of all lines are put in together, asked for
+	// with CalculateOptions.Cartonize or Zone
+	Cartons []CartonLoad `json:"cartons,omitempty"`
+	// Shipping is the carrier the cartons are sent with and the landed cost, set with a zone
+	Shipping *ShippingBreakdown `json:"shipping,omitempty"`
+}
+
+// OrderLineResult is the packing result of a single order line
+type OrderLineResult struct {
+	ProductID int `json:"productId"`
+	// Product is the product name
+	Product string             `json:"product"`
+	Result  *CalculationResult `json:"result"`
+}
+
+// OrderTotals add up the results of the lines of an order
+type OrderTotals struct {
+	// Requested is the ordered quantity over all products
+	Requested int `json:"requested"`
+	// Shipped is the number of items sent out over all products
+	Shipped int `json:"shipped"`
+	// Overshoot is the number of items sent on top of the ordered quantities
+	Overshoot int `json:"overshoot"`
+	// Shortfall is the number of items sent below the ordered quantities
+	Shortfall int `json:"shortfall,omitempty"`
+	// Backordered is the number of items sent later
+	Backordered int `json:"backordered,omitempty"`
+	// TotalPacks is the number of packs sent out over all products
+	TotalPacks int `json:"totalPacks"`
+	// Cost is the price plus the handling cost of all packs
+	Cost float64 `json:"cost"`
+}
+
+// productOrDefault returns the product ID, or DefaultProductID when it is not set
+func productOrDefault(productID int) int {
+	if productID == 0 {
+		return DefaultProductID
+	}
+	return productID
+}
+
+// GetProducts returns all products
+func (a *App) GetProducts() ([]Product, error) {
+	return a.repo.GetProducts()
+}
+
+// AddProduct adds a product with an empty pack catalog
+func (a *App) AddProduct(name string) (Product, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Product{}, fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	}
+	return a.repo.AddProduct(name)
+}
+
+// DeleteProduct deletes a product along with its pack catalog and stock.
+// The default product cannot be deleted.
+func (a *App) DeleteProduct(id int) error {
+	if id == DefaultProductID {
+		return fmt.Errorf("%w: the default product cannot be deleted", ErrInvalidProduct)
+	}
+	if err := a.repo.DeleteProduct(id); err != nil {
+		return err
+	}
+	a.cache.invalidate(id)
+	return nil
+}
+
+// CalculateOrder calculates the packs needed for every line of a multi-line order like Calculate,
+// using the pack catalog and stock of the product of each line, and adds up the results.
+// The product in opts is ignored, and every line has a budget of its own. With opts.Cartonize or
+// opts.Zone the packs of all lines share the cartons, and with a zone they are sent with the
+// carrier of the lowest cost. ModeShipping and ModeSplit are not supported.
+func (a *App) CalculateOrder(ctx context.Context, lines []OrderLine, opts CalculateOptions) (*OrderResult, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no order lines", ErrInvalidOrder)
+	}
+	if len(lines) > MaxOrderLines {
+		return nil, fmt.Errorf("%w: %d lines, at most %d are allowed", ErrInvalidOrder, len(lines), MaxOrderLines)
+	}
+	if len(opts.Constraints) > 0 {
+		// Sizes belong to the catalog of a product, so constraints are given per line
+		return nil, fmt.Errorf("%w: pack constraints must be given per line", ErrInvalidOrder)
+	}
+	if opts.Mode == ModeShipping {
+		return nil, fmt.Errorf("%w: %s only chooses the packs of single orders, give a zone to price the order", ErrInvalidOrder, ModeShipping)
+	}
+	if opts.Mode == ModeSplit {
+		return nil, fmt.Errorf("%w: %s only splits single orders across the warehouses", ErrInvalidOrder, ModeSplit)
+	}
+	if err := a.checkShipping(opts); err != nil {
+		return nil, err
+	}
+	ship := opts.Cartonize || opts.Zone != ""
+
+	products, err := a.repo.GetProducts()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(products))
+	for _, product := range products {
+		names[product.ID] = product.Name
+	}
+
+	order := &OrderResult{Lines: make([]OrderLineResult, 0, len(lines))}
+	seen := make(map[int]bool, len(lines))
+	var cost int64
+	var packs []cartonPack
+	for i, line := range lines {
+		productID := productOrDefault(line.ProductID)
+		name, ok := names[productID]
+		if !ok {
+			return nil, fmt.Errorf("line %d: %w: %d", i+1, ErrProductNotFound, productID)
+		}
+		if seen[productID] {
+			return nil, fmt.Errorf("%w: product %s is on more than one line", ErrInvalidOrder, name)
+		}
+		seen[productID] = true
+
+		lineOpts := opts
+		lineOpts.ProductID = productID
+		lineOpts.Constraints = line.Constraints
+		lineOpts.Cartonize, lineOpts.Zone, lineOpts.Carrier = false, "", ""
+		result, err := a.Calculate(ctx, line.Quantity, lineOpts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d (%s): %w", i+1, name, err)
+		}
+		if ship {
+			snapshot, err := a.snapshot(ctx, productID)
+			if err != nil {
+				return nil, err
+			}
+			packs = append(packs, snapshot.cartonPacks(productID, result.Lines)...)
+		}
+
+		order.Lines = append(order.Lines, OrderLineResult{ProductID: productID, Product: name, Result: result})
+		order.Totals.Requested += result.Requested
+		order.Totals.Shipped += result.Shipped
+		order.Totals.Overshoot += result.Overshoot
+		order.Totals.Shortfall += result.Shortfall
+		order.Totals.Backordered += result.Backordered
+		order.Totals.TotalPacks += result.TotalPacks
+		cost += cents(result.Cost.Total)
+	}
+	order.Totals.Cost = float64(cost) / 100
+	switch {
+	case opts.Zone != "":
+		cartons, err := a.repo.GetCartons()
+		if err != nil {
+			return nil, err
+		}
+		shipment, err := a.cheapestShipment(packs, cost, cartons, opts)
+		if err != nil {
+			return nil, err
+		}
+		order.Cartons, order.Shipping = shipment.loads, shipment.breakdown
+	case opts.Cartonize:
+		if order.Cartons, err = a.cartonize(packs); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_CalculateOrder(t *testing.T) {
+	products := []repo.Product{{ID: 1, Name: "default"}, {ID: 2, Name: "bolts"}}
+
+	tests := []struct {
+		name      string
+		lines     []OrderLine
+		setupMock func(*MockRepository)
+		want      *OrderResult
+		wantErr   error
+	}{
+		{
+			name:  "every line packed from its own catalog",
+			lines: []OrderLine{{ProductID: 2, Quantity: 12}, {Quantity: 251}},
+			setupMock: func(m *MockRepository) {
+				m.On("GetProducts").Return(products, nil)
+				m.On("GetCatalog", 2).Return([]repo.Package{{ID: 4, ProductID: 2, Size: 10, Price: 1.25}}, nil)
+				m.On("GetStock", 2).Return([]repo.StockLevel{}, nil)
+				m.On("GetCatalog", 1).Return([]repo.Package{{ID: 1, ProductID: 1, Size: 250, Price: 2.5}, {ID: 2, ProductID: 1, Size: 500, Price: 4}}, nil)
+				m.On("GetStock", 1).Return([]repo.StockLevel{}, nil)
+			},
+			want: &OrderResult{
+				Lines: []OrderLineResult{
+					{ProductID: 2, Product: "bolts", Result: &CalculationResult{
+						Requested: 12, Shipped: 20, Overshoot: 8, TotalPacks: 2,
+						Lines:   []PackLine{{Size: 10, Count: 2, Items: 20}},
+						Catalog: []int{10}, Solver: "dp", Mode: ModeItems, Policy: &itemsFirst,
+						Cost: &CostBreakdown{Lines: []CostLine{{Size: 10, Count: 2, UnitPrice: 1.25, Total: 2.5}}, Packs: 2.5, Total: 2.5},
+					}},
+					{ProductID: 1, Product: "default", Result: &CalculationResult{
+						Requested: 251, Shipped: 500, Overshoot: 249, TotalPacks: 1,
+						Lines:   []PackLine{{Size: 500, Count: 1, Items: 500}},
+						Catalog: []int{500, 250}, Solver: "dp", Mode: ModeItems, Policy: &itemsFirst,
+						Cost: &CostBreakdown{Lines: []CostLine{{Size: 500, Count: 1, UnitPrice: 4, Total: 4}}, Packs: 4, Total: 4},
+					}},
+				},
+				Totals: OrderTotals{Requested: 263, Shipped: 520, Overshoot: 257, TotalPacks: 3, Cost: 6.5},
+			},
+		},
+		{
+			name:  "unknown product",
+			lines: []OrderLine{{ProductID: 9, Quantity: 12}},
+			setupMock: func(m *MockRepository) {
+				m.On("GetProducts").Return(products, nil)
+			},
+			wantErr: ErrProductNotFound,
+		},
+		{
+			name:  "product on two lines",
+			lines: []OrderLine{{ProductID: 1, Quantity: 12}, {Quantity: 5}},
+			setupMock: func(m *MockRepository) {
+				m.On("GetProducts").Return(products, nil)
+				m.On("GetCatalog", 1).Return([]repo.Package{{ID: 1, ProductID: 1, Size: 5}}, nil)
+				m.On("GetStock", 1).Return([]repo.StockLevel{}, nil)
+			},
+			wantErr: ErrInvalidOrder,
+		},
+		{
+			name:  "line out of stock",
+			lines: []OrderLine{{ProductID: 2, Quantity: 12}},
+			setupMock: func(m *MockRepository) {
+				m.On("GetProducts").Return(products, nil)
+				m.On("GetCatalog", 2).Return([]repo.Package{{ID: 4, ProductID: 2, Size: 10}}, nil)
+				m.On("GetStock", 2).Return([]repo.StockLevel{{Size: 10, Quantity: 1}}, nil)
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name:      "no lines",
+			lines:     nil,
+			setupMock: func(m *MockRepository) {},
+			wantErr:   ErrInvalidOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			tt.setupMock(mockRepo)
+
+			app := NewApp(mockRepo)
+			got, err := app.CalculateOrder(context.Background(), tt.lines, CalculateOptions{})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_AddProduct(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("AddProduct", "bolts").Return(repo.Product{ID: 2, Name: "bolts"}, nil)
+
+	app := NewApp(mockRepo)
+	got, err := app.AddProduct("  bolts ")
+	require.NoError(t, err)
+	require.Equal(t, Product{ID: 2, Name: "bolts"}, got)
+
+	_, err = app.AddProduct(" ")
+	require.ErrorIs(t, err, ErrInvalidProduct)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_DeleteProduct(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("DeleteProduct", 2).Return(nil)
+
+	app := NewApp(mockRepo)
+	require.NoError(t, app.DeleteProduct(2))
+	require.ErrorIs(t, app.DeleteProduct(DefaultProductID), ErrInvalidProduct)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidRateTable is returned for a carrier rate table that cannot be read or makes no sense
+var ErrInvalidRateTable = errors.New("invalid rate table")
+
+// RateTable is the price list of a carrier: a parcel costs the price of the first weight band of
+// its zone it fits in plus the parcel fee
+type RateTable struct {
+	// Carrier is the carrier name
+	Carrier string `json:"carrier"`
+	// ParcelFee is the fee every parcel costs on top of its band
+	ParcelFee float64 `json:"parcelFee"`
+	// Zones are the weight bands by zone, from the lightest to the heaviest
+	Zones map[string][]RateBand `json:"zones"`
+}
+
+// RateBand is the price of a parcel of up to MaxWeight grams
+type RateBand struct {
+	MaxWeight int     `json:"maxWeight"`
+	Price     float64 `json:"price"`
+}
+
+// LoadRateTables reads the rate table of every *.json file of a directory, ordered by file name
+func LoadRateTables(dir string) ([]RateTable, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate tables: %w", err)
+	}
+	sort.Strings(files)
+
+	tables := make([]RateTable, 0, len(files))
+	carriers := make(map[string]string, len(files))
+	for _, file := range files {
+		table, err := readRateTable(file)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := carriers[table.Carrier]; ok {
+			return nil, fmt.Errorf("%w: %s: carrier %s is also in %s", ErrInvalidRateTable, file, table.Carrier, other)
+		}
+		carriers[table.Carrier] = file
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// readRateTable reads and validates the rate table of a file
+func readRateTable(file string) (RateTable, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return RateTable{}, fmt.Errorf("failed to read rate table: %w", err)
+	}
+	var table RateTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return RateTable{}, fmt.Errorf("%w: %s: %v", ErrInvalidRateTable, file, err)
+	}
+	if err := table.validate(); err != nil {
+		return RateTable{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return table, nil
+}
+
+// validate checks the table has a carrier, a non-negative fee and zones of increasing weight bands
+func (t RateTable) validate() error {
+	if strings.TrimSpace(t.Carrier) == "" {
+		return fmt.Errorf("%w: carrier is required", ErrInvalidRateTable)
+	}
+	if t.ParcelFee < 0 {
+		return fmt.Errorf("%w: %s: the parcel fee must not be negative", ErrInvalidRateTable, t.Carrier)
+	}
+	if len(t.Zones) == 0 {
+		return fmt.Errorf("%w: %s: no zones", ErrInvalidRateTable, t.Carrier)
+	}
+	for zone, bands := range t.Zones {
+		if len(bands) == 0 {
+			return fmt.Errorf("%w: %s: zone %s has no weight bands", ErrInvalidRateTable, t.Carrier, zone)
+		}
+		for i, band := range bands {
+			if band.MaxWeight <= 0 || band.Price < 0 {
+				return fmt.Errorf("%w: %s: zone %s: bands need a positive weight and a price of at least 0", ErrInvalidRateTable, t.Carrier, zone)
+			}
+			if i > 0 && band.MaxWeight <= bands[i-1].MaxWeight {
+				return fmt.Errorf("%w: %s: zone %s: bands must go from the lightest to the heaviest", ErrInvalidRateTable, t.Carrier, zone)
+			}
+		}
+	}
+	return nil
+}
+
+// maxWeight returns the weight of the heaviest parcel the carrier takes in a zone
+func (t RateTable) maxWeight(zone string) int {
+	bands := t.Zones[zone]
+	return bands[len(bands)-1].MaxWeight
+}
+
+// band returns the first band of a zone a parcel of the weight fits in
+func (t RateTable) band(zone string, weight int) (RateBand, bool) {
+	for _, band := range t.Zones[zone] {
+		if weight <= band.MaxWeight {
+			return band, true
+		}
+	}
+	return RateBand{}, false
+}
+
+// WithRateTables sets the carrier rate tables shipping costs are calculated with, see LoadRateTables
+func WithRateTables(tables []RateTable) Option {
+	return func(a *App) {
+		a.rates = tables
+	}
+}
+
+// GetCarriers returns the carrier rate tables
+func (a *App) GetCarriers() []RateTable {
+	if a.rates == nil {
+		return []RateTable{}
+	}
+	return a.rates
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRateTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []RateTable
+		wantErr error
+	}{
+		{
+			name: "tables by file name",
+			files: map[string]string{
+				"b.json":     `{"carrier": "ParcelCo", "parcelFee": 1.5, "zones": {"domestic": [{"maxWeight": 2000, "price": 3}, {"maxWeight": 30000, "price": 9.9}]}}`,
+				"a.json":     `{"carrier": "BulkCo", "zones": {"eu": [{"maxWeight": 30000, "price": 12}]}}`,
+				"notes.txt":  `not a rate table`,
+				"empty.json": `{"carrier": "Empty", "zones": {"domestic": [{"maxWeight": 1000, "price": 0}]}}`,
+			},
+			want: []RateTable{
+				{Carrier: "BulkCo", Zones: map[string][]RateBand{"eu": {{MaxWeight: 30000, Price: 12}}}},
+				{Carrier: "ParcelCo", ParcelFee: 1.5, Zones: map[string][]RateBand{"domestic": {{MaxWeight: 2000, Price: 3}, {MaxWeight: 30000, Price: 9.9}}}},
+				{Carrier: "Empty", Zones: map[string][]RateBand{"domestic": {{MaxWeight: 1000, Price: 0}}}},
+			},
+		},
+		{
+			name:  "no tables",
+			files: map[string]string{},
+			want:  []RateTable{},
+		},
+		{
+			name:    "invalid JSON",
+			files:   map[string]string{"a.json": `{"carrier": `},
+			wantErr: ErrInvalidRateTable,
+		},
+		{
+			name:    "no carrier",
+			files:   map[string]string{"a.json": `{"zones": {"eu": [{"maxWeight": 30000, "price": 12}]}}`},
+			wantErr: ErrInvalidRateTable,
+		},
+		{
+			name:    "bands out of order",
+			files:   map[string]string{"a.json": `{"carrier": "BulkCo", "zones": {"eu": [{"maxWeight": 30000, "price": 12}, {"maxWeight": 2000, "price": 5}]}}`},
+			wantErr: ErrInvalidRateTable,
+		},
+		{
+			name:    "zone without bands",
+			files:   map[string]string{"a.json": `{"carrier": "BulkCo", "zones": {"eu": []}}`},
+			wantErr: ErrInvalidRateTable,
+		},
+		{
+			name: "carrier in two files",
+			files: map[string]string{
+				"a.json": `{"carrier": "BulkCo", "zones": {"eu": [{"maxWeight": 30000, "price": 12}]}}`,
+				"b.json": `{"carrier": "BulkCo", "zones": {"domestic": [{"maxWeight": 30000, "price": 8}]}}`,
+			},
+			wantErr: ErrInvalidRateTable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+
+			got, err := LoadRateTables(dir)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,531 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidRecommendation is returned for a recommendation request without demand, with an
+// invalid number of sizes or with bounds no pack size fits in
+var ErrInvalidRecommendation = errors.New("invalid recommendation request")
+
+const (
+	// MaxRecommendedSizes caps the number of pack sizes a recommendation searches for
+	MaxRecommendedSizes = 8
+	// MaxDemandBuckets caps the number of distinct order quantities of a demand histogram
+	MaxDemandBuckets = 10_000
+	// MaxRecommendationAmount caps the largest order quantity plus the largest pack size,
+	// the amounts every candidate catalog is evaluated up to
+	MaxRecommendationAmount = 1 << 22
+
+	// maxRecommendationCandidates caps the pack sizes the search chooses from
+	maxRecommendationCandidates = 256
+	// maxExhaustiveCatalogs is the largest number of catalogs tried one by one, larger searches
+	// run a local search instead
+	maxExhaustiveCatalogs = 5_000
+	// progressInterval is the least time between two progress reports
+	progressInterval = 250 * time.Millisecond
+)
+
+// Phases of a recommendation search
+const (
+	PhaseExhaustive = "exhaustive"
+	PhaseGreedy     = "greedy"
+	PhaseSwap       = "swap"
+	PhaseRefine     = "refine"
+	PhaseDone       = "done"
+)
+
+// DemandBucket is the number of past orders of one quantity
+type DemandBucket struct {
+	Quantity int `json:"quantity"`
+	Count    int `json:"count"`
+}
+
+// RecommendationRequest asks for the pack sizes that suit a demand best
+type RecommendationRequest struct {
+	// Demand is the histogram of order quantities
+	Demand []DemandBucket `json:"demand"`
+	// Sizes is the number of pack sizes to recommend
+	Sizes int `json:"sizes"`
+	// MinSize is the smallest pack size to consider, 1 when zero
+	MinSize int `json:"minSize,omitempty"`
+	// MaxSize is the largest pack size to consider, the largest order quantity when zero
+	MaxSize int `json:"maxSize,omitempty"`
+	// PackWeight is the number of over-shipped items one more pack per order is worth.
+	// When zero the overshoot is minimised first and the pack count only breaks ties.
+	PackWeight float64 `json:"packWeight,omitempty"`
+}
+
+// RecommendationScore is the expected result of an order of the demand
+type RecommendationScore struct {
+	// ExpectedOvershoot is the average number of items shipped on top of an order
+	ExpectedOvershoot float64 `json:"expectedOvershoot"`
+	// ExpectedPacks is the average number of packs an order is shipped in
+	ExpectedPacks float64 `json:"expectedPacks"`
+}
+
+// Recommendation is the best catalog found for a demand
+type Recommendation struct {
+	// Sizes are the recommended pack sizes, largest first
+	Sizes []int `json:"sizes"`
+	RecommendationScore
+	// Orders is the number of orders of the demand
+	Orders int64 `json:"orders"`
+	// Candidates is the number of pack sizes the search chose from
+	Candidates int `json:"candidates"`
+	// Evaluated is the number of catalogs evaluated
+	Evaluated int `json:"evaluated"`
+	// Exhaustive is set when every catalog of candidate sizes was evaluated, otherwise the
+	// result is the best a local search found
+	Exhaustive bool `json:"exhaustive"`
+}
+
+// RecommendationProgress reports on a running recommendation search
+type RecommendationProgress struct {
+	// Phase is the running phase of the search
+	Phase string `json:"phase"`
+	// Done is the number of catalogs the phase has evaluated
+	Done int `json:"done"`
+	// Total is the number of catalogs the phase evaluates, zero when not known in advance
+	Total int `json:"total"`
+	// Evaluated is the number of catalogs evaluated by all phases
+	Evaluated int `json:"evaluated"`
+	// Best are the best pack sizes found so far, largest first
+	Best []int `json:"best"`
+	RecommendationScore
+}
+
+// RecommendCatalog searches the set of pack sizes that minimises the expected overshoot and pack
+// count of the demand. Small searches try every catalog of candidate sizes; larger ones build a
+// catalog greedily and improve it by swapping and moving sizes until no change helps.
+// progress, when set, is called from time to time and when the search ends. The search stops
+// with the context; without progress nobody follows the search, so it also stops once it runs
+// out of the time budget of a calculation.
+func (a *App) RecommendCatalog(ctx context.Context, req RecommendationRequest, progress func(RecommendationProgress)) (*Recommendation, error) {
+	eval, err := a.newDemandEvaluator(&req)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = a.withBudget(ctx)
+		defer cancel()
+	}
+	candidates, step := recommendationCandidates(eval.demand, req.MinSize, req.MaxSize)
+	if len(candidates) < req.Sizes {
+		return nil, fmt.Errorf("%w: only %d pack sizes between %d and %d", ErrInvalidRecommendation, len(candidates), req.MinSize, req.MaxSize)
+	}
+
+	s := &recommendationSearch{
+		ctx:        ctx,
+		eval:       eval,
+		candidates: candidates,
+		k:          req.Sizes,
+		packWeight: req.PackWeight,
+		progress:   progress,
+	}
+	exhaustive := combinations(len(candidates), req.Sizes) <= maxExhaustiveCatalogs
+	if exhaustive {
+		err = s.exhaustive()
+	} else {
+		err = s.localSearch(step)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("recommendation stopped after %d catalogs: %w", s.evaluated, err)
+	}
+
+	s.startPhase(PhaseDone, 0)
+	return &Recommendation{
+		Sizes:               s.best,
+		RecommendationScore: eval.expected(s.bestScore),
+		Orders:              eval.orders,
+		Candidates:          len(candidates),
+		Evaluated:           s.evaluated,
+		Exhaustive:          exhaustive,
+	}, nil
+}
+
+// newDemandEvaluator validates the request, filling in the default bounds, and prepares the
+// evaluation of its demand
+func (a *App) newDemandEvaluator(req *RecommendationRequest) (*demandEvaluator, error) {
+	if req.Sizes < 1 || req.Sizes > MaxRecommendedSizes {
+		return nil, fmt.Errorf("%w: sizes must be between 1 and %d", ErrInvalidRecommendation, MaxRecommendedSizes)
+	}
+	if len(req.Demand) == 0 {
+		return nil, fmt.Errorf("%w: no demand", ErrInvalidRecommendation)
+	}
+	if req.PackWeight < 0 || math.IsNaN(req.PackWeight) || math.IsInf(req.PackWeight, 0) {
+		return nil, fmt.Errorf("%w: packWeight must not be negative", ErrInvalidRecommendation)
+	}
+
+	counts := make(map[int]int64)
+	largest := 0
+	for i, bucket := range req.Demand {
+		if bucket.Quantity <= 0 || bucket.Count <= 0 {
+			return nil, fmt.Errorf("%w: demand %d: quantity and count must be positive integers", ErrInvalidRecommendation, i+1)
+		}
+		counts[bucket.Quantity] += int64(bucket.Count)
+		largest = max(largest, bucket.Quantity)
+	}
+	if len(counts) > MaxDemandBuckets {
+		return nil, fmt.Errorf("%w: %d order quantities, at most %d are allowed", ErrInvalidRecommendation, len(counts), MaxDemandBuckets)
+	}
+
+	if req.MinSize == 0 {
+		req.MinSize = 1
+	}
+	if req.MaxSize == 0 {
+		req.MaxSize = min(largest, a.maxPackSize)
+	}
+	if req.MinSize < 0 || req.MaxSize < 0 {
+		return nil, fmt.Errorf("%w: %d to %d", ErrInvalidPackSize, req.MinSize, req.MaxSize)
+	}
+	if req.MaxSize > a.maxPackSize {
+		return nil, fmt.Errorf("%w: %d is above the maximum of %d", ErrPackSizeTooLarge, req.MaxSize, a.maxPackSize)
+	}
+	if req.MinSize > req.MaxSize {
+		return nil, fmt.Errorf("%w: minSize %d is above maxSize %d", ErrInvalidRecommendation, req.MinSize, req.MaxSize)
+	}
+	if largest > MaxRecommendationAmount-req.MaxSize {
+		return nil, fmt.Errorf("%w: the largest order quantity plus maxSize is above %d", ErrInvalidRecommendation, MaxRecommendationAmount)
+	}
+
+	eval := &demandEvaluator{demand: make([]DemandBucket, 0, len(counts))}
+	for quantity, count := range counts {
+		eval.demand = append(eval.demand, DemandBucket{Quantity: quantity, Count: int(count)})
+		eval.orders += count
+	}
+	// Largest quantity first, the order the evaluation walks the amounts in
+	sort.Slice(eval.demand, func(i, j int) bool { return eval.demand[i].Quantity > eval.demand[j].Quantity })
+	eval.packs = make([]int32, 0, largest+req.MaxSize)
+	return eval, nil
+}
+
+// recommendationCandidates returns the pack sizes the search chooses from, in ascending order,
+// and the distance between them. When the bounds hold too many sizes, half of the candidates
+// are the most frequent order quantities and the rest are spread evenly over the bounds.
+func recommendationCandidates(demand []DemandBucket, minSize, maxSize int) ([]int, int) {
+	if maxSize-minSize < maxRecommendationCandidates {
+		candidates := make([]int, 0, maxSize-minSize+1)
+		for size := minSize; size <= maxSize; size++ {
+			candidates = append(candidates, size)
+		}
+		return candidates, 1
+	}
+
+	frequent := append([]DemandBucket(nil), demand...)
+	sort.SliceStable(frequent, func(i, j int) bool { return frequent[i].Count > frequent[j].Count })
+	seen := make(map[int]bool, maxRecommendationCandidates)
+	for _, bucket := range frequent {
+		if len(seen) == maxRecommendationCandidates/2 {
+			break
+		}
+		if bucket.Quantity >= minSize && bucket.Quantity <= maxSize {
+			seen[bucket.Quantity] = true
+		}
+	}
+	points := maxRecommendationCandidates - len(seen)
+	step := ceilDiv(maxSize-minSize, points-1)
+	for size := minSize; size <= maxSize; size += step {
+		seen[size] = true
+	}
+	seen[maxSize] = true
+
+	candidates := make([]int, 0, len(seen))
+	for size := range seen {
+		candidates = append(candidates, size)
+	}
+	sort.Ints(candidates)
+	return candidates, step
+}
+
+// demandEvaluator scores catalogs against a demand. It runs the table DP of the dp strategy
+// over every amount up to the largest order quantity plus the largest pack size, and ships
+// every order like the solver does: the least items, then the least packs.
+type demandEvaluator struct {
+	// demand is merged by quantity, largest quantity first
+	demand []DemandBucket
+	orders int64
+	// packs is the table DP, reused by every evaluation
+	packs []int32
+}
+
+// demandScore adds up the overshoot and packs of every order of the demand
+type demandScore struct {
+	overshoot int64
+	packs     int64
+}
+
+const unreachablePacks = math.MaxInt32
+
+// evaluate scores a catalog of distinct sizes
+func (e *demandEvaluator) evaluate(sizes []int) demandScore {
+	largest := 0
+	for _, size := range sizes {
+		largest = max(largest, size)
+	}
+	limit := e.demand[0].Quantity + largest - 1
+
+	packs := e.packs[:limit+1]
+	packs[0] = 0
+	for amount := 1; amount <= limit; amount++ {
+		best := int32(unreachablePacks)
+		for _, size := range sizes {
+			if amount >= size && packs[amount-size] != unreachablePacks && packs[amount-size]+1 < best {
+				best = packs[amount-size] + 1
+			}
+		}
+		packs[amount] = best
+	}
+
+	// Walk down the amounts keeping the next reachable one, the shipment of every order
+	// below it. Any order can be shipped within a pack of the smallest size.
+	var score demandScore
+	next, b := limit, 0
+	for amount := limit; amount > 0 && b < len(e.demand); amount-- {
+		if packs[amount] != unreachablePacks {
+			next = amount
+		}
+		for ; b < len(e.demand) && e.demand[b].Quantity == amount; b++ {
+			count := int64(e.demand[b].Count)
+			score.overshoot += int64(next-amount) * count
+			score.packs += int64(packs[next]) * count
+		}
+	}
+	return score
+}
+
+// expected returns the score per order
+func (e *demandEvaluator) expected(score demandScore) RecommendationScore {
+	return RecommendationScore{
+		ExpectedOvershoot: float64(score.overshoot) / float64(e.orders),
+		ExpectedPacks:     float64(score.packs) / float64(e.orders),
+	}
+}
+
+// recommendationSearch keeps the state of a running recommendation
+type recommendationSearch struct {
+	ctx        context.Context
+	eval       *demandEvaluator
+	candidates []int
+	k          int
+	packWeight float64
+	progress   func(RecommendationProgress)
+
+	evaluated int
+	best      []int
+	bestScore demandScore
+
+	phase       string
+	done, total int
+	reported    time.Time
+}
+
+// better reports whether score a beats score b
+func (s *recommendationSearch) better(a, b demandScore) bool {
+	if s.packWeight > 0 {
+		va := float64(a.overshoot) + s.packWeight*float64(a.packs)
+		vb := float64(b.overshoot) + s.packWeight*float64(b.packs)
+		if va != vb {
+			return va < vb
+		}
+	} else if a.overshoot != b.overshoot {
+		return a.overshoot < b.overshoot
+	}
+	return a.packs < b.packs
+}
+
+// try evaluates a catalog, which becomes the best one when it has k sizes and beats it
+func (s *recommendationSearch) try(sizes []int) (demandScore, error) {
+	if err := interrupted(s.ctx); err != nil {
+		return demandScore{}, err
+	}
+	score := s.eval.evaluate(sizes)
+	s.evaluated++
+	s.done++
+	if len(sizes) == s.k && (s.best == nil || s.better(score, s.bestScore)) {
+		s.best = distinctDescending(sizes)
+		s.bestScore = score
+	}
+	s.report(false)
+	return score, nil
+}
+
+func (s *recommendationSearch) startPhase(phase string, total int) {
+	s.phase, s.done, s.total = phase, 0, total
+	s.report(true)
+}
+
+// report calls the progress callback, at most once per progressInterval unless forced
+func (s *recommendationSearch) report(force bool) {
+	if s.progress == nil || (!force && time.Since(s.reported) < progressInterval) {
+		return
+	}
+	s.reported = time.Now()
+	p := RecommendationProgress{Phase: s.phase, Done: s.done, Total: s.total, Evaluated: s.evaluated}
+	if s.best != nil {
+		p.Best = append([]int(nil), s.best...)
+		p.RecommendationScore = s.eval.expected(s.bestScore)
+	}
+	s.progress(p)
+}
+
+// exhaustive tries every catalog of k candidate sizes
+func (s *recommendationSearch) exhaustive() error {
+	n := len(s.candidates)
+	s.startPhase(PhaseExhaustive, combinations(n, s.k))
+	index := make([]int, s.k)
+	for i := range index {
+		index[i] = i
+	}
+	sizes := make([]int, s.k)
+	for {
+		for i, c := range index {
+			sizes[i] = s.candidates[c]
+		}
+		if _, err := s.try(sizes); err != nil {
+			return err
+		}
+
+		// Next combination of indexes in lexicographic order
+		i := s.k - 1
+		for i >= 0 && index[i] == n-s.k+i {
+			i--
+		}
+		if i < 0 {
+			return nil
+		}
+		index[i]++
+		for j := i + 1; j < s.k; j++ {
+			index[j] = index[j-1] + 1
+		}
+	}
+}
+
+// localSearch adds the best size one at a time, then swaps sizes for other candidates and
+// finally moves them by ever smaller steps below the distance between candidates, as long
+// as that improves the catalog
+func (s *recommendationSearch) localSearch(step int) error {
+	n := len(s.candidates)
+	s.startPhase(PhaseGreedy, s.k*n-s.k*(s.k-1)/2)
+	catalog := make([]int, 0, s.k)
+	for len(catalog) < s.k {
+		var pick int
+		var pickScore demandScore
+		for _, candidate := range s.candidates {
+			if contains(catalog, candidate) {
+				continue
+			}
+			score, err := s.try(append(catalog, candidate))
+			if err != nil {
+				return err
+			}
+			if pick == 0 || s.better(score, pickScore) {
+				pick, pickScore = candidate, score
+			}
+		}
+		catalog = append(catalog, pick)
+	}
+
+	for improved := true; improved; {
+		s.startPhase(PhaseSwap, s.k*(n-s.k))
+		improved = false
+		for i := range s.best {
+			for _, candidate := range s.candidates {
+				if contains(s.best, candidate) {
+					continue
+				}
+				ok, err := s.replace(i, candidate)
+				if err != nil {
+					return err
+				}
+				improved = improved || ok
+			}
+		}
+	}
+
+	s.startPhase(PhaseRefine, 0)
+	minSize, maxSize := s.candidates[0], s.candidates[n-1]
+	for move := step / 2; move > 0; move /= 2 {
+		for improved := true; improved; {
+			improved = false
+			for i := range s.best {
+				for _, size := range []int{s.best[i] - move, s.best[i] + move} {
+					if size < minSize || size > maxSize || contains(s.best, size) {
+						continue
+					}
+					ok, err := s.replace(i, size)
+					if err != nil {
+						return err
+					}
+					improved = improved || ok
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// replace tries the best catalog with its size at index i replaced, and reports whether that
+// catalog became the best one
+func (s *recommendationSearch) replace(i, size int) (bool, error) {
+	sizes := append([]int(nil), s.best...)
+	sizes[i] = size
+	before := s.bestScore
+	if _, err := s.try(sizes); err != nil {
+		return false, err
+	}
+	return s.better(s.bestScore, before), nil
+}
+
+func contains(sizes []int, size int) bool {
+	for _, s := range sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// combinations returns n choose k, capped just above maxExhaustiveCatalogs
+func combinations(n, k int) int {
+	c := 1
+	for i := 1; i <= k; i++ {
+		c = c * (n - k + i) / i
+		if c > maxExhaustiveCatalogs {
+			return maxExhaustiveCatalogs + 1
+		}
+	}
+	return c
+}
+
+// ParseDemand reads a demand histogram, an order quantity and optionally its number of orders
+// per line. Lines may be CSV records; blank lines and a header line are skipped.
+func ParseDemand(r io.Reader) ([]DemandBucket, error) {
+	var demand []DemandBucket
+	err := readRecords(r, func(number int, fields []string) error {
+		quantity, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %q is not an order quantity", ErrInvalidRecommendation, number, fields[0])
+		}
+		bucket := DemandBucket{Quantity: quantity, Count: 1}
+		if len(fields) > 1 && fields[1] != "" {
+			if bucket.Count, err = strconv.Atoi(fields[1]); err != nil {
+				return fmt.Errorf("%w: line %d: %q is not a number of orders", ErrInvalidRecommendation, number, fields[1])
+			}
+		}
+		demand = append(demand, bucket)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return demand, nil
+}
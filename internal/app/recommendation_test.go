@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemandEvaluator_MatchesSolver(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	for i := 0; i < 100; i++ {
+		var sizes []int
+		for len(sizes) < 1+rng.Intn(4) {
+			if size := 1 + rng.Intn(60); !contains(sizes, size) {
+				sizes = append(sizes, size)
+			}
+		}
+		var demand []DemandBucket
+		for j := 0; j < 1+rng.Intn(10); j++ {
+			demand = append(demand, DemandBucket{Quantity: 1 + rng.Intn(500), Count: 1 + rng.Intn(5)})
+		}
+
+		eval, err := NewApp(nil).newDemandEvaluator(&RecommendationRequest{Demand: demand, Sizes: 1, MaxSize: 60})
+		require.NoError(t, err)
+		score := eval.evaluate(sizes)
+
+		var want demandScore
+		for _, bucket := range demand {
+			result, err := dpSolver{}.Solve(context.Background(), bucket.Quantity, sizes)
+			require.NoError(t, err)
+			want.overshoot += int64(result.Overshoot * bucket.Count)
+			want.packs += int64(result.TotalPacks * bucket.Count)
+		}
+		require.Equal(t, want, score, "sizes %v demand %v", sizes, demand)
+	}
+}
+
+func TestApp_RecommendCatalog(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            RecommendationRequest
+		wantSizes      []int
+		wantOvershoot  float64
+		wantPacks      float64
+		wantExhaustive bool
+	}{
+		{
+			name: "exhaustive search",
+			req: RecommendationRequest{
+				Demand: []DemandBucket{{Quantity: 6, Count: 1}, {Quantity: 9, Count: 1}, {Quantity: 15, Count: 2}},
+				Sizes:  2,
+			},
+			// 6, 9 and 6+9 ship every order exactly in the fewest packs
+			wantSizes:      []int{9, 6},
+			wantPacks:      1.5,
+			wantExhaustive: true,
+		},
+		{
+			name: "bounds",
+			req: RecommendationRequest{
+				Demand:  []DemandBucket{{Quantity: 6, Count: 1}, {Quantity: 9, Count: 1}, {Quantity: 15, Count: 2}},
+				Sizes:   1,
+				MinSize: 4,
+				MaxSize: 8,
+			},
+			// 5 ships 10, 10 and 15: 4+1 items over in 2+2+3+3 packs
+			wantSizes:      []int{5},
+			wantOvershoot:  1.25,
+			wantPacks:      2.5,
+			wantExhaustive: true,
+		},
+		{
+			name: "pack weight",
+			req: RecommendationRequest{
+				Demand:     []DemandBucket{{Quantity: 6, Count: 1}, {Quantity: 9, Count: 1}, {Quantity: 15, Count: 2}},
+				Sizes:      1,
+				MaxSize:    8,
+				PackWeight: 10,
+			},
+			// Fewer packs of 8 are worth the overshoot: 8, 16 and 16 ship 2+7+1+1 items over
+			wantSizes:      []int{8},
+			wantOvershoot:  2.75,
+			wantPacks:      1.75,
+			wantExhaustive: true,
+		},
+		{
+			name: "local search",
+			req: RecommendationRequest{
+				Demand: []DemandBucket{
+					{Quantity: 250, Count: 50},
+					{Quantity: 500, Count: 30},
+					{Quantity: 1000, Count: 15},
+					{Quantity: 2000, Count: 5},
+				},
+				Sizes: 4,
+			},
+			wantSizes: []int{2000, 1000, 500, 250},
+			wantPacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last RecommendationProgress
+			recommendation, err := NewApp(nil).RecommendCatalog(context.Background(), tt.req, func(p RecommendationProgress) {
+				last = p
+			})
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantSizes, recommendation.Sizes)
+			require.InDelta(t, tt.wantOvershoot, recommendation.ExpectedOvershoot, 1e-9)
+			require.InDelta(t, tt.wantPacks, recommendation.ExpectedPacks, 1e-9)
+			require.Equal(t, tt.wantExhaustive, recommendation.Exhaustive)
+			require.Equal(t, PhaseDone, last.Phase)
+			require.Equal(t, recommendation.Evaluated, last.Evaluated)
+			require.Equal(t, tt.wantSizes, last.Best)
+		})
+	}
+}
+
+func TestApp_RecommendCatalog_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewApp(nil).RecommendCatalog(ctx, RecommendationRequest{
+		Demand: []DemandBucket{{Quantity: 251, Count: 1}},
+		Sizes:  2,
+	}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestApp_RecommendCatalog_Budget(t *testing.T) {
+	req := RecommendationRequest{Demand: []DemandBucket{{Quantity: 251, Count: 1}, {Quantity: 7919, Count: 2}}, Sizes: 3}
+	app := NewApp(nil, WithBudget(Budget{Timeout: time.Nanosecond}))
+
+	// A search nobody follows stops with the time budget
+	_, err := app.RecommendCatalog(context.Background(), req, nil)
+	require.ErrorIs(t, err, ErrTimeBudgetExceeded)
+
+	// One reporting its progress runs until its caller stops it
+	recommendation, err := app.RecommendCatalog(context.Background(), req, func(RecommendationProgress) {})
+	require.NoError(t, err)
+	require.Len(t, recommendation.Sizes, 3)
+}
+
+func TestApp_RecommendCatalog_Errors(t *testing.T) {
+	demand := []DemandBucket{{Quantity: 251, Count: 1}}
+	tests := []struct {
+		name    string
+		req     RecommendationRequest
+		wantErr error
+	}{
+		{name: "no demand", req: RecommendationRequest{Sizes: 2}, wantErr: ErrInvalidRecommendation},
+		{name: "no sizes", req: RecommendationRequest{Demand: demand}, wantErr: ErrInvalidRecommendation},
+		{name: "too many sizes", req: RecommendationRequest{Demand: demand, Sizes: MaxRecommendedSizes + 1}, wantErr: ErrInvalidRecommendation},
+		{name: "zero count", req: RecommendationRequest{Demand: []DemandBucket{{Quantity: 251}}, Sizes: 1}, wantErr: ErrInvalidRecommendation},
+		{name: "negative pack weight", req: RecommendationRequest{Demand: demand, Sizes: 1, PackWeight: -1}, wantErr: ErrInvalidRecommendation},
+		{name: "min above max", req: RecommendationRequest{Demand: demand, Sizes: 1, MinSize: 300}, wantErr: ErrInvalidRecommendation},
+		{name: "fewer candidates than sizes", req: RecommendationRequest{Demand: demand, Sizes: 3, MinSize: 10, MaxSize: 11}, wantErr: ErrInvalidRecommendation},
+		{name: "negative bound", req: RecommendationRequest{Demand: demand, Sizes: 1, MinSize: -5}, wantErr: ErrInvalidPackSize},
+		{name: "max above the maximum pack size", req: RecommendationRequest{Demand: demand, Sizes: 1, MaxSize: DefaultMaxPackSize + 1}, wantErr: ErrPackSizeTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewApp(nil).RecommendCatalog(context.Background(), tt.req, nil)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRecommendationCandidates(t *testing.T) {
+	candidates, step := recommendationCandidates(nil, 5, 9)
+	require.Equal(t, []int{5, 6, 7, 8, 9}, candidates)
+	require.Equal(t, 1, step)
+
+	demand := []DemandBucket{{Quantity: 251, Count: 9}, {Quantity: 20_000, Count: 9}}
+	candidates, step = recommendationCandidates(demand, 1, 10_000)
+	require.LessOrEqual(t, len(candidates), maxRecommendationCandidates+1)
+	require.Contains(t, candidates, 251)
+	require.NotContains(t, candidates, 20_000)
+	require.Equal(t, 1, candidates[0])
+	require.Equal(t, 10_000, candidates[len(candidates)-1])
+	require.Greater(t, step, 1)
+}
+
+func TestParseDemand(t *testing.T) {
+	demand, err := ParseDemand(strings.NewReader("quantity,orders\n251,4\n\n1200\n500;2\n"))
+	require.NoError(t, err)
+	require.Equal(t, []DemandBucket{{Quantity: 251, Count: 4}, {Quantity: 1200, Count: 1}, {Quantity: 500, Count: 2}}, demand)
+
+	_, err = ParseDemand(strings.NewReader("251,4\n500,many\n"))
+	require.ErrorIs(t, err, ErrInvalidRecommendation)
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"container/heap"
+	"context"
+	"math"
+)
+
+// maxExactAmount caps the amounts of a table DP, so its memory stays bounded whatever the order.
+// Larger orders below the residue threshold are searched by branch and bound instead.
+const maxExactAmount = 1 << 20
+
+// residueTable captures the periodic structure of an unbounded pack catalog.
+//
+// Every combination of packs splits into some packs of the largest size L and a remainder
+// made of the other sizes. For each remainder r modulo L the table keeps the combination of
+// the other sizes that minimises packs*L - items, which is the cheapest way to reach any
+// amount T ≡ r (mod L) once T is at least the amount of that combination: the rest is
+// filled with L-packs. Above threshold every order is therefore answered in O(L) time, and
+// the table itself takes O(L) memory regardless of the order quantity.
+type residueTable struct {
+	// sizes are the distinct pack sizes in descending order
+	sizes []int
+	// largest is the largest pack size, the period of the table
+	largest int
+	// weight is packs*largest - amount of the best combination per remainder, -1 when unreachable
+	weight []int
+	// amount is the number of items in the best combination per remainder
+	amount []int
+	// last is the pack size added last on the way to the remainder
+	last []int
+	// threshold is the smallest order quantity answered from the table alone
+	threshold int
+}
+
+// newResidueTable builds the residue table for the given pack sizes.
+// Sizes must be positive; duplicates are ignored.
+func newResidueTable(ctx context.Context, packSizes []int) (*residueTable, error) {
+	sizes := distinctDescending(packSizes)
+	largest := sizes[0]
+	if err := reserve(ctx, tableBytes(largest, 3)); err != nil {
+		return nil, err
+	}
+
+	t := &residueTable{
+		sizes:   sizes,
+		largest: largest,
+		weight:  make([]int, largest),
+		amount:  make([]int, largest),
+		last:    make([]int, largest),
+	}
+	for r := range t.weight {
+		t.weight[r] = -1
+	}
+
+	// Dijkstra over remainders: adding a pack p moves from r to (r+p) mod L and increases the
+	// weight by L-p >= 0. Ties on weight are broken by the lower amount, which keeps the
+	// threshold small and favours larger packs.
+	t.weight[0] = 0
+	queue := &residueQueue{{remainder: 0}}
+	check := cancelCheck{ctx: ctx}
+	for queue.Len() > 0 {
+		if check.stopped() {
+			return nil, check.err
+		}
+		item := heap.Pop(queue).(residueItem)
+		if item.weight != t.weight[item.remainder] || item.amount != t.amount[item.remainder] {
+			// Stale entry, the remainder was reached more cheaply in the meantime
+			continue
+		}
+		for _, pack := range sizes[1:] {
+			next := (item.remainder + pack) % largest
+			weight := item.weight + largest - pack
+			amount := item.amount + pack
+			if t.weight[next] == -1 || weight < t.weight[next] ||
+				(weight == t.weight[next] && amount < t.amount[next]) {
+				t.weight[next] = weight
+				t.amount[next] = amount
+				t.last[next] = pack
+				heap.Push(queue, residueItem{remainder: next, weight: weight, amount: amount})
+			}
+		}
+	}
+
+	for r, weight := range t.weight {
+		if weight != -1 && t.amount[r] > t.threshold {
+			t.threshold = t.amount[r]
+		}
+	}
+
+	return t, nil
+}
+
+// solve finds the least amount of items, and then the least amount of packs, covering the order
+func (t *residueTable) solve(ctx context.Context, orderQuantity int) (*CalculationResult, error) {
+	if orderQuantity > math.MaxInt-t.largest {
+		return nil, errOrderTooLarge
+	}
+	if orderQuantity < t.threshold {
+		// Small orders may need fewer packs than the remainder combinations allow
+		return solveBelowThreshold(ctx, orderQuantity, t.sizes)
+	}
+
+	// Every amount at or above the threshold with a reachable remainder can be shipped,
+	// and one of the next L amounts is a multiple of L
+	shipped := orderQuantity
+	for t.weight[shipped%t.largest] == -1 {
+		shipped++
+	}
+
+	remainder := shipped % t.largest
+	packs := t.combination(remainder)
+	if fill := (shipped - t.amount[remainder]) / t.largest; fill > 0 {
+		packs[t.largest] += fill
+	}
+
+	return newCalculationResult(orderQuantity, packs), nil
+}
+
+// combination rebuilds the best combination of packs for a remainder
+func (t *residueTable) combination(remainder int) map[int]int {
+	packs := make(map[int]int)
+	for remainder != 0 {
+		pack := t.last[remainder]
+		packs[pack]++
+		remainder = (remainder - pack + t.largest) % t.largest
+	}
+	return packs
+}
+
+// solveBelowThreshold answers an order below the residue threshold, which grows with the square
+// of the largest pack. Orders whose table DP stays within maxExactAmount get one, larger ones are
+// searched by branch and bound, so memory depends on the pack sizes only.
+// sizes must be sorted in descending order.
+func solveBelowThreshold(ctx context.Context, orderQuantity int, sizes []int) (*CalculationResult, error) {
+	if orderQuantity > maxExactAmount-sizes[0]+1 {
+		return branchAndBoundSolver{}.Solve(ctx, orderQuantity, sizes)
+	}
+	return solveExact(ctx, orderQuantity, sizes)
+}
+
+// solveExact runs the table DP over every amount up to orderQuantity+largest-1.
+// Memory is linear in the order quantity, so it is only used for orders up to maxExactAmount.
+// sizes must be sorted in descending order.
+func solveExact(ctx context.Context, orderQuantity int, sizes []int) (*CalculationResult, error) {
+	// A shipment of orderQuantity+largest items or more still covers the order after removing
+	// any single pack, so the least amount of items to ship is always below this limit
+	t, err := newExactTable(ctx, sizes, orderQuantity+sizes[0]-1)
+	if err != nil {
+		return nil, err
+	}
+	return t.solve(orderQuantity)
+}
+
+// exactTable is the table DP behind solveExact. It can be extended to larger amounts, so a
+// table kept for a catalog answers every order whose limit it covers.
+type exactTable struct {
+	// sizes are the distinct pack sizes in descending order
+	sizes []int
+	// dp stores the minimum number of packs needed to ship exactly [i] items
+	dp []int
+	// choice stores the last chosen package size to make up [i] items
+	choice []int
+}
+
+// newExactTable builds the table DP for every amount up to limit
+func newExactTable(ctx context.Context, sizes []int, limit int) (*exactTable, error) {
+	if err := reserve(ctx, tableBytes(limit, 2)); err != nil {
+		return nil, err
+	}
+	t := &exactTable{
+		sizes:  sizes,
+		dp:     make([]int, 1, limit+1),
+		choice: make([]int, 1, limit+1),
+	}
+	if err := t.fill(ctx, limit); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// limit returns the largest amount covered by the table
+func (t *exactTable) limit() int {
+	return len(t.dp) - 1
+}
+
+// extend fills in the table up to limit, amounts already covered are kept
+func (t *exactTable) extend(ctx context.Context, limit int) error {
+	if err := reserve(ctx, tableBytes(limit-t.limit(), 2)); err != nil {
+		return err
+	}
+	return t.fill(ctx, limit)
+}
+
+// fill computes the amounts up to limit. When interrupted the amounts computed so far are kept,
+// so the table stays valid for them.
+func (t *exactTable) fill(ctx context.Context, limit int) error {
+	check := cancelCheck{ctx: ctx}
+	for i := len(t.dp); i <= limit; i++ {
+		if check.stopped() {
+			return check.err
+		}
+		// Initialize with large number (infinity)
+		packs, choice := math.MaxInt32, 0
+		for _, pack := range t.sizes {
+			if i >= pack && t.dp[i-pack]+1 < packs {
+				// Update with the minimum number of packs
+				packs = t.dp[i-pack] + 1
+				// Store the pack size used
+				choice = pack
+			}
+		}
+		t.dp = append(t.dp, packs)
+		t.choice = append(t.choice, choice)
+	}
+	return nil
+}
+
+// solve answers an order from the table, which must cover orderQuantity+largest-1
+func (t *exactTable) solve(orderQuantity int) (*CalculationResult, error) {
+	limit := orderQuantity + t.sizes[0] - 1
+
+	// The first reachable amount at or above the order is the least amount of items to ship
+	shipped := -1
+	for i := orderQuantity; i <= limit; i++ {
+		if t.dp[i] != math.MaxInt32 {
+			shipped = i
+			break
+		}
+	}
+	if shipped < 0 {
+		return nil, errCannotFulfill
+	}
+
+	packs := make(map[int]int)
+	remaining := shipped
+	for remaining > 0 {
+		// choice - which packs were used to fulfill the order
+		// remaining - track of how much of the shipment is still left to be packed
+		pack := t.choice[remaining]
+		packs[pack]++
+		remaining -= pack
+	}
+
+	return newCalculationResult(orderQuantity, packs), nil
+}
+
+// residueItem is a priority queue entry of the residue table construction
+type residueItem struct {
+	remainder int
+	weight    int
+	amount    int
+}
+
+// residueQueue is a min-heap of residue items ordered by weight, then amount
+type residueQueue []residueItem
+
+func (q residueQueue) Len() int { return len(q) }
+
+func (q residueQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight < q[j].weight
+	}
+	return q[i].amount < q[j].amount
+}
+
+func (q residueQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *residueQueue) Push(x any) { *q = append(*q, x.(residueItem)) }
+
+func (q *residueQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// packItems returns the total number of items in a combination
+func packItems(packs map[int]int) int {
+	total := 0
+	for size, count := range packs {
+		total += size * count
+	}
+	return total
+}
+
+func TestResidueTable_MatchesExactDP(t *testing.T) {
+	catalogs := [][]int{
+		{250, 500, 1000, 2000, 5000},
+		{23, 31, 53},
+		{6, 9, 20},
+		{4, 10},
+		{7},
+		{3, 5, 7},
+		{12, 18, 45, 50},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		catalog := make([]int, 1+rng.Intn(4))
+		for j := range catalog {
+			catalog[j] = 1 + rng.Intn(60)
+		}
+		catalogs = append(catalogs, catalog)
+	}
+
+	for _, catalog := range catalogs {
+		table, err := newResidueTable(context.Background(), catalog)
+		require.NoError(t, err)
+		limit := 2*table.threshold + 3*table.largest
+
+		// Minimum packs per exact amount, and the first reachable amount at or above each amount
+		minPacks := make([]int, limit+table.largest)
+		for i := 1; i < len(minPacks); i++ {
+			minPacks[i] = -1
+			for _, pack := range catalog {
+				if i >= pack && minPacks[i-pack] != -1 && (minPacks[i] == -1 || minPacks[i-pack]+1 < minPacks[i]) {
+					minPacks[i] = minPacks[i-pack] + 1
+				}
+			}
+		}
+		next := make([]int, len(minPacks)+1)
+		next[len(minPacks)] = -1
+		for i := len(minPacks) - 1; i >= 0; i-- {
+			next[i] = next[i+1]
+			if minPacks[i] != -1 {
+				next[i] = i
+			}
+		}
+
+		for n := 1; n <= limit; n++ {
+			got, err := table.solve(context.Background(), n)
+			require.NoError(t, err)
+
+			require.Equal(t, next[n], got.Shipped, "catalog %v, order %d", catalog, n)
+			require.Equal(t, minPacks[next[n]], got.TotalPacks, "catalog %v, order %d", catalog, n)
+			require.Equal(t, got.Shipped, packItems(got.Packs()), "catalog %v, order %d", catalog, n)
+			require.Equal(t, got.Shipped-n, got.Overshoot)
+		}
+	}
+}
+
+func TestResidueTable_LargeOrders(t *testing.T) {
+	table, err := newResidueTable(context.Background(), []int{250, 500, 1000, 2000, 5000})
+	require.NoError(t, err)
+
+	result, err := table.solve(context.Background(), 5000*1_000_000_000_000 + 1)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{5000: 1_000_000_000_000, 250: 1}, result.Packs())
+	require.Equal(t, 249, result.Overshoot)
+
+	result, err = table.solve(context.Background(), math.MaxInt - 5000)
+	require.NoError(t, err)
+	require.Equal(t, result.Shipped, packItems(result.Packs()))
+
+	_, err = table.solve(context.Background(), math.MaxInt - 1)
+	require.ErrorIs(t, err, errOrderTooLarge)
+}
+
+func TestResidueTable_Threshold(t *testing.T) {
+	// Only the largest size: every order is answered from the table
+	table, err := newResidueTable(context.Background(), []int{10})
+	require.NoError(t, err)
+	require.Equal(t, 0, table.threshold)
+
+	// With gcd 2 odd remainders stay unreachable
+	table, err = newResidueTable(context.Background(), []int{4, 10})
+	require.NoError(t, err)
+	for r := 1; r < 10; r += 2 {
+		require.Equal(t, -1, table.weight[r])
+	}
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoCarrier is returned when no carrier rate table serves the zone, or the carrier, of a
+// calculation
+var ErrNoCarrier = errors.New("no carrier for the shipment")
+
+// shippingAlternatives is the number of the best combinations by items and packs ModeShipping
+// prices on top of those the policies choose
+const shippingAlternatives = 20
+
+// ShippingBreakdown is the cost of sending the packs of a result with a carrier
+type ShippingBreakdown struct {
+	Carrier string `json:"carrier"`
+	Zone    string `json:"zone"`
+	// Parcels are the costs of the parcels, one per carton in the order of the cartons
+	Parcels []ParcelCost `json:"parcels"`
+	// Packs is the price plus the handling cost of the packs
+	Packs float64 `json:"packs"`
+	// Shipping is the cost of all parcels
+	Shipping float64 `json:"shipping"`
+	// Landed is the cost of the packs plus the cost of the parcels
+	Landed float64 `json:"landed"`
+}
+
+// ParcelCost is the cost of sending a single carton
+type ParcelCost struct {
+	// Carton is the carton name
+	Carton string `json:"carton"`
+	// Weight is the weight of the parcel in grams
+	Weight int `json:"weight"`
+	// MaxWeight is the weight band the parcel is charged by
+	MaxWeight int `json:"maxWeight"`
+	// Rate is the price of the weight band
+	Rate float64 `json:"rate"`
+	// Fee is the parcel fee of the carrier
+	Fee float64 `json:"fee"`
+	// Total is the rate plus the fee
+	Total float64 `json:"total"`
+}
+
+// shipment is the packs of a result put in cartons and sent with a carrier
+type shipment struct {
+	loads     []CartonLoad
+	breakdown *ShippingBreakdown
+	// landed is the cost of the packs plus the parcels in cents
+	landed int64
+}
+
+// checkShipping validates the shipping options of a calculation: a carrier and ModeShipping
+// need a zone, and a zone needs rate tables
+func (a *App) checkShipping(opts CalculateOptions) error {
+	if opts.Zone == "" {
+		if opts.Mode == ModeShipping {
+			return fmt.Errorf("%w: %s needs a zone", ErrInvalidMode, ModeShipping)
+		}
+		if opts.Carrier != "" {
+			return fmt.Errorf("%w: a carrier needs a zone", ErrInvalidMode)
+		}
+		return nil
+	}
+	if len(a.rates) == 0 {
+		return fmt.Errorf("%w: no carrier rate tables are configured", ErrNoCarrier)
+	}
+	return nil
+}
+
+// cheapestShipment puts the packs in cartons for every carrier serving the zone of opts, only
+// the carrier of opts if it names one, and returns the shipment with the lowest landed cost.
+// packCost is the cost of the packs in cents.
+func (a *App) cheapestShipment(packs []cartonPack, packCost int64, cartons []Carton, opts CalculateOptions) (*shipment, error) {
+	var best *shipment
+	var lastErr error
+	for _, table := range a.rates {
+		if opts.Carrier != "" && table.Carrier != opts.Carrier {
+			continue
+		}
+		if _, ok := table.Zones[opts.Zone]; !ok {
+			continue
+		}
+		s, err := ship(packs, packCost, cartons, table, opts.Zone)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if best == nil || s.landed < best.landed {
+			best = s
+		}
+	}
+	switch {
+	case best != nil:
+		return best, nil
+	case lastErr != nil:
+		return nil, lastErr
+	case opts.Carrier != "":
+		return nil, fmt.Errorf("%w: carrier %q does not ship to zone %q", ErrNoCarrier, opts.Carrier, opts.Zone)
+	default:
+		return nil, fmt.Errorf("%w: no carrier ships to zone %q", ErrNoCarrier, opts.Zone)
+	}
+}
+
+// ship puts the packs in cartons carrying at most the heaviest parcel the carrier takes in the
+// zone, and prices every carton as a parcel
+func ship(packs []cartonPack, packCost int64, cartons []Carton, table RateTable, zone string) (*shipment, error) {
+	limit := table.maxWeight(zone)
+	capped := make([]Carton, 0, len(cartons))
+	for _, carton := range cartons {
+		carton.MaxWeight = min(carton.MaxWeight, limit)
+		capped = append(capped, carton)
+	}
+	loads, err := packCartons(packs, capped)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", table.Carrier, err)
+	}
+
+	breakdown := &ShippingBreakdown{Carrier: table.Carrier, Zone: zone, Parcels: make([]ParcelCost, 0, len(loads))}
+	fee := cents(table.ParcelFee)
+	var shipping int64
+	for _, load := range loads {
+		// No parcel is heavier than the heaviest band
+		band, _ := table.band(zone, load.Weight)
+		rate := cents(band.Price)
+		breakdown.Parcels = append(breakdown.Parcels, ParcelCost{
+			Carton:    load.Carton,
+			Weight:    load.Weight,
+			MaxWeight: band.MaxWeight,
+			Rate:      float64(rate) / 100,
+			Fee:       float64(fee) / 100,
+			Total:     float64(rate+fee) / 100,
+		})
+		shipping += rate + fee
+	}
+	breakdown.Packs = float64(packCost) / 100
+	breakdown.Shipping = float64(shipping) / 100
+	breakdown.Landed = float64(packCost+shipping) / 100
+	return &shipment{loads: loads, breakdown: breakdown, landed: packCost + shipping}, nil
+}
+
+// shipResult puts the packs of a result in cartons when opts ask for cartons or give a zone,
+// and with a zone sends them with the carrier of the lowest landed cost
+func (a *App) shipResult(s *catalogSnapshot, productID int, result *CalculationResult, opts CalculateOptions) error {
+	if !opts.Cartonize && opts.Zone == "" {
+		return nil
+	}
+	packs := s.cartonPacks(productID, result.Lines)
+	if opts.Zone == "" {
+		var err error
+		result.Cartons, err = a.cartonize(packs)
+		return err
+	}
+	cartons, err := a.repo.GetCartons()
+	if err != nil {
+		return err
+	}
+	shipment, err := a.cheapestShipment(packs, cents(result.Cost.Total), cartons, opts)
+	if err != nil {
+		return err
+	}
+	result.Cartons, result.Shipping = shipment.loads, shipment.breakdown
+	return nil
+}
+
+// calculateShipping calculates the packs of an order in ModeShipping. The combinations every
+// policy chooses, and the best ones by items and packs when neither stock, pack constraints nor
+// an undershoot tolerance limit the order, are put in cartons and priced with every carrier, and
+// the one with the lowest landed cost is shipped. Ties go to fewer packs, then fewer items.
+func (a *App) calculateShipping(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error) {
+	if err := a.checkShipping(opts); err != nil {
+		return nil, err
+	}
+	if opts.Policy != "" {
+		return nil, fmt.Errorf("%w: %s chooses the packs by landed cost, not by a policy", ErrInvalidMode, ModeShipping)
+	}
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: %s does not support alternatives", ErrInvalidMode, ModeShipping)
+	}
+	solver, err := a.solverFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.withBudget(ctx)
+	defer cancel()
+
+	productID := productOrDefault(opts.ProductID)
+	snapshot, limits, err := a.loadCatalog(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := snapshot.shippingCandidates(ctx, orderQuantity, solver, limits, opts)
+	if err != nil {
+		return nil, err
+	}
+	cartons, err := a.repo.GetCartons()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *CalculationResult
+	var bestShipment *shipment
+	for _, candidate := range candidates {
+		s, shipErr := a.cheapestShipment(snapshot.cartonPacks(productID, candidate.Lines), cents(candidate.Cost.Total), cartons, opts)
+		if shipErr != nil {
+			err = shipErr
+			continue
+		}
+		if best == nil || s.landed < bestShipment.landed || (s.landed == bestShipment.landed &&
+			(candidate.TotalPacks < best.TotalPacks || (candidate.TotalPacks == best.TotalPacks && candidate.Shipped < best.Shipped))) {
+			best, bestShipment = candidate, s
+		}
+	}
+	if best == nil {
+		return nil, err
+	}
+	best.Mode = ModeShipping
+	best.Policy = nil
+	best.Stock = stockOf(best.Catalog, limits)
+	best.Cartons, best.Shipping = bestShipment.loads, bestShipment.breakdown
+	return best, nil
+}
+
+// shippingCandidates returns the distinct combinations of packs ModeShipping chooses from, see
+// calculateShipping
+func (s *catalogSnapshot) shippingCandidates(ctx context.Context, orderQuantity int, solver Solver, limits map[int]int, opts CalculateOptions) ([]*CalculationResult, error) {
+	var candidates []*CalculationResult
+	seen := make(map[string]bool)
+	add := func(result *CalculationResult) {
+		if key := fmt.Sprint(result.Lines); !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, result)
+		}
+	}
+
+	var firstErr error
+	for _, name := range PolicyNames() {
+		policyOpts := opts
+		policyOpts.Mode, policyOpts.Policy = "", name
+		result, err := s.fulfill(ctx, orderQuantity, solver, limits, policies[name], policyOpts)
+		if err != nil && (errors.Is(err, ErrBudgetExceeded) || interrupted(ctx) != nil) {
+			return nil, err
+		}
+		if err != nil {
+			// Some policies do not go with the solver of opts
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		add(result)
+	}
+	if len(candidates) == 0 {
+		return nil, firstErr
+	}
+
+	if len(limits) > 0 || len(s.constraints) > 0 || len(opts.Constraints) > 0 || opts.Tolerance > 0 {
+		return candidates, nil
+	}
+	alternatives, err := findAlternatives(ctx, orderQuantity, s.sizes, shippingAlternatives)
+	if err != nil {
+		return nil, err
+	}
+	for _, alternative := range alternatives {
+		packs := make(map[int]int, len(alternative.Lines))
+		for _, line := range alternative.Lines {
+			packs[line.Size] = line.Count
+		}
+		result := newCalculationResult(orderQuantity, packs)
+		result.Catalog = append([]int(nil), s.sizes...)
+		result.Solver = alternativesSolver
+		result.Cost = newCostBreakdown(result.Lines, s.costs)
+		if len(s.nesting) > 0 {
+			result.Tree = packTree(result.Lines, s.nesting)
+		}
+		add(result)
+	}
+	return candidates, nil
+}
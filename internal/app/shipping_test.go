@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// parcelCo is cheap for light parcels, bulkCo for heavy ones
+	parcelCo = RateTable{Carrier: "ParcelCo", ParcelFee: 1, Zones: map[string][]RateBand{
+		"domestic": {{MaxWeight: 2000, Price: 3}, {MaxWeight: 5000, Price: 10}, {MaxWeight: 30000, Price: 20}},
+	}}
+	bulkCo = RateTable{Carrier: "BulkCo", Zones: map[string][]RateBand{
+		"domestic": {{MaxWeight: 30000, Price: 5}},
+		"eu":       {{MaxWeight: 30000, Price: 15}},
+	}}
+)
+
+func TestApp_Calculate_Shipping(t *testing.T) {
+	// Two packs of 250 cost less than one of 500 but weigh more
+	catalog := []repo.Package{
+		{ID: 1, Size: 250, Price: 3, Length: 100, Width: 150, Height: 100, Weight: 1200},
+		{ID: 2, Size: 500, Price: 10, Length: 200, Width: 150, Height: 100, Weight: 1500},
+	}
+	tests := []struct {
+		name        string
+		opts        CalculateOptions
+		wantPacks   map[int]int
+		wantCarrier string
+		wantLanded  float64
+		wantSolver  string
+		wantErr     error
+	}{
+		{
+			name:        "lowest landed cost",
+			opts:        CalculateOptions{Mode: ModeShipping, Zone: "domestic"},
+			wantPacks:   map[int]int{250: 2},
+			wantCarrier: "BulkCo",
+			wantLanded:  11,
+			wantSolver:  "bnb",
+		},
+		{
+			name:        "lowest landed cost with a carrier",
+			opts:        CalculateOptions{Mode: ModeShipping, Zone: "domestic", Carrier: "ParcelCo"},
+			wantPacks:   map[int]int{500: 1},
+			wantCarrier: "ParcelCo",
+			wantLanded:  14,
+			wantSolver:  "dp",
+		},
+		{
+			name:        "cheapest carrier for the packs of the policy",
+			opts:        CalculateOptions{Zone: "domestic"},
+			wantPacks:   map[int]int{500: 1},
+			wantCarrier: "ParcelCo",
+			wantLanded:  14,
+			wantSolver:  "dp",
+		},
+		{
+			name:    "carrier not serving the zone",
+			opts:    CalculateOptions{Mode: ModeShipping, Zone: "eu", Carrier: "ParcelCo"},
+			wantErr: ErrNoCarrier,
+		},
+		{
+			name:    "no zone",
+			opts:    CalculateOptions{Mode: ModeShipping},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name:    "policy",
+			opts:    CalculateOptions{Mode: ModeShipping, Zone: "domestic", Policy: "packs-first"},
+			wantErr: ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+			mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+			mockRepo.On("GetCartons").Return([]repo.Carton{largeCarton}, nil)
+
+			result, err := NewApp(mockRepo, WithRateTables([]RateTable{parcelCo, bulkCo})).Calculate(context.Background(), 500, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			require.Equal(t, tt.wantCarrier, result.Shipping.Carrier)
+			require.Equal(t, tt.wantLanded, result.Shipping.Landed)
+			require.Equal(t, tt.wantSolver, result.Solver)
+			require.Len(t, result.Cartons, len(result.Shipping.Parcels))
+		})
+	}
+}
+
+func TestApp_Calculate_Shipping_Alternative(t *testing.T) {
+	// Two packs of 500 overshoot the order, but are the only combination light enough for the
+	// cheap band; neither policy chooses them
+	catalog := []repo.Package{
+		{ID: 1, Size: 250, Price: 3, Length: 100, Width: 150, Height: 100, Weight: 3000},
+		{ID: 2, Size: 500, Price: 10, Length: 200, Width: 150, Height: 100, Weight: 1500},
+	}
+	lightCo := RateTable{Carrier: "LightCo", Zones: map[string][]RateBand{
+		"domestic": {{MaxWeight: 3000, Price: 1}, {MaxWeight: 30000, Price: 100}},
+	}}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return(catalog, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+	mockRepo.On("GetCartons").Return([]repo.Carton{largeCarton}, nil)
+
+	result, err := NewApp(mockRepo, WithRateTables([]RateTable{lightCo})).
+		Calculate(context.Background(), 750, CalculateOptions{Mode: ModeShipping, Zone: "domestic", Solver: "dp"})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{500: 2}, result.Packs())
+	require.Equal(t, 21.0, result.Shipping.Landed)
+	// The combination was listed, not solved for with the solver of the request
+	require.Equal(t, alternativesSolver, result.Solver)
+}
+
+func TestShip(t *testing.T) {
+	// ParcelCo takes parcels of at most 2000 grams to the islands
+	table := RateTable{Carrier: "ParcelCo", ParcelFee: 0.5, Zones: map[string][]RateBand{
+		"islands": {{MaxWeight: 1000, Price: 4}, {MaxWeight: 2000, Price: 6.25}},
+	}}
+	packs := []cartonPack{{productID: 1, size: 250, count: 3, dims: boxOf250}}
+
+	got, err := ship(packs, 900, []Carton{largeCarton}, table, "islands")
+	require.NoError(t, err)
+	require.Equal(t, &ShippingBreakdown{
+		Carrier: "ParcelCo",
+		Zone:    "islands",
+		Parcels: []ParcelCost{
+			{Carton: "large", Weight: 2000, MaxWeight: 2000, Rate: 6.25, Fee: 0.5, Total: 6.75},
+			{Carton: "large", Weight: 1000, MaxWeight: 1000, Rate: 4, Fee: 0.5, Total: 4.5},
+		},
+		Packs:    9,
+		Shipping: 11.25,
+		Landed:   20.25,
+	}, got.breakdown)
+	require.Equal(t, int64(2025), got.landed)
+}
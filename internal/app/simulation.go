@@ -0,0 +1,316 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSimulation is returned for a simulation without orders or candidate catalog,
+// or with an order quantity that is not positive
+var ErrInvalidSimulation = errors.New("invalid simulation")
+
+// MaxSimulationOrders caps the number of order sizes replayed by a single simulation
+const MaxSimulationOrders = 100_000
+
+// SimulationRequest replays order sizes against the current catalog of a product and a candidate one
+type SimulationRequest struct {
+	// ProductID is the product whose stored catalog is the current one, DefaultProductID when zero
+	ProductID int `json:"productId"`
+	// Current replaces the stored catalog of the product when set
+	Current []Package `json:"current,omitempty"`
+	// Candidate is the proposed catalog
+	Candidate []Package `json:"candidate"`
+	// Orders are the order quantities to replay
+	Orders []int `json:"orders"`
+	// Mode is the first objective of the calculations, see CalculateOptions
+	Mode string `json:"mode,omitempty"`
+	// Policy is the name of the policy choosing the packs, the configured default is used when empty
+	Policy string `json:"policy,omitempty"`
+	// Solver is the name of the packing strategy, the configured default is used when empty
+	Solver string `json:"solver,omitempty"`
+}
+
+// SimulationReport compares the results of the current and the candidate catalog side by side.
+// Every diff is the candidate value minus the current one.
+type SimulationReport struct {
+	// Orders is the number of replayed orders
+	Orders int `json:"orders"`
+	// Mode is the objective the packs were chosen for
+	Mode string `json:"mode"`
+	// Policy is the order of objectives and the tie-break the packs were chosen by
+	Policy Policy `json:"policy"`
+	// CurrentCatalog are the current pack sizes, largest first
+	CurrentCatalog []int `json:"currentCatalog"`
+	// CandidateCatalog are the candidate pack sizes, largest first
+	CandidateCatalog []int `json:"candidateCatalog"`
+	// Totals add up the results of all orders
+	Totals SimulationDiff `json:"totals"`
+	// Sizes are the packs used per size over all orders, largest size first
+	Sizes []SizeUsage `json:"sizes"`
+	// Lines are the results per order, in the order of the request
+	Lines []SimulationLine `json:"lines"`
+}
+
+// SimulationOutcome is the result of one or more orders with one catalog
+type SimulationOutcome struct {
+	// Shipped is the number of items sent out
+	Shipped int `json:"shipped"`
+	// Overshoot is the number of items sent on top of the ordered quantities
+	Overshoot int `json:"overshoot"`
+	// TotalPacks is the number of packs sent out
+	TotalPacks int `json:"totalPacks"`
+	// Cost is the price plus the handling cost of the packs
+	Cost float64 `json:"cost"`
+}
+
+// SimulationDiff compares the outcome of the current and the candidate catalog
+type SimulationDiff struct {
+	Current   SimulationOutcome `json:"current"`
+	Candidate SimulationOutcome `json:"candidate"`
+	Diff      SimulationOutcome `json:"diff"`
+}
+
+// SimulationLine compares the results of a single order
+type SimulationLine struct {
+	// Order is the ordered quantity
+	Order int `json:"order"`
+	SimulationDiff
+}
+
+// SizeUsage is the number of packs of a size used over all orders
+type SizeUsage struct {
+	Size      int `json:"size"`
+	Current   int `json:"current"`
+	Candidate int `json:"candidate"`
+	Diff      int `json:"diff"`
+}
+
+// Simulate replays the order sizes against the current catalog of the product and the candidate
+// catalog and reports the results side by side. Stock levels are not taken into account.
+// Every order is calculated within a budget of its own, and the simulation stops when ctx is done.
+func (a *App) Simulate(ctx context.Context, req SimulationRequest) (*SimulationReport, error) {
+	if len(req.Orders) == 0 {
+		return nil, fmt.Errorf("%w: no orders", ErrInvalidSimulation)
+	}
+	if len(req.Orders) > MaxSimulationOrders {
+		return nil, fmt.Errorf("%w: %d orders, at most %d are allowed", ErrInvalidSimulation, len(req.Orders), MaxSimulationOrders)
+	}
+	for i, order := range req.Orders {
+		if order <= 0 {
+			return nil, fmt.Errorf("%w: order %d: quantity must be a positive integer", ErrInvalidSimulation, i+1)
+		}
+	}
+	if len(req.Candidate) == 0 {
+		return nil, fmt.Errorf("%w: the candidate catalog is empty", ErrInvalidSimulation)
+	}
+	if err := a.checkPackages(req.Candidate); err != nil {
+		return nil, fmt.Errorf("candidate: %w", err)
+	}
+	candidate, err := newCatalogSnapshot(0, req.Candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	var current *catalogSnapshot
+	if req.Current != nil {
+		if err := a.checkPackages(req.Current); err != nil {
+			return nil, fmt.Errorf("current: %w", err)
+		}
+		current, err = newCatalogSnapshot(0, req.Current)
+	} else {
+		current, err = a.snapshot(ctx, productOrDefault(req.ProductID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(current.sizes) == 0 {
+		return nil, fmt.Errorf("%w: the current catalog is empty", ErrInvalidSimulation)
+	}
+
+	opts := CalculateOptions{Mode: req.Mode, Policy: req.Policy, Solver: req.Solver}
+	policy, err := a.policyFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	solver, err := a.solverFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SimulationReport{
+		Orders:           len(req.Orders),
+		Mode:             policy.mode(),
+		Policy:           policy,
+		CurrentCatalog:   append([]int(nil), current.sizes...),
+		CandidateCatalog: append([]int(nil), candidate.sizes...),
+		Lines:            make([]SimulationLine, 0, len(req.Orders)),
+	}
+	usage := make(map[int]*SizeUsage)
+	for _, catalog := range [][]int{current.sizes, candidate.sizes} {
+		for _, size := range catalog {
+			usage[size] = &SizeUsage{Size: size}
+		}
+	}
+	var currentCost, candidateCost int64
+	for i, order := range req.Orders {
+		orderCtx, cancel := a.withBudget(ctx)
+		currentResult, err := current.calculate(orderCtx, order, solver, nil, policy, opts)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("order %d (%d) with the current catalog: %w", i+1, order, err)
+		}
+		candidateResult, err := candidate.calculate(orderCtx, order, solver, nil, policy, opts)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("order %d (%d) with the candidate catalog: %w", i+1, order, err)
+		}
+
+		line := SimulationLine{Order: order, SimulationDiff: newSimulationDiff(
+			simulationOutcome(currentResult), simulationOutcome(candidateResult))}
+		report.Lines = append(report.Lines, line)
+		report.Totals.Current.add(line.Current)
+		report.Totals.Candidate.add(line.Candidate)
+		currentCost += cents(line.Current.Cost)
+		candidateCost += cents(line.Candidate.Cost)
+		for _, packs := range currentResult.Lines {
+			usage[packs.Size].Current += packs.Count
+		}
+		for _, packs := range candidateResult.Lines {
+			usage[packs.Size].Candidate += packs.Count
+		}
+	}
+	// Costs are added up in cents, so many orders do not pile up rounding errors
+	report.Totals.Current.Cost = float64(currentCost) / 100
+	report.Totals.Candidate.Cost = float64(candidateCost) / 100
+	report.Totals = newSimulationDiff(report.Totals.Current, report.Totals.Candidate)
+
+	sizes := make([]int, 0, len(usage))
+	for size := range usage {
+		sizes = append(sizes, size)
+	}
+	for _, size := range distinctDescending(sizes) {
+		u := usage[size]
+		u.Diff = u.Candidate - u.Current
+		report.Sizes = append(report.Sizes, *u)
+	}
+	return report, nil
+}
+
+func simulationOutcome(result *CalculationResult) SimulationOutcome {
+	return SimulationOutcome{
+		Shipped:    result.Shipped,
+		Overshoot:  result.Overshoot,
+		TotalPacks: result.TotalPacks,
+		Cost:       result.Cost.Total,
+	}
+}
+
+func newSimulationDiff(current, candidate SimulationOutcome) SimulationDiff {
+	return SimulationDiff{
+		Current:   current,
+		Candidate: candidate,
+		Diff: SimulationOutcome{
+			Shipped:    candidate.Shipped - current.Shipped,
+			Overshoot:  candidate.Overshoot - current.Overshoot,
+			TotalPacks: candidate.TotalPacks - current.TotalPacks,
+			Cost:       float64(cents(candidate.Cost)-cents(current.Cost)) / 100,
+		},
+	}
+}
+
+// add adds the item and pack counts of another outcome, costs are added up by the caller
+func (o *SimulationOutcome) add(other SimulationOutcome) {
+	o.Shipped += other.Shipped
+	o.Overshoot += other.Overshoot
+	o.TotalPacks += other.TotalPacks
+}
+
+// simulationCSVHeader are the columns of the CSV report
+var simulationCSVHeader = []string{
+	"row",
+	"current_shipped", "candidate_shipped", "shipped_diff",
+	"current_overshoot", "candidate_overshoot", "overshoot_diff",
+	"current_packs", "candidate_packs", "packs_diff",
+	"current_cost", "candidate_cost", "cost_diff",
+}
+
+// WriteCSV writes the report as CSV: a row per order, a total row and a row per pack size
+// with its pack counts only
+func (r *SimulationReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(simulationCSVHeader); err != nil {
+		return err
+	}
+	for _, line := range r.Lines {
+		if err := out.Write(simulationCSVRow(strconv.Itoa(line.Order), line.SimulationDiff)); err != nil {
+			return err
+		}
+	}
+	if err := out.Write(simulationCSVRow("total", r.Totals)); err != nil {
+		return err
+	}
+	for _, size := range r.Sizes {
+		row := make([]string, len(simulationCSVHeader))
+		row[0] = fmt.Sprintf("size %d", size.Size)
+		row[7], row[8], row[9] = strconv.Itoa(size.Current), strconv.Itoa(size.Candidate), strconv.Itoa(size.Diff)
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func simulationCSVRow(label string, d SimulationDiff) []string {
+	money := func(amount float64) string { return strconv.FormatFloat(amount, 'f', 2, 64) }
+	return []string{
+		label,
+		strconv.Itoa(d.Current.Shipped), strconv.Itoa(d.Candidate.Shipped), strconv.Itoa(d.Diff.Shipped),
+		strconv.Itoa(d.Current.Overshoot), strconv.Itoa(d.Candidate.Overshoot), strconv.Itoa(d.Diff.Overshoot),
+		strconv.Itoa(d.Current.TotalPacks), strconv.Itoa(d.Candidate.TotalPacks), strconv.Itoa(d.Diff.TotalPacks),
+		money(d.Current.Cost), money(d.Candidate.Cost), money(d.Diff.Cost),
+	}
+}
+
+// ParseOrderSizes reads order quantities, one per line. Lines may be CSV records with the
+// quantity in the first column; blank lines and a header line are skipped.
+func ParseOrderSizes(r io.Reader) ([]int, error) {
+	var orders []int
+	err := readRecords(r, func(number int, fields []string) error {
+		order, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %q is not an order quantity", ErrInvalidSimulation, number, fields[0])
+		}
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// readRecords calls fn with the trimmed fields of every line split on commas, semicolons and
+// tabs. Blank lines are skipped, and so is the first line when fn fails on it, as a header.
+func readRecords(r io.Reader, fn func(number int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool { return r == ',' || r == ';' || r == '\t' })
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) == 0 || fields[0] == "" {
+			continue
+		}
+		if err := fn(number, fields); err != nil && number != 1 {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Simulate(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{
+		{ID: 1, Size: 250, Price: 1},
+		{ID: 2, Size: 500, Price: 1.5},
+		{ID: 3, Size: 1000, Price: 2.5},
+	}, nil)
+
+	app := NewApp(mockRepo)
+	report, err := app.Simulate(context.Background(), SimulationRequest{
+		Candidate: []Package{{Size: 300, Price: 1.2}, {Size: 1000, Price: 2.5}},
+		Orders:    []int{251, 1000, 1200},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, report.Orders)
+	require.Equal(t, ModeItems, report.Mode)
+	require.Equal(t, []int{1000, 500, 250}, report.CurrentCatalog)
+	require.Equal(t, []int{1000, 300}, report.CandidateCatalog)
+
+	// 251: 500 against 300, 1000: 1000 against 1000, 1200: 1250 against 4 x 300
+	require.Equal(t, SimulationLine{Order: 251, SimulationDiff: SimulationDiff{
+		Current:   SimulationOutcome{Shipped: 500, Overshoot: 249, TotalPacks: 1, Cost: 1.5},
+		Candidate: SimulationOutcome{Shipped: 300, Overshoot: 49, TotalPacks: 1, Cost: 1.2},
+		Diff:      SimulationOutcome{Shipped: -200, Overshoot: -200, TotalPacks: 0, Cost: -0.3},
+	}}, report.Lines[0])
+	require.Equal(t, SimulationDiff{
+		Current:   SimulationOutcome{Shipped: 2750, Overshoot: 299, TotalPacks: 4, Cost: 7.5},
+		Candidate: SimulationOutcome{Shipped: 2500, Overshoot: 49, TotalPacks: 6, Cost: 8.5},
+		Diff:      SimulationOutcome{Shipped: -250, Overshoot: -250, TotalPacks: 2, Cost: 1},
+	}, report.Totals)
+	require.Equal(t, []SizeUsage{
+		{Size: 1000, Current: 2, Candidate: 1, Diff: -1},
+		{Size: 500, Current: 1, Candidate: 0, Diff: -1},
+		{Size: 300, Current: 0, Candidate: 5, Diff: 5},
+		{Size: 250, Current: 1, Candidate: 0, Diff: -1},
+	}, report.Sizes)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_Simulate_Errors(t *testing.T) {
+	candidate := []Package{{Size: 300}}
+	tests := []struct {
+		name    string
+		req     SimulationRequest
+		wantErr error
+	}{
+		{
+			name:    "no orders",
+			req:     SimulationRequest{Candidate: candidate},
+			wantErr: ErrInvalidSimulation,
+		},
+		{
+			name:    "non-positive order",
+			req:     SimulationRequest{Candidate: candidate, Orders: []int{10, 0}},
+			wantErr: ErrInvalidSimulation,
+		},
+		{
+			name:    "empty candidate",
+			req:     SimulationRequest{Orders: []int{10}},
+			wantErr: ErrInvalidSimulation,
+		},
+		{
+			name:    "invalid candidate size",
+			req:     SimulationRequest{Candidate: []Package{{Size: -3}}, Orders: []int{10}},
+			wantErr: ErrInvalidPackSize,
+		},
+		{
+			name:    "empty current catalog",
+			req:     SimulationRequest{Current: []Package{}, Candidate: candidate, Orders: []int{10}},
+			wantErr: ErrInvalidSimulation,
+		},
+		{
+			name:    "unknown mode",
+			req:     SimulationRequest{Current: candidate, Candidate: candidate, Orders: []int{10}, Mode: "fastest"},
+			wantErr: ErrInvalidMode,
+		},
+		{
+			name:    "unknown solver",
+			req:     SimulationRequest{Current: candidate, Candidate: candidate, Orders: []int{10}, Solver: "simplex"},
+			wantErr: ErrUnknownSolver,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewApp(nil).Simulate(context.Background(), tt.req)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSimulationReport_WriteCSV(t *testing.T) {
+	report, err := NewApp(nil).Simulate(context.Background(), SimulationRequest{
+		Current:   []Package{{Size: 250, Price: 1}, {Size: 500, Price: 1.5}},
+		Candidate: []Package{{Size: 300, Price: 1.2}},
+		Orders:    []int{251},
+		Mode:      ModeCost,
+	})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteCSV(&out))
+	require.Equal(t, strings.Join([]string{
+		"row,current_shipped,candidate_shipped,shipped_diff,current_overshoot,candidate_overshoot,overshoot_diff,current_packs,candidate_packs,packs_diff,current_cost,candidate_cost,cost_diff",
+		"251,500,300,-200,249,49,-200,1,1,0,1.50,1.20,-0.30",
+		"total,500,300,-200,249,49,-200,1,1,0,1.50,1.20,-0.30",
+		"size 500,,,,,,,1,0,-1,,,",
+		"size 300,,,,,,,0,1,1,,,",
+		"size 250,,,,,,,0,0,0,,,",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestParseOrderSizes(t *testing.T) {
+	orders, err := ParseOrderSizes(strings.NewReader("quantity,date\n251,2024-01-02\n\n12001;x\n 500 \n"))
+	require.NoError(t, err)
+	require.Equal(t, []int{251, 12001, 500}, orders)
+
+	_, err = ParseOrderSizes(strings.NewReader("251\nmany\n"))
+	require.ErrorIs(t, err, ErrInvalidSimulation)
+}
@@ -0,0 +1,274 @@
+package app
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// CacheStats describes how well the in-memory catalog snapshots serve calculations
+type CacheStats struct {
+	// Version is the catalog version, increased whenever a catalog changes
+	Version uint64 `json:"version"`
+	// Snapshots is the number of product catalogs currently cached
+	Snapshots int `json:"snapshots"`
+	// Hits is the number of calculations served from a cached catalog
+	Hits int64 `json:"hits"`
+	// Misses is the number of calculations that had to load the catalog
+	Misses int64 `json:"misses"`
+	// HitRate is the share of calculations served from a cached catalog, 0 before the first one
+	HitRate float64 `json:"hitRate"`
+	// Rebuilds is the number of catalog snapshots built
+	Rebuilds int64 `json:"rebuilds"`
+	// LastRebuildMs is the time the last snapshot took to load and precompute, in milliseconds
+	LastRebuildMs float64 `json:"lastRebuildMs"`
+	// AverageRebuildMs is the average time a snapshot took to load and precompute, in milliseconds
+	AverageRebuildMs float64 `json:"averageRebuildMs"`
+}
+
+// catalogSnapshot is the pack catalog of a product as of a catalog version, along with the
+// state the packing strategies precomputed for it
+type catalogSnapshot struct {
+	version uint64
+	// packages are the stored packages of the catalog
+	packages []Package
+	// sizes are the distinct pack sizes in descending order
+	sizes []int
+	costs map[int]packCost
+	// constraints are the pack constraints stored with the packages by size
+	constraints map[int]PackConstraint
+	// nesting is the content of the packs holding other packs by size
+	nesting map[int]innerPack
+	// dimensions are the dimensions of the packs by size
+	dimensions map[int]packDimensions
+
+	mu sync.Mutex
+	// prepared are the strategies bound to this catalog by name
+	prepared map[string]Solver
+}
+
+// newCatalogSnapshot builds the snapshot of a stored catalog
+func newCatalogSnapshot(version uint64, catalog []Package) (*catalogSnapshot, error) {
+	packSizes := make([]int, 0, len(catalog))
+	for _, pkg := range catalog {
+		if err := checkPackSize(pkg.Size); err != nil {
+			return nil, err
+		}
+		packSizes = append(packSizes, pkg.Size)
+	}
+	return &catalogSnapshot{
+		version:     version,
+		packages:    catalog,
+		sizes:       distinctDescending(packSizes),
+		costs:       catalogCosts(catalog),
+		constraints: catalogConstraints(catalog),
+		nesting:     catalogNesting(catalog),
+		dimensions:  catalogDimensions(catalog),
+		prepared:    make(map[string]Solver),
+	}, nil
+}
+
+// solver returns the strategy prepared for this catalog, preparing it on first use.
+// Strategies without state per catalog are returned as is.
+func (s *catalogSnapshot) solver(ctx context.Context, solver Solver) (Solver, error) {
+	p, ok := solver.(preparer)
+	if !ok || len(s.sizes) == 0 {
+		return solver, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prepared, ok := s.prepared[solver.Name()]
+	if !ok {
+		var err error
+		if prepared, err = p.prepare(ctx, s.sizes); err != nil {
+			// Another calculation may have a larger budget, so the strategy is prepared again then
+			return nil, err
+		}
+		s.prepared[solver.Name()] = prepared
+	}
+	return prepared, nil
+}
+
+// preparer is implemented by packing strategies that can precompute their work for a catalog
+type preparer interface {
+	// prepare returns the strategy bound to the given distinct pack sizes in descending order.
+	// The bound strategy ignores the pack sizes passed to Solve.
+	prepare(ctx context.Context, sizes []int) (Solver, error)
+}
+
+// catalogCache keeps a snapshot per product catalog until the catalog changes
+type catalogCache struct {
+	mu        sync.Mutex
+	version   uint64
+	snapshots map[int]*catalogSnapshot
+
+	hits        int64
+	misses      int64
+	rebuilds    int64
+	rebuildTime time.Duration
+	lastRebuild time.Duration
+}
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{snapshots: make(map[int]*catalogSnapshot)}
+}
+
+// invalidate drops the snapshot of a product and moves to the next catalog version,
+// so snapshots still being built from the old catalog are not kept
+func (c *catalogCache) invalidate(productID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	delete(c.snapshots, productID)
+}
+
+// invalidateAll drops the snapshots of every product
+func (c *catalogCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	c.snapshots = make(map[int]*catalogSnapshot)
+}
+
+// cached returns the cached snapshot of a product without counting a hit, nil when there is none
+func (c *catalogCache) cached(productID int) *catalogSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshots[productID]
+}
+
+// snapshot returns the catalog snapshot of a product, loading and precomputing it when
+// there is none for the current catalog version
+func (a *App) snapshot(ctx context.Context, productID int) (*catalogSnapshot, error) {
+	c := a.cache
+	c.mu.Lock()
+	snapshot, ok := c.snapshots[productID]
+	version := c.version
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	c.mu.Unlock()
+	if ok {
+		return snapshot, nil
+	}
+
+	start := time.Now()
+	catalog, err := a.repo.GetCatalog(productID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot, err = newCatalogSnapshot(version, catalog); err != nil {
+		return nil, err
+	}
+	if _, err := snapshot.solver(ctx, a.solver); err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	log.Printf("Built catalog snapshot (product: %d, version: %d, sizes: %d) in %s", productID, version, len(snapshot.sizes), elapsed)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebuilds++
+	c.rebuildTime += elapsed
+	c.lastRebuild = elapsed
+	if c.version == version {
+		c.snapshots[productID] = snapshot
+	}
+	return snapshot, nil
+}
+
+// CacheStats returns the hit rate and rebuild times of the catalog snapshots
+func (a *App) CacheStats() CacheStats {
+	c := a.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Version:       c.version,
+		Snapshots:     len(c.snapshots),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Rebuilds:      c.rebuilds,
+		LastRebuildMs: milliseconds(c.lastRebuild),
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	if c.rebuilds > 0 {
+		stats.AverageRebuildMs = milliseconds(c.rebuildTime / time.Duration(c.rebuilds))
+	}
+	return stats
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// preparedDP is the dp strategy bound to a catalog. Orders at or above the residue threshold
+// are answered from the residue table, smaller ones from a table DP that is kept and extended
+// as larger orders come in, so repeated and nearby order sizes reuse the work. The table stops
+// at maxExactAmount, larger orders below the threshold are searched by branch and bound.
+type preparedDP struct {
+	residue *residueTable
+
+	mu    sync.RWMutex
+	exact *exactTable
+	// exactCap is the largest amount the kept table DP is extended to
+	exactCap int
+}
+
+func (dpSolver) prepare(ctx context.Context, sizes []int) (Solver, error) {
+	residue, err := newResidueTable(ctx, sizes)
+	if err != nil {
+		return nil, err
+	}
+	// No order below the threshold needs amounts beyond threshold+largest-1
+	exactCap := maxExactAmount
+	if residue.threshold <= math.MaxInt-residue.largest {
+		exactCap = min(exactCap, residue.threshold+residue.largest-1)
+	}
+	exact, err := newExactTable(ctx, residue.sizes, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedDP{
+		residue:  residue,
+		exact:    exact,
+		exactCap: exactCap,
+	}, nil
+}
+
+func (*preparedDP) Name() string { return "dp" }
+
+func (p *preparedDP) Solve(ctx context.Context, orderQuantity int, _ []int) (*CalculationResult, error) {
+	if orderQuantity >= p.residue.threshold || orderQuantity > math.MaxInt-p.residue.largest {
+		return p.residue.solve(ctx, orderQuantity)
+	}
+
+	limit := orderQuantity + p.residue.largest - 1
+	if limit > p.exactCap {
+		return solveBelowThreshold(ctx, orderQuantity, p.residue.sizes)
+	}
+
+	p.mu.RLock()
+	if p.exact.limit() >= limit {
+		defer p.mu.RUnlock()
+		return p.exact.solve(orderQuantity)
+	}
+	p.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Grow at least twofold so a run of increasing orders does not extend the table every time.
+	// An interrupted extension keeps the amounts it got to for later calculations.
+	if err := p.exact.extend(ctx, max(limit, min(2*p.exact.limit(), p.exactCap))); err != nil {
+		return nil, err
+	}
+	return p.exact.solve(orderQuantity)
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Calculate_CachesCatalog(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}}, nil).Once()
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+
+	app := NewApp(mockRepo)
+	for _, n := range []int{251, 251, 501} {
+		_, err := app.Calculate(context.Background(), n, CalculateOptions{})
+		require.NoError(t, err)
+	}
+
+	stats := app.CacheStats()
+	require.Equal(t, int64(2), stats.Hits)
+	require.Equal(t, int64(1), stats.Misses)
+	require.Equal(t, int64(1), stats.Rebuilds)
+	require.Equal(t, 1, stats.Snapshots)
+	require.InDelta(t, 2.0/3, stats.HitRate, 1e-9)
+
+	// Adding a package invalidates the snapshot, the next calculation sees the new size
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}}, nil).Once()
+	mockRepo.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: DefaultWarehouseID, Size: 1000}).Return(nil)
+	require.NoError(t, app.AddPackage(Package{Size: 1000}))
+	require.Equal(t, uint64(1), app.CacheStats().Version)
+	require.Zero(t, app.CacheStats().Snapshots)
+
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}, {ID: 2, Size: 500}, {ID: 3, Size: 1000}}, nil).Once()
+	result, err := app.Calculate(context.Background(), 1000, CalculateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1000: 1}, result.Packs())
+	require.Equal(t, int64(2), app.CacheStats().Rebuilds)
+
+	// The package ID does not tell the product, deleting one drops every snapshot
+	mockRepo.On("DeletePackageById", "3").Return(nil)
+	require.NoError(t, app.DeletePackage("3"))
+	require.Zero(t, app.CacheStats().Snapshots)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_Calculate_SnapshotPerProduct(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 250}}, nil).Once()
+	mockRepo.On("GetCatalog", 2).Return([]repo.Package{{ID: 2, Size: 6}, {ID: 3, Size: 9}}, nil).Once()
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{}, nil)
+	mockRepo.On("GetStock", 2).Return([]repo.StockLevel{}, nil)
+
+	app := NewApp(mockRepo)
+	for i := 0; i < 2; i++ {
+		result, err := app.Calculate(context.Background(), 10, CalculateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, map[int]int{250: 1}, result.Packs())
+
+		result, err = app.Calculate(context.Background(), 10, CalculateOptions{ProductID: 2})
+		require.NoError(t, err)
+		require.Equal(t, map[int]int{6: 2}, result.Packs())
+	}
+	require.Equal(t, 2, app.CacheStats().Snapshots)
+
+	mockRepo.On("DeleteProduct", 2).Return(nil)
+	require.NoError(t, app.DeleteProduct(2))
+	require.Equal(t, 1, app.CacheStats().Snapshots)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_Calculate_InvalidCachedCatalog(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 0}}, nil)
+
+	app := NewApp(mockRepo)
+	_, err := app.Calculate(context.Background(), 10, CalculateOptions{})
+	require.ErrorIs(t, err, ErrInvalidPackSize)
+	require.Zero(t, app.CacheStats().Snapshots)
+}
+
+func TestPreparedDP_MatchesSolver(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for _, catalog := range [][]int{
+		{250, 500, 1000, 2000, 5000},
+		{23, 31, 53},
+		{6, 9, 20},
+		{4, 6},
+	} {
+		prepared, err := dpSolver{}.prepare(context.Background(), distinctDescending(catalog))
+		require.NoError(t, err)
+		// Random order sizes make the kept table DP grow and answer from what it covers
+		for i := 0; i < 500; i++ {
+			n := 1 + rng.Intn(3*catalog[len(catalog)-1]*catalog[0])
+			want, wantErr := dpSolver{}.Solve(context.Background(), n, catalog)
+			got, err := prepared.Solve(context.Background(), n, nil)
+			if wantErr != nil {
+				require.ErrorIs(t, err, errCannotFulfill)
+				continue
+			}
+			require.NoError(t, err)
+			require.Equal(t, want.Shipped, got.Shipped, "catalog %v, order %d", catalog, n)
+			require.Equal(t, want.TotalPacks, got.TotalPacks, "catalog %v, order %d", catalog, n)
+		}
+	}
+}
+
+func TestPreparedDP_Concurrent(t *testing.T) {
+	catalog := []int{23, 31, 53}
+	prepared, err := dpSolver{}.prepare(context.Background(), distinctDescending(catalog))
+	require.NoError(t, err)
+
+	done := make(chan error)
+	for w := 0; w < 8; w++ {
+		go func(w int) {
+			for n := 1 + w; n < 1500; n += 8 {
+				want, _ := dpSolver{}.Solve(context.Background(), n, catalog)
+				got, err := prepared.Solve(context.Background(), n, nil)
+				if err == nil && (got.Shipped != want.Shipped || got.TotalPacks != want.TotalPacks) {
+					err = errCannotFulfill
+				}
+				if err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}(w)
+	}
+	for w := 0; w < 8; w++ {
+		require.NoError(t, <-done)
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownSolver is returned when a packing strategy name is not registered
+var ErrUnknownSolver = errors.New("unknown solver")
+
+// DefaultSolver is the packing strategy used when none is configured
+const DefaultSolver = "dp"
+
+// Solver finds a combination of packs covering an order.
+// Implementations receive a positive order quantity and a non-empty list of positive pack sizes,
+// and must not modify the pack sizes slice. They stop with the reason, see interrupted, as soon
+// as the context is done, and reserve the memory of their tables from its budget.
+type Solver interface {
+	// Name returns the strategy name used in configuration and requests
+	Name() string
+	// Solve returns the packs to ship for the order
+	Solve(ctx context.Context, orderQuantity int, packSizes []int) (*CalculationResult, error)
+}
+
+// solvers holds every available packing strategy by name
+var solvers = map[string]Solver{
+	"dp":     dpSolver{},
+	"greedy": greedySolver{},
+	"bnb":    branchAndBoundSolver{},
+}
+
+// SolverByName returns the packing strategy registered under the given name
+func SolverByName(name string) (Solver, error) {
+	solver, ok := solvers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownSolver, name, strings.Join(SolverNames(), ", "))
+	}
+	return solver, nil
+}
+
+// SolverNames returns the names of all packing strategies in alphabetical order
+func SolverNames() []string {
+	names := make([]string, 0, len(solvers))
+	for name := range solvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dpSolver is the exact dynamic programming strategy. Orders above the residue threshold
+// are answered from the residue table, smaller ones from the table DP or, past maxExactAmount,
+// by branch and bound.
+// It is the best fit for catalogs with a moderate largest pack size.
+type dpSolver struct{}
+
+func (dpSolver) Name() string { return "dp" }
+
+func (dpSolver) Solve(ctx context.Context, orderQuantity int, packSizes []int) (*CalculationResult, error) {
+	t, err := newResidueTable(ctx, packSizes)
+	if err != nil {
+		return nil, err
+	}
+	return t.solve(ctx, orderQuantity)
+}
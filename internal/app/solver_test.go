@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkCatalogs are catalog shapes the strategies are compared on
+var benchmarkCatalogs = []struct {
+	name  string
+	sizes []int
+}{
+	{name: "classic", sizes: []int{250, 500, 1000, 2000, 5000}},
+	{name: "coprime", sizes: []int{23, 31, 53}},
+	{name: "dense", sizes: []int{3, 5, 7, 11, 13}},
+	{name: "wide", sizes: []int{7, 997, 10007}},
+	{name: "single", sizes: []int{1000}},
+}
+
+func TestSolverByName(t *testing.T) {
+	for _, name := range SolverNames() {
+		solver, err := SolverByName(name)
+		require.NoError(t, err)
+		require.Equal(t, name, solver.Name())
+	}
+
+	_, err := SolverByName("simplex")
+	require.ErrorIs(t, err, ErrUnknownSolver)
+}
+
+func TestSolvers_CrossCheck(t *testing.T) {
+	catalogs := make([][]int, 0, len(benchmarkCatalogs))
+	for _, catalog := range benchmarkCatalogs {
+		catalogs = append(catalogs, catalog.sizes)
+	}
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 30; i++ {
+		catalog := make([]int, 1+rng.Intn(5))
+		for j := range catalog {
+			catalog[j] = 1 + rng.Intn(80)
+		}
+		catalogs = append(catalogs, catalog)
+	}
+
+	orders := []int{1, 2, 3, 7, 12, 99, 100, 101, 251, 501, 999, 1234, 12001, 99999, 500000, 1_000_000_007, 5_000_000_000_001}
+	for i := 0; i < 50; i++ {
+		orders = append(orders, 1+rng.Intn(20000))
+	}
+
+	for _, catalog := range catalogs {
+		for _, n := range orders {
+			want, err := dpSolver{}.Solve(context.Background(), n, catalog)
+			require.NoError(t, err)
+
+			exact, err := branchAndBoundSolver{}.Solve(context.Background(), n, catalog)
+			require.NoError(t, err)
+			require.Equal(t, want.Shipped, exact.Shipped, "bnb: catalog %v, order %d", catalog, n)
+			require.Equal(t, want.TotalPacks, exact.TotalPacks, "bnb: catalog %v, order %d", catalog, n)
+			require.Equal(t, exact.Shipped, packItems(exact.Packs()), "bnb: catalog %v, order %d", catalog, n)
+
+			heuristic, err := greedySolver{}.Solve(context.Background(), n, catalog)
+			require.NoError(t, err)
+			require.Equal(t, heuristic.Shipped, packItems(heuristic.Packs()), "greedy: catalog %v, order %d", catalog, n)
+			require.GreaterOrEqual(t, heuristic.Shipped, n, "greedy: catalog %v, order %d", catalog, n)
+			if heuristic.Shipped == want.Shipped {
+				require.GreaterOrEqual(t, heuristic.TotalPacks, want.TotalPacks, "greedy: catalog %v, order %d", catalog, n)
+			} else {
+				require.Greater(t, heuristic.Shipped, want.Shipped, "greedy: catalog %v, order %d", catalog, n)
+			}
+		}
+	}
+}
+
+func TestSolvers_DoNotModifyPackSizes(t *testing.T) {
+	for _, name := range SolverNames() {
+		solver, err := SolverByName(name)
+		require.NoError(t, err)
+
+		packSizes := []int{500, 250, 5000, 1000, 2000}
+		_, err = solver.Solve(context.Background(), 12001, packSizes)
+		require.NoError(t, err)
+		require.Equal(t, []int{500, 250, 5000, 1000, 2000}, packSizes, name)
+	}
+}
+
+func BenchmarkSolvers(b *testing.B) {
+	orders := []int{12001, 1_000_003, 5_000_000_000_001}
+	for _, name := range SolverNames() {
+		solver, _ := SolverByName(name)
+		for _, catalog := range benchmarkCatalogs {
+			for _, n := range orders {
+				b.Run(fmt.Sprintf("%s/%s/%d", name, catalog.name, n), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						if _, err := solver.Solve(context.Background(), n, catalog.sizes); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// PreferNearest ships from the warehouses closest to the destination first
+	PreferNearest = "nearest"
+	// PreferFewestShipments ships from as few warehouses as possible
+	PreferFewestShipments = "fewest-shipments"
+	// PreferLowestOvershoot ships the least items on top of the order
+	PreferLowestOvershoot = "lowest-overshoot"
+	// DefaultPreference is the split preference used unless configured otherwise
+	DefaultPreference = PreferNearest
+)
+
+// preferences are the split preferences by name, in the order they are listed
+var preferences = []string{PreferNearest, PreferFewestShipments, PreferLowestOvershoot}
+
+// PreferenceNames returns the names of the split preferences
+func PreferenceNames() []string {
+	return append([]string(nil), preferences...)
+}
+
+// CheckPreference returns ErrInvalidMode for an unknown split preference
+func CheckPreference(name string) error {
+	for _, preference := range preferences {
+		if name == preference {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown split preference %q, expected %s, %s or %s", ErrInvalidMode, name, PreferNearest, PreferFewestShipments, PreferLowestOvershoot)
+}
+
+// WithPreference sets how ModeSplit splits an order when a calculation does not ask for a preference
+func WithPreference(name string) Option {
+	return func(a *App) {
+		a.preference = name
+	}
+}
+
+// Shipment is the part of an order sent from a single warehouse
+type Shipment struct {
+	WarehouseID int `json:"warehouseId"`
+	// Warehouse is the warehouse name
+	Warehouse string `json:"warehouse"`
+	// Distance is the distance from the warehouse to the destination in kilometres, set when both
+	// locations are known
+	Distance *float64 `json:"distance,omitempty"`
+	// Result are the packs sent from the warehouse, its Requested is the part of the order
+	// they cover
+	Result *CalculationResult `json:"result"`
+}
+
+// source is a warehouse an order can be split across, with its catalog and stock of the product
+type source struct {
+	warehouse Warehouse
+	snapshot  *catalogSnapshot
+	limits    map[int]int
+	// distance is the distance to the destination in kilometres, -1 when it is not known
+	distance float64
+	// capacity is the most items the stock ships, math.MaxInt when a size never runs out
+	capacity int
+}
+
+// splitPlan is a way to split an order across the sources
+type splitPlan struct {
+	shipments []Shipment
+	// rest is the part of the order none of the shipments covers
+	rest      int
+	overshoot int
+}
+
+// calculateSplit calculates the packs of an order in ModeSplit, splitting it across the warehouses
+// stocking packs of the product. Each warehouse packs its part from its own catalog and stock by
+// the policy of opts, and ships as much of the rest of the order as its stock allows.
+//
+// The preference of opts, or the configured one, chooses the split: nearest first ships from the
+// warehouses in order of distance to opts.Destination, or of their ID without one. Fewest shipments
+// and lowest overshoot pick from that split, the order shipped from each single warehouse and the
+// split shipping from the warehouses with the most stock first, with ties going to the earlier one.
+// With backorders an order the warehouses cannot cover together ships what they have, and the rest
+// is backordered. The catalogs of the warehouses other than the default one are not cached and
+// read on every call.
+func (a *App) calculateSplit(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error) {
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	preference := opts.Preference
+	if preference == "" {
+		preference = a.preference
+	}
+	if err := CheckPreference(preference); err != nil {
+		return nil, err
+	}
+	if opts.Alternatives != 0 || len(opts.Constraints) > 0 || opts.Tolerance != 0 ||
+		opts.Cartonize || opts.Zone != "" || opts.Carrier != "" {
+		return nil, fmt.Errorf("%w: %s does not support alternatives, pack constraints, an undershoot tolerance or cartons", ErrInvalidMode, ModeSplit)
+	}
+	if opts.Destination != nil {
+		if err := checkLocation(*opts.Destination); err != nil {
+			return nil, err
+		}
+	}
+	policyOpts := opts
+	policyOpts.Mode = ""
+	policy, err := a.policyFor(policyOpts)
+	if err != nil {
+		return nil, err
+	}
+	solver, err := a.solverFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.withBudget(ctx)
+	defer cancel()
+
+	productID := productOrDefault(opts.ProductID)
+	sources, err := a.splitSources(ctx, productID, opts.Destination)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w in any warehouse", ErrEmptyCatalog)
+	}
+
+	ship := func(sources []*source) (*splitPlan, error) {
+		return splitAcross(ctx, orderQuantity, sources, solver, policy, opts)
+	}
+	best, err := ship(sources)
+	if err != nil {
+		return nil, err
+	}
+	if preference != PreferNearest {
+		candidates := make([][]*source, 0, len(sources)+1)
+		for _, s := range sources {
+			candidates = append(candidates, []*source{s})
+		}
+		byCapacity := append([]*source(nil), sources...)
+		sort.SliceStable(byCapacity, func(i, j int) bool {
+			return byCapacity[i].capacity > byCapacity[j].capacity
+		})
+		candidates = append(candidates, byCapacity)
+
+		for _, candidate := range candidates {
+			plan, err := ship(candidate)
+			if err != nil {
+				return nil, err
+			}
+			if plan.better(best, preference) {
+				best = plan
+			}
+		}
+	}
+	// Backorders only make up the rest of a shipped order, like with a single warehouse
+	if best.rest > 0 && (!opts.Backorder || len(best.shipments) == 0) {
+		return nil, fmt.Errorf("%w: the warehouses can ship at most %d of %d items", ErrInsufficientStock, orderQuantity-best.rest, orderQuantity)
+	}
+	return newSplitResult(orderQuantity, best, solver, policy, preference), nil
+}
+
+// splitSources returns the warehouses with packs of the product, nearest to the destination first
+func (a *App) splitSources(ctx context.Context, productID int, destination *Location) ([]*source, error) {
+	warehouses, err := a.repo.GetWarehouses()
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*source, 0, len(warehouses))
+	for _, warehouse := range warehouses {
+		snapshot, limits, err := a.warehouseCatalog(ctx, warehouse.ID, productID)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshot.sizes) == 0 {
+			continue
+		}
+		s := &source{warehouse: warehouse, snapshot: snapshot, limits: limits, distance: -1}
+		if location := locationOf(warehouse); location != nil && destination != nil {
+			s.distance = distance(*location, *destination)
+		}
+		available := inStock(snapshot.sizes, limits)
+		s.capacity = stockCapacity(available, limits)[0]
+		sources = append(sources, s)
+	}
+
+	// Warehouses of an unknown distance go last, in the order of their ID
+	sort.SliceStable(sources, func(i, j int) bool {
+		di, dj := sources[i].distance, sources[j].distance
+		return dj < 0 && di >= 0 || di >= 0 && dj >= 0 && di < dj
+	})
+	return sources, nil
+}
+
+// warehouseCatalog returns the catalog snapshot of a product in a warehouse and the stock limits
+// of its sizes. Only the catalogs of the default warehouse come from the cache.
+func (a *App) warehouseCatalog(ctx context.Context, warehouseID, productID int) (*catalogSnapshot, map[int]int, error) {
+	if warehouseID == DefaultWarehouseID {
+		return a.loadCatalog(ctx, productID)
+	}
+	catalog, err := a.repo.GetWarehouseCatalog(warehouseID, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := newCatalogSnapshot(0, catalog)
+	if err != nil {
+		return nil, nil, err
+	}
+	stock, err := a.repo.GetWarehouseStock(warehouseID, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, stockLimits(stock, snapshot.sizes), nil
+}
+
+// splitAcross ships the order from the sources in turn, each shipping as much of the rest of the
+// order as its stock allows, until the order is covered
+func splitAcross(ctx context.Context, orderQuantity int, sources []*source, solver Solver, policy Policy, opts CalculateOptions) (*splitPlan, error) {
+	plan := &splitPlan{rest: orderQuantity}
+	shipOpts := opts
+	shipOpts.Mode, shipOpts.Backorder = "", true
+	for _, s := range sources {
+		if plan.rest == 0 {
+			break
+		}
+		result, err := s.snapshot.fulfill(ctx, plan.rest, solver, s.limits, policy, shipOpts)
+		if uncovered(err) {
+			// Nothing of this warehouse is in stock
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		part := min(result.Shipped, plan.rest)
+		result.Requested = part
+		result.Overshoot = result.Shipped - part
+		result.Shortfall, result.Backordered = 0, 0
+		result.Stock = stockOf(result.Catalog, s.limits)
+
+		shipment := Shipment{WarehouseID: s.warehouse.ID, Warehouse: s.warehouse.Name, Result: result}
+		if s.distance >= 0 {
+			km := math.Round(s.distance*10) / 10
+			shipment.Distance = &km
+		}
+		plan.shipments = append(plan.shipments, shipment)
+		plan.rest -= part
+		plan.overshoot += result.Overshoot
+	}
+	return plan, nil
+}
+
+// better reports whether the plan splits the order better than other by the preference.
+// A plan covering more of the order is always better.
+func (p *splitPlan) better(other *splitPlan, preference string) bool {
+	if p.rest != other.rest {
+		return p.rest < other.rest
+	}
+	shipments, otherShipments := len(p.shipments), len(other.shipments)
+	if preference == PreferLowestOvershoot {
+		if p.overshoot != other.overshoot {
+			return p.overshoot < other.overshoot
+		}
+		return shipments < otherShipments
+	}
+	if shipments != otherShipments {
+		return shipments < otherShipments
+	}
+	return p.overshoot < other.overshoot
+}
+
+// newSplitResult adds up the shipments of a plan to the result of the order
+func newSplitResult(orderQuantity int, plan *splitPlan, solver Solver, policy Policy, preference string) *CalculationResult {
+	packs := make(map[int]int)
+	var sizes []int
+	var costs []*CostBreakdown
+	for _, shipment := range plan.shipments {
+		for _, line := range shipment.Result.Lines {
+			packs[line.Size] += line.Count
+		}
+		sizes = append(sizes, shipment.Result.Catalog...)
+		costs = append(costs, shipment.Result.Cost)
+	}
+
+	result := newCalculationResult(orderQuantity, packs)
+	result.Overshoot = plan.overshoot
+	result.Shortfall, result.Backordered = plan.rest, plan.rest
+	result.Catalog = distinctDescending(sizes)
+	result.Solver = solver.Name()
+	result.Mode = ModeSplit
+	result.Policy = &policy
+	result.Preference = preference
+	result.Cost = sumCosts(costs)
+	result.Shipments = plan.shipments
+	return result
+}
+
+// sumCosts adds up cost breakdowns, keeping a line per size and unit cost
+func sumCosts(breakdowns []*CostBreakdown) *CostBreakdown {
+	total := &CostBreakdown{Lines: make([]CostLine, 0)}
+	var packs, handling int64
+	for _, breakdown := range breakdowns {
+		for _, line := range breakdown.Lines {
+			merged := false
+			for i := range total.Lines {
+				existing := &total.Lines[i]
+				if existing.Size == line.Size && existing.UnitPrice == line.UnitPrice && existing.UnitHandling == line.UnitHandling {
+					existing.Count += line.Count
+					existing.Total = float64(cents(existing.Total)+cents(line.Total)) / 100
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				total.Lines = append(total.Lines, line)
+			}
+		}
+		packs += cents(breakdown.Packs)
+		handling += cents(breakdown.Handling)
+	}
+	sort.SliceStable(total.Lines, func(i, j int) bool {
+		return total.Lines[i].Size > total.Lines[j].Size
+	})
+	total.Packs = float64(packs) / 100
+	total.Handling = float64(handling) / 100
+	total.Total = float64(packs+handling) / 100
+	return total
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+// splitRepository has the main warehouse in Berlin with little stock, one in Hamburg holding
+// a single big pack and one in Munich that never runs out of its packs of 300 and 1000
+func splitRepository(withMunich bool) *MockRepository {
+	berlin, hamburg, munich := [2]float64{52.52, 13.405}, [2]float64{53.551, 9.994}, [2]float64{48.137, 11.575}
+	warehouses := []repo.Warehouse{
+		{ID: 1, Name: "main", Latitude: &berlin[0], Longitude: &berlin[1]},
+		{ID: 2, Name: "north", Latitude: &hamburg[0], Longitude: &hamburg[1]},
+	}
+	if withMunich {
+		warehouses = append(warehouses, repo.Warehouse{ID: 3, Name: "south", Latitude: &munich[0], Longitude: &munich[1]})
+	}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetWarehouses").Return(warehouses, nil)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{
+		{ID: 1, Size: 100, Price: 3},
+		{ID: 2, Size: 500, Price: 12},
+	}, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 100, Quantity: 5}, {Size: 500, Quantity: 0}}, nil)
+	mockRepo.On("GetWarehouseCatalog", 2, DefaultProductID).Return([]repo.Package{{ID: 3, WarehouseID: 2, Size: 1000, Price: 20}}, nil)
+	mockRepo.On("GetWarehouseStock", 2, DefaultProductID).Return([]repo.StockLevel{{Size: 1000, Quantity: 1}}, nil)
+	mockRepo.On("GetWarehouseCatalog", 3, DefaultProductID).Return([]repo.Package{
+		{ID: 4, WarehouseID: 3, Size: 300, Price: 8},
+		{ID: 5, WarehouseID: 3, Size: 1000, Price: 20},
+	}, nil)
+	mockRepo.On("GetWarehouseStock", 3, DefaultProductID).Return([]repo.StockLevel{}, nil)
+	return mockRepo
+}
+
+// emptyWarehouseRepository has a single warehouse out of stock of its only pack
+func emptyWarehouseRepository() *MockRepository {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetWarehouses").Return([]repo.Warehouse{{ID: 1, Name: "main"}}, nil)
+	mockRepo.On("GetCatalog", DefaultProductID).Return([]repo.Package{{ID: 1, Size: 100, Price: 3}}, nil)
+	mockRepo.On("GetStock", DefaultProductID).Return([]repo.StockLevel{{Size: 100, Quantity: 0}}, nil)
+	return mockRepo
+}
+
+func TestApp_Calculate_Split(t *testing.T) {
+	nearHamburg := &Location{Latitude: 53.5, Longitude: 10}
+	tests := []struct {
+		name            string
+		quantity        int
+		withMunich      bool
+		mockRepo        *MockRepository
+		opts            CalculateOptions
+		wantWarehouses  []string
+		wantPacks       map[int]int
+		wantOvershoot   int
+		wantBackordered int
+		wantErr         error
+	}{
+		{
+			name:           "nearest first",
+			quantity:       1100,
+			withMunich:     true,
+			opts:           CalculateOptions{Mode: ModeSplit, Destination: nearHamburg},
+			wantWarehouses: []string{"north", "main"},
+			wantPacks:      map[int]int{1000: 1, 100: 1},
+		},
+		{
+			name:           "in the order of the warehouses without a destination",
+			quantity:       1100,
+			withMunich:     true,
+			opts:           CalculateOptions{Mode: ModeSplit},
+			wantWarehouses: []string{"main", "north"},
+			wantPacks:      map[int]int{100: 5, 1000: 1},
+			wantOvershoot:  400,
+		},
+		{
+			name:           "fewest shipments",
+			quantity:       1100,
+			withMunich:     true,
+			opts:           CalculateOptions{Mode: ModeSplit, Preference: PreferFewestShipments, Destination: nearHamburg},
+			wantWarehouses: []string{"south"},
+			wantPacks:      map[int]int{300: 4},
+			wantOvershoot:  100,
+		},
+		{
+			name:           "lowest overshoot",
+			quantity:       1100,
+			withMunich:     true,
+			opts:           CalculateOptions{Mode: ModeSplit, Preference: PreferLowestOvershoot},
+			wantWarehouses: []string{"south"},
+			wantPacks:      map[int]int{300: 4},
+			wantOvershoot:  100,
+		},
+		{
+			name:           "lowest overshoot across warehouses",
+			quantity:       1100,
+			withMunich:     true,
+			opts:           CalculateOptions{Mode: ModeSplit, Preference: PreferLowestOvershoot, Destination: nearHamburg},
+			wantWarehouses: []string{"north", "main"},
+			wantPacks:      map[int]int{1000: 1, 100: 1},
+		},
+		{
+			name:     "warehouses cannot cover the order",
+			quantity: 2000,
+			opts:     CalculateOptions{Mode: ModeSplit, Preference: PreferFewestShipments},
+			wantErr:  ErrInsufficientStock,
+		},
+		{
+			name:            "backorder",
+			quantity:        2000,
+			opts:            CalculateOptions{Mode: ModeSplit, Backorder: true},
+			wantWarehouses:  []string{"main", "north"},
+			wantPacks:       map[int]int{100: 5, 1000: 1},
+			wantBackordered: 500,
+		},
+		{
+			name:     "backorder without stock in any warehouse",
+			quantity: 250,
+			mockRepo: emptyWarehouseRepository(),
+			opts:     CalculateOptions{Mode: ModeSplit, Backorder: true},
+			wantErr:  ErrInsufficientStock,
+		},
+		{
+			name:     "unknown preference",
+			quantity: 1100,
+			opts:     CalculateOptions{Mode: ModeSplit, Preference: "cheapest"},
+			wantErr:  ErrInvalidMode,
+		},
+		{
+			name:     "tolerance",
+			quantity: 1100,
+			opts:     CalculateOptions{Mode: ModeSplit, Tolerance: 5},
+			wantErr:  ErrInvalidMode,
+		},
+		{
+			name:     "destination out of range",
+			quantity: 1100,
+			opts:     CalculateOptions{Mode: ModeSplit, Destination: &Location{Latitude: 100}},
+			wantErr:  ErrInvalidLocation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := tt.mockRepo
+			if mockRepo == nil {
+				mockRepo = splitRepository(tt.withMunich)
+			}
+			result, err := NewApp(mockRepo).Calculate(context.Background(), tt.quantity, tt.opts)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var warehouses []string
+			requested := 0
+			for _, shipment := range result.Shipments {
+				warehouses = append(warehouses, shipment.Warehouse)
+				requested += shipment.Result.Requested
+			}
+			require.Equal(t, tt.wantWarehouses, warehouses)
+			require.Equal(t, tt.wantPacks, result.Packs())
+			require.Equal(t, tt.quantity, result.Requested)
+			require.Equal(t, tt.quantity-tt.wantBackordered, requested)
+			require.Equal(t, tt.wantOvershoot, result.Overshoot)
+			require.Equal(t, tt.wantBackordered, result.Backordered)
+			require.Equal(t, ModeSplit, result.Mode)
+			require.Equal(t, DefaultSolver, result.Solver)
+		})
+	}
+}
+
+func TestApp_Calculate_Split_Totals(t *testing.T) {
+	app := NewApp(splitRepository(false), WithPreference(PreferFewestShipments))
+	result, err := app.Calculate(context.Background(), 1100, CalculateOptions{Mode: ModeSplit, Destination: &Location{Latitude: 53.5, Longitude: 10}})
+	require.NoError(t, err)
+
+	require.Equal(t, PreferFewestShipments, result.Preference)
+	require.Equal(t, 1100, result.Shipped)
+	require.Equal(t, 2, result.TotalPacks)
+	require.Equal(t, []int{1000, 500, 100}, result.Catalog)
+	require.Equal(t, 23.0, result.Cost.Total)
+	require.Len(t, result.Shipments, 2)
+	require.NotNil(t, result.Shipments[0].Distance)
+	require.InDelta(t, 5.9, *result.Shipments[0].Distance, 1)
+	require.Equal(t, []StockLevel{{Size: 1000, Quantity: 1}}, result.Shipments[0].Result.Stock)
+}
+
+func TestSumCosts(t *testing.T) {
+	got := sumCosts([]*CostBreakdown{
+		{Lines: []CostLine{{Size: 500, Count: 1, UnitPrice: 12, Total: 12}}, Packs: 12, Total: 12},
+		{Lines: []CostLine{{Size: 1000, Count: 1, UnitPrice: 20, UnitHandling: 0.5, Total: 20.5}, {Size: 500, Count: 2, UnitPrice: 12, Total: 24}}, Packs: 44, Handling: 0.5, Total: 44.5},
+	})
+	require.Equal(t, &CostBreakdown{
+		Lines: []CostLine{
+			{Size: 1000, Count: 1, UnitPrice: 20, UnitHandling: 0.5, Total: 20.5},
+			{Size: 500, Count: 3, UnitPrice: 12, Total: 36},
+		},
+		Packs:    56,
+		Handling: 0.5,
+		Total:    56.5,
+	}, got)
+}
@@ -0,0 +1,382 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrInsufficientStock is returned when the packs in stock cannot cover an order or adjustment
+	ErrInsufficientStock = repo.ErrInsufficientStock
+	// ErrStockNotFound is returned when a pack size has no stock level
+	ErrStockNotFound = repo.ErrStockNotFound
+	// ErrInvalidStock is returned for a stock level with a non-positive size or a negative quantity
+	ErrInvalidStock = errors.New("invalid stock level")
+)
+
+// StockLevel is the number of packs of a size in stock
+type StockLevel = repo.StockLevel
+
+// unlimitedStock marks a pack size without a stock level, it never runs out
+const unlimitedStock = -1
+
+// stockLimits returns the stock level of every catalog size that has one.
+// Sizes without a stock level are left out and never run out.
+func stockLimits(levels []StockLevel, packSizes []int) map[int]int {
+	inCatalog := make(map[int]bool, len(packSizes))
+	for _, size := range packSizes {
+		inCatalog[size] = true
+	}
+	limits := make(map[int]int)
+	for _, level := range levels {
+		if inCatalog[level.Size] {
+			limits[level.Size] = level.Quantity
+		}
+	}
+	return limits
+}
+
+// limitOf returns the stock level of a size, or unlimitedStock
+func limitOf(limits map[int]int, size int) int {
+	if limit, ok := limits[size]; ok {
+		return limit
+	}
+	return unlimitedStock
+}
+
+// stockCapacity returns the most items each suffix of the sizes can ship with the stock:
+// capacity[i] covers sizes[i:], saturating at math.MaxInt
+func stockCapacity(sizes []int, limits map[int]int) []int {
+	capacity := make([]int, len(sizes)+1)
+	for i := len(sizes) - 1; i >= 0; i-- {
+		limit := limitOf(limits, sizes[i])
+		switch {
+		case limit == unlimitedStock || capacity[i+1] == math.MaxInt:
+			capacity[i] = math.MaxInt
+		case limit > (math.MaxInt-capacity[i+1])/sizes[i]:
+			capacity[i] = math.MaxInt
+		default:
+			capacity[i] = capacity[i+1] + limit*sizes[i]
+		}
+	}
+	return capacity
+}
+
+// stockOf returns the stock levels of the sizes that have one, keeping their order
+func stockOf(sizes []int, limits map[int]int) []StockLevel {
+	var levels []StockLevel
+	for _, size := range sizes {
+		if limit, ok := limits[size]; ok {
+			levels = append(levels, StockLevel{Size: size, Quantity: limit})
+		}
+	}
+	return levels
+}
+
+// inStock returns the sizes that have at least one pack in stock, keeping their order
+func inStock(sizes []int, limits map[int]int) []int {
+	available := make([]int, 0, len(sizes))
+	for _, size := range sizes {
+		if limitOf(limits, size) != 0 {
+			available = append(available, size)
+		}
+	}
+	return available
+}
+
+// withinStock reports whether the packs take no more of any size than there are in stock
+func withinStock(lines []PackLine, limits map[int]int) bool {
+	for _, line := range lines {
+		if limit := limitOf(limits, line.Size); limit != unlimitedStock && line.Count > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// alternativesInStock returns the alternatives the stock can ship, keeping their order
+func alternativesInStock(alternatives []Alternative, limits map[int]int) []Alternative {
+	var available []Alternative
+	for _, alternative := range alternatives {
+		if withinStock(alternative.Lines, limits) {
+			available = append(available, alternative)
+		}
+	}
+	return available
+}
+
+// calculateWithStock chooses the packs covering the order by the objectives of the policy,
+// taking no more packs of a size than there are in stock and keeping the counts within the
+// pack constraints of opts. The cost objective is left to calculateMinCost.
+func calculateWithStock(ctx context.Context, orderQuantity int, packSizes []int, limits map[int]int, policy Policy, opts CalculateOptions) (*CalculationResult, error) {
+	if orderQuantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if len(packSizes) == 0 {
+		return nil, ErrEmptyCatalog
+	}
+	if !policy.usesSolvers() {
+		if opts.Solver != "" && opts.Solver != "bnb" {
+			return nil, fmt.Errorf("%w: the %s policy is only supported by the bnb solver", ErrInvalidMode, policy.Name)
+		}
+		if opts.Alternatives != 0 {
+			return nil, fmt.Errorf("%w: the %s policy does not support alternatives", ErrInvalidMode, policy.Name)
+		}
+	}
+	if opts.Solver != "" && opts.Solver != "bnb" {
+		return nil, fmt.Errorf("%w: stock limits and pack constraints are only supported by the bnb solver", ErrInvalidMode)
+	}
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: alternatives are not supported with stock limits or pack constraints", ErrInvalidMode)
+	}
+
+	catalog := distinctDescending(packSizes)
+	order, err := applyConstraints(orderQuantity, catalog, limits, opts.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	var rest map[int]int
+	if order.rest > 0 {
+		if rest, err = solveWithStock(ctx, order.rest, catalog, order.limits, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	result := newCalculationResult(orderQuantity, order.packs(rest))
+	result.Catalog = catalog
+	result.Solver = "bnb"
+	result.Mode = policy.mode()
+	result.Constraints = opts.Constraints
+	return result, nil
+}
+
+// solveWithStock is the bounded variant of the branch and bound strategy choosing packs by the
+// items and packs objectives of the policy, sizes are distinct and in descending order
+func solveWithStock(ctx context.Context, orderQuantity int, sizes []int, limits map[int]int, policy Policy) (map[int]int, error) {
+	sizes = inStock(sizes, limits)
+	capacity := stockCapacity(sizes, limits)
+	if capacity[0] < orderQuantity {
+		return nil, fmt.Errorf("%w: %d items ordered, at most %d can be shipped", ErrInsufficientStock, orderQuantity, capacity[0])
+	}
+	if orderQuantity > math.MaxInt-sizes[0] {
+		return nil, errOrderTooLarge
+	}
+
+	s := &stockSearch{
+		cancelCheck: cancelCheck{ctx: ctx},
+		sizes:       sizes,
+		limits:      make([]int, len(sizes)),
+		capacity:    capacity,
+		counts:      make([]int, len(sizes)),
+		best:        make([]int, len(sizes)),
+		suffixGCD:   make([]int, len(sizes)),
+		anchor:      -1,
+		packsFirst:  policy.mode() == ObjectivePacks,
+		tieBreak:    policy.TieBreak,
+	}
+	for i, size := range sizes {
+		s.limits[i] = limitOf(limits, size)
+		if s.anchor < 0 && s.limits[i] == unlimitedStock {
+			s.anchor = i
+		}
+	}
+	for i := len(sizes) - 1; i >= 0; i-- {
+		s.suffixGCD[i] = sizes[i]
+		if i+1 < len(sizes) {
+			s.suffixGCD[i] = gcd(sizes[i], s.suffixGCD[i+1])
+		}
+	}
+
+	s.search(0, orderQuantity, 0)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if !s.found {
+		return nil, fmt.Errorf("%w: no combination of the packs in stock covers %d items", ErrInsufficientStock, orderQuantity)
+	}
+
+	packs := make(map[int]int)
+	for i, count := range s.best {
+		if count > 0 {
+			packs[sizes[i]] = count
+		}
+	}
+	return packs, nil
+}
+
+// stockSearch is a branch and bound search like branchAndBound where some sizes only have
+// a limited number of packs in stock.
+//
+// The bound on smaller packs only holds for the largest size A that never runs out: among any
+// A packs smaller than A some subset sums to a multiple of A and can be swapped for fewer A-packs,
+// so an optimal combination has fewer than A packs smaller than A. The swap ships as many items
+// in fewer packs, so the bound holds whichever of the two objectives comes first. The sizes
+// above A are only limited by their stock.
+type stockSearch struct {
+	cancelCheck
+	// sizes are the distinct pack sizes in stock in descending order
+	sizes []int
+	// limits[i] is the number of packs of sizes[i] in stock, or unlimitedStock
+	limits []int
+	// capacity[i] is the most items sizes[i:] can ship
+	capacity []int
+	// counts are the pack counts of the current branch
+	counts []int
+	// best are the pack counts of the best combination found so far, if found
+	best          []int
+	found         bool
+	bestOvershoot int
+	bestPacks     int
+	// suffixGCD[i] is the gcd of sizes[i:]
+	suffixGCD []int
+	// anchor is the level of the largest size that never runs out, or -1
+	anchor int
+	// packsFirst compares packs before items, see Policy
+	packsFirst bool
+	// tieBreak tells apart combinations with as many items and packs, see Policy
+	tieBreak string
+}
+
+func (s *stockSearch) search(level, remaining, packs int) {
+	size := s.sizes[level]
+	last := level == len(s.sizes)-1
+
+	maxCount := ceilDiv(remaining, size)
+	if s.limits[level] != unlimitedStock {
+		maxCount = min(maxCount, s.limits[level])
+	}
+	minCount := 0
+	if last {
+		minCount = maxCount
+	}
+	if s.anchor >= 0 && !last {
+		anchorSize := s.sizes[s.anchor]
+		if level == s.anchor {
+			minCount = max(minCount, (remaining-(anchorSize-1)*s.sizes[level+1])/anchorSize)
+		}
+	}
+	if s.anchor >= 0 && level > s.anchor {
+		smaller := packs
+		for i := 0; i <= s.anchor; i++ {
+			smaller -= s.counts[i]
+		}
+		maxCount = min(maxCount, s.sizes[s.anchor]-1-smaller)
+	}
+
+	// No completion of this branch ships less than the next multiple of the gcd
+	step := s.suffixGCD[level]
+	overshootFloor := ceilDiv(remaining, step)*step - remaining
+
+	for count := maxCount; count >= minCount; count-- {
+		if s.stopped() {
+			break
+		}
+		rest := remaining - count*size
+		if rest <= 0 {
+			s.consider(level, count, -rest, packs+count)
+			continue
+		}
+		if last || s.capacity[level+1] < rest {
+			// The rest only grows as this count decreases
+			break
+		}
+
+		step := s.suffixGCD[level+1]
+		overshootBound := ceilDiv(rest, step)*step - rest
+		packsBound := packs + count + ceilDiv(rest, s.sizes[level+1])
+		settled := s.bestOvershoot <= overshootFloor
+		if s.found && (s.packsFirst || settled) &&
+			(packsBound > s.bestPacks || (packsBound == s.bestPacks && settled && s.tieBreak == TieBreakNone)) {
+			// The pack bound only grows as this count decreases
+			break
+		}
+		if s.found && !s.improves(overshootBound, packsBound) && !s.ties(overshootBound, packsBound) {
+			continue
+		}
+
+		s.counts[level] = count
+		s.search(level+1, rest, packs+count)
+	}
+	s.counts[level] = 0
+}
+
+// consider records the current branch, completed with count packs at level, if it improves on the best
+func (s *stockSearch) consider(level, count, overshoot, packs int) {
+	if s.found && !s.improves(overshoot, packs) && !(s.ties(overshoot, packs) && s.breaksTie(level, count)) {
+		return
+	}
+	copy(s.best, s.counts[:level])
+	s.best[level] = count
+	for i := level + 1; i < len(s.best); i++ {
+		s.best[i] = 0
+	}
+	s.found = true
+	s.bestOvershoot = overshoot
+	s.bestPacks = packs
+}
+
+// improves reports whether a combination ships fewer items, or as many items in fewer packs,
+// than the best one found so far. Packs first, it ships fewer packs, or as many packs with
+// fewer items.
+func (s *stockSearch) improves(overshoot, packs int) bool {
+	if s.packsFirst {
+		return packs < s.bestPacks || (packs == s.bestPacks && overshoot < s.bestOvershoot)
+	}
+	return overshoot < s.bestOvershoot || (overshoot == s.bestOvershoot && packs < s.bestPacks)
+}
+
+// ties reports whether a combination ships as many items in as many packs as the best one found
+// so far and the tie-break may still prefer it
+func (s *stockSearch) ties(overshoot, packs int) bool {
+	return s.tieBreak != TieBreakNone && overshoot == s.bestOvershoot && packs == s.bestPacks
+}
+
+// breaksTie reports whether the tie-break prefers the current branch, completed with count
+// packs at level, over the best combination found so far
+func (s *stockSearch) breaksTie(level, count int) bool {
+	candidate := func(i int) int {
+		switch {
+		case i < level:
+			return s.counts[i]
+		case i == level:
+			return count
+		default:
+			return 0
+		}
+	}
+	for j := range s.best {
+		i := j
+		if s.tieBreak == TieBreakSmallest {
+			i = len(s.best) - 1 - j
+		}
+		if c := candidate(i); c != s.best[i] {
+			return c > s.best[i]
+		}
+	}
+	return false
+}
+
+// CommitOrder calculates the packs for a confirmed order like Calculate and takes them out of
+// the stock of the product in the default warehouse.
+// The stock is taken out in a single transaction, so if another order took the packs first
+// nothing changes and ErrInsufficientStock is returned.
+func (a *App) CommitOrder(ctx context.Context, orderQuantity int, opts CalculateOptions) (*CalculationResult, error) {
+	if opts.Alternatives != 0 {
+		return nil, fmt.Errorf("%w: alternatives cannot be committed", ErrInvalidAlternatives)
+	}
+	if opts.Mode == ModeSplit {
+		return nil, fmt.Errorf("%w: only the stock of the default warehouse is committed, %s orders are not", ErrInvalidMode, ModeSplit)
+	}
+	result, err := a.Calculate(ctx, orderQuantity, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.repo.CommitStock(productOrDefault(opts.ProductID), result.Packs()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
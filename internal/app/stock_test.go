@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolveWithStock(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderQuantity int
+		sizes         []int
+		limits        map[int]int
+		expected      map[int]int
+		expectError   bool
+	}{
+		{
+			name:          "unlimited",
+			orderQuantity: 12001,
+			sizes:         []int{5000, 2000, 1000, 500, 250},
+			limits:        map[int]int{},
+			expected:      map[int]int{5000: 2, 2000: 1, 250: 1},
+		},
+		{
+			name:          "largest size runs out",
+			orderQuantity: 12001,
+			sizes:         []int{5000, 2000, 1000, 500, 250},
+			limits:        map[int]int{5000: 1},
+			expected:      map[int]int{5000: 1, 2000: 3, 1000: 1, 250: 1},
+		},
+		{
+			name:          "smallest size runs out",
+			orderQuantity: 251,
+			sizes:         []int{500, 250},
+			limits:        map[int]int{250: 1},
+			expected:      map[int]int{500: 1},
+		},
+		{
+			name:          "out of stock size is skipped",
+			orderQuantity: 12001,
+			sizes:         []int{5000, 2000, 1000, 500, 250},
+			limits:        map[int]int{250: 0},
+			expected:      map[int]int{5000: 2, 2000: 1, 500: 1},
+		},
+		{
+			name:          "every size limited",
+			orderQuantity: 10,
+			sizes:         []int{7, 5, 3},
+			limits:        map[int]int{7: 1, 5: 1, 3: 1},
+			expected:      map[int]int{7: 1, 3: 1},
+		},
+		{
+			name:          "large stock on the largest size",
+			orderQuantity: 5_000_000_000_001,
+			sizes:         []int{5000, 250},
+			limits:        map[int]int{5000: 1_000_000_000},
+			expected:      map[int]int{5000: 1_000_000_000, 250: 1},
+		},
+		{
+			name:          "not enough stock",
+			orderQuantity: 16,
+			sizes:         []int{7, 5, 3},
+			limits:        map[int]int{7: 1, 5: 1, 3: 1},
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packs, err := solveWithStock(context.Background(), tt.orderQuantity, tt.sizes, tt.limits, policies[DefaultPolicy])
+			if tt.expectError {
+				require.ErrorIs(t, err, ErrInsufficientStock)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, packs)
+		})
+	}
+}
+
+func TestSolveWithStock_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 300; i++ {
+		sizes := distinctDescending([]int{1 + rng.Intn(20), 1 + rng.Intn(20), 1 + rng.Intn(20)})
+		limits := make(map[int]int)
+		costs := make([]packCost, 0, len(sizes))
+		for _, size := range sizes {
+			if rng.Intn(3) > 0 {
+				limits[size] = rng.Intn(4)
+			}
+			costs = append(costs, packCost{size: size, price: int64(rng.Intn(50)), handling: int64(rng.Intn(5))})
+		}
+		n := 1 + rng.Intn(60)
+
+		// Least items then packs, and cheapest then least items and packs, over every covering
+		// combination within the stock
+		found := false
+		var bestCost int64
+		bestShipped, bestPacks := 0, 0
+		cheapShipped, cheapPacks := 0, 0
+		counts := make([]int, len(sizes))
+		var walk func(level int)
+		walk = func(level int) {
+			if level == len(sizes) {
+				var cost int64
+				shipped, packs := 0, 0
+				for j, c := range counts {
+					cost += int64(c) * costs[j].total()
+					shipped += c * sizes[j]
+					packs += c
+				}
+				if shipped < n {
+					return
+				}
+				if !found || shipped < bestShipped || (shipped == bestShipped && packs < bestPacks) {
+					bestShipped, bestPacks = shipped, packs
+				}
+				if !found || cost < bestCost || (cost == bestCost && (shipped < cheapShipped || (shipped == cheapShipped && packs < cheapPacks))) {
+					bestCost, cheapShipped, cheapPacks = cost, shipped, packs
+				}
+				found = true
+				return
+			}
+			most := ceilDiv(n, sizes[level])
+			if limit := limitOf(limits, sizes[level]); limit != unlimitedStock {
+				most = min(most, limit)
+			}
+			for c := 0; c <= most; c++ {
+				counts[level] = c
+				walk(level + 1)
+			}
+		}
+		walk(0)
+
+		packs, err := solveWithStock(context.Background(), n, sizes, limits, policies[DefaultPolicy])
+		if !found {
+			require.ErrorIs(t, err, ErrInsufficientStock, "sizes %v, limits %v, order %d", sizes, limits, n)
+			continue
+		}
+		require.NoError(t, err)
+		result := newCalculationResult(n, packs)
+		require.Equal(t, bestShipped, result.Shipped, "sizes %v, limits %v, order %d", sizes, limits, n)
+		require.Equal(t, bestPacks, result.TotalPacks, "sizes %v, limits %v, order %d", sizes, limits, n)
+
+		cheapest, err := solveMinCost(context.Background(), n, costs, limits)
+		require.NoError(t, err)
+		var cost int64
+		shipped, total := 0, 0
+		for _, c := range costs {
+			count := cheapest[c.size]
+			if limit := limitOf(limits, c.size); limit != unlimitedStock {
+				require.LessOrEqual(t, count, limit, "sizes %v, limits %v, order %d", sizes, limits, n)
+			}
+			cost += int64(count) * c.total()
+			shipped += count * c.size
+			total += count
+		}
+		require.Equal(t, bestCost, cost, "costs %v, limits %v, order %d", costs, limits, n)
+		require.Equal(t, cheapShipped, shipped, "costs %v, limits %v, order %d", costs, limits, n)
+		require.Equal(t, cheapPacks, total, "costs %v, limits %v, order %d", costs, limits, n)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+)
+
+var (
+	// ErrWarehouseNotFound is returned when a warehouse does not exist
+	ErrWarehouseNotFound = repo.ErrWarehouseNotFound
+	// ErrWarehouseExists is returned when a warehouse name is already taken
+	ErrWarehouseExists = repo.ErrWarehouseExists
+	// ErrInvalidWarehouse is returned for a warehouse without a name or with a half or out of range
+	// location, and for a change to the default warehouse
+	ErrInvalidWarehouse = errors.New("invalid warehouse")
+	// ErrInvalidLocation is returned for a destination outside the range of latitudes and longitudes
+	ErrInvalidLocation = errors.New("invalid location")
+)
+
+// DefaultWarehouseID is the warehouse used when none is given, the catalog and stock endpoints
+// without a warehouse belong to it
+const DefaultWarehouseID = repo.DefaultWarehouseID
+
+// Warehouse is a location orders are shipped from, with a pack catalog and stock of its own
+type Warehouse = repo.Warehouse
+
+// Location is a place on earth in degrees
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// earthRadius is the mean radius of the earth in kilometres
+const earthRadius = 6371.0
+
+// checkLocation validates the latitude and longitude of a location
+func checkLocation(l Location) error {
+	if math.IsNaN(l.Latitude) || l.Latitude < -90 || l.Latitude > 90 {
+		return fmt.Errorf("%w: latitude %v, expected -90 to 90", ErrInvalidLocation, l.Latitude)
+	}
+	if math.IsNaN(l.Longitude) || l.Longitude < -180 || l.Longitude > 180 {
+		return fmt.Errorf("%w: longitude %v, expected -180 to 180", ErrInvalidLocation, l.Longitude)
+	}
+	return nil
+}
+
+// distance returns the great-circle distance between two locations in kilometres
+func distance(from, to Location) float64 {
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := radians(to.Latitude - from.Latitude)
+	dLon := radians(to.Longitude - from.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(from.Latitude))*math.Cos(radians(to.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// locationOf returns the location of a warehouse, nil when it is not known
+func locationOf(w Warehouse) *Location {
+	if w.Latitude == nil || w.Longitude == nil {
+		return nil
+	}
+	return &Location{Latitude: *w.Latitude, Longitude: *w.Longitude}
+}
+
+// warehouseOrDefault returns the warehouse ID, or DefaultWarehouseID when it is not set
+func warehouseOrDefault(warehouseID int) int {
+	if warehouseID == 0 {
+		return DefaultWarehouseID
+	}
+	return warehouseID
+}
+
+// GetWarehouses returns all warehouses
+func (a *App) GetWarehouses() ([]Warehouse, error) {
+	return a.repo.GetWarehouses()
+}
+
+// AddWarehouse adds a warehouse with an empty pack catalog and stock.
+// Its location is optional but given with both the latitude and the longitude.
+func (a *App) AddWarehouse(warehouse Warehouse) (Warehouse, error) {
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
+	if warehouse.Name == "" {
+		return Warehouse{}, fmt.Errorf("%w: name is required", ErrInvalidWarehouse)
+	}
+	if (warehouse.Latitude == nil) != (warehouse.Longitude == nil) {
+		return Warehouse{}, fmt.Errorf("%w: give both the latitude and the longitude, or neither", ErrInvalidWarehouse)
+	}
+	if location := locationOf(warehouse); location != nil {
+		if err := checkLocation(*location); err != nil {
+			return Warehouse{}, fmt.Errorf("%w: %v", ErrInvalidWarehouse, err)
+		}
+	}
+	return a.repo.AddWarehouse(warehouse)
+}
+
+// DeleteWarehouse deletes a warehouse along with its pack catalogs and stock.
+// The default warehouse cannot be deleted.
+func (a *App) DeleteWarehouse(id int) error {
+	if id == DefaultWarehouseID {
+		return fmt.Errorf("%w: the default warehouse cannot be deleted", ErrInvalidWarehouse)
+	}
+	return a.repo.DeleteWarehouse(id)
+}
+
+// GetWarehouseCatalog returns the packages of a product in a warehouse, of the default
+// product when productID is zero
+func (a *App) GetWarehouseCatalog(warehouseID, productID int) ([]Package, error) {
+	return a.repo.GetWarehouseCatalog(warehouseID, productOrDefault(productID))
+}
+
+// GetWarehouseStock returns the stock levels of a product in a warehouse, like GetStock
+func (a *App) GetWarehouseStock(warehouseID, productID int) ([]StockLevel, error) {
+	return a.repo.GetWarehouseStock(warehouseID, productOrDefault(productID))
+}
+
+// SetWarehouseStock sets the number of packs of a size in stock in a warehouse, like SetStock
+func (a *App) SetWarehouseStock(warehouseID, productID, size, quantity int) error {
+	if size <= 0 {
+		return fmt.Errorf("%w: size must be a positive integer", ErrInvalidStock)
+	}
+	if quantity < 0 {
+		return fmt.Errorf("%w: quantity must not be negative", ErrInvalidStock)
+	}
+	return a.repo.SetWarehouseStock(warehouseID, productOrDefault(productID), size, quantity)
+}
+
+// catalogOf returns the packages of a product in a warehouse
+func (a *App) catalogOf(warehouseID, productID int) ([]Package, error) {
+	if warehouseID == DefaultWarehouseID {
+		return a.repo.GetCatalog(productID)
+	}
+	return a.repo.GetWarehouseCatalog(warehouseID, productID)
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/klausborkowski/calculator/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_AddWarehouse(t *testing.T) {
+	latitude, longitude, outOfRange := 53.55, 9.99, 91.0
+	tests := []struct {
+		name      string
+		warehouse Warehouse
+		wantErr   error
+	}{
+		{
+			name:      "with a location",
+			warehouse: Warehouse{Name: " north ", Latitude: &latitude, Longitude: &longitude},
+		},
+		{
+			name:      "without a location",
+			warehouse: Warehouse{Name: "north"},
+		},
+		{
+			name:      "no name",
+			warehouse: Warehouse{Name: " "},
+			wantErr:   ErrInvalidWarehouse,
+		},
+		{
+			name:      "half a location",
+			warehouse: Warehouse{Name: "north", Latitude: &latitude},
+			wantErr:   ErrInvalidWarehouse,
+		},
+		{
+			name:      "latitude out of range",
+			warehouse: Warehouse{Name: "north", Latitude: &outOfRange, Longitude: &longitude},
+			wantErr:   ErrInvalidWarehouse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			stored := tt.warehouse
+			stored.Name = "north"
+			mockRepo.On("AddWarehouse", stored).Return(repo.Warehouse{ID: 2, Name: "north"}, nil)
+
+			got, err := NewApp(mockRepo).AddWarehouse(tt.warehouse)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "AddWarehouse", stored)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 2, got.ID)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApp_DeleteWarehouse(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("DeleteWarehouse", 2).Return(nil)
+
+	app := NewApp(mockRepo)
+	require.NoError(t, app.DeleteWarehouse(2))
+	require.ErrorIs(t, app.DeleteWarehouse(DefaultWarehouseID), ErrInvalidWarehouse)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_SetWarehouseStock(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("SetWarehouseStock", 2, DefaultProductID, 500, 8).Return(nil)
+
+	app := NewApp(mockRepo)
+	require.NoError(t, app.SetWarehouseStock(2, 0, 500, 8))
+	require.ErrorIs(t, app.SetWarehouseStock(2, 0, 0, 8), ErrInvalidStock)
+	require.ErrorIs(t, app.SetWarehouseStock(2, 0, 500, -1), ErrInvalidStock)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApp_AddPackage_Warehouse(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetWarehouseCatalog", 2, DefaultProductID).Return([]repo.Package{{ID: 4, WarehouseID: 2, Size: 500}}, nil)
+	mockRepo.On("AddPackage", Package{ProductID: DefaultProductID, WarehouseID: 2, Size: 1000}).Return(nil)
+
+	app := NewApp(mockRepo)
+	require.NoError(t, app.AddPackage(Package{WarehouseID: 2, Size: 1000}))
+	require.ErrorIs(t, app.AddPackage(Package{WarehouseID: 2, Size: 500}), ErrPackageExists)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetCatalog", DefaultProductID)
+}
+
+func TestDistance(t *testing.T) {
+	berlin := Location{Latitude: 52.52, Longitude: 13.405}
+	hamburg := Location{Latitude: 53.551, Longitude: 9.994}
+
+	require.InDelta(t, 255, distance(berlin, hamburg), 1)
+	require.InDelta(t, distance(hamburg, berlin), distance(berlin, hamburg), 1e-9)
+	require.Zero(t, distance(berlin, berlin))
+}
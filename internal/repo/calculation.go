@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Sort orders of the calculation history
+const (
+	// SortCreated sorts calculations by the time of the call
+	SortCreated = "created"
+	// SortQuantity sorts calculations by the ordered quantity
+	SortQuantity = "quantity"
+)
+
+// Calculation is a recorded /calculate call. Its request, catalog and result are JSON the
+// repository stores as they are.
+type Calculation struct {
+	ID        int64
+	CreatedAt time.Time
+	Caller    string
+	Quantity  int
+	// PackSizes are the sizes the result ships packs of
+	PackSizes     []int64
+	Request       []byte
+	Catalog       []byte
+	Result        []byte
+	Error         string
+	Solver        string
+	SolverVersion int
+}
+
+// CalculationFilter selects calculations from the history, zero fields select all
+type CalculationFilter struct {
+	// From and To bound the time of the call, From inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// MinQuantity and MaxQuantity bound the ordered quantity, both inclusive
+	MinQuantity int
+	MaxQuantity int
+	// PackSize selects the calculations shipping packs of this size
+	PackSize int
+	// Sort is SortCreated or SortQuantity, ties are sorted by ID
+	Sort       string
+	Descending bool
+	// After selects the calculations after this one in the sort order, to page through them
+	After *CalculationCursor
+	Limit int
+}
+
+// CalculationCursor is the position of a calculation in the sort order
+type CalculationCursor struct {
+	CreatedAt time.Time
+	Quantity  int
+	ID        int64
+}
+
+// calculationColumns are the columns scanned by GetCalculations
+const calculationColumns = `id, created_at, caller, quantity, pack_sizes, request, catalog, result, error, solver, solver_version`
+
+func (r *Repository) AddCalculation(c Calculation) error {
+	query := `INSERT INTO calculation (caller, quantity, pack_sizes, request, catalog, result, error, solver, solver_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	var result interface{}
+	if c.Result != nil {
+		result = string(c.Result)
+	}
+	_, err := r.db.Exec(query, c.Caller, c.Quantity, pq.Array(c.PackSizes), string(c.Request), string(c.Catalog), result, c.Error, c.Solver, c.SolverVersion)
+	if err != nil {
+		log.Printf("Error adding calculation (quantity: %d): %v", c.Quantity, err)
+		return fmt.Errorf("failed to add calculation: %w", err)
+	}
+	return nil
+}
+
+// GetCalculations returns up to filter.Limit calculations selected by the filter in its sort order
+func (r *Repository) GetCalculations(filter CalculationFilter) ([]Calculation, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.To))
+	}
+	if filter.MinQuantity > 0 {
+		conditions = append(conditions, "quantity >= "+arg(filter.MinQuantity))
+	}
+	if filter.MaxQuantity > 0 {
+		conditions = append(conditions, "quantity <= "+arg(filter.MaxQuantity))
+	}
+	if filter.PackSize > 0 {
+		conditions = append(conditions, "pack_sizes @> ARRAY["+arg(filter.PackSize)+"::INTEGER]")
+	}
+
+	column, direction, compare := "created_at", "ASC", ">"
+	if filter.Sort == SortQuantity {
+		column = "quantity"
+	}
+	if filter.Descending {
+		direction, compare = "DESC", "<"
+	}
+	if after := filter.After; after != nil {
+		var key interface{} = after.CreatedAt
+		if filter.Sort == SortQuantity {
+			key = after.Quantity
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", column, compare, arg(key), arg(after.ID)))
+	}
+
+	query := `SELECT ` + calculationColumns + ` FROM calculation`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", column, direction, direction, arg(filter.Limit))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying calculations: %v", err)
+		return nil, fmt.Errorf("failed to get calculations: %w", err)
+	}
+	defer rows.Close()
+
+	calculations := make([]Calculation, 0)
+	for rows.Next() {
+		var c Calculation
+		if err := rows.Scan(&c.ID, &c.CreatedAt, &c.Caller, &c.Quantity, pq.Array(&c.PackSizes), &c.Request, &c.Catalog, &c.Result, &c.Error, &c.Solver, &c.SolverVersion); err != nil {
+			log.Printf("Error scanning calculation row: %v", err)
+			return nil, fmt.Errorf("failed to scan calculation: %w", err)
+		}
+		calculations = append(calculations, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating calculation rows: %v", err)
+		return nil, fmt.Errorf("error iterating calculations: %w", err)
+	}
+
+	return calculations, nil
+}
+
+// DeleteCalculations deletes the calculations made before a time and returns how many there were
+func (r *Repository) DeleteCalculations(before time.Time) (int, error) {
+	query := `DELETE FROM calculation WHERE created_at < $1`
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		log.Printf("Error deleting calculations (before: %s): %v", before, err)
+		return 0, fmt.Errorf("failed to delete calculations: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for delete calculations: %v", err)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
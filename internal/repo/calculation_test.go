@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+var calculationRowColumns = []string{"id", "created_at", "caller", "quantity", "pack_sizes", "request", "catalog", "result", "error", "solver", "solver_version"}
+
+func TestRepository_AddCalculation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`INSERT INTO calculation \(caller, quantity, pack_sizes, request, catalog, result, error, solver, solver_version\)`).
+		WithArgs("support", 251, "{500}", `{"quantity":251}`, `[{"size":500}]`, `{"shipped":500}`, "", "dp", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO calculation`).
+		WithArgs("support", 0, "{}", `{"quantity":0}`, `[]`, nil, "order quantity must be a positive integer", "dp", 1).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	require.NoError(t, repo.AddCalculation(Calculation{Caller: "support", Quantity: 251, PackSizes: []int64{500},
+		Request: []byte(`{"quantity":251}`), Catalog: []byte(`[{"size":500}]`), Result: []byte(`{"shipped":500}`), Solver: "dp", SolverVersion: 1}))
+	require.NoError(t, repo.AddCalculation(Calculation{Caller: "support", PackSizes: []int64{},
+		Request: []byte(`{"quantity":0}`), Catalog: []byte(`[]`), Error: "order quantity must be a positive integer", Solver: "dp", SolverVersion: 1}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_Calculations_LargeQuantity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Quantities are stored as BIGINT, so orders above 2^31 are kept and searched as they are
+	const quantity = 3_000_000_000
+	created := time.Date(2024, 5, 7, 9, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectExec(`INSERT INTO calculation`).
+		WithArgs("support", quantity, "{500}", `{"quantity":3000000000}`, `[{"size":500}]`, `{"shipped":3000000000}`, "", "dp", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`FROM calculation WHERE quantity >= \$1 ORDER BY quantity DESC, id DESC LIMIT \$2`).
+		WithArgs(quantity, 2).
+		WillReturnRows(sqlmock.NewRows(calculationRowColumns).
+			AddRow(1, created, "support", int64(quantity), "{500}", []byte(`{"quantity":3000000000}`), []byte(`[{"size":500}]`), []byte(`{"shipped":3000000000}`), "", "dp", 1))
+
+	require.NoError(t, repo.AddCalculation(Calculation{Caller: "support", Quantity: quantity, PackSizes: []int64{500},
+		Request: []byte(`{"quantity":3000000000}`), Catalog: []byte(`[{"size":500}]`), Result: []byte(`{"shipped":3000000000}`), Solver: "dp", SolverVersion: 1}))
+	got, err := repo.GetCalculations(CalculationFilter{MinQuantity: quantity, Sort: SortQuantity, Descending: true, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, quantity, got[0].Quantity)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_GetCalculations(t *testing.T) {
+	from := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	created := from.Add(9 * time.Hour)
+
+	tests := []struct {
+		name      string
+		filter    CalculationFilter
+		wantQuery string
+		wantArgs  []driver.Value
+	}{
+		{
+			name:      "newest first",
+			filter:    CalculationFilter{Sort: SortCreated, Descending: true, Limit: 51},
+			wantQuery: `SELECT id, created_at, .* FROM calculation ORDER BY created_at DESC, id DESC LIMIT \$1`,
+			wantArgs:  []driver.Value{51},
+		},
+		{
+			name: "filtered",
+			filter: CalculationFilter{From: from, To: to, MinQuantity: 250, MaxQuantity: 1000, PackSize: 500,
+				Sort: SortCreated, Limit: 11},
+			wantQuery: `FROM calculation WHERE created_at >= \$1 AND created_at < \$2 AND quantity >= \$3 AND quantity <= \$4 ` +
+				`AND pack_sizes @> ARRAY\[\$5::INTEGER\] ORDER BY created_at ASC, id ASC LIMIT \$6`,
+			wantArgs: []driver.Value{from, to, 250, 1000, 500, 11},
+		},
+		{
+			name:      "next page by quantity",
+			filter:    CalculationFilter{MinQuantity: 250, Sort: SortQuantity, Descending: true, After: &CalculationCursor{CreatedAt: created, Quantity: 751, ID: 9}, Limit: 3},
+			wantQuery: `FROM calculation WHERE quantity >= \$1 AND \(quantity, id\) < \(\$2, \$3\) ORDER BY quantity DESC, id DESC LIMIT \$4`,
+			wantArgs:  []driver.Value{250, 751, int64(9), 3},
+		},
+		{
+			name:      "next page by time",
+			filter:    CalculationFilter{Sort: SortCreated, After: &CalculationCursor{CreatedAt: created, Quantity: 751, ID: 9}, Limit: 3},
+			wantQuery: `FROM calculation WHERE \(created_at, id\) > \(\$1, \$2\) ORDER BY created_at ASC, id ASC LIMIT \$3`,
+			wantArgs:  []driver.Value{created, int64(9), 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			mock.ExpectQuery(tt.wantQuery).
+				WithArgs(tt.wantArgs...).
+				WillReturnRows(sqlmock.NewRows(calculationRowColumns).
+					AddRow(9, created, "support", 751, "{250,500}", []byte(`{}`), []byte(`[]`), []byte(`{}`), "", "dp", 1).
+					AddRow(10, created, "10.0.0.7", 1, "{}", []byte(`{}`), []byte(`[]`), nil, "no package sizes configured", "dp", 1))
+
+			got, err := repo.GetCalculations(tt.filter)
+			require.NoError(t, err)
+			require.Len(t, got, 2)
+			require.Equal(t, []int64{250, 500}, got[0].PackSizes)
+			require.Equal(t, []byte(`{}`), got[0].Result)
+			require.Nil(t, got[1].Result)
+			require.Equal(t, "no package sizes configured", got[1].Error)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_DeleteCalculations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectExec(`DELETE FROM calculation WHERE created_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	deleted, err := repo.DeleteCalculations(before)
+	require.NoError(t, err)
+	require.Equal(t, 12, deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
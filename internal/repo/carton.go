@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	// ErrCartonNotFound is returned when a carton does not exist
+	ErrCartonNotFound = errors.New("carton not found")
+	// ErrCartonExists is returned when a carton name is already taken
+	ErrCartonExists = errors.New("carton already exists")
+)
+
+// Carton is a shipping carton the packs of an order are put in
+type Carton struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Length, Width and Height are the inner dimensions in millimetres
+	Length int `json:"length"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// MaxWeight is the most weight in grams the carton carries
+	MaxWeight int `json:"maxWeight"`
+}
+
+func (r *Repository) GetCartons() ([]Carton, error) {
+	query := `SELECT id, name, length, width, height, max_weight FROM carton ORDER BY id`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Printf("Error querying cartons: %v", err)
+		return nil, fmt.Errorf("failed to get cartons: %w", err)
+	}
+	defer rows.Close()
+
+	cartons := make([]Carton, 0)
+	for rows.Next() {
+		var carton Carton
+		if err := rows.Scan(&carton.ID, &carton.Name, &carton.Length, &carton.Width, &carton.Height, &carton.MaxWeight); err != nil {
+			log.Printf("Error scanning carton row: %v", err)
+			return nil, fmt.Errorf("failed to scan carton: %w", err)
+		}
+		cartons = append(cartons, carton)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating carton rows: %v", err)
+		return nil, fmt.Errorf("error iterating cartons: %w", err)
+	}
+
+	return cartons, nil
+}
+
+func (r *Repository) AddCarton(carton Carton) (Carton, error) {
+	query := `INSERT INTO carton (name, length, width, height, max_weight) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := r.db.QueryRow(query, carton.Name, carton.Length, carton.Width, carton.Height, carton.MaxWeight).Scan(&carton.ID)
+	if err != nil {
+		log.Printf("Error adding carton (name: %s): %v", carton.Name, err)
+		if isPQError(err, uniqueViolation) {
+			return carton, fmt.Errorf("%w: %s", ErrCartonExists, carton.Name)
+		}
+		return carton, fmt.Errorf("failed to add carton: %w", err)
+	}
+	return carton, nil
+}
+
+func (r *Repository) DeleteCarton(id int) error {
+	query := `DELETE FROM carton WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		log.Printf("Error executing delete carton query (id: %d): %v", id, err)
+		return fmt.Errorf("failed to delete carton: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for delete carton (id: %d): %v", id, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrCartonNotFound, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_GetCartons(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, name, length, width, height, max_weight FROM carton ORDER BY id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "length", "width", "height", "max_weight"}).
+			AddRow(1, "small", 300, 200, 200, 10000).
+			AddRow(2, "large", 600, 400, 400, 30000))
+	mock.ExpectQuery(`SELECT id, name, length, width, height, max_weight FROM carton ORDER BY id`).
+		WillReturnError(sql.ErrConnDone)
+
+	got, err := repo.GetCartons()
+	require.NoError(t, err)
+	require.Equal(t, []Carton{
+		{ID: 1, Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000},
+		{ID: 2, Name: "large", Length: 600, Width: 400, Height: 400, MaxWeight: 30000},
+	}, got)
+
+	got, err = repo.GetCartons()
+	require.Error(t, err)
+	require.Nil(t, got)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_AddCarton(t *testing.T) {
+	carton := Carton{Name: "small", Length: 300, Width: 200, Height: 200, MaxWeight: 10000}
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "successful add",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO carton \(name, length, width, height, max_weight\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`).
+					WithArgs("small", 300, 200, 200, 10000).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			},
+		},
+		{
+			name: "name taken",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO carton`).
+					WithArgs("small", 300, 200, 200, 10000).
+					WillReturnError(&pq.Error{Code: uniqueViolation})
+			},
+			wantErr: ErrCartonExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.AddCarton(carton)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				want := carton
+				want.ID = 3
+				require.Equal(t, want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_DeleteCarton(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`DELETE FROM carton WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM carton WHERE id = \$1`).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeleteCarton(2))
+	require.ErrorIs(t, repo.DeleteCarton(9), ErrCartonNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
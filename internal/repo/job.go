@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned when a job does not exist, or no job is queued
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when a job that already finished is changed
+	ErrJobFinished = errors.New("job already finished")
+	// ErrJobLost is returned when a worker saves a job it no longer runs, because the job
+	// finished, was cancelled or was queued again after its lease expired
+	ErrJobLost = errors.New("job no longer owned")
+)
+
+// Job statuses; queued and running jobs are unfinished, the others are final
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a calculation run in the background. Its request and result are JSON the
+// repository stores as they are.
+type Job struct {
+	ID     int
+	Status string
+	// Request is the calculation the job runs
+	Request []byte
+	// Done and Total are the progress of the job
+	Done  int
+	Total int
+	// Result is set once the job succeeded
+	Result []byte
+	// Error is why the job failed
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Owner is the worker running the job, set by ClaimJob
+	Owner string
+}
+
+// jobColumns are the columns scanned by scanJob
+const jobColumns = `id, status, request, done, total, result, error, created_at, updated_at`
+
+// AddJob queues a job for the request with total steps
+func (r *Repository) AddJob(request []byte, total int) (Job, error) {
+	query := `INSERT INTO job (request, total) VALUES ($1, $2) RETURNING ` + jobColumns
+	job, err := scanJob(r.db.QueryRow(query, string(request), total))
+	if err != nil {
+		log.Printf("Error adding job: %v", err)
+		return job, fmt.Errorf("failed to add job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *Repository) GetJob(id int) (Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM job WHERE id = $1`
+	job, err := scanJob(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if err != nil {
+		log.Printf("Error getting job (id: %d): %v", id, err)
+		return job, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// ClaimJob marks the oldest queued job running, leased by owner, and returns it, or
+// ErrJobNotFound when none is queued. Jobs claimed at the same time by other workers are skipped.
+func (r *Repository) ClaimJob(owner string) (Job, error) {
+	query := `UPDATE job SET status = 'running', owner = $1, heartbeat_at = now(), updated_at = now()
+		WHERE id = (SELECT id FROM job WHERE status = 'queued' ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + jobColumns
+	job, err := scanJob(r.db.QueryRow(query, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, ErrJobNotFound
+	}
+	if err != nil {
+		log.Printf("Error claiming job: %v", err)
+		return job, fmt.Errorf("failed to claim job: %w", err)
+	}
+	job.Owner = owner
+	return job, nil
+}
+
+// UpdateJob saves the status, progress, result and error of a running job and renews its lease.
+// Only the owner of the job can save it; a job that finished meanwhile, e.g. because it was
+// cancelled, or that was queued again for another worker is left alone and ErrJobLost returned.
+func (r *Repository) UpdateJob(job Job) error {
+	query := `UPDATE job SET status = $2, done = $3, total = $4, result = $5, error = $6, heartbeat_at = now(), updated_at = now()
+		WHERE id = $1 AND status = 'running' AND owner = $7`
+	var jobResult interface{}
+	if job.Result != nil {
+		jobResult = string(job.Result)
+	}
+	result, err := r.db.Exec(query, job.ID, job.Status, job.Done, job.Total, jobResult, job.Error, job.Owner)
+	if err != nil {
+		log.Printf("Error updating job (id: %d, status: %s): %v", job.ID, job.Status, err)
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for update job (id: %d): %v", job.ID, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrJobLost, job.ID)
+	}
+
+	return nil
+}
+
+// HeartbeatJob renews the lease of owner on a running job, returning ErrJobLost when the job
+// finished or another worker took it over meanwhile
+func (r *Repository) HeartbeatJob(id int, owner string) error {
+	query := `UPDATE job SET heartbeat_at = now() WHERE id = $1 AND status = 'running' AND owner = $2`
+	result, err := r.db.Exec(query, id, owner)
+	if err != nil {
+		log.Printf("Error renewing job lease (id: %d): %v", id, err)
+		return fmt.Errorf("failed to renew job lease: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for job heartbeat (id: %d): %v", id, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrJobLost, id)
+	}
+
+	return nil
+}
+
+// CancelJob marks an unfinished job cancelled and returns it
+func (r *Repository) CancelJob(id int) (Job, error) {
+	query := `UPDATE job SET status = 'cancelled', updated_at = now()
+		WHERE id = $1 AND status IN ('queued', 'running') RETURNING ` + jobColumns
+	job, err := scanJob(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		// Tell a finished job from a missing one
+		if job, err = r.GetJob(id); err != nil {
+			return job, err
+		}
+		return job, fmt.Errorf("%w: %d is %s", ErrJobFinished, id, job.Status)
+	}
+	if err != nil {
+		log.Printf("Error cancelling job (id: %d): %v", id, err)
+		return job, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return job, nil
+}
+
+// RequeueJobs queues the running jobs whose lease expired, with no heartbeat for longer than
+// lease, e.g. because the server running them stopped, and returns how many there were
+func (r *Repository) RequeueJobs(lease time.Duration) (int, error) {
+	query := `UPDATE job SET status = 'queued', owner = '', updated_at = now()
+		WHERE status = 'running' AND heartbeat_at < now() - $1 * interval '1 second'`
+	result, err := r.db.Exec(query, lease.Seconds())
+	if err != nil {
+		log.Printf("Error requeueing jobs: %v", err)
+		return 0, fmt.Errorf("failed to requeue jobs: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for requeue jobs: %v", err)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// scanJob scans a row of jobColumns
+func scanJob(row *sql.Row) (Job, error) {
+	var job Job
+	err := row.Scan(&job.ID, &job.Status, &job.Request, &job.Done, &job.Total, &job.Result, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	return job, err
+}
@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+var jobRowColumns = []string{"id", "status", "request", "done", "total", "result", "error", "created_at", "updated_at"}
+
+func TestRepository_AddJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`INSERT INTO job \(request, total\) VALUES \(\$1, \$2\) RETURNING id, status`).
+		WithArgs(`{"orders":[251]}`, 1).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(3, JobQueued, []byte(`{"orders":[251]}`), 0, 1, nil, "", created, created))
+
+	got, err := repo.AddJob([]byte(`{"orders":[251]}`), 1)
+	require.NoError(t, err)
+	require.Equal(t, Job{ID: 3, Status: JobQueued, Request: []byte(`{"orders":[251]}`), Total: 1, CreatedAt: created, UpdatedAt: created}, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_GetJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, status, .* FROM job WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(3, JobSucceeded, []byte(`{}`), 1, 1, []byte(`[]`), "", created, created))
+	mock.ExpectQuery(`SELECT id, status, .* FROM job WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnError(sql.ErrNoRows)
+
+	got, err := repo.GetJob(3)
+	require.NoError(t, err)
+	require.Equal(t, JobSucceeded, got.Status)
+	require.Equal(t, []byte(`[]`), got.Result)
+
+	_, err = repo.GetJob(4)
+	require.ErrorIs(t, err, ErrJobNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_ClaimJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`UPDATE job SET status = 'running', owner = \$1, heartbeat_at = now\(\).*FOR UPDATE SKIP LOCKED`).
+		WithArgs("server-a").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(3, JobRunning, []byte(`{}`), 0, 1, nil, "", created, created))
+	mock.ExpectQuery(`UPDATE job SET status = 'running'.*FOR UPDATE SKIP LOCKED`).
+		WithArgs("server-a").
+		WillReturnError(sql.ErrNoRows)
+
+	got, err := repo.ClaimJob("server-a")
+	require.NoError(t, err)
+	require.Equal(t, 3, got.ID)
+	require.Equal(t, JobRunning, got.Status)
+	require.Equal(t, "server-a", got.Owner)
+
+	_, err = repo.ClaimJob("server-a")
+	require.ErrorIs(t, err, ErrJobNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdateJob(t *testing.T) {
+	tests := []struct {
+		name      string
+		job       Job
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "progress",
+			job:  Job{ID: 3, Status: JobRunning, Done: 5, Total: 10, Owner: "server-a"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE job SET status = \$2, .* WHERE id = \$1 AND status = 'running' AND owner = \$7`).
+					WithArgs(3, JobRunning, 5, 10, nil, "", "server-a").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "result",
+			job:  Job{ID: 3, Status: JobSucceeded, Done: 10, Total: 10, Result: []byte(`[]`), Owner: "server-a"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE job SET status = \$2, .* WHERE id = \$1 AND status = 'running' AND owner = \$7`).
+					WithArgs(3, JobSucceeded, 10, 10, "[]", "", "server-a").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "finished meanwhile",
+			job:  Job{ID: 3, Status: JobFailed, Error: "invalid batch", Owner: "server-a"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE job SET status = \$2, .* WHERE id = \$1 AND status = 'running' AND owner = \$7`).
+					WithArgs(3, JobFailed, 0, 0, nil, "invalid batch", "server-a").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrJobLost,
+		},
+		{
+			name: "taken over by another worker",
+			job:  Job{ID: 3, Status: JobRunning, Done: 7, Total: 10, Owner: "server-b"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE job SET status = \$2, .* WHERE id = \$1 AND status = 'running' AND owner = \$7`).
+					WithArgs(3, JobRunning, 7, 10, nil, "", "server-b").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrJobLost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			err = repo.UpdateJob(tt.job)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_HeartbeatJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`UPDATE job SET heartbeat_at = now\(\) WHERE id = \$1 AND status = 'running' AND owner = \$2`).
+		WithArgs(3, "server-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE job SET heartbeat_at = now\(\)`).
+		WithArgs(3, "server-b").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.HeartbeatJob(3, "server-a"))
+	require.ErrorIs(t, repo.HeartbeatJob(3, "server-b"), ErrJobLost)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CancelJob(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		setupMock  func(sqlmock.Sqlmock)
+		wantStatus string
+		wantErr    error
+	}{
+		{
+			name: "running job",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE job SET status = 'cancelled'`).
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(3, JobCancelled, []byte(`{}`), 2, 10, nil, "", created, created))
+			},
+			wantStatus: JobCancelled,
+		},
+		{
+			name: "finished job",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE job SET status = 'cancelled'`).
+					WithArgs(3).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT id, status, .* FROM job WHERE id = \$1`).
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(3, JobSucceeded, []byte(`{}`), 10, 10, []byte(`[]`), "", created, created))
+			},
+			wantErr: ErrJobFinished,
+		},
+		{
+			name: "missing job",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE job SET status = 'cancelled'`).
+					WithArgs(3).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT id, status, .* FROM job WHERE id = \$1`).
+					WithArgs(3).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrJobNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.CancelJob(3)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantStatus, got.Status)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_RequeueJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	// Jobs still leased by a running server are left to it
+	mock.ExpectExec(`UPDATE job SET status = 'queued', owner = '', updated_at = now\(\)\s+WHERE status = 'running' AND heartbeat_at < now\(\) - \$1 \* interval '1 second'`).
+		WithArgs(float64(60)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	got, err := repo.RequeueJobs(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 2, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
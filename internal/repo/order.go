@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrOrderNotFound is returned when an order does not exist
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrInvalidTransition is returned when an order cannot move to a status from the one it is in
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+// Order statuses; shipped and cancelled orders are final
+const (
+	OrderPending   = "pending"
+	OrderReserved  = "reserved"
+	OrderPacked    = "packed"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+)
+
+// Stock moves of an order status change, see UpdateOrderStatus
+const (
+	// StockKept leaves the stock alone
+	StockKept = 0
+	// StockReserved takes the packs of the order out of the stock
+	StockReserved = -1
+	// StockReleased puts the packs of the order back into the stock
+	StockReleased = 1
+)
+
+// Order is an order of a product with the pack plan calculated for it. The plan is JSON the
+// repository stores as it is, the packs are the pack counts per size of the plan.
+type Order struct {
+	ID        int
+	ProductID int
+	Quantity  int
+	Status    string
+	Plan      []byte
+	Packs     map[int]int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// orderColumns are the columns scanned by scanOrder
+const orderColumns = `id, product_id, quantity, status, plan, packs, created_at, updated_at`
+
+// AddOrder stores a pending order and returns it
+func (r *Repository) AddOrder(order Order) (Order, error) {
+	packs, err := json.Marshal(order.Packs)
+	if err != nil {
+		return order, fmt.Errorf("failed to encode packs: %w", err)
+	}
+	query := `INSERT INTO orders (product_id, quantity, plan, packs) VALUES ($1, $2, $3, $4) RETURNING ` + orderColumns
+	stored, err := scanOrder(r.db.QueryRow(query, order.ProductID, order.Quantity, string(order.Plan), string(packs)))
+	if err != nil {
+		log.Printf("Error adding order (product: %d, quantity: %d): %v", order.ProductID, order.Quantity, err)
+		if isPQError(err, foreignKeyViolation) {
+			return order, fmt.Errorf("%w: %d", ErrProductNotFound, order.ProductID)
+		}
+		return order, fmt.Errorf("failed to add order: %w", err)
+	}
+	return stored, nil
+}
+
+func (r *Repository) GetOrder(id int) (Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	order, err := scanOrder(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return order, fmt.Errorf("%w: %d", ErrOrderNotFound, id)
+	}
+	if err != nil {
+		log.Printf("Error getting order (id: %d): %v", id, err)
+		return order, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+// GetOrders returns the orders in a status, or all orders when status is empty, newest first
+func (r *Repository) GetOrders(status string) ([]Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE $1 = '' OR status = $1 ORDER BY id DESC`
+	rows, err := r.db.Query(query, status)
+	if err != nil {
+		log.Printf("Error querying orders: %v", err)
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Printf("Error scanning order row: %v", err)
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating order rows: %v", err)
+		return nil, fmt.Errorf("error iterating orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// UpdateOrderStatus moves an order from one status to another and returns it. The packs of the
+// order are moved by stock in the same transaction, taken out of or put back into the stock of
+// the product in the default warehouse; sizes without a stock level are not tracked and left
+// alone. If a tracked size runs short nothing changes and ErrInsufficientStock is returned, an
+// order no longer in the from status is left alone and ErrInvalidTransition returned.
+func (r *Repository) UpdateOrderStatus(id int, from, to string, stock int) (Order, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting order status update: %v", err)
+		return Order{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE orders SET status = $3, updated_at = now() WHERE id = $1 AND status = $2 RETURNING ` + orderColumns
+	order, err := scanOrder(tx.QueryRow(query, id, from, to))
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		// Tell an order in another status from a missing one
+		if order, err = r.GetOrder(id); err != nil {
+			return order, err
+		}
+		return order, fmt.Errorf("%w: order %d is %s, not %s", ErrInvalidTransition, id, order.Status, from)
+	}
+	if err != nil {
+		log.Printf("Error updating order status (id: %d, status: %s): %v", id, to, err)
+		return order, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if stock != StockKept {
+		query := `UPDATE stock SET quantity = quantity + $4 WHERE warehouse_id = $1 AND product_id = $2 AND size = $3`
+		for _, size := range packSizes(order.Packs) {
+			count := order.Packs[size]
+			if _, err := tx.Exec(query, DefaultWarehouseID, order.ProductID, size, stock*count); err != nil {
+				log.Printf("Error moving stock of order (id: %d, size: %d, count: %d): %v", id, size, stock*count, err)
+				return order, fmt.Errorf("failed to move stock for size %d: %w", size, stockError(err))
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing order status transaction: %v", err)
+		return order, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return order, nil
+}
+
+// scanOrder scans a row of orderColumns
+func scanOrder(row interface{ Scan(...interface{}) error }) (Order, error) {
+	var order Order
+	var packs []byte
+	if err := row.Scan(&order.ID, &order.ProductID, &order.Quantity, &order.Status, &order.Plan, &packs, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		return order, err
+	}
+	if err := json.Unmarshal(packs, &order.Packs); err != nil {
+		return order, fmt.Errorf("failed to decode packs of order %d: %w", order.ID, err)
+	}
+	return order, nil
+}
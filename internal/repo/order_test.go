@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var orderRowColumns = []string{"id", "product_id", "quantity", "status", "plan", "packs", "created_at", "updated_at"}
+
+func TestRepository_AddOrder(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	order := Order{ProductID: 2, Quantity: 751, Plan: []byte(`{"requested":751}`), Packs: map[int]int{500: 1, 250: 2}}
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      Order
+		wantErr   error
+	}{
+		{
+			name: "successful add",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO orders \(product_id, quantity, plan, packs\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING id, product_id`).
+					WithArgs(2, 751, `{"requested":751}`, `{"250":2,"500":1}`).
+					WillReturnRows(sqlmock.NewRows(orderRowColumns).
+						AddRow(4, 2, 751, OrderPending, []byte(`{"requested":751}`), []byte(`{"250":2,"500":1}`), created, created))
+			},
+			want: Order{ID: 4, ProductID: 2, Quantity: 751, Status: OrderPending, Plan: []byte(`{"requested":751}`),
+				Packs: map[int]int{500: 1, 250: 2}, CreatedAt: created, UpdatedAt: created},
+		},
+		{
+			name: "unknown product",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO orders`).
+					WillReturnError(&pq.Error{Code: foreignKeyViolation})
+			},
+			wantErr: ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.AddOrder(order)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_GetOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, product_id, .* FROM orders WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(orderRowColumns).AddRow(4, 1, 251, OrderReserved, []byte(`{}`), []byte(`{"500":1}`), created, created))
+	mock.ExpectQuery(`SELECT id, product_id, .* FROM orders WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnError(sql.ErrNoRows)
+
+	got, err := repo.GetOrder(4)
+	require.NoError(t, err)
+	require.Equal(t, OrderReserved, got.Status)
+	require.Equal(t, map[int]int{500: 1}, got.Packs)
+
+	_, err = repo.GetOrder(5)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_GetOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, product_id, .* FROM orders WHERE \$1 = '' OR status = \$1 ORDER BY id DESC`).
+		WithArgs(OrderPending).
+		WillReturnRows(sqlmock.NewRows(orderRowColumns).
+			AddRow(5, 1, 12001, OrderPending, []byte(`{}`), []byte(`{"5000":2,"2000":1,"250":1}`), created, created).
+			AddRow(4, 1, 1, OrderPending, []byte(`{}`), []byte(`{"250":1}`), created, created))
+
+	got, err := repo.GetOrders(OrderPending)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, 5, got[0].ID)
+	require.Equal(t, map[int]int{5000: 2, 2000: 1, 250: 1}, got[0].Packs)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdateOrderStatus(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	row := func(status string) *sqlmock.Rows {
+		return sqlmock.NewRows(orderRowColumns).
+			AddRow(4, 2, 751, status, []byte(`{}`), []byte(`{"500":1,"250":2}`), created, created)
+	}
+	updateOrder := `UPDATE orders SET status = \$3, updated_at = now\(\) WHERE id = \$1 AND status = \$2 RETURNING id, product_id`
+	moveStock := `UPDATE stock SET quantity = quantity \+ \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`
+
+	tests := []struct {
+		name      string
+		from, to  string
+		stock     int
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "reserve takes the packs out of the stock",
+			from: OrderPending, to: OrderReserved, stock: StockReserved,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderPending, OrderReserved).WillReturnRows(row(OrderReserved))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 250, -2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 500, -1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "cancel puts the packs back",
+			from: OrderPacked, to: OrderCancelled, stock: StockReleased,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderPacked, OrderCancelled).WillReturnRows(row(OrderCancelled))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 250, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 500, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "pack leaves the stock alone",
+			from: OrderReserved, to: OrderPacked, stock: StockKept,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderReserved, OrderPacked).WillReturnRows(row(OrderPacked))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "insufficient stock rolls back",
+			from: OrderPending, to: OrderReserved, stock: StockReserved,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderPending, OrderReserved).WillReturnRows(row(OrderReserved))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 250, -2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(moveStock).WithArgs(1, 2, 500, -1).WillReturnError(&pq.Error{Code: checkViolation})
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name: "order moved on meanwhile",
+			from: OrderPending, to: OrderReserved, stock: StockReserved,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderPending, OrderReserved).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				mock.ExpectQuery(`SELECT id, product_id, .* FROM orders WHERE id = \$1`).WithArgs(4).WillReturnRows(row(OrderCancelled))
+			},
+			wantErr: ErrInvalidTransition,
+		},
+		{
+			name: "order not found",
+			from: OrderPending, to: OrderReserved, stock: StockReserved,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateOrder).WithArgs(4, OrderPending, OrderReserved).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				mock.ExpectQuery(`SELECT id, product_id, .* FROM orders WHERE id = \$1`).WithArgs(4).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.UpdateOrderStatus(4, tt.from, tt.to, tt.stock)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.to, got.Status)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
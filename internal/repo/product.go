@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrProductNotFound is returned when a product does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrProductExists is returned when a product name is already taken
+	ErrProductExists = errors.New("product already exists")
+)
+
+// DefaultProductID is the product the packs and stock of the single product setup belong to
+const DefaultProductID = 1
+
+const (
+	// checkViolation is the postgres error code of a failed CHECK constraint
+	checkViolation = "23514"
+	// uniqueViolation is the postgres error code of a failed UNIQUE constraint
+	uniqueViolation = "23505"
+	// foreignKeyViolation is the postgres error code of a failed FOREIGN KEY constraint
+	foreignKeyViolation = "23503"
+)
+
+// Product is an item sold in its own pack sizes
+type Product struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (r *Repository) GetProducts() ([]Product, error) {
+	query := `SELECT id, name FROM product ORDER BY id`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Printf("Error querying products: %v", err)
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]Product, 0)
+	for rows.Next() {
+		var product Product
+		if err := rows.Scan(&product.ID, &product.Name); err != nil {
+			log.Printf("Error scanning product row: %v", err)
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, product)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating product rows: %v", err)
+		return nil, fmt.Errorf("error iterating products: %w", err)
+	}
+
+	return products, nil
+}
+
+func (r *Repository) AddProduct(name string) (Product, error) {
+	query := `INSERT INTO product (name) VALUES ($1) RETURNING id`
+	product := Product{Name: name}
+	if err := r.db.QueryRow(query, name).Scan(&product.ID); err != nil {
+		log.Printf("Error adding product (name: %s): %v", name, err)
+		if isPQError(err, uniqueViolation) {
+			return product, fmt.Errorf("%w: %s", ErrProductExists, name)
+		}
+		return product, fmt.Errorf("failed to add product: %w", err)
+	}
+	return product, nil
+}
+
+// DeleteProduct deletes a product along with its packs and stock
+func (r *Repository) DeleteProduct(id int) error {
+	query := `DELETE FROM product WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		log.Printf("Error executing delete product query (id: %d): %v", id, err)
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for delete product (id: %d): %v", id, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, id)
+	}
+
+	return nil
+}
+
+// isPQError reports whether err is a postgres error with the given code
+func isPQError(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
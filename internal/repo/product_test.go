@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_GetProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, name FROM product ORDER BY id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "default").AddRow(2, "bolts"))
+	mock.ExpectQuery(`SELECT id, name FROM product ORDER BY id`).
+		WillReturnError(sql.ErrConnDone)
+
+	got, err := repo.GetProducts()
+	require.NoError(t, err)
+	require.Equal(t, []Product{{ID: 1, Name: "default"}, {ID: 2, Name: "bolts"}}, got)
+
+	got, err = repo.GetProducts()
+	require.Error(t, err)
+	require.Nil(t, got)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_AddProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      Product
+		wantErr   error
+	}{
+		{
+			name: "successful add",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product \(name\) VALUES \(\$1\) RETURNING id`).
+					WithArgs("bolts").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			},
+			want: Product{ID: 2, Name: "bolts"},
+		},
+		{
+			name: "name taken",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product \(name\) VALUES \(\$1\) RETURNING id`).
+					WithArgs("bolts").
+					WillReturnError(&pq.Error{Code: uniqueViolation})
+			},
+			wantErr: ErrProductExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.AddProduct("bolts")
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_DeleteProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`DELETE FROM product WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM product WHERE id = \$1`).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeleteProduct(2))
+	require.ErrorIs(t, repo.DeleteProduct(9), ErrProductNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,11 +1,46 @@
 package repo
 
+import "time"
+
 // RepositoryInterface defines the interface for Repository to enable mocking in tests
 type RepositoryInterface interface {
-	AddPackage(packageSize int) error
-	GetPackages() ([]int, error)
-	GetPackagesMap() (map[string]int, error)
+	AddPackage(pkg Package) error
+	GetPackages(productID int) ([]int, error)
+	GetCatalog(productID int) ([]Package, error)
+	GetWarehouseCatalog(warehouseID, productID int) ([]Package, error)
+	GetPackagesMap(productID int) (map[string]int, error)
 	DeletePackageById(id string) error
+	SetPackageConstraints(id, minCount, maxCount int) (Package, error)
+	GetProducts() ([]Product, error)
+	AddProduct(name string) (Product, error)
+	DeleteProduct(id int) error
+	GetStock(productID int) ([]StockLevel, error)
+	SetStock(productID, size, quantity int) error
+	GetWarehouseStock(warehouseID, productID int) ([]StockLevel, error)
+	SetWarehouseStock(warehouseID, productID, size, quantity int) error
+	AdjustStock(productID, size, delta int) (StockLevel, error)
+	DeleteStock(productID, size int) error
+	CommitStock(productID int, packs map[int]int) error
+	AddJob(request []byte, total int) (Job, error)
+	GetJob(id int) (Job, error)
+	ClaimJob(owner string) (Job, error)
+	UpdateJob(job Job) error
+	HeartbeatJob(id int, owner string) error
+	CancelJob(id int) (Job, error)
+	RequeueJobs(lease time.Duration) (int, error)
+	GetCartons() ([]Carton, error)
+	AddCarton(carton Carton) (Carton, error)
+	DeleteCarton(id int) error
+	GetWarehouses() ([]Warehouse, error)
+	AddWarehouse(warehouse Warehouse) (Warehouse, error)
+	DeleteWarehouse(id int) error
+	AddOrder(order Order) (Order, error)
+	GetOrder(id int) (Order, error)
+	GetOrders(status string) ([]Order, error)
+	UpdateOrderStatus(id int, from, to string, stock int) (Order, error)
+	AddCalculation(c Calculation) error
+	GetCalculations(filter CalculationFilter) ([]Calculation, error)
+	DeleteCalculations(before time.Time) (int, error)
 	Close() error
 }
 
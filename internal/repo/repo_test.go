@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,27 +14,67 @@ var _ RepositoryInterface = (*Repository)(nil)
 
 func TestRepository_AddPackage(t *testing.T) {
 	tests := []struct {
-		name        string
-		packageSize int
-		setupMock   func(sqlmock.Sqlmock)
-		wantErr     bool
+		name      string
+		pkg       Package
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   bool
 	}{
 		{
-			name:        "successful add",
-			packageSize: 10,
+			name: "successful add",
+			pkg:  Package{ProductID: 1, WarehouseID: 1, Size: 10},
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`INSERT INTO package \(size\) VALUES \(\$1\) RETURNING id`).
-					WithArgs(10).
+				mock.ExpectQuery(`INSERT INTO package \(product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, NULLIF\(\$8, 0\), \$9, \$10, \$11, \$12, \$13\) RETURNING id`).
+					WithArgs(1, 1, 10, 0.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 			},
 			wantErr: false,
 		},
 		{
-			name:        "database error",
-			packageSize: 5,
+			name: "successful add with costs and constraints",
+			pkg:  Package{ProductID: 2, WarehouseID: 3, Size: 5000, Price: 12.5, HandlingCost: 0.75, MaxCount: 2, Length: 400, Width: 300, Height: 250, Weight: 5200},
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`INSERT INTO package \(size\) VALUES \(\$1\) RETURNING id`).
-					WithArgs(5).
+				mock.ExpectQuery(`INSERT INTO package \(product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, NULLIF\(\$8, 0\), \$9, \$10, \$11, \$12, \$13\) RETURNING id`).
+					WithArgs(2, 3, 5000, 12.5, 0.75, 0, 2, 0, 0, 400, 300, 250, 5200).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			},
+			wantErr: false,
+		},
+		{
+			name: "successful add of a nested pack",
+			pkg:  Package{ProductID: 1, WarehouseID: 1, Size: 1000, InnerID: 4, InnerCount: 4},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO package \(product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, NULLIF\(\$8, 0\), \$9, \$10, \$11, \$12, \$13\) RETURNING id`).
+					WithArgs(1, 1, 1000, 0.0, 0.0, 0, 0, 4, 4, 0, 0, 0, 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown product",
+			pkg:  Package{ProductID: 9, WarehouseID: 1, Size: 5},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO package \(product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, NULLIF\(\$8, 0\), \$9, \$10, \$11, \$12, \$13\) RETURNING id`).
+					WithArgs(9, 1, 5, 0.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0).
+					WillReturnError(&pq.Error{Code: foreignKeyViolation})
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown warehouse",
+			pkg:  Package{ProductID: 1, WarehouseID: 9, Size: 5},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO package`).
+					WithArgs(1, 9, 5, 0.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0).
+					WillReturnError(&pq.Error{Code: foreignKeyViolation, Constraint: "package_warehouse_id_fkey"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "database error",
+			pkg:  Package{ProductID: 1, WarehouseID: 1, Size: 5},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO package \(product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, NULLIF\(\$8, 0\), \$9, \$10, \$11, \$12, \$13\) RETURNING id`).
+					WithArgs(1, 1, 5, 0.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0).
 					WillReturnError(sql.ErrConnDone)
 			},
 			wantErr: true,
@@ -49,7 +90,7 @@ func TestRepository_AddPackage(t *testing.T) {
 			repo := &Repository{db: db}
 			tt.setupMock(mock)
 
-			err = repo.AddPackage(tt.packageSize)
+			err = repo.AddPackage(tt.pkg)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -62,6 +103,20 @@ func TestRepository_AddPackage(t *testing.T) {
 	}
 }
 
+func TestRepository_AddPackage_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`INSERT INTO package`).
+		WithArgs(1, 1, 250, 0.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0).
+		WillReturnError(&pq.Error{Code: uniqueViolation})
+
+	require.ErrorIs(t, repo.AddPackage(Package{ProductID: 1, WarehouseID: 1, Size: 250}), ErrPackageExists)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRepository_GetPackages(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -76,7 +131,8 @@ func TestRepository_GetPackages(t *testing.T) {
 					AddRow(1).
 					AddRow(2).
 					AddRow(3)
-				mock.ExpectQuery(`SELECT size FROM package ORDER BY size`).
+				mock.ExpectQuery(`SELECT size FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(DefaultWarehouseID, DefaultProductID).
 					WillReturnRows(rows)
 			},
 			want:    []int{1, 2, 3},
@@ -86,7 +142,8 @@ func TestRepository_GetPackages(t *testing.T) {
 			name: "empty result",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"size"})
-				mock.ExpectQuery(`SELECT size FROM package ORDER BY size`).
+				mock.ExpectQuery(`SELECT size FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(DefaultWarehouseID, DefaultProductID).
 					WillReturnRows(rows)
 			},
 			want:    []int{},
@@ -95,7 +152,8 @@ func TestRepository_GetPackages(t *testing.T) {
 		{
 			name: "database error",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT size FROM package ORDER BY size`).
+				mock.ExpectQuery(`SELECT size FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(DefaultWarehouseID, DefaultProductID).
 					WillReturnError(sql.ErrConnDone)
 			},
 			want:    nil,
@@ -112,7 +170,7 @@ func TestRepository_GetPackages(t *testing.T) {
 			repo := &Repository{db: db}
 			tt.setupMock(mock)
 
-			got, err := repo.GetPackages()
+			got, err := repo.GetPackages(DefaultProductID)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -127,6 +185,117 @@ func TestRepository_GetPackages(t *testing.T) {
 	}
 }
 
+func TestRepository_GetCatalog(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      []Package
+		wantErr   bool
+	}{
+		{
+			name: "successful get",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "product_id", "warehouse_id", "size", "price", "handling_cost", "min_count", "max_count", "inner_id", "inner_count", "length", "width", "height", "weight"}).
+					AddRow(1, 2, 1, 250, 1.5, 0.1, 1, 0, 0, 0, 200, 150, 100, 2600).
+					AddRow(2, 2, 1, 1000, 3.5, 0.2, 0, 0, 1, 4, 0, 0, 0, 0).
+					AddRow(3, 2, 1, 5000, 12.0, 0.5, 0, 2, 0, 0, 0, 0, 0, 0)
+				mock.ExpectQuery(`SELECT id, product_id, warehouse_id, size, price, handling_cost, min_count, max_count, COALESCE\(inner_id, 0\), inner_count, length, width, height, weight FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(1, 2).
+					WillReturnRows(rows)
+			},
+			want: []Package{
+				{ID: 1, ProductID: 2, WarehouseID: 1, Size: 250, Price: 1.5, HandlingCost: 0.1, MinCount: 1, Length: 200, Width: 150, Height: 100, Weight: 2600},
+				{ID: 2, ProductID: 2, WarehouseID: 1, Size: 1000, Price: 3.5, HandlingCost: 0.2, InnerID: 1, InnerCount: 4},
+				{ID: 3, ProductID: 2, WarehouseID: 1, Size: 5000, Price: 12.0, HandlingCost: 0.5, MaxCount: 2},
+			},
+			wantErr: false,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, product_id, warehouse_id, size, price, handling_cost, min_count, max_count, COALESCE\(inner_id, 0\), inner_count, length, width, height, weight FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(1, 2).
+					WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.GetCatalog(2)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_SetPackageConstraints(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      Package
+		wantErr   error
+	}{
+		{
+			name: "successful update",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE package SET min_count = \$2, max_count = \$3 WHERE id = \$1`).
+					WithArgs(7, 1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "warehouse_id", "size", "price", "handling_cost", "min_count", "max_count", "inner_id", "inner_count", "length", "width", "height", "weight"}).
+						AddRow(7, 2, 1, 250, 1.5, 0.1, 1, 2, 0, 0, 0, 0, 0, 0))
+			},
+			want: Package{ID: 7, ProductID: 2, WarehouseID: 1, Size: 250, Price: 1.5, HandlingCost: 0.1, MinCount: 1, MaxCount: 2},
+		},
+		{
+			name: "unknown package",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE package SET min_count`).
+					WithArgs(7, 1, 2).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrPackageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.SetPackageConstraints(7, 1, 2)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestRepository_GetPackagesMap(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -141,7 +310,8 @@ func TestRepository_GetPackagesMap(t *testing.T) {
 					AddRow(1, 10).
 					AddRow(2, 20).
 					AddRow(3, 30)
-				mock.ExpectQuery(`SELECT id, size FROM package`).
+				mock.ExpectQuery(`SELECT id, size FROM package WHERE warehouse_id = \$1 AND product_id = \$2`).
+					WithArgs(DefaultWarehouseID, DefaultProductID).
 					WillReturnRows(rows)
 			},
 			want:    map[string]int{"1": 10, "2": 20, "3": 30},
@@ -150,7 +320,8 @@ func TestRepository_GetPackagesMap(t *testing.T) {
 		{
 			name: "database error",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, size FROM package`).
+				mock.ExpectQuery(`SELECT id, size FROM package WHERE warehouse_id = \$1 AND product_id = \$2`).
+					WithArgs(DefaultWarehouseID, DefaultProductID).
 					WillReturnError(sql.ErrConnDone)
 			},
 			want:    nil,
@@ -167,7 +338,7 @@ func TestRepository_GetPackagesMap(t *testing.T) {
 			repo := &Repository{db: db}
 			tt.setupMock(mock)
 
-			got, err := repo.GetPackagesMap()
+			got, err := repo.GetPackagesMap(DefaultProductID)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -182,12 +353,37 @@ func TestRepository_GetPackagesMap(t *testing.T) {
 	}
 }
 
+func TestRepository_GetPackagesMap_Product(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Only the packs of the product in the default warehouse are selected, not those of the
+	// default product or of other warehouses
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, size FROM package WHERE warehouse_id = \$1 AND product_id = \$2`).
+		WithArgs(DefaultWarehouseID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(3, 6))
+	mock.ExpectQuery(`SELECT size FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+		WithArgs(DefaultWarehouseID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"size"}).AddRow(6))
+
+	packages, err := repo.GetPackagesMap(2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"3": 6}, packages)
+	sizes, err := repo.GetPackages(2)
+	require.NoError(t, err)
+	require.Equal(t, []int{6}, sizes)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRepository_DeletePackageById(t *testing.T) {
 	tests := []struct {
 		name      string
 		id        string
 		setupMock func(sqlmock.Sqlmock)
 		wantErr   bool
+		wantErrIs error
 	}{
 		{
 			name: "successful delete",
@@ -207,7 +403,19 @@ func TestRepository_DeletePackageById(t *testing.T) {
 					WithArgs("999").
 					WillReturnResult(sqlmock.NewResult(0, 0))
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: ErrPackageNotFound,
+		},
+		{
+			name: "held by other packs",
+			id:   "1",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM package WHERE id = \$1`).
+					WithArgs("1").
+					WillReturnError(&pq.Error{Code: foreignKeyViolation})
+			},
+			wantErr:   true,
+			wantErrIs: ErrPackageInUse,
 		},
 		{
 			name: "database error",
@@ -234,6 +442,9 @@ func TestRepository_DeletePackageById(t *testing.T) {
 
 			if tt.wantErr {
 				require.Error(t, err)
+				if tt.wantErrIs != nil {
+					require.ErrorIs(t, err, tt.wantErrIs)
+				}
 			} else {
 				require.NoError(t, err)
 			}
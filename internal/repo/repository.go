@@ -2,6 +2,7 @@ package repo
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -13,6 +14,50 @@ type Repository struct {
 	db *sql.DB
 }
 
+// Package is a pack size of the catalog of a product with its costs
+type Package struct {
+	ID int `json:"id"`
+	// ProductID is the product packed in this size
+	ProductID int `json:"productId"`
+	// WarehouseID is the warehouse whose catalog the package belongs to
+	WarehouseID int `json:"warehouseId"`
+	Size        int `json:"size"`
+	// Price is the cost of a single pack
+	Price float64 `json:"price"`
+	// HandlingCost is the optional extra cost of handling a single pack
+	HandlingCost float64 `json:"handlingCost"`
+	// MinCount is the least number of packs of this size every order ships, 0 for none
+	MinCount int `json:"minCount"`
+	// MaxCount is the most packs of this size a single order ships, 0 for no limit
+	MaxCount int `json:"maxCount"`
+	// InnerID is the package this pack holds InnerCount packs of, 0 for a pack of loose items
+	InnerID    int `json:"innerId,omitempty"`
+	InnerCount int `json:"innerCount,omitempty"`
+	// Length, Width and Height are the outer dimensions of a pack in millimetres, 0 when not known
+	Length int `json:"length,omitempty"`
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Weight is the weight of a full pack in grams, 0 when not known
+	Weight int `json:"weight,omitempty"`
+}
+
+var (
+	// ErrPackageExists is returned when a product already has a package of the same size
+	ErrPackageExists = errors.New("package size already exists")
+	// ErrPackageNotFound is returned when a package does not exist
+	ErrPackageNotFound = errors.New("package not found")
+	// ErrPackageInUse is returned when a package that other packs hold is deleted
+	ErrPackageInUse = errors.New("package is held by other packs")
+)
+
+// packageColumns are the columns scanned into Package.fields
+const packageColumns = `id, product_id, warehouse_id, size, price, handling_cost, min_count, max_count, COALESCE(inner_id, 0), inner_count, length, width, height, weight`
+
+// fields returns the destinations of packageColumns
+func (p *Package) fields() []interface{} {
+	return []interface{}{&p.ID, &p.ProductID, &p.WarehouseID, &p.Size, &p.Price, &p.HandlingCost, &p.MinCount, &p.MaxCount, &p.InnerID, &p.InnerCount, &p.Length, &p.Width, &p.Height, &p.Weight}
+}
+
 // Ensure Repository implements RepositoryInterface
 var _ RepositoryInterface = (*Repository)(nil)
 
@@ -34,20 +79,32 @@ func NewRepository(dbHost, dbPort, dbUser, dbPassword, dbName string) (*Reposito
 	return &Repository{db: db}, nil
 }
 
-func (r *Repository) AddPackage(packageSize int) error {
-	query := `INSERT INTO package (size) VALUES ($1) RETURNING id`
+func (r *Repository) AddPackage(pkg Package) error {
+	query := `INSERT INTO package (product_id, warehouse_id, size, price, handling_cost, min_count, max_count, inner_id, inner_count, length, width, height, weight)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9, $10, $11, $12, $13) RETURNING id`
 	var id int
-	err := r.db.QueryRow(query, packageSize).Scan(&id)
+	err := r.db.QueryRow(query, pkg.ProductID, pkg.WarehouseID, pkg.Size, pkg.Price, pkg.HandlingCost, pkg.MinCount, pkg.MaxCount, pkg.InnerID, pkg.InnerCount,
+		pkg.Length, pkg.Width, pkg.Height, pkg.Weight).Scan(&id)
 	if err != nil {
-		log.Printf("Error adding package (size: %d): %v", packageSize, err)
+		log.Printf("Error adding package (product: %d, warehouse: %d, size: %d): %v", pkg.ProductID, pkg.WarehouseID, pkg.Size, err)
+		if warehouseViolation(err) {
+			return fmt.Errorf("%w: %d", ErrWarehouseNotFound, pkg.WarehouseID)
+		}
+		if isPQError(err, foreignKeyViolation) {
+			return fmt.Errorf("%w: %d", ErrProductNotFound, pkg.ProductID)
+		}
+		if isPQError(err, uniqueViolation) {
+			return fmt.Errorf("%w: %d", ErrPackageExists, pkg.Size)
+		}
 		return fmt.Errorf("failed to add package: %w", err)
 	}
 	return nil
 }
 
-func (r *Repository) GetPackages() ([]int, error) {
-	query := `SELECT size FROM package ORDER BY size`
-	rows, err := r.db.Query(query)
+// GetPackages returns the pack sizes of a product in the default warehouse
+func (r *Repository) GetPackages(productID int) ([]int, error) {
+	query := `SELECT size FROM package WHERE warehouse_id = $1 AND product_id = $2 ORDER BY size`
+	rows, err := r.db.Query(query, DefaultWarehouseID, productID)
 	if err != nil {
 		log.Printf("Error querying packages: %v", err)
 		return nil, fmt.Errorf("failed to get packages: %w", err)
@@ -73,9 +130,42 @@ func (r *Repository) GetPackages() ([]int, error) {
 	return packages, nil
 }
 
-func (r *Repository) GetPackagesMap() (map[string]int, error) {
-	query := `SELECT id, size FROM package`
-	rows, err := r.db.Query(query)
+// GetCatalog returns the packages of a product in the default warehouse
+func (r *Repository) GetCatalog(productID int) ([]Package, error) {
+	return r.GetWarehouseCatalog(DefaultWarehouseID, productID)
+}
+
+func (r *Repository) GetWarehouseCatalog(warehouseID, productID int) ([]Package, error) {
+	query := `SELECT ` + packageColumns + ` FROM package WHERE warehouse_id = $1 AND product_id = $2 ORDER BY size`
+	rows, err := r.db.Query(query, warehouseID, productID)
+	if err != nil {
+		log.Printf("Error querying catalog: %v", err)
+		return nil, fmt.Errorf("failed to get catalog: %w", err)
+	}
+	defer rows.Close()
+
+	catalog := make([]Package, 0)
+	for rows.Next() {
+		var pkg Package
+		if err := rows.Scan(pkg.fields()...); err != nil {
+			log.Printf("Error scanning catalog row: %v", err)
+			return nil, fmt.Errorf("failed to scan package: %w", err)
+		}
+		catalog = append(catalog, pkg)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating catalog rows: %v", err)
+		return nil, fmt.Errorf("error iterating catalog: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// GetPackagesMap returns the pack sizes of a product in the default warehouse by package ID
+func (r *Repository) GetPackagesMap(productID int) (map[string]int, error) {
+	query := `SELECT id, size FROM package WHERE warehouse_id = $1 AND product_id = $2`
+	rows, err := r.db.Query(query, DefaultWarehouseID, productID)
 	if err != nil {
 		log.Printf("Error querying packages map: %v", err)
 		return nil, fmt.Errorf("failed to get packages map: %w", err)
@@ -106,6 +196,9 @@ func (r *Repository) DeletePackageById(id string) error {
 	result, err := r.db.Exec(query, id)
 	if err != nil {
 		log.Printf("Error executing delete package query (id: %s): %v", id, err)
+		if isPQError(err, foreignKeyViolation) {
+			return fmt.Errorf("%w: %s", ErrPackageInUse, id)
+		}
 		return fmt.Errorf("failed to delete package: %w", err)
 	}
 
@@ -117,12 +210,28 @@ func (r *Repository) DeletePackageById(id string) error {
 
 	if rowsAffected == 0 {
 		log.Printf("Package with id %s not found for deletion", id)
-		return fmt.Errorf("package with id %s not found", id)
+		return fmt.Errorf("%w: %s", ErrPackageNotFound, id)
 	}
 
 	return nil
 }
 
+// SetPackageConstraints sets the least and most packs of a package per order and returns the
+// updated package
+func (r *Repository) SetPackageConstraints(id, minCount, maxCount int) (Package, error) {
+	query := `UPDATE package SET min_count = $2, max_count = $3 WHERE id = $1 RETURNING ` + packageColumns
+	var pkg Package
+	err := r.db.QueryRow(query, id, minCount, maxCount).Scan(pkg.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Package{}, fmt.Errorf("%w: %d", ErrPackageNotFound, id)
+	}
+	if err != nil {
+		log.Printf("Error setting package constraints (id: %d): %v", id, err)
+		return Package{}, fmt.Errorf("failed to set package constraints: %w", err)
+	}
+	return pkg, nil
+}
+
 func (r *Repository) Close() error {
 	if r.db != nil {
 		if err := r.db.Close(); err != nil {
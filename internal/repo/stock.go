@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+var (
+	// ErrInsufficientStock is returned when a stock level would drop below zero
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrStockNotFound is returned when a pack size has no stock level
+	ErrStockNotFound = errors.New("stock level not found")
+)
+
+// StockLevel is the number of packs of a size of a product in stock
+type StockLevel struct {
+	Size     int `json:"size"`
+	Quantity int `json:"quantity"`
+}
+
+// GetStock returns the stock levels of a product in the default warehouse, like the other stock
+// methods without a warehouse
+func (r *Repository) GetStock(productID int) ([]StockLevel, error) {
+	return r.GetWarehouseStock(DefaultWarehouseID, productID)
+}
+
+func (r *Repository) GetWarehouseStock(warehouseID, productID int) ([]StockLevel, error) {
+	query := `SELECT size, quantity FROM stock WHERE warehouse_id = $1 AND product_id = $2 ORDER BY size`
+	rows, err := r.db.Query(query, warehouseID, productID)
+	if err != nil {
+		log.Printf("Error querying stock: %v", err)
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	defer rows.Close()
+
+	levels := make([]StockLevel, 0)
+	for rows.Next() {
+		var level StockLevel
+		if err := rows.Scan(&level.Size, &level.Quantity); err != nil {
+			log.Printf("Error scanning stock row: %v", err)
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		levels = append(levels, level)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating stock rows: %v", err)
+		return nil, fmt.Errorf("error iterating stock: %w", err)
+	}
+
+	return levels, nil
+}
+
+func (r *Repository) SetStock(productID, size, quantity int) error {
+	return r.SetWarehouseStock(DefaultWarehouseID, productID, size, quantity)
+}
+
+func (r *Repository) SetWarehouseStock(warehouseID, productID, size, quantity int) error {
+	query := `INSERT INTO stock (warehouse_id, product_id, size, quantity) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (warehouse_id, product_id, size) DO UPDATE SET quantity = EXCLUDED.quantity`
+	if _, err := r.db.Exec(query, warehouseID, productID, size, quantity); err != nil {
+		log.Printf("Error setting stock (warehouse: %d, product: %d, size: %d, quantity: %d): %v", warehouseID, productID, size, quantity, err)
+		if warehouseViolation(err) {
+			return fmt.Errorf("%w: %d", ErrWarehouseNotFound, warehouseID)
+		}
+		if isPQError(err, foreignKeyViolation) {
+			return fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+		return fmt.Errorf("failed to set stock: %w", stockError(err))
+	}
+	return nil
+}
+
+func (r *Repository) AdjustStock(productID, size, delta int) (StockLevel, error) {
+	query := `UPDATE stock SET quantity = quantity + $4 WHERE warehouse_id = $1 AND product_id = $2 AND size = $3 RETURNING quantity`
+	level := StockLevel{Size: size}
+	err := r.db.QueryRow(query, DefaultWarehouseID, productID, size, delta).Scan(&level.Quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return level, fmt.Errorf("%w for size %d", ErrStockNotFound, size)
+	}
+	if err != nil {
+		log.Printf("Error adjusting stock (product: %d, size: %d, delta: %d): %v", productID, size, delta, err)
+		return level, fmt.Errorf("failed to adjust stock: %w", stockError(err))
+	}
+	return level, nil
+}
+
+func (r *Repository) DeleteStock(productID, size int) error {
+	query := `DELETE FROM stock WHERE warehouse_id = $1 AND product_id = $2 AND size = $3`
+	result, err := r.db.Exec(query, DefaultWarehouseID, productID, size)
+	if err != nil {
+		log.Printf("Error executing delete stock query (product: %d, size: %d): %v", productID, size, err)
+		return fmt.Errorf("failed to delete stock: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for delete stock (product: %d, size: %d): %v", productID, size, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w for size %d", ErrStockNotFound, size)
+	}
+
+	return nil
+}
+
+// CommitStock takes the packs of a confirmed order out of the stock of a product in the default
+// warehouse in a single transaction. Sizes without a stock level are not tracked and left alone;
+// if any tracked size runs short nothing is taken out and ErrInsufficientStock is returned.
+func (r *Repository) CommitStock(productID int, packs map[int]int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting stock commit: %v", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE stock SET quantity = quantity - $4 WHERE warehouse_id = $1 AND product_id = $2 AND size = $3`
+	for _, size := range packSizes(packs) {
+		if _, err := tx.Exec(query, DefaultWarehouseID, productID, size, packs[size]); err != nil {
+			log.Printf("Error committing stock (product: %d, size: %d, count: %d): %v", productID, size, packs[size], err)
+			return fmt.Errorf("failed to commit stock for size %d: %w", size, stockError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing stock transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// packSizes returns the sizes with packs in ascending order. Stock rows are locked in this
+// order so concurrent transactions taking packs out cannot deadlock.
+func packSizes(packs map[int]int) []int {
+	sizes := make([]int, 0, len(packs))
+	for size, count := range packs {
+		if count > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+// stockError turns a violated non-negative stock constraint into ErrInsufficientStock
+func stockError(err error) error {
+	if isPQError(err, checkViolation) {
+		return ErrInsufficientStock
+	}
+	return err
+}
@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_GetStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      []StockLevel
+		wantErr   bool
+	}{
+		{
+			name: "successful get",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"size", "quantity"}).
+					AddRow(250, 40).
+					AddRow(5000, 0)
+				mock.ExpectQuery(`SELECT size, quantity FROM stock WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(1, 1).
+					WillReturnRows(rows)
+			},
+			want:    []StockLevel{{Size: 250, Quantity: 40}, {Size: 5000, Quantity: 0}},
+			wantErr: false,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT size, quantity FROM stock WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+					WithArgs(1, 1).
+					WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.GetStock(1)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_SetStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`INSERT INTO stock \(warehouse_id, product_id, size, quantity\) VALUES \(\$1, \$2, \$3, \$4\)\s+ON CONFLICT \(warehouse_id, product_id, size\) DO UPDATE SET quantity = EXCLUDED.quantity`).
+		WithArgs(1, 2, 500, 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock`).
+		WithArgs(1, 9, 500, 12).
+		WillReturnError(&pq.Error{Code: foreignKeyViolation})
+
+	require.NoError(t, repo.SetStock(2, 500, 12))
+	require.ErrorIs(t, repo.SetStock(9, 500, 12), ErrProductNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_AdjustStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		delta     int
+		setupMock func(sqlmock.Sqlmock)
+		want      StockLevel
+		wantErr   error
+	}{
+		{
+			name:  "successful adjust",
+			delta: -5,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE stock SET quantity = quantity \+ \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3 RETURNING quantity`).
+					WithArgs(1, 2, 500, -5).
+					WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(7))
+			},
+			want: StockLevel{Size: 500, Quantity: 7},
+		},
+		{
+			name:  "not tracked",
+			delta: 3,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE stock SET quantity = quantity \+ \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3 RETURNING quantity`).
+					WithArgs(1, 2, 500, 3).
+					WillReturnRows(sqlmock.NewRows([]string{"quantity"}))
+			},
+			wantErr: ErrStockNotFound,
+		},
+		{
+			name:  "below zero",
+			delta: -50,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE stock SET quantity = quantity \+ \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3 RETURNING quantity`).
+					WithArgs(1, 2, 500, -50).
+					WillReturnError(&pq.Error{Code: checkViolation})
+			},
+			wantErr: ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.AdjustStock(2, 500, tt.delta)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_DeleteStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`DELETE FROM stock WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+		WithArgs(1, 2, 500).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM stock WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+		WithArgs(1, 2, 750).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeleteStock(2, 500))
+	require.ErrorIs(t, repo.DeleteStock(2, 750), ErrStockNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CommitStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "successful commit",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE stock SET quantity = quantity - \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+					WithArgs(1, 2, 250, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE stock SET quantity = quantity - \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+					WithArgs(1, 2, 5000, 2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "insufficient stock rolls back",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE stock SET quantity = quantity - \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+					WithArgs(1, 2, 250, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE stock SET quantity = quantity - \$4 WHERE warehouse_id = \$1 AND product_id = \$2 AND size = \$3`).
+					WithArgs(1, 2, 5000, 2).
+					WillReturnError(&pq.Error{Code: checkViolation})
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			err = repo.CommitStock(2, map[int]int{5000: 2, 250: 1, 500: 0})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrWarehouseNotFound is returned when a warehouse does not exist
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+	// ErrWarehouseExists is returned when a warehouse name is already taken
+	ErrWarehouseExists = errors.New("warehouse already exists")
+)
+
+// DefaultWarehouseID is the warehouse the packs and stock of the single warehouse setup belong to
+const DefaultWarehouseID = 1
+
+// Warehouse is a location orders are shipped from, with a pack catalog and stock of its own
+type Warehouse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Latitude and Longitude locate the warehouse in degrees, nil when the location is not known
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+func (r *Repository) GetWarehouses() ([]Warehouse, error) {
+	query := `SELECT id, name, latitude, longitude FROM warehouse ORDER BY id`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Printf("Error querying warehouses: %v", err)
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	warehouses := make([]Warehouse, 0)
+	for rows.Next() {
+		var warehouse Warehouse
+		if err := rows.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Latitude, &warehouse.Longitude); err != nil {
+			log.Printf("Error scanning warehouse row: %v", err)
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating warehouse rows: %v", err)
+		return nil, fmt.Errorf("error iterating warehouses: %w", err)
+	}
+
+	return warehouses, nil
+}
+
+func (r *Repository) AddWarehouse(warehouse Warehouse) (Warehouse, error) {
+	query := `INSERT INTO warehouse (name, latitude, longitude) VALUES ($1, $2, $3) RETURNING id`
+	err := r.db.QueryRow(query, warehouse.Name, warehouse.Latitude, warehouse.Longitude).Scan(&warehouse.ID)
+	if err != nil {
+		log.Printf("Error adding warehouse (name: %s): %v", warehouse.Name, err)
+		if isPQError(err, uniqueViolation) {
+			return warehouse, fmt.Errorf("%w: %s", ErrWarehouseExists, warehouse.Name)
+		}
+		return warehouse, fmt.Errorf("failed to add warehouse: %w", err)
+	}
+	return warehouse, nil
+}
+
+// DeleteWarehouse deletes a warehouse along with its packs and stock
+func (r *Repository) DeleteWarehouse(id int) error {
+	query := `DELETE FROM warehouse WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		log.Printf("Error executing delete warehouse query (id: %d): %v", id, err)
+		return fmt.Errorf("failed to delete warehouse: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected for delete warehouse (id: %d): %v", id, err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrWarehouseNotFound, id)
+	}
+
+	return nil
+}
+
+// warehouseViolation reports whether err is a failed foreign key to the warehouse table
+func warehouseViolation(err error) bool {
+	var pqErr *pq.Error
+	return isPQError(err, foreignKeyViolation) && errors.As(err, &pqErr) &&
+		(pqErr.Constraint == "package_warehouse_id_fkey" || pqErr.Constraint == "stock_warehouse_id_fkey")
+}
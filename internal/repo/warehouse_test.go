@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_GetWarehouses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`SELECT id, name, latitude, longitude FROM warehouse ORDER BY id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude"}).
+			AddRow(1, "main", nil, nil).
+			AddRow(2, "north", 53.55, 9.99))
+	mock.ExpectQuery(`SELECT id, name, latitude, longitude FROM warehouse ORDER BY id`).
+		WillReturnError(sql.ErrConnDone)
+
+	got, err := repo.GetWarehouses()
+	require.NoError(t, err)
+	latitude, longitude := 53.55, 9.99
+	require.Equal(t, []Warehouse{
+		{ID: 1, Name: "main"},
+		{ID: 2, Name: "north", Latitude: &latitude, Longitude: &longitude},
+	}, got)
+
+	got, err = repo.GetWarehouses()
+	require.Error(t, err)
+	require.Nil(t, got)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_AddWarehouse(t *testing.T) {
+	latitude, longitude := 53.55, 9.99
+	warehouse := Warehouse{Name: "north", Latitude: &latitude, Longitude: &longitude}
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "successful add",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO warehouse \(name, latitude, longitude\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
+					WithArgs("north", 53.55, 9.99).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			},
+		},
+		{
+			name: "name taken",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO warehouse`).
+					WithArgs("north", 53.55, 9.99).
+					WillReturnError(&pq.Error{Code: uniqueViolation})
+			},
+			wantErr: ErrWarehouseExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{db: db}
+			tt.setupMock(mock)
+
+			got, err := repo.AddWarehouse(warehouse)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				want := warehouse
+				want.ID = 2
+				require.Equal(t, want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_DeleteWarehouse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectExec(`DELETE FROM warehouse WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM warehouse WHERE id = \$1`).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeleteWarehouse(2))
+	require.ErrorIs(t, repo.DeleteWarehouse(9), ErrWarehouseNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_WarehouseCatalogAndStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{db: db}
+	mock.ExpectQuery(`FROM package WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "warehouse_id", "size", "price", "handling_cost", "min_count", "max_count", "inner_id", "inner_count", "length", "width", "height", "weight"}).
+			AddRow(4, 1, 2, 500, 2.0, 0.0, 0, 0, 0, 0, 0, 0, 0, 0))
+	mock.ExpectQuery(`SELECT size, quantity FROM stock WHERE warehouse_id = \$1 AND product_id = \$2 ORDER BY size`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"size", "quantity"}).AddRow(500, 3))
+	mock.ExpectExec(`INSERT INTO stock`).
+		WithArgs(2, 1, 500, 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock`).
+		WithArgs(9, 1, 500, 8).
+		WillReturnError(&pq.Error{Code: foreignKeyViolation, Constraint: "stock_warehouse_id_fkey"})
+
+	catalog, err := repo.GetWarehouseCatalog(2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []Package{{ID: 4, ProductID: 1, WarehouseID: 2, Size: 500, Price: 2.0}}, catalog)
+
+	stock, err := repo.GetWarehouseStock(2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []StockLevel{{Size: 500, Quantity: 3}}, stock)
+
+	require.NoError(t, repo.SetWarehouseStock(2, 1, 500, 8))
+	require.ErrorIs(t, repo.SetWarehouseStock(9, 1, 500, 8), ErrWarehouseNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
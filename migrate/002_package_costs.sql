@@ -0,0 +1,3 @@
+-- Pack prices and optional handling costs
+ALTER TABLE package ADD COLUMN IF NOT EXISTS price NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE package ADD COLUMN IF NOT EXISTS handling_cost NUMERIC(12, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Stock levels per pack size, sizes without a row are not tracked and never run out
+CREATE TABLE IF NOT EXISTS stock (
+    size INTEGER PRIMARY KEY,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0)
+);
@@ -0,0 +1,15 @@
+-- Products, each with its own pack catalog and stock
+CREATE TABLE IF NOT EXISTS product (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- Existing packs and stock belong to the default product
+INSERT INTO product (id, name) VALUES (1, 'default') ON CONFLICT (id) DO NOTHING;
+SELECT setval('product_id_seq', (SELECT MAX(id) FROM product));
+
+ALTER TABLE package ADD COLUMN IF NOT EXISTS product_id INTEGER NOT NULL DEFAULT 1 REFERENCES product (id) ON DELETE CASCADE;
+
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS product_id INTEGER NOT NULL DEFAULT 1 REFERENCES product (id) ON DELETE CASCADE;
+ALTER TABLE stock DROP CONSTRAINT IF EXISTS stock_pkey;
+ALTER TABLE stock ADD PRIMARY KEY (product_id, size);
@@ -0,0 +1,7 @@
+-- A product has a single package per size, drop duplicates added before the constraint
+DELETE FROM package a USING package b WHERE a.product_id = b.product_id AND a.size = b.size AND a.id > b.id;
+-- Sizes added before validation could be zero or negative, which no order can use
+DELETE FROM package WHERE size <= 0;
+
+ALTER TABLE package ADD CONSTRAINT package_product_size_key UNIQUE (product_id, size);
+ALTER TABLE package ADD CONSTRAINT package_size_check CHECK (size > 0);
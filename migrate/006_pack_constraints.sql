@@ -0,0 +1,6 @@
+-- Least and most packs of a size per order, 0 for no constraint
+ALTER TABLE package ADD COLUMN IF NOT EXISTS min_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE package ADD COLUMN IF NOT EXISTS max_count INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE package ADD CONSTRAINT package_count_check
+    CHECK (min_count >= 0 AND max_count >= 0 AND (max_count = 0 OR min_count <= max_count));
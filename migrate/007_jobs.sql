@@ -0,0 +1,16 @@
+-- Calculations run in the background; the request and the result are kept as JSON
+CREATE TABLE IF NOT EXISTS job (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    request JSONB NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Workers claim the oldest queued job
+CREATE INDEX IF NOT EXISTS job_queued_idx ON job (id) WHERE status = 'queued';
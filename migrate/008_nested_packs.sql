@@ -0,0 +1,7 @@
+-- Packs holding other packs of the same product, e.g. a case of 4 boxes; packs of loose items
+-- have no inner pack
+ALTER TABLE package ADD COLUMN IF NOT EXISTS inner_id INTEGER REFERENCES package (id);
+ALTER TABLE package ADD COLUMN IF NOT EXISTS inner_count INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE package ADD CONSTRAINT package_inner_check
+    CHECK ((inner_id IS NULL AND inner_count = 0) OR (inner_id IS NOT NULL AND inner_count > 0));
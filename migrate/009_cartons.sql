@@ -0,0 +1,19 @@
+-- Outer dimensions in millimetres and weight in grams of a pack, 0 when they are not known
+ALTER TABLE package ADD COLUMN IF NOT EXISTS length INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE package ADD COLUMN IF NOT EXISTS width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE package ADD COLUMN IF NOT EXISTS height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE package ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE package ADD CONSTRAINT package_dimensions_check
+    CHECK (length >= 0 AND width >= 0 AND height >= 0 AND weight >= 0);
+
+-- Shipping cartons the packs of an order are put in, with their inner dimensions in
+-- millimetres and the most weight in grams they carry
+CREATE TABLE IF NOT EXISTS carton (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    length INTEGER NOT NULL CHECK (length > 0),
+    width INTEGER NOT NULL CHECK (width > 0),
+    height INTEGER NOT NULL CHECK (height > 0),
+    max_weight INTEGER NOT NULL CHECK (max_weight > 0)
+);
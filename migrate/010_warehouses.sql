@@ -0,0 +1,25 @@
+-- Warehouses orders are shipped from, each with its own pack catalog and stock, located by
+-- latitude and longitude in degrees when the location is known
+CREATE TABLE IF NOT EXISTS warehouse (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    CONSTRAINT warehouse_location_check CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+-- Existing packs and stock belong to the main warehouse
+INSERT INTO warehouse (id, name) VALUES (1, 'main') ON CONFLICT (id) DO NOTHING;
+SELECT setval('warehouse_id_seq', (SELECT MAX(id) FROM warehouse));
+
+ALTER TABLE package ADD COLUMN IF NOT EXISTS warehouse_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE package ADD CONSTRAINT package_warehouse_id_fkey
+    FOREIGN KEY (warehouse_id) REFERENCES warehouse (id) ON DELETE CASCADE;
+ALTER TABLE package DROP CONSTRAINT IF EXISTS package_product_size_key;
+ALTER TABLE package ADD CONSTRAINT package_warehouse_product_size_key UNIQUE (warehouse_id, product_id, size);
+
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS warehouse_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE stock ADD CONSTRAINT stock_warehouse_id_fkey
+    FOREIGN KEY (warehouse_id) REFERENCES warehouse (id) ON DELETE CASCADE;
+ALTER TABLE stock DROP CONSTRAINT IF EXISTS stock_pkey;
+ALTER TABLE stock ADD PRIMARY KEY (warehouse_id, product_id, size);
@@ -0,0 +1,16 @@
+-- Orders with the pack plan calculated for them, kept as JSON. The packs are the pack counts per
+-- size of the plan, taken out of the stock while the order is reserved or packed.
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'reserved', 'packed', 'shipped', 'cancelled')),
+    plan JSONB NOT NULL,
+    packs JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Orders are listed by status, newest first
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status, id);
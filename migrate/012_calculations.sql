@@ -0,0 +1,20 @@
+-- History of the /calculate calls. The request, the catalog the order was calculated from and
+-- the result are kept as JSON; the pack sizes are those the result ships, for searching by size.
+CREATE TABLE IF NOT EXISTS calculation (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    caller TEXT NOT NULL DEFAULT '',
+    quantity BIGINT NOT NULL,
+    pack_sizes INTEGER[] NOT NULL DEFAULT '{}',
+    request JSONB NOT NULL,
+    catalog JSONB NOT NULL,
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    solver TEXT NOT NULL DEFAULT '',
+    solver_version INTEGER NOT NULL
+);
+
+-- The history is paged in the order of the time of the call or of the quantity
+CREATE INDEX IF NOT EXISTS calculation_created_idx ON calculation (created_at, id);
+CREATE INDEX IF NOT EXISTS calculation_quantity_idx ON calculation (quantity, id);
+CREATE INDEX IF NOT EXISTS calculation_pack_sizes_idx ON calculation USING GIN (pack_sizes);
@@ -0,0 +1,6 @@
+-- A running job is leased by the worker that claimed it. The worker refreshes heartbeat_at while
+-- it runs, jobs whose heartbeat stopped are queued again for other workers.
+ALTER TABLE job ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE job ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS job_running_idx ON job (heartbeat_at) WHERE status = 'running';